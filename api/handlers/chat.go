@@ -27,6 +27,11 @@ type Chat struct {
 	messageRepo message.MessageRepository
 }
 
+// clientRequest is a frame of the raw JSON protocol spoken before the envelope protocol of the server package.
+type clientRequest struct {
+	Message string `json:"message"`
+}
+
 type WorkerTask struct {
 	Client  *wss.Client
 	Message *models.Message
@@ -120,7 +125,7 @@ func (c *Chat) HandleChatSession() http.HandlerFunc {
 			// Update pre-listen object with valid client.
 			preListen = retrievedClient
 
-			var newElement wss.ClientRequest
+			var newElement clientRequest
 
 			_, p, err := ws.ReadMessage()
 			if err != nil {
@@ -135,9 +140,6 @@ func (c *Chat) HandleChatSession() http.HandlerFunc {
 				break
 			}
 
-			newElement.EntryToken = r.URL.Query().Get("token")
-			newElement.WebSocket = ws
-
 			m := &models.Message{Author: *preListen.User, Message: newElement.Message}
 			if err := c.messageRepo.Create(ctx, m); err != nil {
 				return
@@ -160,13 +162,19 @@ func (c *Chat) retrieveClient(ctx context.Context, token string, ws *websocket.C
 	c.logger.Println("Entry Token is : ", token)
 
 	if clientObj := c.data.LoadClient(token); clientObj != nil {
-		// Update mapped client's web socket.
-		c.data.DeleteClient(clientObj)
+		if clientObj.WebSocket == ws {
+			return clientObj, nil
+		}
+
+		// Resume on a new client, the writer of the old one may still use its web socket.
+		resumed := wss.NewClientObject(clientObj.JoinedAt, clientObj.User, token, ws, wss.ClientOptions{})
+		c.data.StoreToken(token, resumed)
+		c.data.StoreClient(resumed)
 
-		clientObj.WebSocket = ws
-		c.data.StoreClient(clientObj)
+		clientObj.Stop()
+		c.data.DeleteClient(clientObj)
 
-		return clientObj, nil
+		return resumed, nil
 	}
 
 	t, err := c.tokenRepo.Get(ctx, token)
@@ -179,7 +187,7 @@ func (c *Chat) retrieveClient(ctx context.Context, token string, ws *websocket.C
 		return nil, err
 	}
 
-	clientObject := wss.NewClientObject(time.Now(), &u, token, ws)
+	clientObject := wss.NewClientObject(time.Now(), &u, token, ws, wss.ClientOptions{})
 
 	// Invalidate token.
 	if err = c.tokenRepo.Delete(ctx, t.Token); err != nil {
@@ -220,7 +228,7 @@ func (c *Chat) retrieveClient(ctx context.Context, token string, ws *websocket.C
 }
 
 func (c *Chat) broadcastMessage(tasksCh chan WorkerTask, m *models.Message) {
-	for client := range c.data.Clients {
+	for _, client := range c.data.GetAllClients() {
 		go func(c *wss.Client) {
 			tasksCh <- WorkerTask{
 				Client:  c,
@@ -102,13 +102,18 @@ func TestChat_HandleChatSession_ProcessValidMessage(t *testing.T) {
 	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
 	loggerMock.On("Warningln", mock.AnythingOfType("string")).Maybe().Return()
 
-	user := *models.NewUser("testuser", "12345678")
+	user := *mustNewUser("testuser", "12345678")
 	tokenString := generators.RandomString(16)
 
 	tokenRepoMock.On("Get", mock.Anything, tokenString).Return(models.Token{Token: tokenString, UserId: user.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
 	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
 
 	userRepoMock.On("GetById", mock.Anything, user.ID).Return(user, nil)
+	userRepoMock.On("UpdateLastActivity", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	messageRepoMock := &mocks.MessageRepository{}
+	messageRepoMock.On("GetAll", mock.Anything).Return([]models.Message{}, nil)
+	messageRepoMock.On("Create", mock.Anything, mock.AnythingOfType("*models.Message")).Return(nil)
 
 	handlers := &Chat{
 		logger: loggerMock,
@@ -116,9 +121,10 @@ func TestChat_HandleChatSession_ProcessValidMessage(t *testing.T) {
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
 		},
-		data:      wss.NewChatData(),
-		userRepo:  userRepoMock,
-		tokenRepo: tokenRepoMock,
+		data:        wss.NewChatData(),
+		userRepo:    userRepoMock,
+		tokenRepo:   tokenRepoMock,
+		messageRepo: messageRepoMock,
 	}
 
 	s := httptest.NewServer(handlers.HandleChatSession())
@@ -145,13 +151,19 @@ func TestChat_HandleChatSession_ProcessValidMessage(t *testing.T) {
 			t.Fatalf("%v", err)
 		}
 
-		requestMessage := &wss.ClientRequest{}
-		responseMessage := &wss.ClientRequest{}
+		requestMessage := &clientRequest{}
+		responseMessage := &clientRequest{}
+		envelope := &wss.Envelope{}
 
 		json.Unmarshal([]byte(message), requestMessage)
-		err = json.Unmarshal(p, responseMessage)
+		err = json.Unmarshal(p, envelope)
 
 		assert.NoError(t, err, "json should be valid")
+		assert.Equal(t, wss.EventMessage, envelope.Type, "message should be broadcast as message event")
+
+		err = json.Unmarshal(envelope.Payload, responseMessage)
+
+		assert.NoError(t, err, "payload should be valid")
 		assert.Equal(t, requestMessage, responseMessage, "objects should be equal")
 	}
 
@@ -168,6 +180,16 @@ func getChatHandlerMocks() (*mocks.FieldLogger, *mocks.UserRepository, *mocks.To
 	return loggerMock, userRepoMock, tokenRepoMock
 }
 
+// mustNewUser creates a user for tests, it panics if the password cannot be hashed.
+func mustNewUser(username, password string) *models.User {
+	u, err := models.NewUser(username, password)
+	if err != nil {
+		panic(err)
+	}
+
+	return u
+}
+
 func generateClientsData(count int) *wss.ChatData {
 	data := wss.NewChatData()
 
@@ -177,7 +199,7 @@ func generateClientsData(count int) *wss.ChatData {
 
 		client := &wss.Client{
 			JoinedAt:   time.Now(),
-			User:       models.NewUser(username, "password"),
+			User:       mustNewUser(username, "password"),
 			EntryToken: token,
 			IPAddress:  "1.1.1.1",
 			WebSocket:  nil,
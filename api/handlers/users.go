@@ -62,7 +62,13 @@ func (s Users) HandleUserCreate() http.HandlerFunc {
 			return
 		}
 
-		user := models.NewUser(username, password)
+		user, err := models.NewUser(username, password)
+		if err != nil {
+			s.logger.Errorln("Could not hash password. ", err)
+			http.Error(w, fmt.Sprintf("Could not create user %s", username), http.StatusInternalServerError)
+			return
+		}
+
 		if err := s.userRepo.Create(nil, user); err != nil {
 			http.Error(w, fmt.Sprintf("Could not create user %s", username), http.StatusBadRequest)
 			return
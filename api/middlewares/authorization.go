@@ -1,20 +1,52 @@
 package middlewares
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"time"
 
-	"github.com/id-tarzanych/lets-go-chat/db/token"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/pkg/jwt"
 )
 
+type contextKey string
+
+const claimsKey contextKey = "claims"
+
+// UserIdFromContext returns id of the user authenticated by ValidateToken.
+func UserIdFromContext(ctx context.Context) (types.Uuid, bool) {
+	claims, ok := ctx.Value(claimsKey).(jwt.Claims)
+	if !ok {
+		return "", false
+	}
+
+	return types.Uuid(claims.Subject), true
+}
+
+// SessionIdFromContext returns id of the session the access token authenticated by ValidateToken was issued for.
+// It is empty for tokens issued without a session.
+func SessionIdFromContext(ctx context.Context) (string, bool) {
+	claims, ok := ctx.Value(claimsKey).(jwt.Claims)
+
+	return claims.SessionId, ok
+}
+
+// AccessTokenVerifier returns the claims of an access token, its subject is the id of the user it was issued to.
+// Expired tokens are rejected with jwt.ErrExpired.
+type AccessTokenVerifier interface {
+	Verify(token string) (jwt.Claims, error)
+}
+
 type AuthMiddleware struct {
-	tokenRepo token.TokenRepository
+	verifier AccessTokenVerifier
 }
 
-func NewAuthMiddleware(tokenRepo token.TokenRepository) *AuthMiddleware {
-	return &AuthMiddleware{tokenRepo: tokenRepo}
+func NewAuthMiddleware(verifier AccessTokenVerifier) *AuthMiddleware {
+	return &AuthMiddleware{verifier: verifier}
 }
 
+// ValidateToken authenticates requests with the access token in the token query parameter.
+// One-time chat tokens are not access tokens and are rejected, joining the chat consumes them.
 func (a AuthMiddleware) ValidateToken(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		tokenString := r.URL.Query().Get("token")
@@ -24,17 +56,16 @@ func (a AuthMiddleware) ValidateToken(next http.Handler) http.Handler {
 			return
 		}
 
-		requestToken, err := a.tokenRepo.Get(nil, tokenString)
-		if err != nil {
-			http.Error(w, "Access token is invalid.", http.StatusBadRequest)
+		claims, err := a.verifier.Verify(tokenString)
+		if errors.Is(err, jwt.ErrExpired) {
+			http.Error(w, "Access token expired.", http.StatusBadRequest)
 			return
 		}
-
-		if requestToken.Expiration.Before(time.Now()) {
-			http.Error(w, "Access token expired.", http.StatusBadRequest)
+		if err != nil {
+			http.Error(w, "Access token is invalid.", http.StatusBadRequest)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey, claims)))
 	})
 }
@@ -1,28 +1,64 @@
 package middlewares
 
 import (
-	"errors"
+	"encoding/base64"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
-	"github.com/stretchr/testify/mock"
-
+	"github.com/id-tarzanych/lets-go-chat/auth"
+	"github.com/id-tarzanych/lets-go-chat/configurations"
 	"github.com/id-tarzanych/lets-go-chat/internal/testserver"
-	"github.com/id-tarzanych/lets-go-chat/mocks"
-	"github.com/id-tarzanych/lets-go-chat/models"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/pkg/generators"
 )
 
-const (
-	validToken   = "validtoken"
-	invalidToken = "invalidToken"
-	expiredToken = "expiredToken"
+var (
+	signingKey = "test:HS256:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	// forgedKey has the id of signingKey but another secret.
+	forgedKey = "test:HS256:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("f", 32)))
 )
 
+// testTokens returns a valid, a forged and an expired access token of user "uuid".
+func testTokens(t *testing.T) (*auth.AccessTokens, string, string, string) {
+	accessTokens, err := auth.NewAccessTokens(configurations.Auth{SigningKeys: []string{signingKey}, AccessTokenTTL: time.Hour})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	validToken, _, err := accessTokens.Issue("uuid", "session", time.Now())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	expiredToken, _, err := accessTokens.Issue("uuid", "session", time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	forger, err := auth.NewAccessTokens(configurations.Auth{SigningKeys: []string{forgedKey}, AccessTokenTTL: time.Hour})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	invalidToken, _, err := forger.Issue("uuid", "session", time.Now())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return accessTokens, validToken, invalidToken, expiredToken
+}
+
 func TestAuthMiddleware_ValidateToken(t *testing.T) {
 	s := &testserver.Server{}
+	accessTokens, validToken, invalidToken, expiredToken := testTokens(t)
+
+	chatToken, err := generators.Token()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
 
 	tests := []struct {
 		name        string
@@ -47,6 +83,12 @@ func TestAuthMiddleware_ValidateToken(t *testing.T) {
 			wantCode:    http.StatusBadRequest,
 			wantMessage: "Access token is invalid.",
 		},
+		{
+			name:        "Chat Token",
+			req:         httptest.NewRequest(http.MethodGet, "/test?token="+chatToken, nil),
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Access token is invalid.",
+		},
 		{
 			name:        "Expired Token",
 			req:         httptest.NewRequest(http.MethodGet, "/test?token="+expiredToken, nil),
@@ -55,33 +97,11 @@ func TestAuthMiddleware_ValidateToken(t *testing.T) {
 		},
 	}
 
-	tokenRepoMock := &mocks.TokenRepository{}
-
-	tokenRepoMock.On(
-		"Get",
-		mock.Anything,
-		validToken,
-	).Return(*models.NewToken(validToken, "uuid", time.Now().Add(time.Hour*24)), nil)
-
-	tokenRepoMock.On(
-		"Get",
-		mock.Anything,
-		invalidToken,
-	).Return(models.Token{}, errors.New("token not found"))
-
-	tokenRepoMock.On(
-		"Get",
-		mock.Anything,
-		expiredToken,
-	).Return(*models.NewToken(expiredToken, "uuid", time.Now().Add(-24*time.Hour)), nil)
-
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			w := httptest.NewRecorder()
 
-			authMiddleware := &AuthMiddleware{
-				tokenRepo: tokenRepoMock,
-			}
+			authMiddleware := NewAuthMiddleware(accessTokens)
 
 			authMiddleware.ValidateToken(s).ServeHTTP(w, tt.req)
 			result := w.Result()
@@ -97,3 +117,19 @@ func TestAuthMiddleware_ValidateToken(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthMiddleware_ValidateToken_UserId(t *testing.T) {
+	accessTokens, validToken, _, _ := testTokens(t)
+	authMiddleware := NewAuthMiddleware(accessTokens)
+
+	var userId types.Uuid
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userId, _ = UserIdFromContext(r.Context())
+	})
+
+	authMiddleware.ValidateToken(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test?token="+validToken, nil))
+
+	if userId != "uuid" {
+		t.Errorf("Incorrect user id in request context, wanted \"uuid\", got \"%s\"", userId)
+	}
+}
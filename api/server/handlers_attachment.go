@@ -0,0 +1,268 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/id-tarzanych/lets-go-chat/api/middlewares"
+	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+	"github.com/id-tarzanych/lets-go-chat/storage"
+)
+
+const (
+	// maxMessageAttachments caps files sent with a single message.
+	maxMessageAttachments = 10
+	// multipartOverhead leaves room for multipart framing on top of the largest file accepted.
+	multipartOverhead = 64 << 10
+)
+
+func (s Server) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	if r.ContentLength > s.maxUploadSize+multipartOverhead {
+		http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUploadSize+multipartOverhead)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "File is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, s.maxUploadSize+1))
+	if err != nil {
+		http.Error(w, "Could not read file", http.StatusBadRequest)
+		return
+	}
+
+	if int64(len(data)) > s.maxUploadSize {
+		http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if len(data) == 0 {
+		http.Error(w, "File is empty", http.StatusBadRequest)
+		return
+	}
+
+	// The type claimed by the client is not trusted, it is detected from file content.
+	contentType := http.DetectContentType(data)
+	if !s.allowedType(contentType) {
+		http.Error(w, fmt.Sprintf("File type %s is not allowed", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	a := &models.Attachment{
+		UploaderUuid: userId,
+		FileName:     filepath.Base(header.Filename),
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		StorageKey:   "attachments/" + uuid.NewString(),
+	}
+
+	if err := s.blobs.Put(r.Context(), a.StorageKey, bytes.NewReader(data), a.Size, a.ContentType); err != nil {
+		s.logger.Errorln("Could not store attachment file. ", err)
+		http.Error(w, "Could not store attachment", http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.attachmentRepo.Create(r.Context(), a); err != nil {
+		s.logger.Errorln("Could not store attachment. ", err)
+
+		if err := s.blobs.Delete(r.Context(), a.StorageKey); err != nil {
+			s.logger.Warningln("Could not remove attachment file. ", err)
+		}
+
+		http.Error(w, "Could not store attachment", http.StatusInternalServerError)
+		return
+	}
+
+	js, _ := json.Marshal(attachmentResponse(a))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+func (s Server) GetAttachment(w http.ResponseWriter, r *http.Request, attachmentId int) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	// Attachments the user can not see are reported missing to not leak their existence.
+	a, err := s.attachmentRepo.GetById(r.Context(), uint(attachmentId))
+	if err != nil || !s.canDownload(r.Context(), userId, &a) {
+		http.Error(w, fmt.Sprintf("Attachment %d does not exist", attachmentId), http.StatusNotFound)
+		return
+	}
+
+	content, err := s.blobs.Get(r.Context(), a.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("Attachment %d does not exist", attachmentId), http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		s.logger.Errorln("Could not load attachment file. ", err)
+		http.Error(w, "Could not load attachment", http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(a.ContentType, "image/") {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if _, err := io.Copy(w, content); err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+// messageAttachments loads attachments listed by a send_message command.
+// Only files uploaded by the sender and not sent with another message yet can be attached.
+func (s Server) messageAttachments(ctx context.Context, client *wss.Client, ids []uint) ([]models.Attachment, error) {
+	if len(ids) > maxMessageAttachments {
+		return nil, wss.NewError(wss.ErrorInvalidPayload, "at most %d attachments are allowed", maxMessageAttachments)
+	}
+
+	attachments := make([]models.Attachment, 0, len(ids))
+	seen := make(map[uint]bool, len(ids))
+
+	for _, id := range ids {
+		if seen[id] {
+			return nil, wss.NewError(wss.ErrorInvalidPayload, "attachment %d is listed twice", id)
+		}
+
+		seen[id] = true
+
+		a, err := s.attachmentRepo.GetById(ctx, id)
+		if err != nil {
+			return nil, wss.NewError(wss.ErrorNotFound, "attachment %d does not exist", id)
+		}
+
+		if a.UploaderUuid != client.User.ID {
+			return nil, wss.NewError(wss.ErrorForbidden, "attachment %d belongs to another user", id)
+		}
+
+		if a.MessageID != nil {
+			return nil, wss.NewError(wss.ErrorInvalidPayload, "attachment %d is already sent", id)
+		}
+
+		attachments = append(attachments, a)
+	}
+
+	return attachments, nil
+}
+
+// canDownload tells whether the user uploaded the attachment or can read the message it was sent with.
+func (s Server) canDownload(ctx context.Context, userId types.Uuid, a *models.Attachment) bool {
+	if a.MessageID == nil {
+		return a.UploaderUuid == userId
+	}
+
+	m, err := s.messageRepo.GetById(ctx, *a.MessageID)
+	if err != nil {
+		return false
+	}
+
+	rooms, err := s.roomRepo.GetByMember(ctx, userId)
+	if err != nil {
+		return false
+	}
+
+	return newAudience(userId, rooms).Includes(&m)
+}
+
+// allowedType tells whether the media type of contentType is configured as allowed for attachments.
+func (s Server) allowedType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range s.allowedTypes {
+		if strings.EqualFold(strings.TrimSpace(allowed), mediaType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func attachmentResponse(a *models.Attachment) Attachment {
+	return Attachment{
+		Id:          int(a.ID),
+		FileName:    a.FileName,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		Url:         wss.AttachmentURL(a.ID),
+	}
+}
+
+func (s Server) runAttachmentSweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.sweepAttachments(context.Background(), now)
+	}
+}
+
+// sweepAttachments removes uploads that were not sent with a message within the TTL, with their files.
+func (s Server) sweepAttachments(ctx context.Context, now time.Time) {
+	attachments, err := s.attachmentRepo.GetUnattached(ctx, now.Add(-s.unattachedTTL))
+	if err != nil {
+		s.logger.Errorln("Could not load expired attachments. ", err)
+		return
+	}
+
+	for _, a := range attachments {
+		// An attachment sent since it was loaded is kept.
+		deleted, err := s.attachmentRepo.DeleteUnattached(ctx, a.ID)
+		if err != nil {
+			s.logger.Errorln("Could not delete expired attachment. ", err)
+			continue
+		}
+
+		if !deleted {
+			continue
+		}
+
+		if err := s.blobs.Delete(ctx, a.StorageKey); err != nil {
+			s.logger.Warningln("Could not remove attachment file. ", err)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/id-tarzanych/lets-go-chat/mocks"
+	"github.com/id-tarzanych/lets-go-chat/models"
+	"github.com/id-tarzanych/lets-go-chat/storage"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestServer_UploadAttachment(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+	attachmentRepoMock := &mocks.AttachmentRepository{}
+
+	alice := *mustNewUser("alice", "12345678")
+
+	tokenRepoMock.On("Get", mock.Anything, "aliceToken").Return(models.Token{Token: "aliceToken", UserId: alice.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+	tokenRepoMock.On("Get", mock.Anything, mock.Anything).Return(models.Token{}, errors.New("record not found"))
+
+	attachmentRepoMock.On("Create", mock.Anything, mock.AnythingOfType("*models.Attachment")).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Attachment).ID = 5
+	}).Return(nil)
+
+	blobs, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.attachmentRepo = attachmentRepoMock
+	srv.blobs = blobs
+	srv.maxUploadSize = 64
+	srv.allowedTypes = []string{"image/png", "text/plain"}
+
+	tests := []struct {
+		name     string
+		token    string
+		field    string
+		content  []byte
+		wantCode int
+		want     Attachment
+	}{
+		{
+			name:     "Invalid token",
+			token:    "stolenToken",
+			field:    "file",
+			content:  pngHeader,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Missing file",
+			token:    "aliceToken",
+			field:    "document",
+			content:  pngHeader,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Empty file",
+			token:    "aliceToken",
+			field:    "file",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Too large",
+			token:    "aliceToken",
+			field:    "file",
+			content:  bytes.Repeat([]byte("a"), 65),
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "Disallowed type",
+			token:    "aliceToken",
+			field:    "file",
+			content:  []byte("%PDF-1.4\n"),
+			wantCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:     "Image",
+			token:    "aliceToken",
+			field:    "file",
+			content:  pngHeader,
+			wantCode: http.StatusCreated,
+			want:     Attachment{Id: 5, FileName: "cat.png", ContentType: "image/png", Size: int64(len(pngHeader)), Url: "/chat/attachments/5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			form := multipart.NewWriter(body)
+			part, _ := form.CreateFormFile(tt.field, "../../cat.png")
+			_, _ = part.Write(tt.content)
+			_ = form.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/chat/attachments?token="+tt.token, body)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantCode == http.StatusCreated {
+				response := Attachment{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+				assert.Equal(t, tt.want, response)
+			}
+		})
+	}
+}
+
+func TestServer_GetAttachment(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+	attachmentRepoMock := &mocks.AttachmentRepository{}
+
+	alice := *mustNewUser("alice", "12345678")
+	bob := *mustNewUser("bob", "12345678")
+	carol := *mustNewUser("carol", "12345678")
+
+	for _, u := range []models.User{alice, bob, carol} {
+		tokenRepoMock.On("Get", mock.Anything, u.UserName+"Token").Return(models.Token{Token: u.UserName + "Token", UserId: u.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+		roomRepoMock.On("GetByMember", mock.Anything, u.ID).Return([]models.Room{}, nil)
+	}
+
+	blobs, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	dm := models.Message{AuthorUuid: alice.ID, RecipientUuid: &bob.ID, Message: "look"}
+	dm.ID = 7
+
+	draft := models.Attachment{UploaderUuid: alice.ID, FileName: "cat.png", ContentType: "image/png", Size: int64(len(pngHeader)), StorageKey: "attachments/draft"}
+	draft.ID = 1
+
+	sent := models.Attachment{UploaderUuid: alice.ID, MessageID: &dm.ID, FileName: "notes.txt", ContentType: "text/plain; charset=utf-8", Size: 5, StorageKey: "attachments/sent"}
+	sent.ID = 2
+
+	lost := models.Attachment{UploaderUuid: alice.ID, FileName: "lost.txt", ContentType: "text/plain; charset=utf-8", Size: 5, StorageKey: "attachments/lost"}
+	lost.ID = 3
+
+	_ = blobs.Put(context.Background(), draft.StorageKey, bytes.NewReader(pngHeader), draft.Size, draft.ContentType)
+	_ = blobs.Put(context.Background(), sent.StorageKey, strings.NewReader("hello"), sent.Size, sent.ContentType)
+
+	for _, a := range []models.Attachment{draft, sent, lost} {
+		attachmentRepoMock.On("GetById", mock.Anything, a.ID).Return(a, nil)
+	}
+	attachmentRepoMock.On("GetById", mock.Anything, mock.Anything).Return(models.Attachment{}, errors.New("record not found"))
+	messageRepoMock.On("GetById", mock.Anything, dm.ID).Return(dm, nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.attachmentRepo = attachmentRepoMock
+	srv.blobs = blobs
+
+	tests := []struct {
+		name            string
+		url             string
+		wantCode        int
+		wantBody        string
+		wantDisposition string
+	}{
+		{
+			name:     "Unknown attachment",
+			url:      "/chat/attachments/42?token=aliceToken",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:            "Unsent attachment of the uploader",
+			url:             "/chat/attachments/1?token=aliceToken",
+			wantCode:        http.StatusOK,
+			wantBody:        string(pngHeader),
+			wantDisposition: `inline; filename=cat.png`,
+		},
+		{
+			name:     "Unsent attachment of another user",
+			url:      "/chat/attachments/1?token=bobToken",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:            "Attachment of a direct message to the user",
+			url:             "/chat/attachments/2?token=bobToken",
+			wantCode:        http.StatusOK,
+			wantBody:        "hello",
+			wantDisposition: `attachment; filename=notes.txt`,
+		},
+		{
+			name:     "Attachment of a direct message to another user",
+			url:      "/chat/attachments/2?token=carolToken",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Missing file",
+			url:      "/chat/attachments/3?token=aliceToken",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantCode == http.StatusOK {
+				assert.Equal(t, tt.wantBody, w.Body.String())
+				assert.Equal(t, tt.wantDisposition, w.Header().Get("Content-Disposition"))
+				assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+			}
+		})
+	}
+}
+
+func TestServer_SweepAttachments(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+	attachmentRepoMock := &mocks.AttachmentRepository{}
+
+	now := time.Now()
+
+	expired := models.Attachment{ID: 1, StorageKey: "attachments/expired"}
+	sent := models.Attachment{ID: 2, StorageKey: "attachments/sent"}
+	failing := models.Attachment{ID: 3, StorageKey: "attachments/failing"}
+
+	attachmentRepoMock.On("GetUnattached", mock.Anything, now.Add(-time.Hour)).Return([]models.Attachment{expired, sent, failing}, nil)
+	attachmentRepoMock.On("DeleteUnattached", mock.Anything, expired.ID).Return(true, nil).Once()
+	attachmentRepoMock.On("DeleteUnattached", mock.Anything, sent.ID).Return(false, nil).Once()
+	attachmentRepoMock.On("DeleteUnattached", mock.Anything, failing.ID).Return(false, errors.New("connection lost")).Once()
+
+	loggerMock.On("Errorln", "Could not delete expired attachment. ", mock.Anything).Return().Once()
+
+	blobs, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, a := range []models.Attachment{expired, sent, failing} {
+		_ = blobs.Put(context.Background(), a.StorageKey, bytes.NewReader(pngHeader), int64(len(pngHeader)), "image/png")
+	}
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.attachmentRepo = attachmentRepoMock
+	srv.blobs = blobs
+	srv.unattachedTTL = time.Hour
+
+	srv.sweepAttachments(context.Background(), now)
+
+	tests := []struct {
+		name     string
+		key      string
+		wantKept bool
+	}{
+		{"Expired", expired.StorageKey, false},
+		{"Sent meanwhile", sent.StorageKey, true},
+		{"Delete failure", failing.StorageKey, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := blobs.Get(context.Background(), tt.key)
+			if err == nil {
+				_ = content.Close()
+			}
+
+			assert.Equal(t, tt.wantKept, err == nil, "unexpected file state")
+		})
+	}
+
+	attachmentRepoMock.AssertExpectations(t)
+	loggerMock.AssertExpectations(t)
+}
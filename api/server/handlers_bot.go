@@ -0,0 +1,283 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/id-tarzanych/lets-go-chat/api/middlewares"
+	"github.com/id-tarzanych/lets-go-chat/db/user"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+	"github.com/id-tarzanych/lets-go-chat/pkg/generators"
+)
+
+var (
+	errBotNotFound    = errors.New("bot not found")
+	errBotKeyNotFound = errors.New("API key not found")
+)
+
+func (s Server) LoginBot(w http.ResponseWriter, r *http.Request) {
+	var reqBody LoginBotJSONRequestBody
+
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		http.Error(w, "Syntax error", http.StatusBadRequest)
+		return
+	}
+
+	apiKey := strings.TrimSpace(reqBody.ApiKey)
+	if apiKey == "" {
+		http.Error(w, "Empty API key", http.StatusBadRequest)
+		return
+	}
+
+	key, err := s.apiKeyRepo.GetByKey(r.Context(), apiKey)
+	if err != nil {
+		http.Error(w, "Invalid API key", http.StatusBadRequest)
+		return
+	}
+
+	bot, err := s.userRepo.GetById(r.Context(), key.UserId)
+	if err != nil || !bot.Bot {
+		http.Error(w, "Invalid API key", http.StatusBadRequest)
+		return
+	}
+
+	token, err := s.issueChatToken(r.Context(), bot.ID, "")
+	if err != nil {
+		http.Error(w, "Could not generate one-time token", http.StatusInternalServerError)
+		return
+	}
+
+	respBody := LoginBotResponse{Url: chatURL(r, token), Id: string(bot.ID), UserName: bot.UserName}
+
+	js, _ := json.Marshal(respBody)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Expires-After", token.Expiration.Format(time.RFC1123))
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+func (s Server) CreateBot(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	var reqBody CreateBotJSONRequestBody
+
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		http.Error(w, "Syntax error", http.StatusBadRequest)
+		return
+	}
+
+	username := strings.TrimSpace(reqBody.UserName)
+	if len(username) < minUserNameLength || strings.IndexFunc(username, unicode.IsSpace) >= 0 {
+		http.Error(w, fmt.Sprintf("User name must have at least %d characters and no spaces", minUserNameLength), http.StatusBadRequest)
+		return
+	}
+
+	owner, err := s.userRepo.GetById(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Could not create bot", http.StatusInternalServerError)
+		return
+	}
+
+	if owner.Bot {
+		http.Error(w, "Bots can not create bots", http.StatusForbidden)
+		return
+	}
+
+	if _, err := s.userRepo.GetByUserName(r.Context(), username); err == nil {
+		http.Error(w, fmt.Sprintf("User with username %s already exists", username), http.StatusBadRequest)
+		return
+	}
+
+	bot := models.NewBot(username, owner.ID)
+	if err := s.userRepo.Create(r.Context(), bot); err != nil {
+		if errors.Is(err, user.ErrUserNameTaken) {
+			http.Error(w, fmt.Sprintf("User with username %s already exists", username), http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, fmt.Sprintf("Could not create bot %s", username), http.StatusInternalServerError)
+		return
+	}
+
+	key, err := s.issueAPIKey(r.Context(), bot.ID)
+	if err != nil {
+		s.logger.Errorln("Could not issue API key. ", err)
+		http.Error(w, fmt.Sprintf("Could not issue API key for bot %s", username), http.StatusInternalServerError)
+		return
+	}
+
+	respBody := CreateBotResponse{Id: string(bot.ID), UserName: bot.UserName, Key: key}
+
+	js, _ := json.Marshal(respBody)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+func (s Server) ListBotKeys(w http.ResponseWriter, r *http.Request, botId string) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	bot, err := s.managedBot(r.Context(), userId, types.Uuid(botId))
+	if err != nil {
+		s.botError(w, err, botId)
+		return
+	}
+
+	keys, err := s.apiKeyRepo.GetByUserId(r.Context(), bot.ID)
+	if err != nil {
+		s.botError(w, err, botId)
+		return
+	}
+
+	respBody := BotKeysResponse{Keys: make([]BotKey, 0, len(keys))}
+	for i := range keys {
+		respBody.Keys = append(respBody.Keys, botKeyResponse(&keys[i], ""))
+	}
+
+	js, _ := json.Marshal(respBody)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+func (s Server) CreateBotKey(w http.ResponseWriter, r *http.Request, botId string) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	bot, err := s.managedBot(r.Context(), userId, types.Uuid(botId))
+	if err != nil {
+		s.botError(w, err, botId)
+		return
+	}
+
+	key, err := s.issueAPIKey(r.Context(), bot.ID)
+	if err != nil {
+		s.botError(w, err, botId)
+		return
+	}
+
+	js, _ := json.Marshal(key)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+func (s Server) DeleteBotKey(w http.ResponseWriter, r *http.Request, botId string, keyId int) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	bot, err := s.managedBot(r.Context(), userId, types.Uuid(botId))
+	if err != nil {
+		s.botError(w, err, botId)
+		return
+	}
+
+	key, err := s.apiKeyRepo.GetById(r.Context(), uint(keyId))
+	if err != nil || key.UserId != bot.ID {
+		s.botError(w, errBotKeyNotFound, botId)
+		return
+	}
+
+	if err := s.apiKeyRepo.Delete(r.Context(), key.ID); err != nil {
+		s.botError(w, err, botId)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// managedBot loads a bot the user owns. Admins manage every bot, other users see bots of others as missing.
+func (s Server) managedBot(ctx context.Context, userId, botId types.Uuid) (models.User, error) {
+	bot, err := s.userRepo.GetById(ctx, botId)
+	if err != nil || !bot.Bot {
+		return models.User{}, errBotNotFound
+	}
+
+	if bot.OwnerId != nil && *bot.OwnerId == userId {
+		return bot, nil
+	}
+
+	u, err := s.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if !u.Admin {
+		return models.User{}, errBotNotFound
+	}
+
+	return bot, nil
+}
+
+// issueAPIKey stores a new key of the bot. The key is only part of the returned response, not of the stored record.
+func (s Server) issueAPIKey(ctx context.Context, botId types.Uuid) (BotKey, error) {
+	apiKey, err := generators.Token()
+	if err != nil {
+		return BotKey{}, err
+	}
+
+	key := models.NewAPIKey(apiKey, botId)
+	if err := s.apiKeyRepo.Create(ctx, key); err != nil {
+		return BotKey{}, err
+	}
+
+	return botKeyResponse(key, apiKey), nil
+}
+
+func (s Server) botError(w http.ResponseWriter, err error, botId string) {
+	switch {
+	case errors.Is(err, errBotNotFound):
+		http.Error(w, fmt.Sprintf("Bot %s does not exist", botId), http.StatusNotFound)
+	case errors.Is(err, errBotKeyNotFound):
+		http.Error(w, fmt.Sprintf("Bot %s has no such API key", botId), http.StatusNotFound)
+	default:
+		s.logger.Errorln("Could not manage bot. ", err)
+		http.Error(w, fmt.Sprintf("Could not manage bot %s", botId), http.StatusInternalServerError)
+	}
+}
+
+func botKeyResponse(key *models.APIKey, apiKey string) BotKey {
+	resp := BotKey{Id: int(key.ID), CreatedAt: key.CreatedAt}
+	if apiKey != "" {
+		resp.ApiKey = &apiKey
+	}
+
+	return resp
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/id-tarzanych/lets-go-chat/mocks"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func TestServer_LoginBot(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+	apiKeyRepoMock := &mocks.APIKeyRepository{}
+
+	alice := *mustNewUser("alice", "12345678")
+	robot := *models.NewBot("robot", alice.ID)
+
+	apiKeyRepoMock.On("GetByKey", mock.Anything, "robotKey").Return(*models.NewAPIKey("robotKey", robot.ID), nil)
+	apiKeyRepoMock.On("GetByKey", mock.Anything, "aliceKey").Return(*models.NewAPIKey("aliceKey", alice.ID), nil)
+	apiKeyRepoMock.On("GetByKey", mock.Anything, mock.Anything).Return(models.APIKey{}, errors.New("record not found"))
+	userRepoMock.On("GetById", mock.Anything, robot.ID).Return(robot, nil)
+	userRepoMock.On("GetById", mock.Anything, alice.ID).Return(alice, nil)
+	userRepoMock.On("GetByUserName", mock.Anything, robot.UserName).Return(robot, nil)
+	tokenRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(t *models.Token) bool { return t.UserId == robot.ID })).Return(nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.apiKeyRepo = apiKeyRepoMock
+
+	tests := []struct {
+		name     string
+		url      string
+		body     string
+		wantCode int
+	}{
+		{"Syntax error", "/bot/login", `{"apiKey":`, http.StatusBadRequest},
+		{"Empty key", "/bot/login", `{"apiKey":" "}`, http.StatusBadRequest},
+		{"Unknown key", "/bot/login", `{"apiKey":"stolenKey"}`, http.StatusBadRequest},
+		{"Key of a user", "/bot/login", `{"apiKey":"aliceKey"}`, http.StatusBadRequest},
+		{"Password login of a bot", "/user/login", `{"userName":"robot","password":"12345678"}`, http.StatusBadRequest},
+		{"Bot key", "/bot/login", `{"apiKey":"robotKey"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantCode == http.StatusOK {
+				response := LoginBotResponse{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+				assert.Equal(t, string(robot.ID), response.Id)
+				assert.Equal(t, robot.UserName, response.UserName)
+				assert.True(t, strings.HasPrefix(response.Url, "ws://example.com/chat/ws.rtm.start?token="), "unexpected url %s", response.Url)
+			}
+		})
+	}
+
+	tokenRepoMock.AssertExpectations(t)
+}
+
+func TestServer_CreateBot(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+	apiKeyRepoMock := &mocks.APIKeyRepository{}
+
+	alice := *mustNewUser("alice", "12345678")
+	robot := *models.NewBot("robot", alice.ID)
+
+	for _, u := range []models.User{alice, robot} {
+		tokenRepoMock.On("Get", mock.Anything, u.UserName+"Token").Return(models.Token{Token: u.UserName + "Token", UserId: u.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+		userRepoMock.On("GetById", mock.Anything, u.ID).Return(u, nil)
+	}
+	tokenRepoMock.On("Get", mock.Anything, mock.Anything).Return(models.Token{}, errors.New("record not found"))
+	userRepoMock.On("GetByUserName", mock.Anything, robot.UserName).Return(robot, nil)
+	userRepoMock.On("GetByUserName", mock.Anything, mock.Anything).Return(models.User{}, errors.New("record not found"))
+	userRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+		return u.UserName == "helper" && u.Bot && *u.OwnerId == alice.ID && u.PasswordHash == ""
+	})).Return(nil).Once()
+	apiKeyRepoMock.On("Create", mock.Anything, mock.AnythingOfType("*models.APIKey")).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.APIKey).ID = 3
+	}).Return(nil).Once()
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.apiKeyRepo = apiKeyRepoMock
+
+	tests := []struct {
+		name     string
+		token    string
+		body     string
+		wantCode int
+	}{
+		{"Invalid token", "stolenToken", `{"userName":"helper"}`, http.StatusBadRequest},
+		{"Short name", "aliceToken", `{"userName":"bot"}`, http.StatusBadRequest},
+		{"Taken name", "aliceToken", `{"userName":"robot"}`, http.StatusBadRequest},
+		{"Bot creating a bot", "robotToken", `{"userName":"helper"}`, http.StatusForbidden},
+		{"Bot", "aliceToken", `{"userName":"helper"}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bots?token="+tt.token, strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantCode == http.StatusCreated {
+				response := CreateBotResponse{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+				assert.Equal(t, "helper", response.UserName)
+				assert.Equal(t, 3, response.Key.Id)
+
+				if assert.NotNil(t, response.Key.ApiKey, "key should be returned once") {
+					assert.Len(t, *response.Key.ApiKey, 43)
+				}
+			}
+		})
+	}
+
+	userRepoMock.AssertExpectations(t)
+	apiKeyRepoMock.AssertExpectations(t)
+}
+
+func TestServer_BotKeys(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+	apiKeyRepoMock := &mocks.APIKeyRepository{}
+
+	alice := *mustNewUser("alice", "12345678")
+	bob := *mustNewUser("bob", "12345678")
+	admin := *mustNewUser("admin", "12345678")
+	admin.Admin = true
+	robot := *models.NewBot("robot", alice.ID)
+
+	for _, u := range []models.User{alice, bob, admin, robot} {
+		tokenRepoMock.On("Get", mock.Anything, u.UserName+"Token").Return(models.Token{Token: u.UserName + "Token", UserId: u.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+		userRepoMock.On("GetById", mock.Anything, u.ID).Return(u, nil)
+	}
+	userRepoMock.On("GetById", mock.Anything, mock.Anything).Return(models.User{}, errors.New("record not found"))
+
+	first := models.APIKey{ID: 1, UserId: robot.ID, CreatedAt: time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)}
+	second := models.APIKey{ID: 2, UserId: robot.ID, CreatedAt: time.Date(2021, 12, 2, 0, 0, 0, 0, time.UTC)}
+	foreign := models.APIKey{ID: 9, UserId: bob.ID}
+
+	apiKeyRepoMock.On("GetByUserId", mock.Anything, robot.ID).Return([]models.APIKey{first, second}, nil)
+	apiKeyRepoMock.On("GetById", mock.Anything, second.ID).Return(second, nil)
+	apiKeyRepoMock.On("GetById", mock.Anything, foreign.ID).Return(foreign, nil)
+	apiKeyRepoMock.On("Delete", mock.Anything, second.ID).Return(nil).Once()
+	apiKeyRepoMock.On("Create", mock.Anything, mock.AnythingOfType("*models.APIKey")).Return(nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.apiKeyRepo = apiKeyRepoMock
+
+	botUrl := "/bots/" + string(robot.ID) + "/keys"
+
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		wantCode int
+		wantKeys []int
+	}{
+		{name: "Keys of another user's bot", method: http.MethodGet, url: botUrl + "?token=bobToken", wantCode: http.StatusNotFound},
+		{name: "Keys of a user", method: http.MethodGet, url: "/bots/" + string(bob.ID) + "/keys?token=aliceToken", wantCode: http.StatusNotFound},
+		{name: "Keys of own bot", method: http.MethodGet, url: botUrl + "?token=aliceToken", wantCode: http.StatusOK, wantKeys: []int{1, 2}},
+		{name: "Keys as admin", method: http.MethodGet, url: botUrl + "?token=adminToken", wantCode: http.StatusOK, wantKeys: []int{1, 2}},
+		{name: "Issue key for another user's bot", method: http.MethodPost, url: botUrl + "?token=bobToken", wantCode: http.StatusNotFound},
+		{name: "Issue key", method: http.MethodPost, url: botUrl + "?token=aliceToken", wantCode: http.StatusCreated},
+		{name: "Revoke key of another bot", method: http.MethodDelete, url: botUrl + "/9?token=aliceToken", wantCode: http.StatusNotFound},
+		{name: "Revoke key of another user's bot", method: http.MethodDelete, url: botUrl + "/2?token=bobToken", wantCode: http.StatusNotFound},
+		{name: "Revoke key", method: http.MethodDelete, url: botUrl + "/2?token=aliceToken", wantCode: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantKeys != nil {
+				response := BotKeysResponse{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+
+				ids := make([]int, 0, len(response.Keys))
+				for _, key := range response.Keys {
+					assert.Nil(t, key.ApiKey, "keys are only returned when issued")
+					ids = append(ids, key.Id)
+				}
+
+				assert.Equal(t, tt.wantKeys, ids)
+			}
+		})
+	}
+
+	apiKeyRepoMock.AssertExpectations(t)
+}
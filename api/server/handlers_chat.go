@@ -3,19 +3,47 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/websocket"
 
+	"github.com/id-tarzanych/lets-go-chat/api/middlewares"
 	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
 	"github.com/id-tarzanych/lets-go-chat/models"
+	"github.com/id-tarzanych/lets-go-chat/webhooks"
 )
 
-type WorkerTask struct {
-	Context context.Context
-	Client  *wss.Client
-	Message *models.Message
+var errInvalidChatToken = errors.New("chat token is invalid")
+
+// WsRTMConnect issues the one-time url the authenticated user starts a chat session with.
+func (s Server) WsRTMConnect(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	sessionId, _ := middlewares.SessionIdFromContext(r.Context())
+
+	token, err := s.issueChatToken(r.Context(), userId, sessionId)
+	if err != nil {
+		s.logger.Errorln("Could not issue chat token. ", err)
+		http.Error(w, "Could not generate one-time token", http.StatusInternalServerError)
+		return
+	}
+
+	js, _ := json.Marshal(WsRTMConnectResponse{Url: chatURL(r, token)})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Expires-After", token.Expiration.Format(time.RFC1123))
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
 }
 
 func (s Server) WsRTMStart(w http.ResponseWriter, r *http.Request, params WsRTMStartParams) {
@@ -39,6 +67,8 @@ func (s Server) WsRTMStart(w http.ResponseWriter, r *http.Request, params WsRTMS
 			return
 		}
 
+		preListen.Stop()
+
 		if err := preListen.WebSocket.Close(); err != nil {
 			s.logger.Println(err)
 		}
@@ -64,8 +94,6 @@ func (s Server) WsRTMStart(w http.ResponseWriter, r *http.Request, params WsRTMS
 		// Update pre-listen object with valid client.
 		preListen = retrievedClient
 
-		var newElement wss.ClientRequest
-
 		_, p, err := ws.ReadMessage()
 		if err != nil {
 			s.logger.Println("Client Disconnected: ", err, preListen.EntryToken)
@@ -73,27 +101,96 @@ func (s Server) WsRTMStart(w http.ResponseWriter, r *http.Request, params WsRTMS
 			break
 		}
 
-		if err = json.Unmarshal(p, &newElement); err != nil {
-			s.logger.Warningln("Invalid request. ", err, p)
+		if err := preListen.Touch(); err != nil {
+			s.logger.Println("Client Disconnected: ", err, preListen.EntryToken)
 
 			break
 		}
 
-		newElement.EntryToken = token
-		newElement.WebSocket = ws
+		request, err := wss.DecodeEnvelope(p)
+		if err == nil {
+			err = s.handleCommand(ctx, preListen, request)
+		}
 
-		m := &models.Message{Author: *preListen.User, Message: newElement.Message}
-		if err := s.messageRepo.Create(ctx, m); err != nil {
-			return
+		var protocolErr *wss.Error
+		if errors.As(err, &protocolErr) {
+			if err := preListen.SendError(request.Id, protocolErr); err != nil {
+				s.logger.Errorln(err)
+			}
+
+			continue
 		}
 
-		// Broadcast message.
-		s.broadcastMessage(s.taskCh, ctx, m)
+		if err != nil {
+			s.logger.Errorln("Could not process request. ", err)
+
+			return
+		}
 	}
 }
 
 func (s Server) GetActiveUsers(w http.ResponseWriter, r *http.Request) {
-	respBody := ActiveUsersResponse{Count: len(s.chatData.Clients)}
+	_, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	respBody := ActiveUsersResponse{Count: s.chatData.ClientCount()}
+
+	js, _ := json.Marshal(respBody)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err := w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+func (s Server) GetOnlineUsers(w http.ResponseWriter, r *http.Request) {
+	_, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	now := time.Now()
+
+	respBody := OnlineUsersResponse{Users: []OnlineUser{}}
+	for _, presence := range s.chatData.OnlineUsers() {
+		respBody.Users = append(respBody.Users, OnlineUser{
+			UserName:    presence.User.UserName,
+			JoinedAt:    presence.JoinedAt,
+			IdleSeconds: int(now.Sub(presence.LastActive).Seconds()),
+			Status:      OnlineUserStatus(presence.Status),
+		})
+	}
+
+	js, _ := json.Marshal(respBody)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err := w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+func (s Server) GetChatMetrics(w http.ResponseWriter, r *http.Request) {
+	_, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	metrics := s.chatData.QueueMetrics()
+
+	respBody := ChatMetricsResponse{
+		Clients:        metrics.Clients,
+		QueuedFrames:   metrics.QueuedFrames,
+		MaxQueueDepth:  metrics.MaxQueueDepth,
+		DroppedFrames:  int(metrics.DroppedFrames),
+		EvictedClients: int(metrics.EvictedClients),
+	}
 
 	js, _ := json.Marshal(respBody)
 
@@ -113,13 +210,25 @@ func (s Server) chuckClient(client *wss.Client) {
 
 func (s Server) retrieveClient(ctx context.Context, token string, ws *websocket.Conn) (*wss.Client, error) {
 	if clientObj := s.chatData.LoadClient(token); clientObj != nil {
-		// Update mapped client's web socket.
+		if clientObj.WebSocket == ws {
+			return clientObj, nil
+		}
+
+		// Resume the session on the new web socket.
+		// The new client is stored first, so the user does not appear to leave and rejoin.
+		resumed := wss.NewClientObject(clientObj.JoinedAt, clientObj.User, token, ws, s.clientOptions)
+		resumed.SessionId = clientObj.SessionId
+		s.chatData.StoreToken(token, resumed)
+		s.chatData.StoreClient(resumed)
+
+		clientObj.Stop()
 		s.chatData.DeleteClient(clientObj)
 
-		clientObj.WebSocket = ws
-		s.chatData.StoreClient(clientObj)
+		if _, err := s.restoreRooms(ctx, resumed); err != nil {
+			return resumed, err
+		}
 
-		return clientObj, nil
+		return resumed, nil
 	}
 
 	t, err := s.tokenRepo.Get(ctx, token)
@@ -127,41 +236,65 @@ func (s Server) retrieveClient(ctx context.Context, token string, ws *websocket.
 		return nil, err
 	}
 
+	// Refresh tokens can not start a chat, they are only exchanged for access tokens.
+	if t.IsRefresh() {
+		return nil, errInvalidChatToken
+	}
+
+	if t.Expiration.Before(time.Now()) {
+		return nil, errInvalidChatToken
+	}
+
 	u, err := s.userRepo.GetById(ctx, t.UserId)
 	if err != nil {
 		return nil, err
 	}
 
-	clientObject := wss.NewClientObject(time.Now(), &u, token, ws)
+	clientObject := wss.NewClientObject(time.Now(), &u, token, ws, s.clientOptions)
+	clientObject.SessionId = t.FamilyId
 
 	// Invalidate token.
-	if err = s.tokenRepo.Delete(ctx, t.Token); err != nil {
+	if err = s.tokenRepo.Delete(ctx, token); err != nil {
 		return nil, err
 	}
 
-	// Retrieve missed messages.
+	audience, err := s.restoreRooms(ctx, clientObject)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retrieve missed messages, older history is available through GET /chat/messages.
 	var missedMessages []models.Message
 	if clientObject.User.LastActivity.IsZero() {
-		missedMessages, err = s.messageRepo.GetAll(ctx)
+		missedMessages, err = s.messageRepo.GetPageFor(ctx, audience, message.Cursor{Limit: s.historyReplayLimit})
 	} else {
-		missedMessages, err = s.messageRepo.GetNewerThan(ctx, clientObject.User.LastActivity)
+		missedMessages, err = s.messageRepo.GetNewerThanFor(ctx, audience, clientObject.User.LastActivity, s.historyReplayLimit)
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
-	var lastMessage models.Message
+	if err := s.attachReactions(ctx, missedMessages); err != nil {
+		return nil, err
+	}
+
+	// Old messages are replayed when they were edited, deleted or replied to since, the cursor
+	// follows the latest of these changes and never moves backwards.
+	lastActivity := clientObject.User.LastActivity
 	for i := range missedMessages {
-		lastMessage = missedMessages[i]
-		err := clientObject.SendMessage(&lastMessage)
+		err := clientObject.SendMessage(&missedMessages[i])
 		if err != nil {
 			return nil, err
 		}
+
+		if changedAt := missedMessages[i].ChangedAt(); changedAt.After(lastActivity) {
+			lastActivity = changedAt
+		}
 	}
 
-	if len(missedMessages) > 0 {
-		if err := s.userRepo.UpdateLastActivity(ctx, clientObject.User, lastMessage.CreatedAt); err != nil {
+	if lastActivity.After(clientObject.User.LastActivity) {
+		if err := s.userRepo.UpdateLastActivity(ctx, clientObject.User, lastActivity); err != nil {
 			return nil, err
 		}
 	}
@@ -175,14 +308,137 @@ func (s Server) retrieveClient(ctx context.Context, token string, ws *websocket.
 	return clientObject, nil
 }
 
-func (s Server) broadcastMessage(tasksCh chan WorkerTask, ctx context.Context, m *models.Message) {
-	for client := range s.chatData.Clients {
-		go func(c *wss.Client) {
-			tasksCh <- WorkerTask{
-				Context: ctx,
-				Client:  c,
-				Message: m,
-			}
-		}(client)
+// restoreRooms subscribes client to live broadcasts of every room its user is a member of.
+func (s Server) restoreRooms(ctx context.Context, client *wss.Client) (message.Audience, error) {
+	rooms, err := s.roomRepo.GetByMember(ctx, client.User.ID)
+	if err != nil {
+		return message.Audience{}, err
+	}
+
+	for i := range rooms {
+		s.chatData.JoinRoom(rooms[i].ID, client)
+	}
+
+	return newAudience(client.User.ID, rooms), nil
+}
+
+// newAudience describes what a member of rooms is allowed to read.
+func newAudience(userId types.Uuid, rooms []models.Room) message.Audience {
+	audience := message.Audience{UserId: userId, RoomIds: make([]uint, 0, len(rooms))}
+	for i := range rooms {
+		audience.RoomIds = append(audience.RoomIds, rooms[i].ID)
+	}
+
+	return audience
+}
+
+// deliverMessage pushes a message event to every live session that can see the message,
+// except origin which learns the outcome of its own command from an ack.
+// Room and public messages are echoed back to origin as well.
+func (s Server) deliverMessage(event string, m *models.Message, origin *wss.Client) {
+	if m.RecipientUuid != nil {
+		s.sendDirectMessage(event, m, origin)
+
+		return
+	}
+
+	s.broadcastMessage(event, m)
+
+	// Direct messages stay private to their participants and are never sent to webhooks.
+	s.publishWebhookEvent(messageWebhookEvents[event], wss.NewMessagePayload(m))
+}
+
+func (s Server) broadcastMessage(event string, m *models.Message) {
+	s.dispatchMessage(event, m, s.chatData.GetRecipients(m.RoomID))
+}
+
+// sendDirectMessage delivers a direct message to every live session of its recipient
+// and to the author's sessions other than the one it was sent from.
+func (s Server) sendDirectMessage(event string, m *models.Message, origin *wss.Client) {
+	clients := s.chatData.GetUserClients(*m.RecipientUuid)
+
+	if *m.RecipientUuid != m.AuthorUuid {
+		clients = append(clients, s.chatData.GetUserClients(m.AuthorUuid)...)
+	}
+
+	recipients := make([]*wss.Client, 0, len(clients))
+	for _, client := range clients {
+		if client != origin {
+			recipients = append(recipients, client)
+		}
+	}
+
+	s.dispatchMessage(event, m, recipients)
+}
+
+// conversationClients returns every live session taking part in the conversation of the message.
+func (s Server) conversationClients(m *models.Message) []*wss.Client {
+	if m.RecipientUuid == nil {
+		return s.chatData.GetRecipients(m.RoomID)
+	}
+
+	clients := s.chatData.GetUserClients(m.AuthorUuid)
+	if *m.RecipientUuid != m.AuthorUuid {
+		clients = append(clients, s.chatData.GetUserClients(*m.RecipientUuid)...)
+	}
+
+	return clients
+}
+
+// dispatchMessage queues the event on every client without waiting for slow sockets.
+func (s Server) dispatchMessage(event string, m *models.Message, clients []*wss.Client) {
+	payload := wss.NewMessagePayload(m)
+
+	for _, client := range clients {
+		var onWritten func()
+
+		// Only newly posted messages move the replay cursor forward.
+		if event == wss.EventMessage {
+			onWritten = s.activityUpdater(client.User, m.CreatedAt)
+		}
+
+		if err := client.SendEventFunc(event, "", payload, onWritten); err != nil {
+			s.logger.Warningln("Could not deliver message. ", err)
+		}
+	}
+}
+
+func (s Server) activityUpdater(u *models.User, lastActivity time.Time) func() {
+	return func() {
+		if err := s.userRepo.UpdateLastActivity(context.Background(), u, lastActivity); err != nil {
+			s.logger.Errorln(err)
+		}
+	}
+}
+
+// announcePresence tells everyone else that the user connected its first session or closed its last one.
+func (s Server) announcePresence(event string, u *models.User) {
+	status := wss.StatusOffline
+	if event == wss.EventUserJoined {
+		status = s.chatData.Status(u.ID)
+	}
+
+	s.broadcastPresence(event, wss.PresencePayload{User: u.UserName, Status: status}, func(client *wss.Client) bool {
+		return client.User != nil && client.User.ID != u.ID
+	})
+
+	webhookEvent := models.WebhookUserLeft
+	if event == wss.EventUserJoined {
+		webhookEvent = models.WebhookUserJoined
+	}
+
+	s.publishWebhookEvent(webhookEvent, webhooks.NewUserData(u))
+}
+
+// broadcastPresence queues a presence event on every live session accepted by filter.
+func (s Server) broadcastPresence(event string, payload wss.PresencePayload, filter func(client *wss.Client) bool) {
+	for _, client := range s.chatData.GetAllClients() {
+		if !filter(client) {
+			continue
+		}
+
+		if err := client.SendEvent(event, "", payload); err != nil {
+			s.logger.Warningln("Could not deliver presence. ", err)
+		}
 	}
 }
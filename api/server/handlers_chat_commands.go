@@ -0,0 +1,510 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+type commandHandler func(s Server, ctx context.Context, client *wss.Client, request wss.Envelope) error
+
+// commandHandlers maps client command types to their handlers.
+// Handlers report client mistakes as *wss.Error, any other error terminates the session.
+var commandHandlers = map[string]commandHandler{
+	wss.CommandSendMessage:    Server.sendMessageCommand,
+	wss.CommandEditMessage:    Server.editMessageCommand,
+	wss.CommandDeleteMessage:  Server.deleteMessageCommand,
+	wss.CommandJoinRoom:       Server.joinRoomCommand,
+	wss.CommandLeaveRoom:      Server.leaveRoomCommand,
+	wss.CommandSetStatus:      Server.setStatusCommand,
+	wss.CommandTyping:         Server.typingCommand,
+	wss.CommandMarkRead:       Server.markReadCommand,
+	wss.CommandAddReaction:    Server.addReactionCommand,
+	wss.CommandRemoveReaction: Server.removeReactionCommand,
+}
+
+func (s Server) handleCommand(ctx context.Context, client *wss.Client, request wss.Envelope) error {
+	handler, ok := commandHandlers[request.Type]
+	if !ok {
+		return wss.NewError(wss.ErrorUnknownType, "unknown frame type %s", request.Type)
+	}
+
+	return handler(s, ctx, client, request)
+}
+
+// sendMessageCommand posts the message, messages starting with a slash run slash commands instead.
+// A leading double slash posts the text with a single slash.
+func (s Server) sendMessageCommand(ctx context.Context, client *wss.Client, request wss.Envelope) error {
+	var payload wss.SendMessagePayload
+	if err := request.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	switch {
+	case strings.HasPrefix(payload.Message, "//"):
+		payload.Message = payload.Message[1:]
+	case strings.HasPrefix(payload.Message, "/"):
+		return s.slashCommand(ctx, client, request.Id, payload)
+	}
+
+	return s.postMessage(ctx, client, request.Id, payload)
+}
+
+func (s Server) postMessage(ctx context.Context, client *wss.Client, requestId string, payload wss.SendMessagePayload) error {
+	if strings.TrimSpace(payload.Message) == "" && len(payload.Attachments) == 0 {
+		return wss.NewError(wss.ErrorInvalidPayload, "message is required")
+	}
+
+	// A retried message is acknowledged again instead of being posted twice.
+	if payload.ClientId != "" {
+		if existing, err := s.messageRepo.GetByClientId(ctx, client.User.ID, payload.ClientId); err == nil {
+			return client.SendAck(requestId, wss.NewMessagePayload(&existing))
+		}
+	}
+
+	m := &models.Message{AuthorUuid: client.User.ID, Author: client.UserCopy(), Message: payload.Message}
+	if payload.ClientId != "" {
+		m.ClientId = &payload.ClientId
+	}
+
+	attachments, err := s.messageAttachments(ctx, client, payload.Attachments)
+	if err != nil {
+		return err
+	}
+
+	m.Attachments = attachments
+
+	switch {
+	case payload.ParentId != 0 && (payload.To != "" || payload.Room != ""):
+		return wss.NewError(wss.ErrorInvalidPayload, "reply is posted to the conversation of its parent")
+	case payload.ParentId != 0:
+		if err := s.addressReply(ctx, client, m, payload.ParentId); err != nil {
+			return err
+		}
+	case payload.To != "" && payload.Room != "":
+		return wss.NewError(wss.ErrorInvalidPayload, "direct message can not be addressed to a room")
+	case payload.To != "":
+		recipient, err := s.userRepo.GetByUserName(ctx, payload.To)
+		if err != nil {
+			return wss.NewError(wss.ErrorNotFound, "user %s does not exist", payload.To)
+		}
+
+		m.RecipientUuid = &recipient.ID
+		m.Recipient = &recipient
+	case payload.Room != "":
+		room, err := s.memberRoom(ctx, client, payload.Room)
+		if err != nil {
+			return err
+		}
+
+		m.RoomID = &room.ID
+		m.Room = &room
+	}
+
+	if m.Room != nil {
+		if err := s.checkMuted(ctx, client, m.Room); err != nil {
+			return err
+		}
+	}
+
+	if err := s.messageRepo.Create(ctx, m); err != nil {
+		s.logger.Errorln("Could not store message. ", err)
+
+		return wss.NewError(wss.ErrorInternal, "message could not be stored")
+	}
+
+	// The message replaces the typing indicator of its author, participants clear it on arrival.
+	s.typing.Stop(typingKey(client.User.ID, m.RoomID, m.RecipientUuid))
+
+	if err := client.SendAck(requestId, wss.NewMessagePayload(m)); err != nil {
+		return err
+	}
+
+	s.deliverMessage(wss.EventMessage, m, client)
+	s.notifyMentions(ctx, m)
+
+	return nil
+}
+
+// addressReply attaches the reply to the thread of the parent and posts it to the parent's conversation.
+func (s Server) addressReply(ctx context.Context, client *wss.Client, m *models.Message, parentId uint) error {
+	root, err := s.threadRoot(ctx, parentId)
+	if err != nil {
+		return wss.NewError(wss.ErrorNotFound, "message %d does not exist", parentId)
+	}
+
+	if !s.canRead(client, &root) {
+		return wss.NewError(wss.ErrorForbidden, "message %d is not visible to user %s", parentId, client.UserName())
+	}
+
+	m.ParentID = &root.ID
+	m.RoomID, m.Room = root.RoomID, root.Room
+
+	if root.RecipientUuid != nil {
+		// A direct reply goes to the other participant of the conversation.
+		if root.AuthorUuid == client.User.ID {
+			m.RecipientUuid, m.Recipient = root.RecipientUuid, root.Recipient
+		} else {
+			m.RecipientUuid, m.Recipient = &root.AuthorUuid, &root.Author
+		}
+	}
+
+	return nil
+}
+
+func (s Server) editMessageCommand(ctx context.Context, client *wss.Client, request wss.Envelope) error {
+	var payload wss.EditMessagePayload
+	if err := request.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	m, err := s.editMessage(ctx, client.User.ID, payload.Id, payload.Message)
+	if err != nil {
+		return s.messageCommandError(err, payload.Id)
+	}
+
+	if err := client.SendAck(request.Id, wss.NewMessagePayload(m)); err != nil {
+		return err
+	}
+
+	s.deliverMessage(wss.EventMessageEdited, m, client)
+
+	return nil
+}
+
+func (s Server) deleteMessageCommand(ctx context.Context, client *wss.Client, request wss.Envelope) error {
+	var payload wss.DeleteMessagePayload
+	if err := request.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	m, err := s.deleteMessage(ctx, client.User.ID, payload.Id)
+	if err != nil {
+		return s.messageCommandError(err, payload.Id)
+	}
+
+	if err := client.SendAck(request.Id, wss.NewMessagePayload(m)); err != nil {
+		return err
+	}
+
+	s.deliverMessage(wss.EventMessageDeleted, m, client)
+
+	return nil
+}
+
+// messageCommandError translates failures of message modifications into protocol errors.
+func (s Server) messageCommandError(err error, id uint) error {
+	switch {
+	case errors.Is(err, errEmptyMessage):
+		return wss.NewError(wss.ErrorInvalidPayload, "message is required")
+	case errors.Is(err, errMessageNotFound):
+		return wss.NewError(wss.ErrorNotFound, "message %d does not exist", id)
+	case errors.Is(err, errNotMessageAuthor):
+		return wss.NewError(wss.ErrorForbidden, "message %d belongs to another user", id)
+	}
+
+	s.logger.Errorln("Could not modify message. ", err)
+
+	return wss.NewError(wss.ErrorInternal, "message %d could not be modified", id)
+}
+
+func (s Server) joinRoomCommand(ctx context.Context, client *wss.Client, request wss.Envelope) error {
+	var payload wss.RoomPayload
+	if err := request.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	room, err := s.roomRepo.GetByName(ctx, payload.Room)
+	if err != nil {
+		return wss.NewError(wss.ErrorNotFound, "room %s does not exist", payload.Room)
+	}
+
+	if err = s.roomRepo.AddMember(ctx, room.ID, client.User.ID); err != nil {
+		s.logger.Errorln("Could not store room membership. ", err)
+
+		return wss.NewError(wss.ErrorInternal, "could not join room %s", payload.Room)
+	}
+
+	s.chatData.JoinRoom(room.ID, client)
+
+	return client.SendAck(request.Id, payload)
+}
+
+func (s Server) leaveRoomCommand(ctx context.Context, client *wss.Client, request wss.Envelope) error {
+	var payload wss.RoomPayload
+	if err := request.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	room, err := s.roomRepo.GetByName(ctx, payload.Room)
+	if err != nil {
+		return wss.NewError(wss.ErrorNotFound, "room %s does not exist", payload.Room)
+	}
+
+	if err = s.roomRepo.RemoveMember(ctx, room.ID, client.User.ID); err != nil {
+		s.logger.Errorln("Could not store room membership. ", err)
+
+		return wss.NewError(wss.ErrorInternal, "could not leave room %s", payload.Room)
+	}
+
+	s.chatData.LeaveRoom(room.ID, client)
+
+	return client.SendAck(request.Id, payload)
+}
+
+func (s Server) setStatusCommand(ctx context.Context, client *wss.Client, request wss.Envelope) error {
+	var payload wss.StatusPayload
+	if err := request.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	switch payload.Status {
+	case wss.StatusOnline, wss.StatusAway, wss.StatusDoNotDisturb:
+	default:
+		return wss.NewError(wss.ErrorInvalidPayload, "unknown status %s", payload.Status)
+	}
+
+	s.chatData.SetStatus(client.User.ID, payload.Status)
+
+	presence := wss.PresencePayload{User: client.UserName(), Status: payload.Status}
+	if err := client.SendAck(request.Id, presence); err != nil {
+		return err
+	}
+
+	// Other sessions of the user learn about the change as well.
+	s.broadcastPresence(wss.EventPresence, presence, func(c *wss.Client) bool {
+		return c != client
+	})
+
+	return nil
+}
+
+// typingCommand relays typing indicators to other participants of the conversation.
+// Indicators are transient, they are neither stored nor acknowledged.
+func (s Server) typingCommand(ctx context.Context, client *wss.Client, request wss.Envelope) error {
+	var payload wss.TypingPayload
+	if err := request.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	var roomId *uint
+	var to *types.Uuid
+
+	switch {
+	case payload.To != "" && payload.Room != "":
+		return wss.NewError(wss.ErrorInvalidPayload, "direct conversation can not be in a room")
+	case payload.To != "":
+		recipient, err := s.userRepo.GetByUserName(ctx, payload.To)
+		if err != nil {
+			return wss.NewError(wss.ErrorNotFound, "user %s does not exist", payload.To)
+		}
+
+		to = &recipient.ID
+	case payload.Room != "":
+		room, err := s.memberRoom(ctx, client, payload.Room)
+		if err != nil {
+			return err
+		}
+
+		roomId = &room.ID
+	}
+
+	payload.User = client.UserName()
+	key := typingKey(client.User.ID, roomId, to)
+
+	if !payload.Typing {
+		if s.typing.Stop(key) {
+			s.relayTyping(payload, client.User.ID, roomId, to)
+		}
+
+		return nil
+	}
+
+	stopped := payload
+	stopped.Typing = false
+
+	expire := func() {
+		s.relayTyping(stopped, client.User.ID, roomId, to)
+	}
+
+	if s.typing.Start(key, time.Now(), expire) {
+		s.relayTyping(payload, client.User.ID, roomId, to)
+	}
+
+	return nil
+}
+
+// relayTyping queues a typing event on the live sessions of other participants of the conversation.
+func (s Server) relayTyping(payload wss.TypingPayload, userId types.Uuid, roomId *uint, to *types.Uuid) {
+	var clients []*wss.Client
+	if to != nil {
+		clients = s.chatData.GetUserClients(*to)
+	} else {
+		clients = s.chatData.GetRecipients(roomId)
+	}
+
+	for _, client := range clients {
+		if client.User == nil || client.User.ID == userId {
+			continue
+		}
+
+		if err := client.SendEvent(wss.EventTyping, "", payload); err != nil {
+			s.logger.Warningln("Could not deliver typing indicator. ", err)
+		}
+	}
+}
+
+func typingKey(userId types.Uuid, roomId *uint, to *types.Uuid) wss.TypingKey {
+	key := wss.TypingKey{UserId: userId}
+	if roomId != nil {
+		key.RoomId = *roomId
+	}
+
+	if to != nil {
+		key.To = *to
+	}
+
+	return key
+}
+
+func (s Server) markReadCommand(ctx context.Context, client *wss.Client, request wss.Envelope) error {
+	var payload wss.MarkReadPayload
+	if err := request.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	m, err := s.messageRepo.GetById(ctx, payload.Id)
+	if err != nil {
+		return wss.NewError(wss.ErrorNotFound, "message %d does not exist", payload.Id)
+	}
+
+	if !s.canRead(client, &m) {
+		return wss.NewError(wss.ErrorForbidden, "message %d is not visible to user %s", payload.Id, client.UserName())
+	}
+
+	marker := newReadMarker(client.User.ID, &m)
+
+	moved, err := s.readMarkerRepo.Mark(ctx, &marker)
+	if err != nil {
+		s.logger.Errorln("Could not store read marker. ", err)
+
+		return wss.NewError(wss.ErrorInternal, "message %d could not be marked read", payload.Id)
+	}
+
+	reader := client.UserCopy()
+	receipt := newReadReceipt(&reader, &m, marker)
+	if err := client.SendAck(request.Id, receipt); err != nil {
+		return err
+	}
+
+	// Marking an older message keeps the marker where it is, participants already know it.
+	if moved {
+		s.relayReadReceipt(receipt, &m, client)
+	}
+
+	return nil
+}
+
+func (s Server) addReactionCommand(ctx context.Context, client *wss.Client, request wss.Envelope) error {
+	return s.reactionCommand(ctx, client, request, wss.EventReactionAdded, s.reactionRepo.Add)
+}
+
+func (s Server) removeReactionCommand(ctx context.Context, client *wss.Client, request wss.Envelope) error {
+	return s.reactionCommand(ctx, client, request, wss.EventReactionRemoved, s.reactionRepo.Remove)
+}
+
+// reactionCommand applies change to the reaction of the client's user and announces it
+// to the conversation with event, unless the reaction was already in the requested state.
+func (s Server) reactionCommand(
+	ctx context.Context,
+	client *wss.Client,
+	request wss.Envelope,
+	event string,
+	change func(ctx context.Context, r *models.Reaction) (bool, error),
+) error {
+	var payload wss.ReactionPayload
+	if err := request.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	if !validEmoji(payload.Emoji) {
+		return wss.NewError(wss.ErrorInvalidPayload, "emoji is required and may not contain spaces")
+	}
+
+	m, err := s.messageRepo.GetById(ctx, payload.Id)
+	if err != nil {
+		return wss.NewError(wss.ErrorNotFound, "message %d does not exist", payload.Id)
+	}
+
+	if !s.canRead(client, &m) {
+		return wss.NewError(wss.ErrorForbidden, "message %d is not visible to user %s", payload.Id, client.UserName())
+	}
+
+	changed, err := change(ctx, &models.Reaction{MessageID: m.ID, UserUuid: client.User.ID, Emoji: payload.Emoji, CreatedAt: time.Now()})
+	if err != nil {
+		s.logger.Errorln("Could not store reaction. ", err)
+
+		return wss.NewError(wss.ErrorInternal, "reaction to message %d could not be stored", payload.Id)
+	}
+
+	reactions, err := s.reactionRepo.CountFor(ctx, []uint{m.ID})
+	if err != nil {
+		s.logger.Errorln("Could not count reactions. ", err)
+
+		return wss.NewError(wss.ErrorInternal, "reactions to message %d could not be counted", payload.Id)
+	}
+
+	payload.User = client.UserName()
+	payload.Count = 0
+	for _, reaction := range reactions[m.ID] {
+		if reaction.Emoji == payload.Emoji {
+			payload.Count = reaction.Count
+		}
+	}
+
+	if err := client.SendAck(request.Id, payload); err != nil {
+		return err
+	}
+
+	if !changed {
+		return nil
+	}
+
+	for _, participant := range s.conversationClients(&m) {
+		if participant == client {
+			continue
+		}
+
+		if err := participant.SendEvent(event, "", payload); err != nil {
+			s.logger.Warningln("Could not deliver reaction. ", err)
+		}
+	}
+
+	return nil
+}
+
+func (s Server) memberRoom(ctx context.Context, client *wss.Client, name string) (models.Room, error) {
+	room, err := s.roomRepo.GetByName(ctx, name)
+	if err != nil {
+		return models.Room{}, wss.NewError(wss.ErrorNotFound, "room %s does not exist", name)
+	}
+
+	if !s.chatData.InRoom(room.ID, client) {
+		return models.Room{}, wss.NewError(wss.ErrorForbidden, "user %s is not a member of room %s", client.UserName(), name)
+	}
+
+	return room, nil
+}
+
+// maxEmojiLength bounds reactions in bytes, enough for emoji sequences and :shortcodes:.
+const maxEmojiLength = 64
+
+func validEmoji(emoji string) bool {
+	return emoji != "" && len(emoji) <= maxEmojiLength && strings.IndexFunc(emoji, unicode.IsSpace) < 0
+}
@@ -1,10 +1,13 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -12,15 +15,29 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 
+	"github.com/id-tarzanych/lets-go-chat/api/middlewares"
 	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
 	"github.com/id-tarzanych/lets-go-chat/mocks"
 	"github.com/id-tarzanych/lets-go-chat/models"
 	"github.com/id-tarzanych/lets-go-chat/pkg/generators"
+	"github.com/id-tarzanych/lets-go-chat/pkg/jwt"
 )
 
 func TestServer_GetActiveUsers(t *testing.T) {
-	loggerMock, userRepoMock, tokenRepoMock := getChatHandlerMocks()
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	bob := mustNewUser("bob", "12345678")
+	tokenRepoMock.On("Get", mock.Anything, "bobToken").Return(models.Token{Token: "bobToken", UserId: bob.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/active", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code, "active users should not be counted without access token")
 
 	tests := []struct {
 		name            string
@@ -44,25 +61,13 @@ func TestServer_GetActiveUsers(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			srv := &Server{
-				logger: loggerMock,
-				requestUpgrader: websocket.Upgrader{
-					ReadBufferSize:  1024,
-					WriteBufferSize: 1024,
-				},
-				chatData:  tt.data,
-				userRepo:  userRepoMock,
-				tokenRepo: tokenRepoMock,
-			}
+			srv.chatData = tt.data
 
 			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/active?token=bobToken", nil))
 
-			srv.GetActiveUsers(w, httptest.NewRequest(http.MethodPost, "/test", nil))
-			response := &struct {
-				Count int `json:"count"`
-			}{}
-
-			json.Unmarshal([]byte(w.Body.String()), response)
+			response := ActiveUsersResponse{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
 
 			if tt.wantActiveUsers != response.Count {
 				t.Errorf("Invalid active users count, expected %d, got %d", tt.wantActiveUsers, response.Count)
@@ -71,8 +76,64 @@ func TestServer_GetActiveUsers(t *testing.T) {
 	}
 }
 
+func TestServer_GetOnlineUsers(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	bob := mustNewUser("bob", "12345678")
+	tokenRepoMock.On("Get", mock.Anything, "bobToken").Return(models.Token{Token: "bobToken", UserId: bob.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+
+	data := wss.NewChatData()
+
+	joinedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, name := range []string{"zoe", "alice"} {
+		client := &wss.Client{JoinedAt: joinedAt, User: mustNewUser(name, "password")}
+		data.StoreClient(client)
+	}
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.chatData = data
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/online", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code, "online users should not be listed without access token")
+
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/online?token=bobToken", nil))
+
+	response := OnlineUsersResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+
+	if assert.Len(t, response.Users, 2) {
+		assert.Equal(t, "alice", response.Users[0].UserName, "users should be ordered by name")
+		assert.Equal(t, "zoe", response.Users[1].UserName, "users should be ordered by name")
+		assert.True(t, joinedAt.Equal(response.Users[0].JoinedAt), "join time expected")
+		assert.Equal(t, OnlineUserStatusOnline, response.Users[0].Status)
+	}
+}
+
+func TestServer_GetChatMetrics(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	bob := mustNewUser("bob", "12345678")
+	tokenRepoMock.On("Get", mock.Anything, "bobToken").Return(models.Token{Token: "bobToken", UserId: bob.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.chatData = generateClientsData(3)
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chat/metrics", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code, "metrics should not be shown without access token")
+
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chat/metrics?token=bobToken", nil))
+
+	response := ChatMetricsResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+	assert.Equal(t, ChatMetricsResponse{Clients: 3}, response)
+}
+
 func TestServer_WebsocketInitiationError(t *testing.T) {
-	loggerMock, userRepoMock, tokenRepoMock := getChatHandlerMocks()
+	loggerMock, userRepoMock, tokenRepoMock, _, _ := getChatHandlerMocks()
 	loggerMock.On("Error", mock.AnythingOfType("string")).Return()
 
 	upgrader := websocket.Upgrader{}
@@ -92,33 +153,195 @@ func TestServer_WebsocketInitiationError(t *testing.T) {
 	loggerMock.AssertCalled(t, "Error", "Could not initiate WebSocket connection.")
 }
 
+func TestServer_WsRTMConnect(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	alice := *mustNewUser("alice", "12345678")
+
+	tokenRepoMock.On("Get", mock.Anything, "aliceToken").Return(models.Token{UserId: alice.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+	tokenRepoMock.On("Get", mock.Anything, "expiredToken").Return(models.Token{UserId: alice.ID, Expiration: time.Now().Add(-time.Hour)}, nil)
+	tokenRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(t *models.Token) bool {
+		return t.UserId == alice.ID && t.Kind == models.TokenChat && t.Token != ""
+	})).Return(nil).Once()
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+
+	tests := []struct {
+		name     string
+		url      string
+		wantCode int
+	}{
+		{"No token", "/chat/ws.rtm.connect", http.StatusBadRequest},
+		{"Expired token", "/chat/ws.rtm.connect?token=expiredToken", http.StatusBadRequest},
+		{"Access token", "/chat/ws.rtm.connect?token=aliceToken", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.url, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantCode == http.StatusOK {
+				response := WsRTMConnectResponse{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+				assert.True(t, strings.HasPrefix(response.Url, "ws://example.com/chat/ws.rtm.start?token="), "unexpected url %s", response.Url)
+			}
+		})
+	}
+
+	tokenRepoMock.AssertExpectations(t)
+}
+
+func TestServer_WsRTMStart_RejectsTokens(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	rejected := make(chan struct{}, 2)
+	loggerMock.On("Error", "Could not initiate WebSocket connection.").Run(func(mock.Arguments) {
+		rejected <- struct{}{}
+	}).Return()
+
+	tokenRepoMock.On("Get", mock.Anything, "refreshToken").Return(*models.NewRefreshToken("refreshToken", "alice", "family", time.Now().Add(time.Hour)), nil)
+	tokenRepoMock.On("Get", mock.Anything, "expiredToken").Return(*models.NewToken("expiredToken", "alice", time.Now().Add(-time.Minute)), nil)
+
+	s := httptest.NewServer(newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock).Router())
+	defer s.Close()
+
+	for _, token := range []string{"refreshToken", "expiredToken"} {
+		ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/chat/ws.rtm.start?token="+token, nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		select {
+		case <-rejected:
+		case <-time.After(time.Second):
+			t.Errorf("%s should not start a chat", token)
+		}
+
+		ws.Close()
+	}
+
+	userRepoMock.AssertNotCalled(t, "GetById", mock.Anything, mock.Anything)
+	tokenRepoMock.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestServer_RESTAfterChatConnect(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	loggerMock.On("Println", mock.Anything, mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+
+	alice := *mustNewUser("alice", "12345678")
+
+	var chatToken models.Token
+	consumed := make(chan struct{})
+
+	tokenRepoMock.On("Create", mock.Anything, mock.AnythingOfType("*models.Token")).Run(func(args mock.Arguments) {
+		chatToken = *args.Get(1).(*models.Token)
+	}).Return(nil).Once()
+	tokenRepoMock.On("Get", mock.Anything, mock.Anything).Return(
+		func(_ context.Context, token string) models.Token { return chatToken },
+		func(_ context.Context, token string) error {
+			if token != chatToken.Token {
+				return errors.New("record not found")
+			}
+
+			return nil
+		},
+	)
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		close(consumed)
+	}).Return(nil).Once()
+
+	userRepoMock.On("GetById", mock.Anything, alice.ID).Return(alice, nil)
+	userRepoMock.On("UpdateLastActivity", mock.Anything, mock.Anything, mock.Anything).Maybe().Return(nil)
+	messageRepoMock.On("GetPageFor", mock.Anything, mock.Anything, mock.Anything).Return([]models.Message{}, nil)
+	roomRepoMock.On("GetByMember", mock.Anything, alice.ID).Return([]models.Room{}, nil)
+	roomRepoMock.On("GetAll", mock.Anything).Return([]models.Room{}, nil)
+
+	accessTokens := testAccessTokens(t)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.accessTokens = accessTokens
+	srv.authMiddleware = middlewares.NewAuthMiddleware(accessTokens)
+
+	s := httptest.NewServer(srv.Router())
+	defer s.Close()
+
+	accessToken, _, err := accessTokens.Issue(alice.ID, "session", time.Now())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	resp, err := http.Post(s.URL+"/chat/ws.rtm.connect?token="+accessToken, "application/json", nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	connect := WsRTMConnectResponse{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&connect), "json should be valid")
+	resp.Body.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/chat/ws.rtm.start?token="+chatToken.Token, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer ws.Close()
+
+	select {
+	case <-consumed:
+	case <-time.After(time.Second):
+		t.Fatal("chat token should be consumed by the connect")
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode int
+	}{
+		{"Access token", accessToken, http.StatusOK},
+		{"Consumed chat token", chatToken.Token, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(s.URL + "/chat/rooms?token=" + tt.token)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			resp.Body.Close()
+
+			assert.Equal(t, tt.wantCode, resp.StatusCode, "unexpected status code")
+		})
+	}
+
+	tokenRepoMock.AssertNotCalled(t, "Get", mock.Anything, accessToken)
+}
+
 func TestChat_HandleChatSession_ProcessValidMessage(t *testing.T) {
-	loggerMock, userRepoMock, tokenRepoMock := getChatHandlerMocks()
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
 
 	loggerMock.On("Error", mock.AnythingOfType("string")).Maybe().Return()
 	loggerMock.On("Println", mock.Anything).Maybe().Return()
-	loggerMock.On("Println", mock.Anything, mock.Anything).Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything).Maybe().Return()
 	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
 	loggerMock.On("Warningln", mock.AnythingOfType("string")).Maybe().Return()
 
-	user := *models.NewUser("testuser", "12345678")
+	user := *mustNewUser("testuser", "12345678")
 	tokenString := generators.RandomString(16)
 
 	tokenRepoMock.On("Get", mock.Anything, tokenString).Return(models.Token{Token: tokenString, UserId: user.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
 	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
 
 	userRepoMock.On("GetById", mock.Anything, user.ID).Return(user, nil)
+	userRepoMock.On("UpdateLastActivity", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-	srv := &Server{
-		logger: loggerMock,
-		requestUpgrader: websocket.Upgrader{
-			ReadBufferSize:  1024,
-			WriteBufferSize: 1024,
-		},
-		chatData:  wss.NewChatData(),
-		userRepo:  userRepoMock,
-		tokenRepo: tokenRepoMock,
-	}
+	messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: user.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+	messageRepoMock.On("Create", mock.Anything, mock.AnythingOfType("*models.Message")).Return(nil)
+
+	roomRepoMock.On("GetByMember", mock.Anything, user.ID).Return([]models.Room{}, nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
 
 	s := httptest.NewServer(HandlerWithOptions(srv, ChiServerOptions{}))
 	defer s.Close()
@@ -133,38 +356,1363 @@ func TestChat_HandleChatSession_ProcessValidMessage(t *testing.T) {
 	defer ws.Close()
 
 	for i := 0; i < 10; i++ {
-		message := fmt.Sprintf("{\"message\": \"%s\"}", generators.RandomString(16))
+		text := generators.RandomString(16)
+		request := fmt.Sprintf(`{"type": "send_message", "id": "%d", "payload": {"message": "%s"}}`, i, text)
 
-		if err := ws.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
 			t.Fatalf("%v", err)
 		}
 
-		_, p, err := ws.ReadMessage()
+		ack := readEnvelope(t, ws)
+		assert.Equal(t, wss.EventAck, ack.Type, "ack expected")
+		assert.Equal(t, strconv.Itoa(i), ack.Id, "ack should reference request id")
+
+		response := readEnvelope(t, ws)
+		assert.Equal(t, wss.EventMessage, response.Type, "message event expected")
+		assert.Equal(t, wss.ProtocolVersion, response.Version, "current protocol version expected")
+
+		payload := wss.MessagePayload{}
+		assert.NoError(t, response.DecodePayload(&payload), "payload should be valid")
+		assert.Equal(t, "testuser", payload.Author, "author should be set")
+		assert.Equal(t, text, payload.Message, "messages should be equal")
+	}
+
+	loggerMock.AssertExpectations(t)
+	userRepoMock.AssertExpectations(t)
+	tokenRepoMock.AssertExpectations(t)
+	messageRepoMock.AssertExpectations(t)
+	roomRepoMock.AssertExpectations(t)
+}
+
+func TestChat_HandleChatSession_ReplayCursor(t *testing.T) {
+	lastActivity := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	postedAt := lastActivity.Add(-24 * time.Hour)
+	editedAt := lastActivity.Add(10 * time.Minute)
+
+	edited := models.Message{Model: gorm.Model{ID: 1, CreatedAt: postedAt}, Message: "edited", EditedAt: &editedAt}
+	deleted := models.Message{Model: gorm.Model{ID: 2, CreatedAt: postedAt.Add(time.Minute), DeletedAt: gorm.DeletedAt{Time: lastActivity.Add(5 * time.Minute), Valid: true}}}
+	stale := models.Message{Model: gorm.Model{ID: 3, CreatedAt: postedAt.Add(2 * time.Minute)}}
+
+	tests := []struct {
+		name     string
+		missed   []models.Message
+		wantMove bool
+		want     time.Time
+	}{
+		{"Edited old messages", []models.Message{edited, deleted}, true, editedAt},
+		{"Only older changes", []models.Message{stale}, false, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+			loggerMock.On("Println", mock.Anything, mock.Anything).Maybe().Return()
+			loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+
+			user := *mustNewUser("testuser", "12345678")
+			user.LastActivity = lastActivity
+
+			tokenString := generators.RandomString(16)
+			stored := make(chan struct{}, 1)
+
+			tokenRepoMock.On("Get", mock.Anything, tokenString).Return(models.Token{Token: tokenString, UserId: user.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+			tokenRepoMock.On("Delete", mock.Anything, tokenString).Return(nil)
+
+			userRepoMock.On("GetById", mock.Anything, user.ID).Return(user, nil)
+			userRepoMock.On("UpdateLastActivity", mock.Anything, mock.Anything, tt.want).Return(nil)
+
+			messageRepoMock.On("GetNewerThanFor", mock.Anything, message.Audience{UserId: user.ID, RoomIds: []uint{}}, lastActivity, historyReplayLimit).Return(tt.missed, nil)
+
+			roomRepoMock.On("GetByMember", mock.Anything, user.ID).Return([]models.Room{}, nil)
+
+			reactionRepoMock := &mocks.ReactionRepository{}
+			reactionRepoMock.On("CountFor", mock.Anything, mock.Anything).Return(map[uint][]models.ReactionCount{}, nil)
+
+			srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+			srv.reactionRepo = reactionRepoMock
+			srv.chatData.SetPresenceHandler(func(string, *models.User) {
+				select {
+				case stored <- struct{}{}:
+				default:
+				}
+			})
+
+			s := httptest.NewServer(srv.Router())
+			defer s.Close()
+
+			ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/chat/ws.rtm.start?token="+tokenString, nil)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			defer ws.Close()
+
+			for range tt.missed {
+				assert.Equal(t, wss.EventMessage, readEnvelope(t, ws).Type, "missed message expected")
+			}
+
+			select {
+			case <-stored:
+			case <-time.After(time.Second):
+				t.Fatal("client should join the chat")
+			}
+
+			if tt.wantMove {
+				userRepoMock.AssertCalled(t, "UpdateLastActivity", mock.Anything, mock.Anything, tt.want)
+			} else {
+				userRepoMock.AssertNotCalled(t, "UpdateLastActivity", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestChat_HandleChatSession_RoomMessages(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	loggerMock.On("Error", mock.AnythingOfType("string")).Maybe().Return()
+	loggerMock.On("Println", mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+	loggerMock.On("Warningln", mock.Anything, mock.Anything).Maybe().Return()
+	loggerMock.On("Errorln", mock.Anything, mock.Anything).Return()
+
+	user := *mustNewUser("testuser", "12345678")
+	tokenString := generators.RandomString(16)
+
+	room := models.Room{Name: "general"}
+	room.ID = 1
+	broken := models.Room{Name: "broken"}
+	broken.ID = 2
+
+	tokenRepoMock.On("Get", mock.Anything, tokenString).Return(models.Token{Token: tokenString, UserId: user.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+	userRepoMock.On("GetById", mock.Anything, user.ID).Return(user, nil)
+	userRepoMock.On("UpdateLastActivity", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: user.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+	messageRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(m *models.Message) bool {
+		return m.RoomID != nil && *m.RoomID == room.ID
+	})).Return(nil)
+
+	roomRepoMock.On("GetByMember", mock.Anything, user.ID).Return([]models.Room{}, nil)
+	roomRepoMock.On("GetByName", mock.Anything, room.Name).Return(room, nil)
+	roomRepoMock.On("GetByName", mock.Anything, "missing").Return(models.Room{}, errors.New("record not found"))
+	roomRepoMock.On("AddMember", mock.Anything, room.ID, user.ID).Return(nil)
+	roomRepoMock.On("GetByName", mock.Anything, broken.Name).Return(broken, nil)
+	roomRepoMock.On("AddMember", mock.Anything, broken.ID, user.ID).Return(errors.New("connection lost"))
+	roomRepoMock.On("RemoveMember", mock.Anything, broken.ID, user.ID).Return(errors.New("connection lost"))
+	roomRepoMock.On("GetMember", mock.Anything, room.ID, user.ID).Return(models.RoomMember{RoomID: room.ID, UserUuid: user.ID}, nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+
+	s := httptest.NewServer(HandlerWithOptions(srv, ChiServerOptions{}))
+	defer s.Close()
+
+	u := "ws" + strings.TrimPrefix(s.URL, "http") + "/chat/ws.rtm.start?token=" + tokenString
+
+	ws, _, err := websocket.DefaultDialer.Dial(u, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer ws.Close()
+
+	tests := []struct {
+		name     string
+		request  string
+		wantType string
+		wantCode string
+	}{
+		{"Not a member", `{"type": "send_message", "id": "1", "payload": {"message": "not a member yet", "room": "general"}}`, wss.EventError, wss.ErrorForbidden},
+		{"Missing room", `{"type": "join_room", "id": "2", "payload": {"room": "missing"}}`, wss.EventError, wss.ErrorNotFound},
+		{"Join failure", `{"type": "join_room", "id": "2a", "payload": {"room": "broken"}}`, wss.EventError, wss.ErrorInternal},
+		{"Leave failure", `{"type": "leave_room", "id": "2b", "payload": {"room": "broken"}}`, wss.EventError, wss.ErrorInternal},
+		{"Join room", `{"type": "join_room", "id": "3", "payload": {"room": "general"}}`, wss.EventAck, ""},
+		{"Room message", `{"type": "send_message", "id": "4", "payload": {"message": "hello room", "room": "general"}}`, wss.EventAck, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ws.WriteMessage(websocket.TextMessage, []byte(tt.request)); err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			response := readEnvelope(t, ws)
+			assert.Equal(t, tt.wantType, response.Type, "unexpected frame type")
+
+			if tt.wantCode != "" {
+				protocolErr := wss.Error{}
+				assert.NoError(t, response.DecodePayload(&protocolErr), "payload should be valid")
+				assert.Equal(t, tt.wantCode, protocolErr.Code, "unexpected error code")
+			}
+		})
+	}
+
+	response := wss.MessagePayload{}
+	assert.NoError(t, readEnvelope(t, ws).DecodePayload(&response), "payload should be valid")
+	assert.Equal(t, "general", response.Room, "room message should be delivered")
+
+	messageRepoMock.AssertNumberOfCalls(t, "Create", 1)
+	roomRepoMock.AssertExpectations(t)
+}
+
+func TestChat_HandleChatSession_DirectMessages(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	loggerMock.On("Error", mock.AnythingOfType("string")).Maybe().Return()
+	loggerMock.On("Println", mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+
+	alice := *mustNewUser("alice", "12345678")
+	bob := *mustNewUser("bob", "12345678")
+
+	messageRepoMock.On("Create", mock.Anything, mock.AnythingOfType("*models.Message")).Return(nil)
+	userRepoMock.On("UpdateLastActivity", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	userRepoMock.On("GetByUserName", mock.Anything, bob.UserName).Return(bob, nil)
+
+	tokens := make(map[types.Uuid]string)
+	for _, u := range []models.User{alice, bob} {
+		tokens[u.ID] = generators.RandomString(16)
+
+		tokenRepoMock.On("Get", mock.Anything, tokens[u.ID]).Return(models.Token{Token: tokens[u.ID], UserId: u.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
+		userRepoMock.On("GetById", mock.Anything, u.ID).Return(u, nil)
+		roomRepoMock.On("GetByMember", mock.Anything, u.ID).Return([]models.Room{}, nil)
+		messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: u.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+	}
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+
+	s := httptest.NewServer(HandlerWithOptions(srv, ChiServerOptions{}))
+	defer s.Close()
+
+	connect := func(u models.User) *websocket.Conn {
+		url := "ws" + strings.TrimPrefix(s.URL, "http") + "/chat/ws.rtm.start?token=" + tokens[u.ID]
+
+		ws, _, err := websocket.DefaultDialer.Dial(url, nil)
 		if err != nil {
 			t.Fatalf("%v", err)
 		}
 
-		requestMessage := &wss.ClientRequest{}
-		responseMessage := &wss.ClientRequest{}
+		return ws
+	}
+
+	send := func(ws *websocket.Conn, request string) {
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	// receive skips acks of the connection's own commands and presence events
+	// and returns the next delivered message.
+	receive := func(ws *websocket.Conn) wss.MessagePayload {
+		envelope := readEnvelope(t, ws)
+		for envelope.Type == wss.EventAck || envelope.Type == wss.EventUserJoined {
+			envelope = readEnvelope(t, ws)
+		}
 
-		json.Unmarshal([]byte(message), requestMessage)
-		err = json.Unmarshal(p, responseMessage)
+		response := wss.MessagePayload{}
+		assert.NoError(t, envelope.DecodePayload(&response), "payload should be valid")
 
-		assert.NoError(t, err, "json should be valid")
-		assert.Equal(t, requestMessage, responseMessage, "objects should be equal")
+		return response
 	}
 
-	loggerMock.AssertExpectations(t)
-	userRepoMock.AssertExpectations(t)
-	tokenRepoMock.AssertExpectations(t)
+	aliceWs := connect(alice)
+	defer aliceWs.Close()
+
+	send(aliceWs, `{"type": "send_message", "payload": {"message": "hello"}}`)
+	assert.Equal(t, "hello", receive(aliceWs).Message)
+
+	bobWs := connect(bob)
+	defer bobWs.Close()
+
+	send(bobWs, `{"type": "send_message", "payload": {"message": "hi"}}`)
+	assert.Equal(t, "hi", receive(bobWs).Message)
+	assert.Equal(t, "hi", receive(aliceWs).Message)
+
+	send(aliceWs, `{"type": "send_message", "payload": {"message": "psst", "to": "bob"}}`)
+	direct := receive(bobWs)
+	assert.Equal(t, "alice", direct.Author, "author should be set")
+	assert.Equal(t, "bob", direct.To, "recipient should be set")
+	assert.Equal(t, "psst", direct.Message, "recipient should receive direct message")
+
+	// The sending session must not get an echo of the direct message.
+	send(aliceWs, `{"type": "send_message", "payload": {"message": "public"}}`)
+	assert.Equal(t, "public", receive(aliceWs).Message)
+	assert.Equal(t, "public", receive(bobWs).Message)
 }
 
-func getChatHandlerMocks() (*mocks.FieldLogger, *mocks.UserRepository, *mocks.TokenRepository) {
-	loggerMock := &mocks.FieldLogger{}
-	userRepoMock := &mocks.UserRepository{}
-	tokenRepoMock := &mocks.TokenRepository{}
+func TestChat_HandleChatSession_Acknowledgements(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	loggerMock.On("Error", mock.AnythingOfType("string")).Maybe().Return()
+	loggerMock.On("Errorln", "Could not store message. ", mock.Anything).Return()
+	loggerMock.On("Println", mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+
+	user := *mustNewUser("testuser", "12345678")
+	tokenString := generators.RandomString(16)
+
+	tokenRepoMock.On("Get", mock.Anything, tokenString).Return(models.Token{Token: tokenString, UserId: user.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	userRepoMock.On("GetById", mock.Anything, user.ID).Return(user, nil)
+	userRepoMock.On("UpdateLastActivity", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	roomRepoMock.On("GetByMember", mock.Anything, user.ID).Return([]models.Room{}, nil)
+
+	sentAt := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+	clientId := "c-1"
+	stored := models.Message{Author: user, Message: "once", ClientId: &clientId}
+	stored.ID = 42
+	stored.CreatedAt = sentAt
+
+	messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: user.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+	messageRepoMock.On("GetByClientId", mock.Anything, user.ID, "c-1").Return(models.Message{}, errors.New("record not found")).Once()
+	messageRepoMock.On("GetByClientId", mock.Anything, user.ID, "c-1").Return(stored, nil)
+	messageRepoMock.On("GetByClientId", mock.Anything, user.ID, "c-2").Return(models.Message{}, errors.New("record not found"))
+	messageRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(m *models.Message) bool {
+		return m.ClientId != nil && *m.ClientId == "c-1"
+	})).Run(func(args mock.Arguments) {
+		m := args.Get(1).(*models.Message)
+		m.ID = stored.ID
+		m.CreatedAt = stored.CreatedAt
+	}).Return(nil).Once()
+	messageRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(m *models.Message) bool {
+		return m.ClientId != nil && *m.ClientId == "c-2"
+	})).Return(errors.New("database is down"))
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+
+	s := httptest.NewServer(HandlerWithOptions(srv, ChiServerOptions{}))
+	defer s.Close()
+
+	u := "ws" + strings.TrimPrefix(s.URL, "http") + "/chat/ws.rtm.start?token=" + tokenString
+
+	ws, _, err := websocket.DefaultDialer.Dial(u, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer ws.Close()
+
+	send := func(request string) {
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	// First attempt is stored, acknowledged and echoed.
+	send(`{"type": "send_message", "id": "1", "payload": {"clientId": "c-1", "message": "once"}}`)
+
+	ack := readEnvelope(t, ws)
+	assert.Equal(t, wss.EventAck, ack.Type, "ack expected")
+	assert.Equal(t, "1", ack.Id, "ack should reference request id")
+
+	acked := wss.MessagePayload{}
+	assert.NoError(t, ack.DecodePayload(&acked), "payload should be valid")
+	assert.Equal(t, uint(42), acked.Id, "ack should carry stored message id")
+	assert.Equal(t, "c-1", acked.ClientId, "ack should carry client message id")
+	assert.True(t, sentAt.Equal(acked.SentAt), "ack should carry stored message timestamp")
+
+	assert.Equal(t, wss.EventMessage, readEnvelope(t, ws).Type, "message event expected")
+
+	// Retry is acknowledged with the stored message and not posted again.
+	send(`{"type": "send_message", "id": "2", "payload": {"clientId": "c-1", "message": "once"}}`)
+
+	ack = readEnvelope(t, ws)
+	assert.Equal(t, wss.EventAck, ack.Type, "ack expected")
+	assert.Equal(t, "2", ack.Id, "ack should reference request id")
+	assert.NoError(t, ack.DecodePayload(&acked), "payload should be valid")
+	assert.Equal(t, uint(42), acked.Id, "retry should be acknowledged with stored message id")
+
+	// Storage failure is reported without dropping the connection.
+	send(`{"type": "send_message", "id": "3", "payload": {"clientId": "c-2", "message": "lost"}}`)
+
+	failure := readEnvelope(t, ws)
+	assert.Equal(t, wss.EventError, failure.Type, "error frame expected")
+	assert.Equal(t, "3", failure.Id, "error should reference request id")
+
+	protocolErr := wss.Error{}
+	assert.NoError(t, failure.DecodePayload(&protocolErr), "payload should be valid")
+	assert.Equal(t, wss.ErrorInternal, protocolErr.Code, "unexpected error code")
+
+	send(`{"type": "dance", "id": "4"}`)
+	assert.Equal(t, "4", readEnvelope(t, ws).Id, "connection should stay open after storage failure")
+
+	messageRepoMock.AssertNumberOfCalls(t, "Create", 2)
+	loggerMock.AssertExpectations(t)
+}
+
+func TestChat_HandleChatSession_EditAndDeleteMessages(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	loggerMock.On("Error", mock.AnythingOfType("string")).Maybe().Return()
+	loggerMock.On("Println", mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+
+	alice := *mustNewUser("alice", "12345678")
+	bob := *mustNewUser("bob", "12345678")
+
+	tokens := make(map[types.Uuid]string)
+	for _, u := range []models.User{alice, bob} {
+		tokens[u.ID] = generators.RandomString(16)
+
+		tokenRepoMock.On("Get", mock.Anything, tokens[u.ID]).Return(models.Token{Token: tokens[u.ID], UserId: u.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
+		userRepoMock.On("GetById", mock.Anything, u.ID).Return(u, nil)
+		roomRepoMock.On("GetByMember", mock.Anything, u.ID).Return([]models.Room{}, nil)
+		messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: u.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+	}
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+	posted := models.Message{AuthorUuid: alice.ID, Author: alice, Message: "helo"}
+	posted.ID = 7
+
+	messageRepoMock.On("GetById", mock.Anything, posted.ID).Return(posted, nil)
+	messageRepoMock.On("Update", mock.Anything, mock.Anything).Return(nil)
+	messageRepoMock.On("Delete", mock.Anything, posted.ID).Return(nil)
+
+	reactionRepoMock := &mocks.ReactionRepository{}
+	reactionRepoMock.On("CountFor", mock.Anything, []uint{posted.ID}).Return(map[uint][]models.ReactionCount{
+		posted.ID: {{Emoji: "👍", Count: 2}},
+	}, nil)
+	reactionRepoMock.On("DeleteByMessage", mock.Anything, posted.ID).Return(nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.reactionRepo = reactionRepoMock
+
+	s := httptest.NewServer(HandlerWithOptions(srv, ChiServerOptions{}))
+	defer s.Close()
+
+	connect := func(u models.User) *websocket.Conn {
+		url := "ws" + strings.TrimPrefix(s.URL, "http") + "/chat/ws.rtm.start?token=" + tokens[u.ID]
+
+		ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		return ws
+	}
+
+	send := func(ws *websocket.Conn, request string) {
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	aliceWs := connect(alice)
+	defer aliceWs.Close()
+
+	bobWs := connect(bob)
+	defer bobWs.Close()
+
+	assert.Equal(t, wss.EventUserJoined, readEnvelope(t, aliceWs).Type, "user_joined event expected")
+
+	// Only the author may modify a message.
+	send(bobWs, `{"type": "edit_message", "id": "1", "payload": {"id": 7, "message": "hijacked"}}`)
+
+	failure := readEnvelope(t, bobWs)
+	protocolErr := wss.Error{}
+	assert.Equal(t, wss.EventError, failure.Type, "error frame expected")
+	assert.NoError(t, failure.DecodePayload(&protocolErr), "payload should be valid")
+	assert.Equal(t, wss.ErrorForbidden, protocolErr.Code, "unexpected error code")
+
+	send(aliceWs, `{"type": "edit_message", "id": "2", "payload": {"id": 7, "message": "hello"}}`)
+	assert.Equal(t, wss.EventAck, readEnvelope(t, aliceWs).Type, "ack expected")
+	assert.Equal(t, wss.EventMessageEdited, readEnvelope(t, aliceWs).Type, "public edits are echoed to the author")
+
+	edited := readEnvelope(t, bobWs)
+	payload := wss.MessagePayload{}
+	assert.Equal(t, wss.EventMessageEdited, edited.Type, "message_edited event expected")
+	assert.NoError(t, edited.DecodePayload(&payload), "payload should be valid")
+	assert.Equal(t, uint(7), payload.Id, "edited message id expected")
+	assert.Equal(t, "hello", payload.Message, "edited text expected")
+	assert.NotNil(t, payload.EditedAt, "edit time expected")
+	assert.Equal(t, []wss.ReactionCount{{Emoji: "👍", Count: 2}}, payload.Reactions, "edits should keep reactions")
+
+	send(aliceWs, `{"type": "delete_message", "id": "3", "payload": {"id": 7}}`)
+	assert.Equal(t, wss.EventAck, readEnvelope(t, aliceWs).Type, "ack expected")
+
+	deleted := readEnvelope(t, bobWs)
+	payload = wss.MessagePayload{}
+	assert.Equal(t, wss.EventMessageDeleted, deleted.Type, "message_deleted event expected")
+	assert.NoError(t, deleted.DecodePayload(&payload), "payload should be valid")
+	assert.Equal(t, uint(7), payload.Id, "deleted message id expected")
+	assert.True(t, payload.Deleted, "tombstone expected")
+	assert.Empty(t, payload.Message, "tombstone should not carry text")
+	assert.Empty(t, payload.Reactions, "tombstone should not carry reactions")
+
+	userRepoMock.AssertNotCalled(t, "UpdateLastActivity", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestChat_HandleChatSession_Presence(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	loggerMock.On("Error", mock.AnythingOfType("string")).Maybe().Return()
+	loggerMock.On("Println", mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+
+	alice := *mustNewUser("alice", "12345678")
+	bob := *mustNewUser("bob", "12345678")
+
+	tokens := make(map[types.Uuid]string)
+	for _, u := range []models.User{alice, bob} {
+		tokens[u.ID] = generators.RandomString(16)
+
+		tokenRepoMock.On("Get", mock.Anything, tokens[u.ID]).Return(models.Token{Token: tokens[u.ID], UserId: u.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
+		userRepoMock.On("GetById", mock.Anything, u.ID).Return(u, nil)
+		roomRepoMock.On("GetByMember", mock.Anything, u.ID).Return([]models.Room{}, nil)
+		messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: u.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+	}
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+
+	s := httptest.NewServer(HandlerWithOptions(srv, ChiServerOptions{}))
+	defer s.Close()
+
+	connect := func(u models.User) *websocket.Conn {
+		url := "ws" + strings.TrimPrefix(s.URL, "http") + "/chat/ws.rtm.start?token=" + tokens[u.ID]
+
+		ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		return ws
+	}
+
+	send := func(ws *websocket.Conn, request string) {
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	expectPresence := func(ws *websocket.Conn, wantType string, want wss.PresencePayload) {
+		envelope := readEnvelope(t, ws)
+		payload := wss.PresencePayload{}
+		assert.Equal(t, wantType, envelope.Type, "unexpected frame type")
+		assert.NoError(t, envelope.DecodePayload(&payload), "payload should be valid")
+		assert.Equal(t, want, payload)
+	}
+
+	aliceWs := connect(alice)
+	defer aliceWs.Close()
+
+	bobWs := connect(bob)
+
+	expectPresence(aliceWs, wss.EventUserJoined, wss.PresencePayload{User: "bob", Status: wss.StatusOnline})
+
+	send(bobWs, `{"type": "set_status", "id": "1", "payload": {"status": "sleeping"}}`)
+
+	failure := readEnvelope(t, bobWs)
+	protocolErr := wss.Error{}
+	assert.Equal(t, wss.EventError, failure.Type, "error frame expected")
+	assert.NoError(t, failure.DecodePayload(&protocolErr), "payload should be valid")
+	assert.Equal(t, wss.ErrorInvalidPayload, protocolErr.Code, "unexpected error code")
+
+	send(bobWs, `{"type": "set_status", "id": "2", "payload": {"status": "dnd"}}`)
+	expectPresence(bobWs, wss.EventAck, wss.PresencePayload{User: "bob", Status: wss.StatusDoNotDisturb})
+	expectPresence(aliceWs, wss.EventPresence, wss.PresencePayload{User: "bob", Status: wss.StatusDoNotDisturb})
+
+	online := srv.chatData.OnlineUsers()
+	if assert.Len(t, online, 2) {
+		assert.Equal(t, wss.StatusDoNotDisturb, online[1].Status, "status should be listed")
+	}
+
+	bobWs.Close()
+	expectPresence(aliceWs, wss.EventUserLeft, wss.PresencePayload{User: "bob", Status: wss.StatusOffline})
+}
+
+func TestChat_HandleChatSession_Typing(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	loggerMock.On("Error", mock.AnythingOfType("string")).Maybe().Return()
+	loggerMock.On("Println", mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+
+	alice := *mustNewUser("alice", "12345678")
+	bob := *mustNewUser("bob", "12345678")
+
+	tokens := make(map[types.Uuid]string)
+	for _, u := range []models.User{alice, bob} {
+		tokens[u.ID] = generators.RandomString(16)
+
+		tokenRepoMock.On("Get", mock.Anything, tokens[u.ID]).Return(models.Token{Token: tokens[u.ID], UserId: u.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
+		userRepoMock.On("GetById", mock.Anything, u.ID).Return(u, nil)
+		roomRepoMock.On("GetByMember", mock.Anything, u.ID).Return([]models.Room{}, nil)
+		messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: u.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+	}
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	userRepoMock.On("GetByUserName", mock.Anything, bob.UserName).Return(bob, nil)
+	userRepoMock.On("GetByUserName", mock.Anything, mock.Anything).Return(models.User{}, errors.New("record not found"))
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.typing = wss.NewTypingTracker(time.Hour, 500*time.Millisecond)
+
+	s := httptest.NewServer(HandlerWithOptions(srv, ChiServerOptions{}))
+	defer s.Close()
+
+	connect := func(u models.User) *websocket.Conn {
+		url := "ws" + strings.TrimPrefix(s.URL, "http") + "/chat/ws.rtm.start?token=" + tokens[u.ID]
+
+		ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		return ws
+	}
+
+	send := func(ws *websocket.Conn, request string) {
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	expectTyping := func(ws *websocket.Conn, want wss.TypingPayload) {
+		envelope := readEnvelope(t, ws)
+		payload := wss.TypingPayload{}
+		assert.Equal(t, wss.EventTyping, envelope.Type, "typing event expected")
+		assert.NoError(t, envelope.DecodePayload(&payload), "payload should be valid")
+		assert.Equal(t, want, payload)
+	}
+
+	bobWs := connect(bob)
+	defer bobWs.Close()
+
+	aliceWs := connect(alice)
+	defer aliceWs.Close()
+
+	assert.Equal(t, wss.EventUserJoined, readEnvelope(t, bobWs).Type, "user_joined event expected")
+
+	send(aliceWs, `{"type": "typing", "id": "1", "payload": {"to": "carol", "typing": true}}`)
+
+	failure := readEnvelope(t, aliceWs)
+	protocolErr := wss.Error{}
+	assert.Equal(t, wss.EventError, failure.Type, "error frame expected")
+	assert.NoError(t, failure.DecodePayload(&protocolErr), "payload should be valid")
+	assert.Equal(t, wss.ErrorNotFound, protocolErr.Code, "unexpected error code")
+
+	// The repeated notification is throttled, so the stop is the next frame bob receives.
+	send(aliceWs, `{"type": "typing", "payload": {"typing": true}}`)
+	send(aliceWs, `{"type": "typing", "payload": {"typing": true}}`)
+	send(aliceWs, `{"type": "typing", "payload": {"typing": false}}`)
+
+	expectTyping(bobWs, wss.TypingPayload{User: "alice", Typing: true})
+	expectTyping(bobWs, wss.TypingPayload{User: "alice", Typing: false})
+
+	// Without a stop the indicator expires on its own.
+	send(aliceWs, `{"type": "typing", "payload": {"to": "bob", "typing": true}}`)
+
+	expectTyping(bobWs, wss.TypingPayload{User: "alice", To: "bob", Typing: true})
+	expectTyping(bobWs, wss.TypingPayload{User: "alice", To: "bob", Typing: false})
+
+	messageRepoMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestChat_HandleChatSession_MarkRead(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+	readMarkerRepoMock := &mocks.ReadMarkerRepository{}
+
+	loggerMock.On("Error", mock.AnythingOfType("string")).Maybe().Return()
+	loggerMock.On("Println", mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+
+	alice := *mustNewUser("alice", "12345678")
+	bob := *mustNewUser("bob", "12345678")
+	carol := *mustNewUser("carol", "12345678")
+
+	tokens := make(map[types.Uuid]string)
+	for _, u := range []models.User{alice, bob} {
+		tokens[u.ID] = generators.RandomString(16)
+
+		tokenRepoMock.On("Get", mock.Anything, tokens[u.ID]).Return(models.Token{Token: tokens[u.ID], UserId: u.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
+		userRepoMock.On("GetById", mock.Anything, u.ID).Return(u, nil)
+		roomRepoMock.On("GetByMember", mock.Anything, u.ID).Return([]models.Room{}, nil)
+		messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: u.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+	}
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+	public := models.Message{AuthorUuid: alice.ID, Author: alice, Message: "hello"}
+	public.ID = 7
+	direct := models.Message{AuthorUuid: alice.ID, Author: alice, RecipientUuid: &bob.ID, Recipient: &bob, Message: "psst"}
+	direct.ID = 8
+	private := models.Message{AuthorUuid: alice.ID, Author: alice, RecipientUuid: &carol.ID, Recipient: &carol, Message: "secret"}
+	private.ID = 9
+
+	for _, m := range []models.Message{public, direct, private} {
+		messageRepoMock.On("GetById", mock.Anything, m.ID).Return(m, nil)
+	}
+	messageRepoMock.On("GetById", mock.Anything, mock.Anything).Return(models.Message{}, errors.New("record not found"))
+
+	readMarkerRepoMock.On("Mark", mock.Anything, mock.MatchedBy(func(m *models.ReadMarker) bool { return m.MessageID == public.ID })).Return(true, nil).Once()
+	readMarkerRepoMock.On("Mark", mock.Anything, mock.MatchedBy(func(m *models.ReadMarker) bool { return m.MessageID == public.ID })).Return(false, nil)
+	readMarkerRepoMock.On("Mark", mock.Anything, mock.MatchedBy(func(m *models.ReadMarker) bool {
+		return m.MessageID == direct.ID && m.PeerUuid == alice.ID && m.UserUuid == bob.ID
+	})).Return(true, nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.readMarkerRepo = readMarkerRepoMock
+
+	s := httptest.NewServer(HandlerWithOptions(srv, ChiServerOptions{}))
+	defer s.Close()
+
+	connect := func(u models.User) *websocket.Conn {
+		url := "ws" + strings.TrimPrefix(s.URL, "http") + "/chat/ws.rtm.start?token=" + tokens[u.ID]
+
+		ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		return ws
+	}
+
+	send := func(ws *websocket.Conn, request string) {
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	expectReceipt := func(ws *websocket.Conn, wantType string, want wss.ReadReceiptPayload) {
+		envelope := readEnvelope(t, ws)
+		payload := wss.ReadReceiptPayload{}
+		assert.Equal(t, wantType, envelope.Type, "unexpected frame type")
+		assert.NoError(t, envelope.DecodePayload(&payload), "payload should be valid")
+
+		payload.ReadAt = time.Time{}
+		assert.Equal(t, want, payload)
+	}
+
+	expectError := func(ws *websocket.Conn, wantCode string) {
+		failure := readEnvelope(t, ws)
+		protocolErr := wss.Error{}
+		assert.Equal(t, wss.EventError, failure.Type, "error frame expected")
+		assert.NoError(t, failure.DecodePayload(&protocolErr), "payload should be valid")
+		assert.Equal(t, wantCode, protocolErr.Code, "unexpected error code")
+	}
+
+	aliceWs := connect(alice)
+	defer aliceWs.Close()
+
+	bobWs := connect(bob)
+	defer bobWs.Close()
+
+	assert.Equal(t, wss.EventUserJoined, readEnvelope(t, aliceWs).Type, "user_joined event expected")
+
+	send(bobWs, `{"type": "mark_read", "id": "1", "payload": {"id": 404}}`)
+	expectError(bobWs, wss.ErrorNotFound)
+
+	send(bobWs, `{"type": "mark_read", "id": "2", "payload": {"id": 9}}`)
+	expectError(bobWs, wss.ErrorForbidden)
+
+	send(bobWs, `{"type": "mark_read", "id": "3", "payload": {"id": 7}}`)
+	expectReceipt(bobWs, wss.EventAck, wss.ReadReceiptPayload{User: "bob", Id: 7})
+	expectReceipt(aliceWs, wss.EventReadReceipt, wss.ReadReceiptPayload{User: "bob", Id: 7})
+
+	// A marker that did not move is acknowledged but not relayed.
+	send(bobWs, `{"type": "mark_read", "id": "4", "payload": {"id": 7}}`)
+	expectReceipt(bobWs, wss.EventAck, wss.ReadReceiptPayload{User: "bob", Id: 7})
+
+	send(bobWs, `{"type": "mark_read", "id": "5", "payload": {"id": 8}}`)
+	expectReceipt(bobWs, wss.EventAck, wss.ReadReceiptPayload{User: "bob", Id: 8, To: "alice"})
+	expectReceipt(aliceWs, wss.EventReadReceipt, wss.ReadReceiptPayload{User: "bob", Id: 8, To: "alice"})
+
+	messageRepoMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestChat_HandleChatSession_Reactions(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+	reactionRepoMock := &mocks.ReactionRepository{}
+
+	loggerMock.On("Error", mock.AnythingOfType("string")).Maybe().Return()
+	loggerMock.On("Println", mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+
+	alice := *mustNewUser("alice", "12345678")
+	bob := *mustNewUser("bob", "12345678")
+
+	posted := models.Message{AuthorUuid: alice.ID, Author: alice, Message: "hello"}
+	posted.ID = 7
+
+	tokens := make(map[types.Uuid]string)
+	for _, u := range []models.User{alice, bob} {
+		tokens[u.ID] = generators.RandomString(16)
+
+		tokenRepoMock.On("Get", mock.Anything, tokens[u.ID]).Return(models.Token{Token: tokens[u.ID], UserId: u.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
+		userRepoMock.On("GetById", mock.Anything, u.ID).Return(u, nil)
+		roomRepoMock.On("GetByMember", mock.Anything, u.ID).Return([]models.Room{}, nil)
+	}
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	userRepoMock.On("UpdateLastActivity", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: alice.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+	messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: bob.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{posted}, nil)
+	messageRepoMock.On("GetById", mock.Anything, posted.ID).Return(posted, nil)
+	messageRepoMock.On("GetById", mock.Anything, mock.Anything).Return(models.Message{}, errors.New("record not found"))
+
+	counted := func(count int) map[uint][]models.ReactionCount {
+		return map[uint][]models.ReactionCount{posted.ID: {{Emoji: "👍", Count: count}}}
+	}
+
+	isThumbsUp := mock.MatchedBy(func(r *models.Reaction) bool {
+		return r.MessageID == posted.ID && r.UserUuid == bob.ID && r.Emoji == "👍"
+	})
+
+	reactionRepoMock.On("Add", mock.Anything, isThumbsUp).Return(true, nil).Once()
+	reactionRepoMock.On("Add", mock.Anything, isThumbsUp).Return(false, nil).Once()
+	reactionRepoMock.On("Remove", mock.Anything, isThumbsUp).Return(true, nil).Once()
+	reactionRepoMock.On("CountFor", mock.Anything, []uint{posted.ID}).Return(counted(1), nil).Once()
+	reactionRepoMock.On("CountFor", mock.Anything, []uint{posted.ID}).Return(counted(2), nil).Twice()
+	reactionRepoMock.On("CountFor", mock.Anything, []uint{posted.ID}).Return(counted(1), nil).Once()
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.reactionRepo = reactionRepoMock
+
+	s := httptest.NewServer(HandlerWithOptions(srv, ChiServerOptions{}))
+	defer s.Close()
+
+	connect := func(u models.User) *websocket.Conn {
+		url := "ws" + strings.TrimPrefix(s.URL, "http") + "/chat/ws.rtm.start?token=" + tokens[u.ID]
+
+		ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		return ws
+	}
+
+	send := func(ws *websocket.Conn, request string) {
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	expectReaction := func(ws *websocket.Conn, wantType string, want wss.ReactionPayload) {
+		envelope := readEnvelope(t, ws)
+		payload := wss.ReactionPayload{}
+		assert.Equal(t, wantType, envelope.Type, "unexpected frame type")
+		assert.NoError(t, envelope.DecodePayload(&payload), "payload should be valid")
+		assert.Equal(t, want, payload)
+	}
+
+	aliceWs := connect(alice)
+	defer aliceWs.Close()
+
+	bobWs := connect(bob)
+	defer bobWs.Close()
+
+	replayed := readEnvelope(t, bobWs)
+	payload := wss.MessagePayload{}
+	assert.Equal(t, wss.EventMessage, replayed.Type, "replayed message expected")
+	assert.NoError(t, replayed.DecodePayload(&payload), "payload should be valid")
+	assert.Equal(t, []wss.ReactionCount{{Emoji: "👍", Count: 1}}, payload.Reactions, "replay should carry reactions")
+
+	assert.Equal(t, wss.EventUserJoined, readEnvelope(t, aliceWs).Type, "user_joined event expected")
+
+	send(bobWs, `{"type": "add_reaction", "id": "1", "payload": {"id": 7, "emoji": "thumbs up"}}`)
+
+	failure := readEnvelope(t, bobWs)
+	protocolErr := wss.Error{}
+	assert.Equal(t, wss.EventError, failure.Type, "error frame expected")
+	assert.NoError(t, failure.DecodePayload(&protocolErr), "payload should be valid")
+	assert.Equal(t, wss.ErrorInvalidPayload, protocolErr.Code, "unexpected error code")
+
+	send(bobWs, `{"type": "add_reaction", "id": "2", "payload": {"id": 7, "emoji": "👍"}}`)
+	expectReaction(bobWs, wss.EventAck, wss.ReactionPayload{Id: 7, Emoji: "👍", User: "bob", Count: 2})
+	expectReaction(aliceWs, wss.EventReactionAdded, wss.ReactionPayload{Id: 7, Emoji: "👍", User: "bob", Count: 2})
+
+	// Repeating a reaction is acknowledged but not announced.
+	send(bobWs, `{"type": "add_reaction", "id": "3", "payload": {"id": 7, "emoji": "👍"}}`)
+	expectReaction(bobWs, wss.EventAck, wss.ReactionPayload{Id: 7, Emoji: "👍", User: "bob", Count: 2})
+
+	send(bobWs, `{"type": "remove_reaction", "id": "4", "payload": {"id": 7, "emoji": "👍"}}`)
+	expectReaction(bobWs, wss.EventAck, wss.ReactionPayload{Id: 7, Emoji: "👍", User: "bob", Count: 1})
+	expectReaction(aliceWs, wss.EventReactionRemoved, wss.ReactionPayload{Id: 7, Emoji: "👍", User: "bob", Count: 1})
+
+	reactionRepoMock.AssertExpectations(t)
+}
+
+func TestChat_HandleChatSession_Threads(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	loggerMock.On("Error", mock.AnythingOfType("string")).Maybe().Return()
+	loggerMock.On("Println", mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+
+	alice := *mustNewUser("alice", "12345678")
+	bob := *mustNewUser("bob", "12345678")
+	carol := *mustNewUser("carol", "12345678")
+
+	root := models.Message{AuthorUuid: alice.ID, Author: alice, RecipientUuid: &bob.ID, Recipient: &bob, Message: "hello"}
+	root.ID = 7
+
+	reply := models.Message{AuthorUuid: alice.ID, Author: alice, RecipientUuid: &bob.ID, Recipient: &bob, ParentID: &root.ID, Message: "hi"}
+	reply.ID = 8
+
+	private := models.Message{AuthorUuid: alice.ID, Author: alice, RecipientUuid: &carol.ID, Recipient: &carol, Message: "secret"}
+	private.ID = 9
+
+	tokens := make(map[types.Uuid]string)
+	for _, u := range []models.User{alice, bob} {
+		tokens[u.ID] = generators.RandomString(16)
+
+		tokenRepoMock.On("Get", mock.Anything, tokens[u.ID]).Return(models.Token{Token: tokens[u.ID], UserId: u.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
+		userRepoMock.On("GetById", mock.Anything, u.ID).Return(u, nil)
+		roomRepoMock.On("GetByMember", mock.Anything, u.ID).Return([]models.Room{}, nil)
+		messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: u.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+	}
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	userRepoMock.On("UpdateLastActivity", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	for _, m := range []models.Message{root, reply, private} {
+		messageRepoMock.On("GetById", mock.Anything, m.ID).Return(m, nil)
+	}
+	messageRepoMock.On("GetById", mock.Anything, mock.Anything).Return(models.Message{}, errors.New("record not found"))
+
+	messageRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(m *models.Message) bool {
+		return m.ParentID != nil && *m.ParentID == root.ID && m.RecipientUuid != nil && *m.RecipientUuid == alice.ID && m.RoomID == nil
+	})).Return(nil).Twice()
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+
+	s := httptest.NewServer(HandlerWithOptions(srv, ChiServerOptions{}))
+	defer s.Close()
+
+	connect := func(u models.User) *websocket.Conn {
+		url := "ws" + strings.TrimPrefix(s.URL, "http") + "/chat/ws.rtm.start?token=" + tokens[u.ID]
+
+		ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		return ws
+	}
+
+	send := func(ws *websocket.Conn, request string) {
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	expectReply := func(ws *websocket.Conn, wantType string) {
+		envelope := readEnvelope(t, ws)
+		payload := wss.MessagePayload{}
+		assert.Equal(t, wantType, envelope.Type, "unexpected frame type")
+		assert.NoError(t, envelope.DecodePayload(&payload), "payload should be valid")
+		assert.Equal(t, root.ID, payload.ParentId, "reply should point at the thread root")
+		assert.Equal(t, "alice", payload.To, "reply should go to the other participant")
+	}
+
+	expectError := func(ws *websocket.Conn, wantCode string) {
+		envelope := readEnvelope(t, ws)
+		protocolErr := wss.Error{}
+		assert.Equal(t, wss.EventError, envelope.Type, "error frame expected")
+		assert.NoError(t, envelope.DecodePayload(&protocolErr), "payload should be valid")
+		assert.Equal(t, wantCode, protocolErr.Code, "unexpected error code")
+	}
+
+	aliceWs := connect(alice)
+	defer aliceWs.Close()
+
+	bobWs := connect(bob)
+	defer bobWs.Close()
+
+	assert.Equal(t, wss.EventUserJoined, readEnvelope(t, aliceWs).Type, "user_joined event expected")
+
+	send(bobWs, `{"type": "send_message", "id": "1", "payload": {"message": "hey", "parentId": 7, "to": "alice"}}`)
+	expectError(bobWs, wss.ErrorInvalidPayload)
+
+	send(bobWs, `{"type": "send_message", "id": "2", "payload": {"message": "hey", "parentId": 42}}`)
+	expectError(bobWs, wss.ErrorNotFound)
+
+	send(bobWs, `{"type": "send_message", "id": "3", "payload": {"message": "hey", "parentId": 9}}`)
+	expectError(bobWs, wss.ErrorForbidden)
+
+	send(bobWs, `{"type": "send_message", "id": "4", "payload": {"message": "hey", "parentId": 7}}`)
+	expectReply(bobWs, wss.EventAck)
+	expectReply(aliceWs, wss.EventMessage)
+
+	// Replying to a reply continues the thread of its root.
+	send(bobWs, `{"type": "send_message", "id": "5", "payload": {"message": "again", "parentId": 8}}`)
+	expectReply(bobWs, wss.EventAck)
+	expectReply(aliceWs, wss.EventMessage)
+
+	messageRepoMock.AssertExpectations(t)
+}
+
+func TestChat_HandleChatSession_Mentions(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+	mentionRepoMock := &mocks.MentionRepository{}
+
+	loggerMock.On("Error", mock.AnythingOfType("string")).Maybe().Return()
+	loggerMock.On("Println", mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+
+	alice := *mustNewUser("alice", "12345678")
+	bob := *mustNewUser("bob", "12345678")
+
+	tokens := make(map[types.Uuid]string)
+	for _, u := range []models.User{alice, bob} {
+		tokens[u.ID] = generators.RandomString(16)
+
+		tokenRepoMock.On("Get", mock.Anything, tokens[u.ID]).Return(models.Token{Token: tokens[u.ID], UserId: u.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
+		userRepoMock.On("GetById", mock.Anything, u.ID).Return(u, nil)
+		userRepoMock.On("GetByUserName", mock.Anything, u.UserName).Return(u, nil)
+		roomRepoMock.On("GetByMember", mock.Anything, u.ID).Return([]models.Room{}, nil)
+		messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: u.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+	}
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	userRepoMock.On("UpdateLastActivity", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	userRepoMock.On("GetByUserName", mock.Anything, "dave").Return(models.User{}, errors.New("record not found"))
+
+	messageRepoMock.On("Create", mock.Anything, mock.AnythingOfType("*models.Message")).Return(nil)
+
+	// Unknown users and the author are not mentioned, repeated names are recorded once.
+	mentionRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(mentions []models.Mention) bool {
+		return len(mentions) == 1 && mentions[0].UserUuid == bob.ID
+	})).Return(nil).Once()
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.mentionRepo = mentionRepoMock
+
+	s := httptest.NewServer(HandlerWithOptions(srv, ChiServerOptions{}))
+	defer s.Close()
+
+	connect := func(u models.User) *websocket.Conn {
+		url := "ws" + strings.TrimPrefix(s.URL, "http") + "/chat/ws.rtm.start?token=" + tokens[u.ID]
+
+		ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		return ws
+	}
+
+	bobWs := connect(bob)
+	defer bobWs.Close()
+
+	aliceWs := connect(alice)
+	defer aliceWs.Close()
+
+	assert.Equal(t, wss.EventUserJoined, readEnvelope(t, bobWs).Type, "user_joined event expected")
+
+	request := `{"type": "send_message", "id": "1", "payload": {"message": "lunch @bob? (@dave, @alice and @bob!)"}}`
+	if err := aliceWs.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	assert.Equal(t, wss.EventAck, readEnvelope(t, aliceWs).Type, "ack expected")
+
+	for _, wantType := range []string{wss.EventMessage, wss.EventMention} {
+		envelope := readEnvelope(t, bobWs)
+		payload := wss.MessagePayload{}
+		assert.Equal(t, wantType, envelope.Type, "unexpected frame type")
+		assert.NoError(t, envelope.DecodePayload(&payload), "payload should be valid")
+		assert.Equal(t, "alice", payload.Author, "author should be set")
+	}
+
+	mentionRepoMock.AssertExpectations(t)
+}
+
+func TestChat_HandleChatSession_Attachments(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+	attachmentRepoMock := &mocks.AttachmentRepository{}
+
+	loggerMock.On("Error", mock.AnythingOfType("string")).Maybe().Return()
+	loggerMock.On("Println", mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+
+	alice := *mustNewUser("alice", "12345678")
+	bob := *mustNewUser("bob", "12345678")
+
+	token := generators.RandomString(16)
+	tokenRepoMock.On("Get", mock.Anything, token).Return(models.Token{Token: token, UserId: alice.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	userRepoMock.On("GetById", mock.Anything, alice.ID).Return(alice, nil)
+	userRepoMock.On("UpdateLastActivity", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	roomRepoMock.On("GetByMember", mock.Anything, alice.ID).Return([]models.Room{}, nil)
+	messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: alice.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+
+	photo := models.Attachment{UploaderUuid: alice.ID, FileName: "cat.png", ContentType: "image/png", Size: 42}
+	photo.ID = 1
+
+	foreign := models.Attachment{UploaderUuid: bob.ID, FileName: "dog.png", ContentType: "image/png", Size: 42}
+	foreign.ID = 2
+
+	sentId := uint(9)
+	sent := models.Attachment{UploaderUuid: alice.ID, MessageID: &sentId, FileName: "old.png", ContentType: "image/png", Size: 42}
+	sent.ID = 3
+
+	for _, a := range []models.Attachment{photo, foreign, sent} {
+		attachmentRepoMock.On("GetById", mock.Anything, a.ID).Return(a, nil)
+	}
+	attachmentRepoMock.On("GetById", mock.Anything, mock.Anything).Return(models.Attachment{}, errors.New("record not found"))
+
+	messageRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(m *models.Message) bool {
+		return len(m.Attachments) == 1 && m.Attachments[0].ID == photo.ID
+	})).Return(nil).Once()
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.attachmentRepo = attachmentRepoMock
+
+	s := httptest.NewServer(HandlerWithOptions(srv, ChiServerOptions{}))
+	defer s.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/chat/ws.rtm.start?token="+token, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer ws.Close()
+
+	tests := []struct {
+		name      string
+		request   string
+		wantType  string
+		wantError string
+	}{
+		{"Unknown attachment", `{"type": "send_message", "id": "1", "payload": {"message": "", "attachments": [42]}}`, wss.EventError, wss.ErrorNotFound},
+		{"Attachment of another user", `{"type": "send_message", "id": "2", "payload": {"message": "", "attachments": [2]}}`, wss.EventError, wss.ErrorForbidden},
+		{"Attachment sent before", `{"type": "send_message", "id": "3", "payload": {"message": "", "attachments": [3]}}`, wss.EventError, wss.ErrorInvalidPayload},
+		{"Attachment listed twice", `{"type": "send_message", "id": "4", "payload": {"message": "", "attachments": [1, 1]}}`, wss.EventError, wss.ErrorInvalidPayload},
+		{"Attachment without text", `{"type": "send_message", "id": "5", "payload": {"message": "", "attachments": [1]}}`, wss.EventAck, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ws.WriteMessage(websocket.TextMessage, []byte(tt.request)); err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			envelope := readEnvelope(t, ws)
+			assert.Equal(t, tt.wantType, envelope.Type, "unexpected frame type")
+
+			if tt.wantError != "" {
+				protocolErr := wss.Error{}
+				assert.NoError(t, envelope.DecodePayload(&protocolErr), "payload should be valid")
+				assert.Equal(t, tt.wantError, protocolErr.Code, "unexpected error code")
+				return
+			}
+
+			payload := wss.MessagePayload{}
+			assert.NoError(t, envelope.DecodePayload(&payload), "payload should be valid")
+			assert.Equal(t, []wss.AttachmentPayload{{Id: 1, FileName: "cat.png", ContentType: "image/png", Size: 42, Url: "/chat/attachments/1"}}, payload.Attachments)
+		})
+	}
+
+	messageRepoMock.AssertExpectations(t)
+}
+
+func TestChat_ReapStaleClients(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	loggerMock.On("Error", mock.AnythingOfType("string")).Maybe().Return()
+	loggerMock.On("Println", mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+
+	user := *mustNewUser("testuser", "12345678")
+	tokenString := generators.RandomString(16)
+
+	tokenRepoMock.On("Get", mock.Anything, tokenString).Return(models.Token{Token: tokenString, UserId: user.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	userRepoMock.On("GetById", mock.Anything, user.ID).Return(user, nil)
+	messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: user.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+	roomRepoMock.On("GetByMember", mock.Anything, user.ID).Return([]models.Room{}, nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.idleTimeout = time.Minute
+
+	s := httptest.NewServer(HandlerWithOptions(srv, ChiServerOptions{}))
+	defer s.Close()
+
+	u := "ws" + strings.TrimPrefix(s.URL, "http") + "/chat/ws.rtm.start?token=" + tokenString
+
+	ws, _, err := websocket.DefaultDialer.Dial(u, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer ws.Close()
+
+	// Round trip a command so the session is registered.
+	if err := ws.WriteMessage(websocket.TextMessage, []byte(`{"type": "dance"}`)); err != nil {
+		t.Fatalf("%v", err)
+	}
+	readEnvelope(t, ws)
+
+	srv.reapStaleClients(time.Now())
+	assert.Len(t, srv.chatData.GetAllClients(), 1, "active client should not be reaped")
+
+	srv.reapStaleClients(time.Now().Add(2 * time.Minute))
+	assert.Len(t, srv.chatData.GetAllClients(), 0, "idle client should be reaped")
+
+	_, _, err = ws.ReadMessage()
+
+	closeErr := &websocket.CloseError{}
+	if assert.ErrorAs(t, err, &closeErr, "close frame expected") {
+		assert.Equal(t, websocket.CloseGoingAway, closeErr.Code, "unexpected close code")
+		assert.Equal(t, "idle timeout", closeErr.Text, "unexpected close reason")
+	}
+}
+
+func TestChat_HandleChatSession_ProtocolErrors(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	loggerMock.On("Error", mock.AnythingOfType("string")).Maybe().Return()
+	loggerMock.On("Println", mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+
+	user := *mustNewUser("testuser", "12345678")
+	tokenString := generators.RandomString(16)
+
+	tokenRepoMock.On("Get", mock.Anything, tokenString).Return(models.Token{Token: tokenString, UserId: user.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	userRepoMock.On("GetById", mock.Anything, user.ID).Return(user, nil)
+	messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: user.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+	roomRepoMock.On("GetByMember", mock.Anything, user.ID).Return([]models.Room{}, nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+
+	s := httptest.NewServer(HandlerWithOptions(srv, ChiServerOptions{}))
+	defer s.Close()
+
+	u := "ws" + strings.TrimPrefix(s.URL, "http") + "/chat/ws.rtm.start?token=" + tokenString
+
+	ws, _, err := websocket.DefaultDialer.Dial(u, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer ws.Close()
+
+	tests := []struct {
+		name     string
+		request  string
+		wantId   string
+		wantCode string
+	}{
+		{"Invalid JSON", `{"type": `, "", wss.ErrorInvalidFrame},
+		{"Missing type", `{"id": "1"}`, "1", wss.ErrorInvalidFrame},
+		{"Unsupported version", `{"version": 99, "type": "send_message", "id": "2"}`, "2", wss.ErrorUnsupportedVersion},
+		{"Unknown type", `{"type": "dance", "id": "3"}`, "3", wss.ErrorUnknownType},
+		{"Invalid payload", `{"type": "send_message", "id": "4", "payload": {"message": 42}}`, "4", wss.ErrorInvalidPayload},
+		{"Empty message", `{"type": "send_message", "id": "5", "payload": {"message": " "}}`, "5", wss.ErrorInvalidPayload},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ws.WriteMessage(websocket.TextMessage, []byte(tt.request)); err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			response := readEnvelope(t, ws)
+			assert.Equal(t, wss.EventError, response.Type, "error frame expected")
+			assert.Equal(t, tt.wantId, response.Id, "error should reference request id")
+
+			protocolErr := wss.Error{}
+			assert.NoError(t, response.DecodePayload(&protocolErr), "payload should be valid")
+			assert.Equal(t, tt.wantCode, protocolErr.Code, "unexpected error code")
+		})
+	}
+
+	messageRepoMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func readEnvelope(t *testing.T, ws *websocket.Conn) wss.Envelope {
+	_, p, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	response := wss.Envelope{}
+	assert.NoError(t, json.Unmarshal(p, &response), "json should be valid")
+
+	return response
+}
+
+// historyReplayLimit is the connect-time replay window of servers built by newChatTestServer.
+const historyReplayLimit = 20
+
+// mustNewUser creates a user for tests, it panics if the password cannot be hashed.
+func mustNewUser(username, password string) *models.User {
+	u, err := models.NewUser(username, password)
+	if err != nil {
+		panic(err)
+	}
+
+	return u
+}
+
+func getChatHandlerMocks() (*mocks.FieldLogger, *mocks.UserRepository, *mocks.TokenRepository, *mocks.MessageRepository, *mocks.RoomRepository) {
+	loggerMock := &mocks.FieldLogger{}
+	userRepoMock := &mocks.UserRepository{}
+	tokenRepoMock := &mocks.TokenRepository{}
+	messageRepoMock := &mocks.MessageRepository{}
+	roomRepoMock := &mocks.RoomRepository{}
+
+	return loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock
+}
+
+func newChatTestServer(
+	loggerMock *mocks.FieldLogger,
+	userRepoMock *mocks.UserRepository,
+	tokenRepoMock *mocks.TokenRepository,
+	messageRepoMock *mocks.MessageRepository,
+	roomRepoMock *mocks.RoomRepository,
+) *Server {
+	srv := &Server{
+		historyReplayLimit: historyReplayLimit,
+		logger:             loggerMock,
+		requestUpgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
+		authMiddleware: middlewares.NewAuthMiddleware(repoAccessTokens{tokenRepoMock}),
+		accessTokens:   repoAccessTokens{tokenRepoMock},
+		chatData:       wss.NewChatData(),
+		typing:         wss.NewTypingTracker(time.Hour, time.Hour),
+		slashCommands:  defaultSlashCommands(),
+		clientOptions: wss.ClientOptions{
+			QueueSize:    16,
+			WriteTimeout: time.Second,
+			Policy:       wss.PolicyDisconnect,
+		},
+		userRepo:    userRepoMock,
+		tokenRepo:   tokenRepoMock,
+		messageRepo: messageRepoMock,
+		roomRepo:    roomRepoMock,
+	}
+
+	srv.chatData.SetPresenceHandler(srv.announcePresence)
+
+	return srv
+}
+
+// repoAccessTokens authenticates with the tokens of the mocked token repository, so tests can name their tokens.
+type repoAccessTokens struct {
+	tokenRepo *mocks.TokenRepository
+}
+
+func (a repoAccessTokens) Issue(userId types.Uuid, _ string, now time.Time) (string, time.Time, error) {
+	return string(userId) + "AccessToken", now.Add(15 * time.Minute), nil
+}
+
+// Revoke and RevokeSession are no-ops, the tokens of the double only live in the mocked repository.
+func (a repoAccessTokens) Revoke(types.Uuid, time.Time) {}
+
+func (a repoAccessTokens) RevokeSession(string, time.Time) {}
+
+// Verify returns the user of the token, its family is the session.
+func (a repoAccessTokens) Verify(token string) (jwt.Claims, error) {
+	t, err := a.tokenRepo.Get(nil, token)
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+
+	if t.Expiration.Before(time.Now()) {
+		return jwt.Claims{}, jwt.ErrExpired
+	}
 
-	return loggerMock, userRepoMock, tokenRepoMock
+	return jwt.Claims{Subject: string(t.UserId), SessionId: t.FamilyId}, nil
 }
 
 func generateClientsData(count int) *wss.ChatData {
@@ -176,7 +1724,7 @@ func generateClientsData(count int) *wss.ChatData {
 
 		client := &wss.Client{
 			JoinedAt:   time.Now(),
-			User:       models.NewUser(username, "password"),
+			User:       mustNewUser(username, "password"),
 			EntryToken: token,
 			IPAddress:  "1.1.1.1",
 			WebSocket:  nil,
@@ -0,0 +1,273 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	netUrl "net/url"
+	"strings"
+
+	"github.com/id-tarzanych/lets-go-chat/api/middlewares"
+	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/models"
+	"github.com/id-tarzanych/lets-go-chat/pkg/generators"
+)
+
+// maxIncomingWebhookBodySize bounds the JSON payload posted to an incoming webhook.
+const maxIncomingWebhookBodySize = 64 << 10
+
+var errIncomingWebhookNotFound = errors.New("incoming webhook not found")
+
+// PostIncomingWebhook posts the message as the user of the webhook. The token in the path is the only credential.
+func (s Server) PostIncomingWebhook(w http.ResponseWriter, r *http.Request, token string) {
+	hook, err := s.webhookRepo.GetIncomingByToken(r.Context(), token)
+	if err != nil {
+		http.Error(w, "Unknown webhook", http.StatusNotFound)
+		return
+	}
+
+	var reqBody PostIncomingWebhookJSONRequestBody
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxIncomingWebhookBodySize)
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Syntax error", http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(reqBody.Message) == "" {
+		http.Error(w, "Message is required", http.StatusBadRequest)
+		return
+	}
+
+	m, err := s.postIncomingMessage(r.Context(), hook, reqBody.Message)
+	if err != nil {
+		s.logger.Errorln("Could not post message of incoming webhook. ", err)
+		http.Error(w, "Could not post message", http.StatusInternalServerError)
+		return
+	}
+
+	js, _ := json.Marshal(messageResponse(m))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+func (s Server) ListIncomingWebhooks(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.requireAdmin(r.Context(), userId); err != nil {
+		s.incomingWebhookError(w, err, 0)
+		return
+	}
+
+	hooks, err := s.webhookRepo.GetAllIncoming(r.Context())
+	if err != nil {
+		s.incomingWebhookError(w, err, 0)
+		return
+	}
+
+	respBody := IncomingWebhooksResponse{Webhooks: make([]IncomingWebhook, 0, len(hooks))}
+	for i := range hooks {
+		resp, err := s.incomingWebhookResponse(r.Context(), &hooks[i])
+		if err != nil {
+			s.incomingWebhookError(w, err, 0)
+			return
+		}
+
+		respBody.Webhooks = append(respBody.Webhooks, resp)
+	}
+
+	js, _ := json.Marshal(respBody)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+func (s Server) CreateIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.requireAdmin(r.Context(), userId); err != nil {
+		s.incomingWebhookError(w, err, 0)
+		return
+	}
+
+	var reqBody CreateIncomingWebhookJSONRequestBody
+
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		http.Error(w, "Syntax error", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(reqBody.Name)
+	if name == "" {
+		http.Error(w, "Empty name", http.StatusBadRequest)
+		return
+	}
+
+	// Webhooks post as bots, so their messages can not pass for those of a person.
+	author, err := s.userRepo.GetByUserName(r.Context(), reqBody.UserName)
+	if err != nil || !author.Bot {
+		http.Error(w, fmt.Sprintf("Bot %s does not exist", reqBody.UserName), http.StatusBadRequest)
+		return
+	}
+
+	var roomId *uint
+	if reqBody.Room != nil {
+		room, err := s.roomRepo.GetByName(r.Context(), *reqBody.Room)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Room %s does not exist", *reqBody.Room), http.StatusBadRequest)
+			return
+		}
+
+		roomId = &room.ID
+	}
+
+	token, err := generators.Token()
+	if err != nil {
+		s.incomingWebhookError(w, err, 0)
+		return
+	}
+
+	hook := models.NewIncomingWebhook(name, token, author.ID, roomId, userId)
+	if err := s.webhookRepo.CreateIncoming(r.Context(), hook); err != nil {
+		s.incomingWebhookError(w, err, 0)
+		return
+	}
+
+	respBody, err := s.incomingWebhookResponse(r.Context(), hook)
+	if err != nil {
+		s.incomingWebhookError(w, err, 0)
+		return
+	}
+
+	url := incomingWebhookURL(r, token)
+	respBody.Url = &url
+
+	js, _ := json.Marshal(respBody)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+func (s Server) DeleteIncomingWebhook(w http.ResponseWriter, r *http.Request, webhookId int) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.requireAdmin(r.Context(), userId); err != nil {
+		s.incomingWebhookError(w, err, webhookId)
+		return
+	}
+
+	if _, err := s.webhookRepo.GetIncomingById(r.Context(), uint(webhookId)); err != nil {
+		s.incomingWebhookError(w, errIncomingWebhookNotFound, webhookId)
+		return
+	}
+
+	if err := s.webhookRepo.DeleteIncoming(r.Context(), uint(webhookId)); err != nil {
+		s.incomingWebhookError(w, err, webhookId)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// postIncomingMessage stores the message of the webhook and broadcasts it like a message sent over WebSocket.
+// It skips deliverMessage, so messages of integrations never reach outgoing webhooks and can not loop between systems.
+func (s Server) postIncomingMessage(ctx context.Context, hook models.IncomingWebhook, text string) (*models.Message, error) {
+	author, err := s.userRepo.GetById(ctx, hook.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &models.Message{AuthorUuid: author.ID, Author: author, Message: text}
+
+	if hook.RoomID != nil {
+		room, err := s.roomRepo.GetById(ctx, *hook.RoomID)
+		if err != nil {
+			return nil, err
+		}
+
+		m.RoomID = &room.ID
+		m.Room = &room
+	}
+
+	if err := s.messageRepo.Create(ctx, m); err != nil {
+		return nil, err
+	}
+
+	s.broadcastMessage(wss.EventMessage, m)
+	s.notifyMentions(ctx, m)
+
+	return m, nil
+}
+
+func (s Server) incomingWebhookResponse(ctx context.Context, hook *models.IncomingWebhook) (IncomingWebhook, error) {
+	author, err := s.userRepo.GetById(ctx, hook.UserId)
+	if err != nil {
+		return IncomingWebhook{}, err
+	}
+
+	resp := IncomingWebhook{Id: int(hook.ID), Name: hook.Name, UserName: author.UserName, CreatedAt: hook.CreatedAt}
+
+	if hook.RoomID != nil {
+		room, err := s.roomRepo.GetById(ctx, *hook.RoomID)
+		if err != nil {
+			return IncomingWebhook{}, err
+		}
+
+		resp.Room = &room.Name
+	}
+
+	return resp, nil
+}
+
+func (s Server) incomingWebhookError(w http.ResponseWriter, err error, webhookId int) {
+	switch {
+	case errors.Is(err, errNotAdmin):
+		http.Error(w, "Only admins can manage webhooks", http.StatusForbidden)
+	case errors.Is(err, errIncomingWebhookNotFound):
+		http.Error(w, fmt.Sprintf("Incoming webhook %d does not exist", webhookId), http.StatusNotFound)
+	default:
+		s.logger.Errorln("Could not manage incoming webhooks. ", err)
+		http.Error(w, "Could not manage incoming webhooks", http.StatusInternalServerError)
+	}
+}
+
+func incomingWebhookURL(r *http.Request, token string) string {
+	url := netUrl.URL{
+		Scheme: "http",
+		Host:   r.Host,
+		Path:   "/hooks/" + token,
+	}
+
+	if r.TLS != nil {
+		url.Scheme = "https"
+	}
+
+	return url.String()
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/mocks"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func TestServer_PostIncomingWebhook(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+	webhookRepoMock := &mocks.WebhookRepository{}
+
+	loggerMock.On("Errorln", "Could not post message of incoming webhook. ", mock.Anything).Return()
+
+	bob := *mustNewUser("bob", "12345678")
+	ci := *models.NewBot("ci", "admin")
+
+	general := models.Room{Name: "general"}
+	general.ID = 1
+
+	hook := *models.NewIncomingWebhook("builds", "hookToken", ci.ID, &general.ID, "admin")
+	broken := *models.NewIncomingWebhook("broken", "brokenToken", "missing", nil, "admin")
+
+	tokenRepoMock.On("Get", mock.Anything, "bobToken").Return(models.Token{Token: "bobToken", UserId: bob.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	webhookRepoMock.On("GetIncomingByToken", mock.Anything, "hookToken").Return(hook, nil)
+	webhookRepoMock.On("GetIncomingByToken", mock.Anything, "brokenToken").Return(broken, nil)
+	webhookRepoMock.On("GetIncomingByToken", mock.Anything, mock.Anything).Return(models.IncomingWebhook{}, errors.New("record not found"))
+	userRepoMock.On("GetById", mock.Anything, bob.ID).Return(bob, nil)
+	userRepoMock.On("GetById", mock.Anything, ci.ID).Return(ci, nil)
+	userRepoMock.On("GetById", mock.Anything, mock.Anything).Return(models.User{}, errors.New("record not found"))
+	userRepoMock.On("UpdateLastActivity", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	roomRepoMock.On("GetById", mock.Anything, general.ID).Return(general, nil)
+	roomRepoMock.On("GetByMember", mock.Anything, bob.ID).Return([]models.Room{general}, nil)
+	messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: bob.ID, RoomIds: []uint{general.ID}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+	messageRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(m *models.Message) bool {
+		return m.AuthorUuid == ci.ID && *m.RoomID == general.ID && m.Message == "build #42 passed"
+	})).Return(nil).Once()
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.webhookRepo = webhookRepoMock
+
+	s := httptest.NewServer(srv.Router())
+	defer s.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/chat/ws.rtm.start?token=bobToken", nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer ws.Close()
+
+	tests := []struct {
+		name     string
+		token    string
+		body     string
+		wantCode int
+	}{
+		{"Unknown token", "stolenToken", `{"message":"build #42 passed"}`, http.StatusNotFound},
+		{"Syntax error", "hookToken", `{"message":`, http.StatusBadRequest},
+		{"Too large", "hookToken", `{"message":"` + strings.Repeat("a", maxIncomingWebhookBodySize) + `"}`, http.StatusBadRequest},
+		{"Empty message", "hookToken", `{"message":" "}`, http.StatusBadRequest},
+		{"Missing author", "brokenToken", `{"message":"build #42 passed"}`, http.StatusInternalServerError},
+		{"Message", "hookToken", `{"message":"build #42 passed"}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(s.URL+"/hooks/"+tt.token, "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.wantCode, resp.StatusCode, "unexpected status code")
+
+			if tt.wantCode == http.StatusCreated {
+				response := Message{}
+				assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response), "json should be valid")
+				assert.Equal(t, "ci", response.Author)
+				assert.Equal(t, "general", *response.Room)
+			}
+		})
+	}
+
+	// The message reaches live sessions like one sent over WebSocket.
+	envelope := readEnvelope(t, ws)
+	payload := wss.MessagePayload{}
+	assert.Equal(t, wss.EventMessage, envelope.Type, "unexpected frame type")
+	assert.NoError(t, envelope.DecodePayload(&payload), "payload should be valid")
+	assert.Equal(t, wss.MessagePayload{Author: "ci", Room: "general", Message: "build #42 passed", SentAt: payload.SentAt}, payload)
+
+	messageRepoMock.AssertExpectations(t)
+}
+
+func TestServer_CreateIncomingWebhook(t *testing.T) {
+	srv, userRepoMock, webhookRepoMock := newWebhookTestServer()
+	roomRepoMock := srv.roomRepo.(*mocks.RoomRepository)
+
+	ci := *models.NewBot("ci", "admin")
+	bob := *mustNewUser("bob", "12345678")
+
+	general := models.Room{Name: "general"}
+	general.ID = 1
+
+	userRepoMock.On("GetByUserName", mock.Anything, "ci").Return(ci, nil)
+	userRepoMock.On("GetByUserName", mock.Anything, "bob").Return(bob, nil)
+	userRepoMock.On("GetByUserName", mock.Anything, mock.Anything).Return(models.User{}, errors.New("record not found"))
+	userRepoMock.On("GetById", mock.Anything, ci.ID).Return(ci, nil)
+	roomRepoMock.On("GetByName", mock.Anything, "general").Return(general, nil)
+	roomRepoMock.On("GetByName", mock.Anything, mock.Anything).Return(models.Room{}, errors.New("record not found"))
+	roomRepoMock.On("GetById", mock.Anything, general.ID).Return(general, nil)
+	webhookRepoMock.On("CreateIncoming", mock.Anything, mock.MatchedBy(func(w *models.IncomingWebhook) bool {
+		return w.Name == "builds" && w.UserId == ci.ID && w.RoomID != nil && *w.RoomID == general.ID && len(w.TokenHash) == 64
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.IncomingWebhook).ID = 3
+	}).Return(nil).Once()
+
+	tests := []struct {
+		name     string
+		token    string
+		body     string
+		wantCode int
+	}{
+		{"Not an admin", "bobToken", `{"name":"builds","userName":"ci"}`, http.StatusForbidden},
+		{"Empty name", "aliceToken", `{"name":" ","userName":"ci"}`, http.StatusBadRequest},
+		{"Unknown bot", "aliceToken", `{"name":"builds","userName":"robot"}`, http.StatusBadRequest},
+		{"Posting as a person", "aliceToken", `{"name":"builds","userName":"bob"}`, http.StatusBadRequest},
+		{"Unknown room", "aliceToken", `{"name":"builds","userName":"ci","room":"random"}`, http.StatusBadRequest},
+		{"Incoming webhook", "aliceToken", `{"name":"builds","userName":"ci","room":"general"}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/incoming-webhooks?token="+tt.token, strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantCode == http.StatusCreated {
+				response := IncomingWebhook{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+				assert.Equal(t, 3, response.Id)
+				assert.Equal(t, "ci", response.UserName)
+				assert.Equal(t, "general", *response.Room)
+
+				if assert.NotNil(t, response.Url, "url should be returned once") {
+					assert.True(t, strings.HasPrefix(*response.Url, "http://example.com/hooks/"), "unexpected url %s", *response.Url)
+				}
+			}
+		})
+	}
+
+	webhookRepoMock.AssertExpectations(t)
+}
+
+func TestServer_ListIncomingWebhooks(t *testing.T) {
+	srv, userRepoMock, webhookRepoMock := newWebhookTestServer()
+
+	ci := *models.NewBot("ci", "admin")
+	hook := *models.NewIncomingWebhook("builds", "hookToken", ci.ID, nil, "admin")
+	hook.ID = 3
+
+	userRepoMock.On("GetById", mock.Anything, ci.ID).Return(ci, nil)
+	webhookRepoMock.On("GetAllIncoming", mock.Anything).Return([]models.IncomingWebhook{hook}, nil)
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/incoming-webhooks?token=bobToken", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code, "only admins list incoming webhooks")
+
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/incoming-webhooks?token=aliceToken", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "unexpected status code")
+
+	response := IncomingWebhooksResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+	assert.Equal(t, []IncomingWebhook{{Id: 3, Name: "builds", UserName: "ci"}}, response.Webhooks, "urls should not be listed")
+}
+
+func TestServer_DeleteIncomingWebhook(t *testing.T) {
+	srv, _, webhookRepoMock := newWebhookTestServer()
+
+	webhookRepoMock.On("GetIncomingById", mock.Anything, uint(3)).Return(models.IncomingWebhook{ID: 3}, nil)
+	webhookRepoMock.On("GetIncomingById", mock.Anything, mock.Anything).Return(models.IncomingWebhook{}, errors.New("record not found"))
+	webhookRepoMock.On("DeleteIncoming", mock.Anything, uint(3)).Return(nil).Once()
+
+	tests := []struct {
+		name     string
+		token    string
+		url      string
+		wantCode int
+	}{
+		{"Not an admin", "bobToken", "/incoming-webhooks/3", http.StatusForbidden},
+		{"Unknown webhook", "aliceToken", "/incoming-webhooks/4", http.StatusNotFound},
+		{"Incoming webhook", "aliceToken", "/incoming-webhooks/3", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodDelete, tt.url+"?token="+tt.token, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+		})
+	}
+
+	webhookRepoMock.AssertExpectations(t)
+}
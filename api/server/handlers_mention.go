@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/id-tarzanych/lets-go-chat/api/middlewares"
+	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+// maxMentions caps users notified about a single message.
+const maxMentions = 20
+
+func (s Server) GetMentions(w http.ResponseWriter, r *http.Request, params GetMentionsParams) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	cursor, err := newCursor(params.Before, params.After, params.Limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rooms, err := s.roomRepo.GetByMember(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Could not load mentions", http.StatusInternalServerError)
+		return
+	}
+
+	// One extra message tells whether another page follows.
+	limit := cursor.Limit
+	cursor.Limit++
+
+	messages, err := s.messageRepo.GetMentionsFor(r.Context(), newAudience(userId, rooms), cursor)
+	if err != nil {
+		http.Error(w, "Could not load mentions", http.StatusInternalServerError)
+		return
+	}
+
+	messages, hasMore := trimPage(messages, cursor, limit)
+	respBody := MessagesResponse{HasMore: hasMore, Messages: make([]Message, 0, len(messages))}
+
+	if err := s.attachReactions(r.Context(), messages); err != nil {
+		http.Error(w, "Could not load mentions", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range messages {
+		respBody.Messages = append(respBody.Messages, messageResponse(&messages[i]))
+	}
+
+	js, _ := json.Marshal(respBody)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+// notifyMentions records users named in the message and pushes a mention event to every live session
+// of theirs, whatever conversation the session is looking at.
+func (s Server) notifyMentions(ctx context.Context, m *models.Message) {
+	users := s.mentionedUsers(ctx, m)
+	if len(users) == 0 {
+		return
+	}
+
+	mentions := make([]models.Mention, 0, len(users))
+	for i := range users {
+		mentions = append(mentions, models.Mention{MessageID: m.ID, UserUuid: users[i].ID, CreatedAt: m.CreatedAt})
+	}
+
+	if err := s.mentionRepo.Create(ctx, mentions); err != nil {
+		s.logger.Errorln("Could not store mentions. ", err)
+
+		return
+	}
+
+	payload := wss.NewMessagePayload(m)
+	for i := range users {
+		for _, client := range s.chatData.GetUserClients(users[i].ID) {
+			if err := client.SendEvent(wss.EventMention, "", payload); err != nil {
+				s.logger.Warningln("Could not deliver mention. ", err)
+			}
+		}
+	}
+}
+
+// mentionedUsers resolves usernames named in the message to users able to read it, its author aside.
+func (s Server) mentionedUsers(ctx context.Context, m *models.Message) []models.User {
+	var users []models.User
+
+	for _, name := range parseMentions(m.Message) {
+		if len(users) == maxMentions {
+			break
+		}
+
+		user, err := s.userRepo.GetByUserName(ctx, name)
+		if err != nil || user.ID == m.AuthorUuid {
+			continue
+		}
+
+		rooms, err := s.roomRepo.GetByMember(ctx, user.ID)
+		if err != nil || !newAudience(user.ID, rooms).Includes(m) {
+			continue
+		}
+
+		users = append(users, user)
+	}
+
+	return users
+}
+
+// parseMentions returns distinct usernames named by @username tokens of the text, in order of appearance.
+// Punctuation around a token is not part of the username.
+func parseMentions(text string) []string {
+	var names []string
+
+	seen := make(map[string]bool)
+	for _, field := range strings.Fields(text) {
+		field = strings.TrimLeft(field, "(\"'")
+		if !strings.HasPrefix(field, "@") {
+			continue
+		}
+
+		name := strings.TrimRight(field[1:], ".,;:!?)\"'")
+		if name == "" || seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	return names
+}
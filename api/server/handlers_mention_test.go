@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/mocks"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func Test_parseMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"No mentions", "hello world", nil},
+		{"Single mention", "hi @bob", []string{"bob"}},
+		{"Punctuation", "(@bob), @carol! \"@dave\"", []string{"bob", "carol", "dave"}},
+		{"Repeated mention", "@bob @carol @bob", []string{"bob", "carol"}},
+		{"Lone at sign", "meet @ noon, mail me@example.com", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseMentions(tt.text))
+		})
+	}
+}
+
+func TestServer_GetMentions(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+	reactionRepoMock := &mocks.ReactionRepository{}
+
+	alice := *mustNewUser("alice", "12345678")
+	bob := *mustNewUser("bob", "12345678")
+
+	sentAt := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	first := models.Message{AuthorUuid: alice.ID, Author: alice, Message: "hi @bob"}
+	first.ID, first.CreatedAt = 4, sentAt
+
+	second := models.Message{AuthorUuid: alice.ID, Author: alice, RecipientUuid: &bob.ID, Recipient: &bob, Message: "@bob ping"}
+	second.ID, second.CreatedAt = 6, sentAt.Add(time.Minute)
+
+	tokenRepoMock.On("Get", mock.Anything, "bobToken").Return(models.Token{Token: "bobToken", UserId: bob.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+	tokenRepoMock.On("Get", mock.Anything, mock.Anything).Return(models.Token{}, errors.New("record not found"))
+
+	roomRepoMock.On("GetByMember", mock.Anything, bob.ID).Return([]models.Room{}, nil)
+
+	audience := message.Audience{UserId: bob.ID, RoomIds: []uint{}}
+	messageRepoMock.On("GetMentionsFor", mock.Anything, audience, message.Cursor{Limit: defaultMessagesPageLimit + 1}).Return([]models.Message{first, second}, nil)
+	messageRepoMock.On("GetMentionsFor", mock.Anything, audience, message.Cursor{Before: 7, Limit: 2}).Return([]models.Message{first, second}, nil)
+
+	reactionRepoMock.On("CountFor", mock.Anything, mock.Anything).Return(map[uint][]models.ReactionCount{}, nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.reactionRepo = reactionRepoMock
+
+	firstResponse := Message{Id: 4, Author: "alice", Message: "hi @bob", SentAt: first.CreatedAt}
+	secondResponse := Message{Id: 6, Author: "alice", To: &bob.UserName, Message: "@bob ping", SentAt: second.CreatedAt}
+
+	tests := []struct {
+		name     string
+		url      string
+		wantCode int
+		want     MessagesResponse
+	}{
+		{
+			name:     "Invalid token",
+			url:      "/chat/mentions?token=stolenToken",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Invalid limit",
+			url:      "/chat/mentions?token=bobToken&limit=0",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Latest mentions",
+			url:      "/chat/mentions?token=bobToken",
+			wantCode: http.StatusOK,
+			want:     MessagesResponse{Messages: []Message{firstResponse, secondResponse}},
+		},
+		{
+			name:     "Older mentions",
+			url:      "/chat/mentions?token=bobToken&before=7&limit=1",
+			wantCode: http.StatusOK,
+			want:     MessagesResponse{Messages: []Message{secondResponse}, HasMore: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantCode == http.StatusOK {
+				response := MessagesResponse{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+				assert.Equal(t, tt.want, response)
+			}
+		})
+	}
+}
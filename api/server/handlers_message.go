@@ -0,0 +1,340 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/api/middlewares"
+	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+const (
+	defaultMessagesPageLimit = 50
+	maxMessagesPageLimit     = 100
+)
+
+var (
+	errEmptyMessage     = errors.New("message is empty")
+	errMessageNotFound  = errors.New("message not found")
+	errNotMessageAuthor = errors.New("message belongs to another user")
+)
+
+func (s Server) GetMessages(w http.ResponseWriter, r *http.Request, params GetMessagesParams) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	cursor, err := newCursor(params.Before, params.After, params.Limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rooms, err := s.roomRepo.GetByMember(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Could not load messages", http.StatusInternalServerError)
+		return
+	}
+
+	audience := newAudience(userId, rooms)
+
+	// One extra message tells whether another page follows.
+	limit := cursor.Limit
+	cursor.Limit++
+
+	messages, err := s.messageRepo.GetPageFor(r.Context(), audience, cursor)
+	if err != nil {
+		http.Error(w, "Could not load messages", http.StatusInternalServerError)
+		return
+	}
+
+	messages, hasMore := trimPage(messages, cursor, limit)
+	respBody := MessagesResponse{HasMore: hasMore, Messages: make([]Message, 0, limit)}
+
+	if err := s.attachReactions(r.Context(), messages); err != nil {
+		http.Error(w, "Could not load messages", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range messages {
+		respBody.Messages = append(respBody.Messages, messageResponse(&messages[i]))
+	}
+
+	js, _ := json.Marshal(respBody)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+// newCursor validates paging parameters of a message list.
+func newCursor(before, after, limit *int) (message.Cursor, error) {
+	if before != nil && after != nil {
+		return message.Cursor{}, errors.New("Only one of before and after is allowed")
+	}
+
+	cursor := message.Cursor{Limit: defaultMessagesPageLimit}
+	if limit != nil {
+		if *limit < 1 {
+			return message.Cursor{}, errors.New("Limit must be positive")
+		}
+
+		cursor.Limit = *limit
+		if cursor.Limit > maxMessagesPageLimit {
+			cursor.Limit = maxMessagesPageLimit
+		}
+	}
+
+	if before != nil {
+		cursor.Before = uint(*before)
+	}
+
+	if after != nil {
+		cursor.After = uint(*after)
+	}
+
+	return cursor, nil
+}
+
+// trimPage drops the extra message fetched to tell whether another page follows.
+// Pages are ordered oldest first, the extra message is on the side the cursor moves to.
+func trimPage(messages []models.Message, cursor message.Cursor, limit int) ([]models.Message, bool) {
+	if len(messages) <= limit {
+		return messages, false
+	}
+
+	if cursor.After > 0 {
+		return messages[:limit], true
+	}
+
+	return messages[len(messages)-limit:], true
+}
+
+func (s Server) EditMessage(w http.ResponseWriter, r *http.Request, messageId int) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	var reqBody EditMessageJSONRequestBody
+
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		http.Error(w, "Syntax error", http.StatusBadRequest)
+		return
+	}
+
+	m, err := s.editMessage(r.Context(), userId, uint(messageId), reqBody.Message)
+	if err != nil {
+		s.messageError(w, err, messageId)
+		return
+	}
+
+	s.deliverMessage(wss.EventMessageEdited, m, nil)
+
+	js, _ := json.Marshal(messageResponse(m))
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+func (s Server) DeleteMessage(w http.ResponseWriter, r *http.Request, messageId int) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	m, err := s.deleteMessage(r.Context(), userId, uint(messageId))
+	if err != nil {
+		s.messageError(w, err, messageId)
+		return
+	}
+
+	s.deliverMessage(wss.EventMessageDeleted, m, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// editMessage replaces text of a message owned by userId.
+func (s Server) editMessage(ctx context.Context, userId types.Uuid, id uint, text string) (*models.Message, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, errEmptyMessage
+	}
+
+	m, err := s.ownMessage(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Edit events carry the whole message, reactions included.
+	reactions, err := s.reactionRepo.CountFor(ctx, []uint{m.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	m.Reactions = reactions[m.ID]
+
+	editedAt := time.Now()
+	m.Message = text
+	m.EditedAt = &editedAt
+
+	if err := s.messageRepo.Update(ctx, m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// deleteMessage removes a message owned by userId and returns its tombstone.
+func (s Server) deleteMessage(ctx context.Context, userId types.Uuid, id uint) (*models.Message, error) {
+	m, err := s.ownMessage(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tombstones carry no reactions.
+	if err := s.reactionRepo.DeleteByMessage(ctx, m.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.messageRepo.Delete(ctx, m.ID); err != nil {
+		return nil, err
+	}
+
+	// The message is gone already, a file left behind only wastes space.
+	for _, attachment := range m.Attachments {
+		if err := s.blobs.Delete(ctx, attachment.StorageKey); err != nil {
+			s.logger.Warningln("Could not remove attachment file. ", err)
+		}
+	}
+
+	m.Message = ""
+	m.Attachments = nil
+	m.DeletedAt.Time = time.Now()
+	m.DeletedAt.Valid = true
+
+	return m, nil
+}
+
+// attachReactions aggregates reactions of the messages into their Reactions.
+func (s Server) attachReactions(ctx context.Context, messages []models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(messages))
+	for i := range messages {
+		ids = append(ids, messages[i].ID)
+	}
+
+	reactions, err := s.reactionRepo.CountFor(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range messages {
+		messages[i].Reactions = reactions[messages[i].ID]
+	}
+
+	return nil
+}
+
+func (s Server) ownMessage(ctx context.Context, userId types.Uuid, id uint) (*models.Message, error) {
+	m, err := s.messageRepo.GetById(ctx, id)
+	if err != nil {
+		return nil, errMessageNotFound
+	}
+
+	if m.AuthorUuid != userId {
+		return nil, errNotMessageAuthor
+	}
+
+	return &m, nil
+}
+
+func (s Server) messageError(w http.ResponseWriter, err error, messageId int) {
+	switch {
+	case errors.Is(err, errEmptyMessage):
+		http.Error(w, "Empty message", http.StatusBadRequest)
+	case errors.Is(err, errMessageNotFound):
+		http.Error(w, fmt.Sprintf("Message %d does not exist", messageId), http.StatusNotFound)
+	case errors.Is(err, errNotMessageAuthor):
+		http.Error(w, fmt.Sprintf("Message %d belongs to another user", messageId), http.StatusForbidden)
+	default:
+		s.logger.Errorln("Could not modify message. ", err)
+		http.Error(w, fmt.Sprintf("Could not modify message %d", messageId), http.StatusInternalServerError)
+	}
+}
+
+func messageResponse(m *models.Message) Message {
+	resp := Message{
+		Id:      int(m.ID),
+		Author:  m.Author.UserName,
+		Message: m.Message,
+		SentAt:  m.CreatedAt,
+	}
+
+	if m.Room != nil {
+		resp.Room = &m.Room.Name
+	}
+
+	if m.Recipient != nil {
+		resp.To = &m.Recipient.UserName
+	}
+
+	if m.EditedAt != nil {
+		resp.EditedAt = m.EditedAt
+	}
+
+	if m.DeletedAt.Valid {
+		deleted := true
+		resp.Deleted = &deleted
+	}
+
+	if m.ParentID != nil {
+		parentId := int(*m.ParentID)
+		resp.ParentId = &parentId
+	}
+
+	if m.ReplyCount > 0 {
+		resp.ReplyCount = &m.ReplyCount
+		resp.LastReplyAt = m.LastReplyAt
+	}
+
+	if len(m.Attachments) > 0 {
+		attachments := make([]Attachment, 0, len(m.Attachments))
+		for i := range m.Attachments {
+			attachments = append(attachments, attachmentResponse(&m.Attachments[i]))
+		}
+
+		resp.Attachments = &attachments
+	}
+
+	if len(m.Reactions) > 0 {
+		reactions := make([]ReactionCount, 0, len(m.Reactions))
+		for _, reaction := range m.Reactions {
+			reactions = append(reactions, ReactionCount{Emoji: reaction.Emoji, Count: reaction.Count})
+		}
+
+		resp.Reactions = &reactions
+	}
+
+	return resp
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/mocks"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func TestServer_GetMessages(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	user := *mustNewUser("reader", "12345678")
+
+	general := models.Room{Name: "general"}
+	general.ID = 3
+
+	tokenRepoMock.On("Get", mock.Anything, "readerToken").Return(models.Token{Token: "readerToken", UserId: user.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+	roomRepoMock.On("GetByMember", mock.Anything, user.ID).Return([]models.Room{general}, nil)
+
+	page := func(ids ...uint) []models.Message {
+		messages := make([]models.Message, 0, len(ids))
+		for _, id := range ids {
+			m := models.Message{Author: user, Message: fmt.Sprintf("message %d", id)}
+			m.ID = id
+			messages = append(messages, m)
+		}
+
+		return messages
+	}
+
+	audience := message.Audience{UserId: user.ID, RoomIds: []uint{general.ID}}
+	messageRepoMock.On("GetPageFor", mock.Anything, audience, message.Cursor{Limit: defaultMessagesPageLimit + 1}).Return(page(1, 2, 3), nil)
+	messageRepoMock.On("GetPageFor", mock.Anything, audience, message.Cursor{Before: 10, Limit: 3}).Return(page(7, 8, 9), nil)
+	messageRepoMock.On("GetPageFor", mock.Anything, audience, message.Cursor{After: 10, Limit: 3}).Return(page(11, 12, 13), nil)
+	messageRepoMock.On("GetPageFor", mock.Anything, audience, message.Cursor{Limit: maxMessagesPageLimit + 1}).Return(page(), nil)
+
+	reactionRepoMock := &mocks.ReactionRepository{}
+	reactionRepoMock.On("CountFor", mock.Anything, mock.Anything).Return(map[uint][]models.ReactionCount{
+		2: {{Emoji: "👍", Count: 3}, {Emoji: "🎉", Count: 1}},
+	}, nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.reactionRepo = reactionRepoMock
+
+	tests := []struct {
+		name        string
+		url         string
+		wantCode    int
+		wantIds     []int
+		wantHasMore bool
+	}{
+		{name: "No token", url: "/chat/messages", wantCode: http.StatusBadRequest},
+		{name: "Both cursors", url: "/chat/messages?token=readerToken&before=1&after=2", wantCode: http.StatusBadRequest},
+		{name: "Invalid limit", url: "/chat/messages?token=readerToken&limit=0", wantCode: http.StatusBadRequest},
+		{name: "Latest", url: "/chat/messages?token=readerToken", wantCode: http.StatusOK, wantIds: []int{1, 2, 3}},
+		{name: "Before", url: "/chat/messages?token=readerToken&before=10&limit=2", wantCode: http.StatusOK, wantIds: []int{8, 9}, wantHasMore: true},
+		{name: "After", url: "/chat/messages?token=readerToken&after=10&limit=2", wantCode: http.StatusOK, wantIds: []int{11, 12}, wantHasMore: true},
+		{name: "Limit capped", url: "/chat/messages?token=readerToken&limit=1000", wantCode: http.StatusOK, wantIds: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			response := MessagesResponse{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+
+			ids := make([]int, 0, len(response.Messages))
+			for _, m := range response.Messages {
+				ids = append(ids, m.Id)
+			}
+
+			assert.Equal(t, tt.wantIds, ids, "unexpected page")
+			assert.Equal(t, tt.wantHasMore, response.HasMore, "unexpected hasMore")
+
+			for _, m := range response.Messages {
+				if m.Id == 2 {
+					assert.Equal(t, &[]ReactionCount{{Emoji: "👍", Count: 3}, {Emoji: "🎉", Count: 1}}, m.Reactions, "aggregated reactions expected")
+				} else {
+					assert.Nil(t, m.Reactions, "message without reactions")
+				}
+			}
+		})
+	}
+}
+
+func TestServer_EditMessage(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	loggerMock.On("Errorln", "Could not modify message. ", mock.Anything).Return()
+
+	author := *mustNewUser("author", "12345678")
+	other := *mustNewUser("other", "12345678")
+
+	tokenRepoMock.On("Get", mock.Anything, "authorToken").Return(models.Token{Token: "authorToken", UserId: author.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+	tokenRepoMock.On("Get", mock.Anything, "otherToken").Return(models.Token{Token: "otherToken", UserId: other.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+
+	original := models.Message{AuthorUuid: author.ID, Author: author, Message: "helo"}
+	original.ID = 1
+
+	broken := models.Message{AuthorUuid: author.ID, Author: author, Message: "broken"}
+	broken.ID = 2
+
+	messageRepoMock.On("GetById", mock.Anything, uint(1)).Return(original, nil)
+	messageRepoMock.On("GetById", mock.Anything, uint(2)).Return(broken, nil)
+	messageRepoMock.On("GetById", mock.Anything, mock.Anything).Return(models.Message{}, errors.New("record not found"))
+	messageRepoMock.On("Update", mock.Anything, mock.MatchedBy(func(m *models.Message) bool { return m.ID == 2 })).Return(errors.New("storage error"))
+	messageRepoMock.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+	reactionRepoMock := &mocks.ReactionRepository{}
+	reactionRepoMock.On("CountFor", mock.Anything, mock.Anything).Return(map[uint][]models.ReactionCount{}, nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.reactionRepo = reactionRepoMock
+
+	tests := []struct {
+		name        string
+		url         string
+		requestJSON string
+		wantCode    int
+		wantMessage string
+	}{
+		{
+			name:        "No token",
+			url:         "/chat/messages/1",
+			requestJSON: `{"message": "hello"}`,
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Access token is required.",
+		},
+		{
+			name:        "Invalid syntax",
+			url:         "/chat/messages/1?token=authorToken",
+			requestJSON: "{123]",
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Syntax error",
+		},
+		{
+			name:        "Empty message",
+			url:         "/chat/messages/1?token=authorToken",
+			requestJSON: `{"message": " "}`,
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Empty message",
+		},
+		{
+			name:        "Missing message",
+			url:         "/chat/messages/3?token=authorToken",
+			requestJSON: `{"message": "hello"}`,
+			wantCode:    http.StatusNotFound,
+			wantMessage: "Message 3 does not exist",
+		},
+		{
+			name:        "Foreign message",
+			url:         "/chat/messages/1?token=otherToken",
+			requestJSON: `{"message": "hello"}`,
+			wantCode:    http.StatusForbidden,
+			wantMessage: "Message 1 belongs to another user",
+		},
+		{
+			name:        "Storage error",
+			url:         "/chat/messages/2?token=authorToken",
+			requestJSON: `{"message": "hello"}`,
+			wantCode:    http.StatusInternalServerError,
+			wantMessage: "Could not modify message 2",
+		},
+		{
+			name:        "Own message",
+			url:         "/chat/messages/1?token=authorToken",
+			requestJSON: `{"message": "hello"}`,
+			wantCode:    http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPatch, tt.url, strings.NewReader(tt.requestJSON)))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantCode != http.StatusOK {
+				assert.Equal(t, tt.wantMessage, strings.TrimSpace(w.Body.String()), "unexpected error message")
+				return
+			}
+
+			response := Message{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+			assert.Equal(t, "hello", response.Message, "message text should be updated")
+			assert.NotNil(t, response.EditedAt, "message should be marked as edited")
+		})
+	}
+}
+
+func TestServer_DeleteMessage(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	author := *mustNewUser("author", "12345678")
+	other := *mustNewUser("other", "12345678")
+
+	tokenRepoMock.On("Get", mock.Anything, "authorToken").Return(models.Token{Token: "authorToken", UserId: author.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+	tokenRepoMock.On("Get", mock.Anything, "otherToken").Return(models.Token{Token: "otherToken", UserId: other.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+
+	original := models.Message{AuthorUuid: author.ID, Author: author, Message: "oops"}
+	original.ID = 1
+
+	messageRepoMock.On("GetById", mock.Anything, uint(1)).Return(original, nil)
+	messageRepoMock.On("GetById", mock.Anything, mock.Anything).Return(models.Message{}, errors.New("record not found"))
+	messageRepoMock.On("Delete", mock.Anything, uint(1)).Return(nil)
+
+	reactionRepoMock := &mocks.ReactionRepository{}
+	reactionRepoMock.On("DeleteByMessage", mock.Anything, uint(1)).Return(nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.reactionRepo = reactionRepoMock
+
+	tests := []struct {
+		name     string
+		url      string
+		wantCode int
+	}{
+		{"No token", "/chat/messages/1", http.StatusBadRequest},
+		{"Invalid id", "/chat/messages/first?token=authorToken", http.StatusBadRequest},
+		{"Missing message", "/chat/messages/3?token=authorToken", http.StatusNotFound},
+		{"Foreign message", "/chat/messages/1?token=otherToken", http.StatusForbidden},
+		{"Own message", "/chat/messages/1?token=authorToken", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodDelete, tt.url, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+		})
+	}
+
+	messageRepoMock.AssertNumberOfCalls(t, "Delete", 1)
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/api/middlewares"
+	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func (s Server) GetUnreadCounts(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	rooms, err := s.roomRepo.GetByMember(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Could not count unread messages", http.StatusInternalServerError)
+		return
+	}
+
+	counts, err := s.messageRepo.CountUnreadFor(r.Context(), newAudience(userId, rooms))
+	if err != nil {
+		http.Error(w, "Could not count unread messages", http.StatusInternalServerError)
+		return
+	}
+
+	roomNames := make(map[uint]string, len(rooms))
+	for i := range rooms {
+		roomNames[rooms[i].ID] = rooms[i].Name
+	}
+
+	respBody := UnreadCountsResponse{Conversations: make([]UnreadCount, 0, len(counts))}
+	for _, count := range counts {
+		conversation := UnreadCount{Count: count.Count}
+
+		if count.RoomID != 0 {
+			room := roomNames[count.RoomID]
+			conversation.Room = &room
+		}
+
+		if count.PeerUuid != "" {
+			peer, err := s.userRepo.GetById(r.Context(), count.PeerUuid)
+			if err != nil {
+				http.Error(w, "Could not count unread messages", http.StatusInternalServerError)
+				return
+			}
+
+			conversation.To = &peer.UserName
+		}
+
+		respBody.Conversations = append(respBody.Conversations, conversation)
+		respBody.Total += count.Count
+	}
+
+	js, _ := json.Marshal(respBody)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+// canRead tells whether the user of client takes part in the conversation of the message.
+func (s Server) canRead(client *wss.Client, m *models.Message) bool {
+	switch {
+	case m.RecipientUuid != nil:
+		return m.AuthorUuid == client.User.ID || *m.RecipientUuid == client.User.ID
+	case m.RoomID != nil:
+		return s.chatData.InRoom(*m.RoomID, client)
+	}
+
+	return true
+}
+
+// relayReadReceipt queues a read receipt on the live sessions of the conversation participants,
+// except origin which learns about it from an ack.
+func (s Server) relayReadReceipt(receipt wss.ReadReceiptPayload, m *models.Message, origin *wss.Client) {
+	for _, client := range s.conversationClients(m) {
+		if client == origin {
+			continue
+		}
+
+		if err := client.SendEvent(wss.EventReadReceipt, "", receipt); err != nil {
+			s.logger.Warningln("Could not deliver read receipt. ", err)
+		}
+	}
+}
+
+// newReadMarker points the marker of the reader in the conversation of the message at the message.
+func newReadMarker(userId types.Uuid, m *models.Message) models.ReadMarker {
+	marker := models.ReadMarker{UserUuid: userId, MessageID: m.ID, ReadAt: time.Now()}
+
+	if m.RoomID != nil {
+		marker.RoomID = *m.RoomID
+	}
+
+	if m.RecipientUuid != nil {
+		marker.PeerUuid = directPeer(userId, m)
+	}
+
+	return marker
+}
+
+func newReadReceipt(reader *models.User, m *models.Message, marker models.ReadMarker) wss.ReadReceiptPayload {
+	receipt := wss.ReadReceiptPayload{User: reader.UserName, Id: marker.MessageID, ReadAt: marker.ReadAt}
+
+	if m.Room != nil {
+		receipt.Room = m.Room.Name
+	}
+
+	if m.RecipientUuid != nil {
+		receipt.To = m.Author.UserName
+		if m.AuthorUuid == reader.ID && m.Recipient != nil {
+			receipt.To = m.Recipient.UserName
+		}
+	}
+
+	return receipt
+}
+
+// directPeer returns the other participant of the direct conversation of the message.
+func directPeer(userId types.Uuid, m *models.Message) types.Uuid {
+	if m.AuthorUuid == userId {
+		return *m.RecipientUuid
+	}
+
+	return m.AuthorUuid
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func TestServer_GetUnreadCounts(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	alice := *mustNewUser("alice", "12345678")
+	bob := *mustNewUser("bob", "12345678")
+
+	general := models.Room{Name: "general"}
+	general.ID = 3
+
+	tokenRepoMock.On("Get", mock.Anything, "aliceToken").Return(models.Token{Token: "aliceToken", UserId: alice.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+	tokenRepoMock.On("Get", mock.Anything, mock.Anything).Return(models.Token{}, errors.New("record not found"))
+
+	roomRepoMock.On("GetByMember", mock.Anything, alice.ID).Return([]models.Room{general}, nil)
+	userRepoMock.On("GetById", mock.Anything, bob.ID).Return(bob, nil)
+	messageRepoMock.On("CountUnreadFor", mock.Anything, message.Audience{UserId: alice.ID, RoomIds: []uint{general.ID}}).Return([]message.UnreadCount{
+		{Count: 4},
+		{PeerUuid: bob.ID, Count: 1},
+		{RoomID: general.ID, Count: 2},
+	}, nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode int
+		want     UnreadCountsResponse
+	}{
+		{
+			name:     "Invalid token",
+			token:    "stolenToken",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Unread counts",
+			token:    "aliceToken",
+			wantCode: http.StatusOK,
+			want: UnreadCountsResponse{
+				Conversations: []UnreadCount{
+					{Count: 4},
+					{Count: 1, To: &bob.UserName},
+					{Count: 2, Room: &general.Name},
+				},
+				Total: 7,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chat/unread?token="+tt.token, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantCode == http.StatusOK {
+				response := UnreadCountsResponse{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+				assert.Equal(t, tt.want, response)
+			}
+		})
+	}
+}
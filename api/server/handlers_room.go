@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/id-tarzanych/lets-go-chat/api/middlewares"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func (s Server) ListRooms(w http.ResponseWriter, r *http.Request) {
+	_, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	rooms, err := s.roomRepo.GetAll(r.Context())
+	if err != nil {
+		http.Error(w, "Could not load rooms", http.StatusInternalServerError)
+		return
+	}
+
+	respBody := RoomsResponse{Rooms: make([]Room, 0, len(rooms))}
+	for i := range rooms {
+		respBody.Rooms = append(respBody.Rooms, roomResponse(rooms[i]))
+	}
+
+	js, _ := json.Marshal(respBody)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+func (s Server) CreateRoom(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	var reqBody CreateRoomJSONRequestBody
+
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		http.Error(w, "Syntax error", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(reqBody.Name)
+	if name == "" {
+		http.Error(w, "Empty room name", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.roomRepo.GetByName(r.Context(), name); err == nil {
+		http.Error(w, fmt.Sprintf("Room with name %s already exists", name), http.StatusBadRequest)
+		return
+	}
+
+	var topic string
+	if reqBody.Topic != nil {
+		topic = strings.TrimSpace(*reqBody.Topic)
+	}
+
+	room := models.NewRoom(name, topic, userId)
+	if err := s.roomRepo.Create(r.Context(), room); err != nil {
+		http.Error(w, fmt.Sprintf("Could not create room %s", name), http.StatusInternalServerError)
+		return
+	}
+
+	// The creator is a member, its live sessions receive messages of the room right away.
+	for _, client := range s.chatData.GetUserClients(userId) {
+		s.chatData.JoinRoom(room.ID, client)
+	}
+
+	js, _ := json.Marshal(roomResponse(*room))
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+func roomResponse(room models.Room) Room {
+	resp := Room{Id: int(room.ID), Name: room.Name}
+	if room.Topic != "" {
+		resp.Topic = &room.Topic
+	}
+
+	return resp
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func TestServer_ListRooms(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	bob := mustNewUser("bob", "12345678")
+
+	general := models.Room{Name: "general", Topic: "Everything goes"}
+	general.ID = 1
+	random := models.Room{Name: "random"}
+	random.ID = 2
+
+	tokenRepoMock.On("Get", mock.Anything, "bobToken").Return(models.Token{Token: "bobToken", UserId: bob.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+	roomRepoMock.On("GetAll", mock.Anything).Return([]models.Room{general, random}, nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chat/rooms", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code, "rooms should not be listed without access token")
+
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chat/rooms?token=bobToken", nil))
+
+	response := RoomsResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+
+	topic := "Everything goes"
+	assert.NoError(t, err, "json should be valid")
+	assert.Equal(t, RoomsResponse{Rooms: []Room{{Id: 1, Name: "general", Topic: &topic}, {Id: 2, Name: "random"}}}, response)
+}
+
+func TestServer_CreateRoom(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	bob := mustNewUser("bob", "12345678")
+
+	tokenRepoMock.On("Get", mock.Anything, "bobToken").Return(models.Token{Token: "bobToken", UserId: bob.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+	tokenRepoMock.On("Get", mock.Anything, mock.Anything).Return(models.Token{}, errors.New("record not found"))
+
+	roomRepoMock.On("GetByName", mock.Anything, "existingRoom").Return(models.Room{Name: "existingRoom"}, nil)
+	roomRepoMock.On("GetByName", mock.Anything, mock.Anything).Return(models.Room{}, errors.New("record not found"))
+	roomRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(r *models.Room) bool { return r.Name == "storageErrorRoom" })).Return(errors.New("storage error"))
+	roomRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(r *models.Room) bool {
+		return r.CreatedBy != nil && *r.CreatedBy == bob.ID
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Room).ID = 7
+	}).Return(nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+
+	bobClient := &wss.Client{JoinedAt: time.Now(), User: bob}
+	srv.chatData.StoreClient(bobClient)
+
+	tests := []struct {
+		name        string
+		token       string
+		requestJSON string
+		wantCode    int
+		wantMessage string
+	}{
+		{
+			name:        "Missing token",
+			requestJSON: "{\"name\": \"newRoom\"}",
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Access token is required.",
+		},
+		{
+			name:        "Invalid token",
+			token:       "stolenToken",
+			requestJSON: "{\"name\": \"newRoom\"}",
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Access token is invalid.",
+		},
+		{
+			name:        "Invalid syntax",
+			token:       "bobToken",
+			requestJSON: "{123]",
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Syntax error",
+		},
+		{
+			name:        "Empty name",
+			token:       "bobToken",
+			requestJSON: "{\"name\": \"  \"}",
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Empty room name",
+		},
+		{
+			name:        "Name conflict",
+			token:       "bobToken",
+			requestJSON: "{\"name\": \"existingRoom\"}",
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Room with name existingRoom already exists",
+		},
+		{
+			name:        "Storage operation error",
+			token:       "bobToken",
+			requestJSON: "{\"name\": \"storageErrorRoom\"}",
+			wantCode:    http.StatusInternalServerError,
+			wantMessage: "Could not create room storageErrorRoom",
+		},
+		{
+			name:        "Successful room creation",
+			token:       "bobToken",
+			requestJSON: "{\"name\": \"newRoom\", \"topic\": \"Project news\"}",
+			wantCode:    http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/chat/rooms?token="+tt.token, strings.NewReader(tt.requestJSON)))
+			response := w.Result()
+
+			if response.StatusCode != tt.wantCode {
+				t.Errorf("Incorrect status code, wanted %d, got %d.", tt.wantCode, response.StatusCode)
+			}
+
+			responseBody := strings.TrimSpace(w.Body.String())
+			if tt.wantCode != http.StatusOK && tt.wantMessage != responseBody {
+				t.Errorf("Incorrect error message, wanted \"%s\", got \"%s\"", tt.wantMessage, responseBody)
+			}
+		})
+	}
+
+	assert.True(t, srv.chatData.InRoom(7, bobClient), "live sessions of the creator should join the room")
+}
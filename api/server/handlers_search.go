@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/id-tarzanych/lets-go-chat/api/middlewares"
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+)
+
+func (s Server) SearchMessages(w http.ResponseWriter, r *http.Request, params SearchMessagesParams) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	cursor, err := newCursor(params.Before, params.After, params.Limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if strings.IndexFunc(params.Q, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+		http.Error(w, "Search query has no words", http.StatusBadRequest)
+		return
+	}
+
+	filter := message.SearchFilter{Query: params.Q}
+
+	if params.From != nil {
+		filter.Since = *params.From
+	}
+
+	if params.To != nil {
+		filter.Until = *params.To
+	}
+
+	// Absent dates are bound as zero times, they leave the range open.
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		http.Error(w, "from must be earlier than to", http.StatusBadRequest)
+		return
+	}
+
+	if params.Author != nil {
+		author, err := s.userRepo.GetByUserName(r.Context(), *params.Author)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("User %s does not exist", *params.Author), http.StatusBadRequest)
+			return
+		}
+
+		filter.AuthorUuid = author.ID
+	}
+
+	rooms, err := s.roomRepo.GetByMember(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Could not search messages", http.StatusInternalServerError)
+		return
+	}
+
+	if params.Room != nil {
+		for i := range rooms {
+			if rooms[i].Name == *params.Room {
+				filter.RoomID = rooms[i].ID
+			}
+		}
+
+		if filter.RoomID == 0 {
+			http.Error(w, fmt.Sprintf("Not a member of room %s", *params.Room), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// One extra message tells whether another page follows.
+	limit := cursor.Limit
+	cursor.Limit++
+
+	messages, err := s.messageRepo.Search(r.Context(), newAudience(userId, rooms), filter, cursor)
+	if err != nil {
+		http.Error(w, "Could not search messages", http.StatusInternalServerError)
+		return
+	}
+
+	messages, hasMore := trimPage(messages, cursor, limit)
+	respBody := MessagesResponse{HasMore: hasMore, Messages: make([]Message, 0, len(messages))}
+
+	if err := s.attachReactions(r.Context(), messages); err != nil {
+		http.Error(w, "Could not search messages", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range messages {
+		respBody.Messages = append(respBody.Messages, messageResponse(&messages[i]))
+	}
+
+	js, _ := json.Marshal(respBody)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/mocks"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func TestServer_SearchMessages(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+	reactionRepoMock := &mocks.ReactionRepository{}
+
+	alice := *mustNewUser("alice", "12345678")
+	bob := *mustNewUser("bob", "12345678")
+
+	general := models.Room{Name: "general"}
+	general.ID = 3
+
+	sentAt := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	first := models.Message{AuthorUuid: alice.ID, Author: alice, RoomID: &general.ID, Room: &general, Message: "lunch at noon"}
+	first.ID, first.CreatedAt = 4, sentAt
+
+	second := models.Message{AuthorUuid: alice.ID, Author: alice, RoomID: &general.ID, Room: &general, Message: "lunch is late"}
+	second.ID, second.CreatedAt = 6, sentAt.Add(time.Minute)
+
+	tokenRepoMock.On("Get", mock.Anything, "bobToken").Return(models.Token{Token: "bobToken", UserId: bob.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+	tokenRepoMock.On("Get", mock.Anything, mock.Anything).Return(models.Token{}, errors.New("record not found"))
+
+	userRepoMock.On("GetByUserName", mock.Anything, "alice").Return(alice, nil)
+	userRepoMock.On("GetByUserName", mock.Anything, mock.Anything).Return(models.User{}, errors.New("record not found"))
+
+	roomRepoMock.On("GetByMember", mock.Anything, bob.ID).Return([]models.Room{general}, nil)
+
+	audience := message.Audience{UserId: bob.ID, RoomIds: []uint{general.ID}}
+	from := sentAt.Add(-time.Hour)
+
+	messageRepoMock.On("Search", mock.Anything, audience, message.SearchFilter{Query: "lunch"}, message.Cursor{Limit: defaultMessagesPageLimit + 1}).Return([]models.Message{first, second}, nil)
+	messageRepoMock.On("Search", mock.Anything, audience, message.SearchFilter{Query: "lunch", AuthorUuid: alice.ID, RoomID: general.ID, Since: from}, message.Cursor{Limit: 2}).Return([]models.Message{first, second}, nil)
+
+	reactionRepoMock.On("CountFor", mock.Anything, mock.Anything).Return(map[uint][]models.ReactionCount{}, nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.reactionRepo = reactionRepoMock
+
+	firstResponse := Message{Id: 4, Author: "alice", Room: &general.Name, Message: "lunch at noon", SentAt: first.CreatedAt}
+	secondResponse := Message{Id: 6, Author: "alice", Room: &general.Name, Message: "lunch is late", SentAt: second.CreatedAt}
+
+	tests := []struct {
+		name     string
+		url      string
+		wantCode int
+		want     MessagesResponse
+	}{
+		{
+			name:     "Invalid token",
+			url:      "/chat/messages/search?q=lunch&token=stolenToken",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Missing query",
+			url:      "/chat/messages/search?token=bobToken",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Query without words",
+			url:      "/chat/messages/search?q=%3F%21&token=bobToken",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Empty date range",
+			url:      "/chat/messages/search?q=lunch&from=2021-12-02T00:00:00Z&to=2021-12-01T00:00:00Z&token=bobToken",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Unknown author",
+			url:      "/chat/messages/search?q=lunch&author=dave&token=bobToken",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Room of another user",
+			url:      "/chat/messages/search?q=lunch&room=random&token=bobToken",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Matching messages",
+			url:      "/chat/messages/search?q=lunch&token=bobToken",
+			wantCode: http.StatusOK,
+			want:     MessagesResponse{Messages: []Message{firstResponse, secondResponse}},
+		},
+		{
+			name:     "Latest matching message with filters",
+			url:      "/chat/messages/search?q=lunch&author=alice&room=general&from=2021-12-01T09:00:00Z&limit=1&token=bobToken",
+			wantCode: http.StatusOK,
+			want:     MessagesResponse{Messages: []Message{secondResponse}, HasMore: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantCode == http.StatusOK {
+				response := MessagesResponse{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+				assert.Equal(t, tt.want, response)
+			}
+		})
+	}
+}
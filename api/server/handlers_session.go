@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/id-tarzanych/lets-go-chat/api/middlewares"
+	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+)
+
+// Reasons sent in the close frame of revoked chat sessions.
+const (
+	closeReasonLoggedOut        = "logged out of all sessions"
+	closeReasonLoggedOutSession = "logged out"
+	closeReasonRevoked          = "sessions revoked by an admin"
+	closeReasonTokenReused      = "session revoked, its refresh token was reused"
+)
+
+var errUserNotFound = errors.New("user not found")
+
+// LogoutUser ends the current session. The refresh token must belong to the session of the access token,
+// the tokens of its family are deleted, its access tokens rejected and its live chat sessions closed.
+func (s Server) LogoutUser(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	sessionId, _ := middlewares.SessionIdFromContext(r.Context())
+
+	var reqBody LogoutUserJSONRequestBody
+
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		http.Error(w, "Syntax error", http.StatusBadRequest)
+		return
+	}
+
+	if reqBody.RefreshToken == "" {
+		http.Error(w, "Refresh token is required.", http.StatusBadRequest)
+		return
+	}
+
+	t, err := s.tokenRepo.Get(r.Context(), reqBody.RefreshToken)
+	if err != nil || !t.IsRefresh() || t.UserId != userId || t.FamilyId != sessionId {
+		http.Error(w, "Refresh token is invalid.", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.tokenRepo.DeleteFamily(r.Context(), t.FamilyId); err != nil {
+		s.sessionError(w, err, "")
+		return
+	}
+
+	s.endSession(userId, sessionId, closeReasonLoggedOutSession)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAllSessions ends every session of the authenticated user, including the current one.
+func (s Server) LogoutAllSessions(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.revokeSessions(r.Context(), userId, closeReasonLoggedOut); err != nil {
+		s.sessionError(w, err, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeUserSessions lets admins end every session of a user, e.g. of a compromised account.
+func (s Server) RevokeUserSessions(w http.ResponseWriter, r *http.Request, userName string) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.requireAdmin(r.Context(), userId); err != nil {
+		s.sessionError(w, err, userName)
+		return
+	}
+
+	u, err := s.userRepo.GetByUserName(r.Context(), userName)
+	if err != nil {
+		s.sessionError(w, errUserNotFound, userName)
+		return
+	}
+
+	// Bots log in with their API keys, so the keys are revoked first to keep the bot from starting new sessions.
+	if u.Bot {
+		if err := s.apiKeyRepo.DeleteByUserId(r.Context(), u.ID); err != nil {
+			s.sessionError(w, err, userName)
+			return
+		}
+	}
+
+	if err := s.revokeSessions(r.Context(), u.ID, closeReasonRevoked); err != nil {
+		s.sessionError(w, err, userName)
+		return
+	}
+
+	s.logger.Println("Sessions revoked by an admin. User: ", u.UserName)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeSessions deletes the refresh and chat tokens of the user, rejects its access tokens and closes
+// its live chat sessions with a close frame telling the reason. Disconnected sessions can not be resumed either.
+func (s Server) revokeSessions(ctx context.Context, userId types.Uuid, reason string) error {
+	if err := s.tokenRepo.DeleteByUserId(ctx, userId); err != nil {
+		return err
+	}
+
+	s.accessTokens.Revoke(userId, time.Now())
+	s.chatData.DeleteUserTokens(userId)
+
+	for _, client := range s.chatData.GetUserClients(userId) {
+		s.closeRevokedClient(client, reason)
+	}
+
+	return nil
+}
+
+// endSession rejects the access tokens of the session and closes the chat sessions joined with them.
+// Its stored tokens are deleted by the caller.
+func (s Server) endSession(userId types.Uuid, sessionId string, reason string) {
+	s.accessTokens.RevokeSession(sessionId, time.Now())
+	s.chatData.DeleteSessionTokens(userId, sessionId)
+
+	for _, client := range s.chatData.GetUserClients(userId) {
+		if client.SessionId == sessionId {
+			s.closeRevokedClient(client, reason)
+		}
+	}
+}
+
+// closeRevokedClient closes the chat session with a close frame telling the reason and forgets it.
+func (s Server) closeRevokedClient(client *wss.Client, reason string) {
+	if err := client.Close(websocket.ClosePolicyViolation, reason); err != nil {
+		s.logger.Println("Could not close revoked client: ", err)
+	}
+
+	s.chatData.DeleteClient(client)
+}
+
+func (s Server) sessionError(w http.ResponseWriter, err error, userName string) {
+	switch {
+	case errors.Is(err, errNotAdmin):
+		http.Error(w, "Only admins can revoke sessions of other users", http.StatusForbidden)
+	case errors.Is(err, errUserNotFound):
+		http.Error(w, fmt.Sprintf("User %s does not exist", userName), http.StatusNotFound)
+	default:
+		s.logger.Errorln("Could not revoke sessions. ", err)
+		http.Error(w, "Could not revoke sessions", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,279 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/id-tarzanych/lets-go-chat/api/middlewares"
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/mocks"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+// newSessionTestServer authenticates admin alice and regular user bob by their name followed by "Token".
+// Bot helper with the id "helper" belongs to alice.
+// Bob can join the chat once with "bobChatToken".
+func newSessionTestServer() (*Server, *mocks.FieldLogger, *mocks.UserRepository, *mocks.TokenRepository, models.User) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	alice := *mustNewUser("alice", "12345678")
+	alice.Admin = true
+	bob := *mustNewUser("bob", "12345678")
+
+	for _, u := range []models.User{alice, bob} {
+		tokenRepoMock.On("Get", mock.Anything, u.UserName+"Token").Return(*models.NewToken(u.UserName+"Token", u.ID, time.Now().Add(time.Hour)), nil).Maybe()
+		userRepoMock.On("GetById", mock.Anything, u.ID).Return(u, nil)
+		userRepoMock.On("GetByUserName", mock.Anything, u.UserName).Return(u, nil)
+	}
+	helper := *models.NewBot("helper", alice.ID)
+	helper.ID = "helper"
+	userRepoMock.On("GetByUserName", mock.Anything, helper.UserName).Return(helper, nil)
+	userRepoMock.On("GetByUserName", mock.Anything, mock.Anything).Return(models.User{}, errors.New("record not found"))
+	userRepoMock.On("UpdateLastActivity", mock.Anything, mock.Anything, mock.Anything).Maybe().Return(nil)
+
+	tokenRepoMock.On("Get", mock.Anything, "bobChatToken").Return(*models.NewToken("bobChatToken", bob.ID, time.Now().Add(time.Hour)), nil).Once()
+	tokenRepoMock.On("Delete", mock.Anything, "bobChatToken").Maybe().Return(nil)
+	roomRepoMock.On("GetByMember", mock.Anything, bob.ID).Maybe().Return([]models.Room{}, nil)
+	messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: bob.ID, RoomIds: []uint{}}, message.Cursor{Limit: historyReplayLimit}).Maybe().Return([]models.Message{}, nil)
+
+	loggerMock.On("Println", mock.Anything, mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+
+	return newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock), loggerMock, userRepoMock, tokenRepoMock, bob
+}
+
+func TestServer_LogoutUser(t *testing.T) {
+	srv, _, _, tokenRepoMock, bob := newSessionTestServer()
+
+	sessionToken := *models.NewToken("bobSessionToken", bob.ID, time.Now().Add(time.Hour))
+	sessionToken.FamilyId = "family"
+
+	tokenRepoMock.On("Get", mock.Anything, "bobSessionToken").Return(sessionToken, nil)
+	tokenRepoMock.On("Get", mock.Anything, "refreshToken").Return(*models.NewRefreshToken("refreshToken", bob.ID, "family", time.Now().Add(time.Hour)), nil)
+	tokenRepoMock.On("Get", mock.Anything, "otherRefreshToken").Return(*models.NewRefreshToken("otherRefreshToken", "other", "other", time.Now().Add(time.Hour)), nil)
+	tokenRepoMock.On("Get", mock.Anything, mock.Anything).Return(models.Token{}, errors.New("record not found"))
+	tokenRepoMock.On("DeleteFamily", mock.Anything, "family").Return(nil).Once()
+
+	tests := []struct {
+		name     string
+		token    string
+		body     string
+		wantCode int
+	}{
+		{"Invalid access token", "stolenToken", `{"refreshToken":"refreshToken"}`, http.StatusBadRequest},
+		{"Syntax error", "bobSessionToken", `{"refreshToken":`, http.StatusBadRequest},
+		{"Empty refresh token", "bobSessionToken", `{}`, http.StatusBadRequest},
+		{"Unknown refresh token", "bobSessionToken", `{"refreshToken":"unknownToken"}`, http.StatusBadRequest},
+		{"Not a refresh token", "bobSessionToken", `{"refreshToken":"bobChatToken"}`, http.StatusBadRequest},
+		{"Refresh token of another user", "bobSessionToken", `{"refreshToken":"otherRefreshToken"}`, http.StatusBadRequest},
+		{"Refresh token of another session", "bobToken", `{"refreshToken":"refreshToken"}`, http.StatusBadRequest},
+		{"Refresh token", "bobSessionToken", `{"refreshToken":"refreshToken"}`, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/user/logout?token="+tt.token, strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+		})
+	}
+
+	tokenRepoMock.AssertExpectations(t)
+}
+
+func TestServer_LogoutUser_EndsSession(t *testing.T) {
+	srv, _, _, tokenRepoMock, bob := newSessionTestServer()
+
+	accessTokens := testAccessTokens(t)
+	srv.accessTokens = accessTokens
+	srv.authMiddleware = middlewares.NewAuthMiddleware(accessTokens)
+
+	var chatToken models.Token
+
+	tokenRepoMock.On("Create", mock.Anything, mock.AnythingOfType("*models.Token")).Run(func(args mock.Arguments) {
+		chatToken = *args.Get(1).(*models.Token)
+	}).Return(nil).Once()
+	tokenRepoMock.On("Get", mock.Anything, "refreshToken").Return(*models.NewRefreshToken("refreshToken", bob.ID, "family", time.Now().Add(time.Hour)), nil)
+	tokenRepoMock.On("Get", mock.Anything, mock.Anything).Return(
+		func(_ context.Context, token string) models.Token { return chatToken },
+		func(_ context.Context, token string) error {
+			if token != chatToken.Token {
+				return errors.New("record not found")
+			}
+
+			return nil
+		},
+	)
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Maybe().Return(nil)
+	tokenRepoMock.On("DeleteFamily", mock.Anything, "family").Return(nil).Once()
+
+	s := httptest.NewServer(srv.Router())
+	defer s.Close()
+
+	accessToken, _, err := accessTokens.Issue(bob.ID, "family", time.Now())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	resp, err := http.Post(s.URL+"/chat/ws.rtm.connect?token="+accessToken, "application/json", nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, "family", chatToken.FamilyId, "chat tokens should belong to the session of the access token")
+
+	wsURL := "ws" + strings.TrimPrefix(s.URL, "http") + "/chat/ws.rtm.start?token="
+
+	ws, _, err := websocket.DefaultDialer.Dial(wsURL+chatToken.Token, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer ws.Close()
+
+	other, _, err := websocket.DefaultDialer.Dial(wsURL+"bobChatToken", nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer other.Close()
+
+	assert.Eventually(t, func() bool { return len(srv.chatData.GetUserClients(bob.ID)) == 2 }, time.Second, 10*time.Millisecond)
+
+	resp, err = http.Post(s.URL+"/user/logout?token="+accessToken, "application/json", strings.NewReader(`{"refreshToken":"refreshToken"}`))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode, "unexpected status code")
+
+	assertClosed(t, ws, closeReasonLoggedOutSession)
+	assert.Nil(t, srv.chatData.LoadClient(chatToken.Token), "the session should not be resumable")
+
+	if clients := srv.chatData.GetUserClients(bob.ID); assert.Len(t, clients, 1, "other sessions should stay connected") {
+		assert.Equal(t, "bobChatToken", clients[0].EntryToken)
+	}
+
+	resp, err = http.Post(s.URL+"/chat/ws.rtm.connect?token="+accessToken, "application/json", nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "the access token of the session should be rejected")
+
+	tokenRepoMock.AssertExpectations(t)
+}
+
+func TestServer_LogoutAllSessions(t *testing.T) {
+	srv, _, _, tokenRepoMock, bob := newSessionTestServer()
+
+	tokenRepoMock.On("Get", mock.Anything, mock.Anything).Return(models.Token{}, errors.New("record not found"))
+	tokenRepoMock.On("DeleteByUserId", mock.Anything, bob.ID).Return(nil).Once()
+
+	s := httptest.NewServer(srv.Router())
+	defer s.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(s.URL, "http") + "/chat/ws.rtm.start?token=bobChatToken"
+
+	ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer ws.Close()
+
+	// The session is stored once the server waits for the first frame.
+	assert.Eventually(t, func() bool { return len(srv.chatData.GetUserClients(bob.ID)) == 1 }, time.Second, 10*time.Millisecond)
+
+	resp, err := http.Post(s.URL+"/user/logout/all?token=bobToken", "application/json", nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode, "unexpected status code")
+
+	assertClosed(t, ws, closeReasonLoggedOut)
+	assert.Empty(t, srv.chatData.GetUserClients(bob.ID), "sessions should be removed")
+	assert.Nil(t, srv.chatData.LoadClient("bobChatToken"), "sessions should not be resumable")
+
+	tokenRepoMock.AssertExpectations(t)
+}
+
+func TestServer_RevokeUserSessions(t *testing.T) {
+	srv, _, _, tokenRepoMock, bob := newSessionTestServer()
+
+	tokenRepoMock.On("Get", mock.Anything, mock.Anything).Return(models.Token{}, errors.New("record not found"))
+	tokenRepoMock.On("DeleteByUserId", mock.Anything, bob.ID).Return(nil).Once()
+	tokenRepoMock.On("DeleteByUserId", mock.Anything, types.Uuid("helper")).Return(nil).Once()
+
+	apiKeyRepoMock := &mocks.APIKeyRepository{}
+	apiKeyRepoMock.On("DeleteByUserId", mock.Anything, types.Uuid("helper")).Return(nil).Once()
+	srv.apiKeyRepo = apiKeyRepoMock
+
+	s := httptest.NewServer(srv.Router())
+	defer s.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/chat/ws.rtm.start?token=bobChatToken", nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer ws.Close()
+
+	tests := []struct {
+		name     string
+		token    string
+		url      string
+		wantCode int
+	}{
+		{"Not an admin", "bobToken", "/user/alice/revoke", http.StatusForbidden},
+		{"Unknown user", "aliceToken", "/user/carol/revoke", http.StatusNotFound},
+		{"User", "aliceToken", "/user/bob/revoke", http.StatusNoContent},
+		{"Bot", "aliceToken", "/user/helper/revoke", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.url+"?token="+tt.token, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+		})
+	}
+
+	assertClosed(t, ws, closeReasonRevoked)
+
+	tokenRepoMock.AssertExpectations(t)
+	apiKeyRepoMock.AssertExpectations(t)
+}
+
+// assertClosed reads frames until the server closes the socket and checks the close frame.
+func assertClosed(t *testing.T, ws *websocket.Conn, wantReason string) {
+	if err := ws.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for {
+		_, _, err := ws.ReadMessage()
+		if err == nil {
+			continue
+		}
+
+		closeErr := &websocket.CloseError{}
+		if assert.True(t, errors.As(err, &closeErr), "expected a close frame, got %v", err) {
+			assert.Equal(t, websocket.ClosePolicyViolation, closeErr.Code)
+			assert.Equal(t, wantReason, closeErr.Text)
+		}
+
+		return
+	}
+}
@@ -0,0 +1,290 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/db/user"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+const (
+	// minUserNameLength matches the shortest user name accepted on registration.
+	minUserNameLength = 4
+	// defaultMuteDuration applies when /mute is given no duration, maxMuteDuration bounds the duration.
+	defaultMuteDuration = 10 * time.Minute
+	maxMuteDuration     = 7 * 24 * time.Hour
+)
+
+// builtinSlashCommands are available in every chat.
+var builtinSlashCommands = []SlashCommand{
+	{
+		Name:        "help",
+		Description: "List available commands",
+		MaxArgs:     0,
+		Run:         Server.helpSlashCommand,
+	},
+	{
+		Name:        "me",
+		Usage:       "<action>",
+		Description: "Post an action, e.g. /me waves",
+		MinArgs:     1,
+		MaxArgs:     -1,
+		Run:         Server.meSlashCommand,
+	},
+	{
+		Name:        "nick",
+		Usage:       "<name>",
+		Description: "Change your user name",
+		MinArgs:     1,
+		MaxArgs:     1,
+		Run:         Server.nickSlashCommand,
+	},
+	{
+		Name:        "who",
+		Description: "List online users of the conversation",
+		MaxArgs:     0,
+		Run:         Server.whoSlashCommand,
+	},
+	{
+		Name:        "topic",
+		Usage:       "[topic]",
+		Description: "Show the topic of the room, its creator and admins can change it",
+		MaxArgs:     -1,
+		Authorize:   requireRoom,
+		Run:         Server.topicSlashCommand,
+	},
+	{
+		Name:        "mute",
+		Usage:       "<user> [minutes]",
+		Description: "Keep a member from posting to the room, 0 minutes lifts the mute",
+		MinArgs:     1,
+		MaxArgs:     2,
+		Authorize:   requireAdminInRoom,
+		Run:         Server.muteSlashCommand,
+	},
+}
+
+// defaultSlashCommands builds a registry of the built-in commands.
+func defaultSlashCommands() *SlashCommands {
+	commands := NewSlashCommands()
+	for _, command := range builtinSlashCommands {
+		if err := commands.Register(command); err != nil {
+			panic(err)
+		}
+	}
+
+	return commands
+}
+
+func requireRoom(s Server, ctx context.Context, call *SlashCall) error {
+	if call.Room == nil {
+		return wss.NewError(wss.ErrorInvalidPayload, "/%s can only be used in a room", call.Name)
+	}
+
+	return nil
+}
+
+func requireAdminInRoom(s Server, ctx context.Context, call *SlashCall) error {
+	if !call.Client.User.Admin {
+		return wss.NewError(wss.ErrorForbidden, "only admins can use /%s", call.Name)
+	}
+
+	return requireRoom(s, ctx, call)
+}
+
+func (s Server) helpSlashCommand(ctx context.Context, call *SlashCall) error {
+	lines := make([]string, 0)
+	for _, command := range s.slashCommands.List() {
+		line := "/" + command.Name
+		if command.Usage != "" {
+			line += " " + command.Usage
+		}
+
+		lines = append(lines, line+" - "+command.Description)
+	}
+
+	return call.Reply("%s", strings.Join(lines, "\n"))
+}
+
+// meSlashCommand posts the action as a message of the invoker to the conversation the command was sent to.
+func (s Server) meSlashCommand(ctx context.Context, call *SlashCall) error {
+	payload := call.Payload
+	payload.Message = fmt.Sprintf("* %s %s", call.Client.UserName(), call.Text)
+
+	return s.postMessage(ctx, call.Client, call.RequestId, payload)
+}
+
+// nickSlashCommand renames the invoker, every session of the user picks the new name up.
+func (s Server) nickSlashCommand(ctx context.Context, call *SlashCall) error {
+	name := call.Args[0]
+	if len(name) < minUserNameLength || strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		return wss.NewError(wss.ErrorInvalidPayload, "user name must have at least %d characters and no spaces", minUserNameLength)
+	}
+
+	if name == call.Client.UserName() {
+		return call.Reply("You are already known as %s", name)
+	}
+
+	// Checking first gives the common case a clear answer, the unique index settles concurrent renames.
+	_, err := s.userRepo.GetByUserName(ctx, name)
+	if err == nil {
+		return wss.NewError(wss.ErrorInvalidPayload, "user name %s is taken", name)
+	}
+
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.logger.Errorln("Could not look up user name. ", err)
+
+		return wss.NewError(wss.ErrorInternal, "user name could not be changed")
+	}
+
+	if err := s.userRepo.UpdateUserName(ctx, call.Client.User, name); err != nil {
+		if errors.Is(err, user.ErrUserNameTaken) {
+			return wss.NewError(wss.ErrorInvalidPayload, "user name %s is taken", name)
+		}
+
+		s.logger.Errorln("Could not rename user. ", err)
+
+		return wss.NewError(wss.ErrorInternal, "user name could not be changed")
+	}
+
+	s.chatData.RenameUser(call.Client.User.ID, name)
+
+	return call.Reply("You are now known as %s", name)
+}
+
+// whoSlashCommand lists online participants of the room, the direct conversation or the whole chat.
+func (s Server) whoSlashCommand(ctx context.Context, call *SlashCall) error {
+	var clients []*wss.Client
+
+	switch {
+	case call.Room != nil:
+		clients = s.chatData.GetRecipients(&call.Room.ID)
+	case call.Payload.To != "":
+		peer, err := s.userRepo.GetByUserName(ctx, call.Payload.To)
+		if err != nil {
+			return wss.NewError(wss.ErrorNotFound, "user %s does not exist", call.Payload.To)
+		}
+
+		clients = append(s.chatData.GetUserClients(peer.ID), call.Client)
+	default:
+		clients = s.chatData.GetRecipients(nil)
+	}
+
+	seen := make(map[string]bool)
+	names := make([]string, 0, len(clients))
+
+	for _, client := range clients {
+		if client.User == nil || seen[client.UserName()] {
+			continue
+		}
+
+		seen[client.UserName()] = true
+		names = append(names, client.UserName())
+	}
+
+	sort.Strings(names)
+
+	if call.Room != nil {
+		return call.Reply("Online in %s: %s", call.Room.Name, strings.Join(names, ", "))
+	}
+
+	return call.Reply("Online: %s", strings.Join(names, ", "))
+}
+
+// canManageRoom reports whether the user created the room or is an admin.
+func canManageRoom(u *models.User, room *models.Room) bool {
+	return u.Admin || (room.CreatedBy != nil && *room.CreatedBy == u.ID)
+}
+
+// topicSlashCommand shows the topic of the room to its members and lets its creator and admins change it.
+func (s Server) topicSlashCommand(ctx context.Context, call *SlashCall) error {
+	if call.Text == "" {
+		if call.Room.Topic == "" {
+			return call.Reply("Room %s has no topic", call.Room.Name)
+		}
+
+		return call.Reply("Topic of %s: %s", call.Room.Name, call.Room.Topic)
+	}
+
+	if !canManageRoom(call.Client.User, call.Room) {
+		return wss.NewError(wss.ErrorForbidden, "only the creator of room %s and admins can change its topic", call.Room.Name)
+	}
+
+	if err := s.roomRepo.SetTopic(ctx, call.Room.ID, call.Text); err != nil {
+		s.logger.Errorln("Could not store room topic. ", err)
+
+		return wss.NewError(wss.ErrorInternal, "topic of room %s could not be changed", call.Room.Name)
+	}
+
+	return call.Reply("Topic of %s set to: %s", call.Room.Name, call.Text)
+}
+
+func (s Server) muteSlashCommand(ctx context.Context, call *SlashCall) error {
+	duration := defaultMuteDuration
+
+	if len(call.Args) > 1 {
+		minutes, err := strconv.Atoi(call.Args[1])
+		if err != nil || minutes < 0 || time.Duration(minutes)*time.Minute > maxMuteDuration {
+			return wss.NewError(wss.ErrorInvalidPayload, "minutes must be a number from 0 to %d", int(maxMuteDuration/time.Minute))
+		}
+
+		duration = time.Duration(minutes) * time.Minute
+	}
+
+	target, err := s.userRepo.GetByUserName(ctx, call.Args[0])
+	if err != nil {
+		return wss.NewError(wss.ErrorNotFound, "user %s does not exist", call.Args[0])
+	}
+
+	if target.ID == call.Client.User.ID {
+		return wss.NewError(wss.ErrorInvalidPayload, "you can not mute yourself")
+	}
+
+	var until *time.Time
+	if duration > 0 {
+		t := time.Now().Add(duration).UTC()
+		until = &t
+	}
+
+	if _, err := s.roomRepo.GetMember(ctx, call.Room.ID, target.ID); err != nil {
+		return wss.NewError(wss.ErrorNotFound, "user %s is not a member of room %s", target.UserName, call.Room.Name)
+	}
+
+	if err := s.roomRepo.Mute(ctx, call.Room.ID, target.ID, until); err != nil {
+		s.logger.Errorln("Could not mute room member. ", err)
+
+		return wss.NewError(wss.ErrorInternal, "user %s could not be muted", target.UserName)
+	}
+
+	if until == nil {
+		return call.Reply("%s may post to %s again", target.UserName, call.Room.Name)
+	}
+
+	return call.Reply("%s is muted in %s until %s", target.UserName, call.Room.Name, until.Format(time.RFC3339))
+}
+
+// checkMuted rejects messages to a room from members muted in it.
+func (s Server) checkMuted(ctx context.Context, client *wss.Client, room *models.Room) error {
+	member, err := s.roomRepo.GetMember(ctx, room.ID, client.User.ID)
+	if err != nil {
+		s.logger.Warningln("Could not load room membership. ", err)
+
+		return nil
+	}
+
+	if member.Muted(time.Now()) {
+		return wss.NewError(wss.ErrorForbidden, "user %s is muted in room %s until %s", client.UserName(), room.Name, member.MutedUntil.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
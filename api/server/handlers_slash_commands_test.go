@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+
+	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/db/user"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+	"github.com/id-tarzanych/lets-go-chat/pkg/generators"
+)
+
+func Test_parseSlashCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		wantName string
+		wantArgs []string
+		wantRest string
+		wantErr  bool
+	}{
+		{"No arguments", "/who", "who", nil, "", false},
+		{"Arguments", "/mute  bob 5 ", "mute", []string{"bob", "5"}, "bob 5", false},
+		{"Quoted argument", `/kick "john doe" now`, "kick", []string{"john doe", "now"}, `"john doe" now`, false},
+		{"Empty quoted argument", `/topic ""`, "topic", []string{""}, `""`, false},
+		{"Unterminated quote", `/me says "hi`, "me", nil, `says "hi`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, args, rest, err := parseSlashCommand(tt.text)
+
+			assert.Equal(t, tt.wantErr, err != nil, "unexpected error %v", err)
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantRest, rest)
+
+			if !tt.wantErr {
+				assert.Equal(t, tt.wantArgs, args)
+			}
+		})
+	}
+}
+
+func TestSlashCommands_Register(t *testing.T) {
+	run := func(s Server, ctx context.Context, call *SlashCall) error { return nil }
+
+	commands := NewSlashCommands()
+
+	assert.NoError(t, commands.Register(SlashCommand{Name: "/Roll", Run: run}))
+	assert.Error(t, commands.Register(SlashCommand{Name: "roll", Run: run}), "names are unique")
+	assert.Error(t, commands.Register(SlashCommand{Name: "two words", Run: run}), "names have no spaces")
+	assert.Error(t, commands.Register(SlashCommand{Name: "noop"}), "commands must run something")
+
+	command, ok := commands.Lookup("ROLL")
+	assert.True(t, ok, "lookup ignores case")
+	assert.Equal(t, "roll", command.Name)
+}
+
+func Test_canManageRoom(t *testing.T) {
+	creator := mustNewUser("creator", "12345678")
+	member := mustNewUser("member", "12345678")
+	admin := mustNewUser("admin", "12345678")
+	admin.Admin = true
+
+	room := &models.Room{Name: "general", CreatedBy: &creator.ID}
+
+	assert.True(t, canManageRoom(creator, room), "creators manage their rooms")
+	assert.True(t, canManageRoom(admin, room), "admins manage every room")
+	assert.False(t, canManageRoom(member, room), "members do not manage rooms")
+	assert.False(t, canManageRoom(member, &models.Room{Name: "legacy"}), "rooms without creator are managed by admins only")
+}
+
+func TestChat_HandleChatSession_SlashCommands(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+
+	loggerMock.On("Error", mock.AnythingOfType("string")).Maybe().Return()
+	loggerMock.On("Println", mock.Anything).Maybe().Return()
+	loggerMock.On("Println", mock.Anything, mock.Anything, mock.Anything).Maybe().Return()
+	loggerMock.On("Errorln", "Could not look up user name. ", mock.Anything).Return().Once()
+
+	alice := *mustNewUser("alice", "12345678")
+	alice.Admin = true
+	bob := *mustNewUser("bob", "12345678")
+
+	general := models.Room{Name: "general", Topic: "anything goes", CreatedBy: &bob.ID}
+	general.ID = 1
+
+	mutedUntil := time.Now().Add(time.Hour)
+
+	tokens := make(map[types.Uuid]string)
+	for _, u := range []models.User{alice, bob} {
+		tokens[u.ID] = generators.RandomString(16)
+
+		tokenRepoMock.On("Get", mock.Anything, tokens[u.ID]).Return(models.Token{Token: tokens[u.ID], UserId: u.ID, Expiration: time.Now().Add(time.Hour * 24)}, nil)
+		userRepoMock.On("GetById", mock.Anything, u.ID).Return(u, nil)
+		userRepoMock.On("GetByUserName", mock.Anything, u.UserName).Return(u, nil)
+		roomRepoMock.On("GetByMember", mock.Anything, u.ID).Return([]models.Room{general}, nil)
+		messageRepoMock.On("GetPageFor", mock.Anything, message.Audience{UserId: u.ID, RoomIds: []uint{general.ID}}, message.Cursor{Limit: historyReplayLimit}).Return([]models.Message{}, nil)
+	}
+	tokenRepoMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	userRepoMock.On("UpdateLastActivity", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	userRepoMock.On("GetByUserName", mock.Anything, "broken").Return(models.User{}, errors.New("connection lost"))
+	userRepoMock.On("GetByUserName", mock.Anything, mock.Anything).Return(models.User{}, gorm.ErrRecordNotFound)
+	userRepoMock.On("UpdateUserName", mock.Anything, mock.MatchedBy(func(u *models.User) bool { return u.ID == bob.ID }), "racer").Return(user.ErrUserNameTaken).Once()
+	userRepoMock.On("UpdateUserName", mock.Anything, mock.MatchedBy(func(u *models.User) bool { return u.ID == bob.ID }), "bobby").Return(nil).Once()
+
+	roomRepoMock.On("GetByName", mock.Anything, general.Name).Return(general, nil)
+	roomRepoMock.On("SetTopic", mock.Anything, general.ID, "lunch plans").Return(nil).Once()
+	roomRepoMock.On("SetTopic", mock.Anything, general.ID, "dinner plans").Return(nil).Once()
+	roomRepoMock.On("GetMember", mock.Anything, general.ID, alice.ID).Return(models.RoomMember{RoomID: general.ID, UserUuid: alice.ID}, nil)
+	roomRepoMock.On("GetMember", mock.Anything, general.ID, bob.ID).Return(models.RoomMember{RoomID: general.ID, UserUuid: bob.ID, MutedUntil: &mutedUntil}, nil)
+	roomRepoMock.On("Mute", mock.Anything, general.ID, bob.ID, mock.AnythingOfType("*time.Time")).Return(nil).Once()
+
+	messageRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(m *models.Message) bool {
+		return m.Message == "* alice waves" && m.RoomID != nil && *m.RoomID == general.ID
+	})).Return(nil).Once()
+	messageRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(m *models.Message) bool {
+		return m.Message == "/usr/bin is full" && m.RoomID == nil
+	})).Return(nil).Once()
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+
+	s := httptest.NewServer(HandlerWithOptions(srv, ChiServerOptions{}))
+	defer s.Close()
+
+	connect := func(u models.User) *websocket.Conn {
+		url := "ws" + strings.TrimPrefix(s.URL, "http") + "/chat/ws.rtm.start?token=" + tokens[u.ID]
+
+		ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		return ws
+	}
+
+	aliceWs := connect(alice)
+	defer aliceWs.Close()
+
+	bobWs := connect(bob)
+	defer bobWs.Close()
+
+	assert.Equal(t, wss.EventUserJoined, readEnvelope(t, aliceWs).Type, "user_joined event expected")
+
+	tests := []struct {
+		name      string
+		ws        *websocket.Conn
+		message   string
+		room      string
+		wantType  string
+		wantError string
+		wantReply string
+	}{
+		{name: "Unknown command", ws: bobWs, message: "/dance", wantType: wss.EventError, wantError: wss.ErrorNotFound},
+		{name: "Missing argument", ws: bobWs, message: "/nick", wantType: wss.EventError, wantError: wss.ErrorInvalidPayload},
+		{name: "Who is online", ws: bobWs, message: "/who", wantType: wss.EventAck, wantReply: "Online: alice, bob"},
+		{name: "Who is online in a room", ws: bobWs, message: "/who", room: "general", wantType: wss.EventAck, wantReply: "Online in general: alice, bob"},
+		{name: "Topic outside of rooms", ws: bobWs, message: "/topic", wantType: wss.EventError, wantError: wss.ErrorInvalidPayload},
+		{name: "Show topic", ws: bobWs, message: "/topic", room: "general", wantType: wss.EventAck, wantReply: "Topic of general: anything goes"},
+		{name: "Change topic as creator", ws: bobWs, message: "/topic lunch plans", room: "general", wantType: wss.EventAck, wantReply: "Topic of general set to: lunch plans"},
+		{name: "Change topic as admin", ws: aliceWs, message: "/topic dinner plans", room: "general", wantType: wss.EventAck, wantReply: "Topic of general set to: dinner plans"},
+		{name: "Mute without permission", ws: bobWs, message: "/mute alice", room: "general", wantType: wss.EventError, wantError: wss.ErrorForbidden},
+		{name: "Mute for too long", ws: aliceWs, message: "/mute bob 99999", room: "general", wantType: wss.EventError, wantError: wss.ErrorInvalidPayload},
+		{name: "Mute oneself", ws: aliceWs, message: "/mute alice", room: "general", wantType: wss.EventError, wantError: wss.ErrorInvalidPayload},
+		{name: "Mute", ws: aliceWs, message: "/mute bob 60", room: "general", wantType: wss.EventAck},
+		{name: "Post while muted", ws: bobWs, message: "hello?", room: "general", wantType: wss.EventError, wantError: wss.ErrorForbidden},
+		{name: "Taken name", ws: bobWs, message: "/nick alice", wantType: wss.EventError, wantError: wss.ErrorInvalidPayload},
+		{name: "Short name", ws: bobWs, message: "/nick bo", wantType: wss.EventError, wantError: wss.ErrorInvalidPayload},
+		{name: "Name taken concurrently", ws: bobWs, message: "/nick racer", wantType: wss.EventError, wantError: wss.ErrorInvalidPayload},
+		{name: "Name lookup failure", ws: bobWs, message: "/nick broken", wantType: wss.EventError, wantError: wss.ErrorInternal},
+		{name: "Change name", ws: bobWs, message: "/nick bobby", wantType: wss.EventAck, wantReply: "You are now known as bobby"},
+		{name: "Renamed session", ws: aliceWs, message: "/who", wantType: wss.EventAck, wantReply: "Online: alice, bobby"},
+		{name: "Action", ws: aliceWs, message: "/me waves", room: "general", wantType: wss.EventAck},
+		{name: "Escaped slash", ws: aliceWs, message: "//usr/bin is full", wantType: wss.EventAck},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, _ := wss.NewEnvelope(wss.CommandSendMessage, "1", wss.SendMessagePayload{Message: tt.message, Room: tt.room})
+			if err := tt.ws.WriteJSON(request); err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			// Messages posted to rooms and the public chat reach their author as well.
+			response := readEnvelope(t, tt.ws)
+			for response.Type == wss.EventMessage {
+				response = readEnvelope(t, tt.ws)
+			}
+
+			assert.Equal(t, tt.wantType, response.Type, "unexpected frame type")
+
+			if tt.wantError != "" {
+				protocolErr := wss.Error{}
+				assert.NoError(t, response.DecodePayload(&protocolErr), "payload should be valid")
+				assert.Equal(t, tt.wantError, protocolErr.Code, "unexpected error code")
+			}
+
+			if tt.wantReply != "" {
+				reply := wss.SystemPayload{}
+				assert.NoError(t, response.DecodePayload(&reply), "payload should be valid")
+				assert.Equal(t, tt.wantReply, reply.Message)
+			}
+		})
+	}
+
+	// Replies stay with the invoker, only posted messages reach other participants.
+	for _, want := range []string{"* alice waves", "/usr/bin is full"} {
+		envelope := readEnvelope(t, bobWs)
+		payload := wss.MessagePayload{}
+		assert.Equal(t, wss.EventMessage, envelope.Type, "unexpected frame type")
+		assert.NoError(t, envelope.DecodePayload(&payload), "payload should be valid")
+		assert.Equal(t, want, payload.Message)
+	}
+
+	userRepoMock.AssertExpectations(t)
+	roomRepoMock.AssertExpectations(t)
+	messageRepoMock.AssertExpectations(t)
+}
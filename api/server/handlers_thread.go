@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/id-tarzanych/lets-go-chat/api/middlewares"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func (s Server) GetMessageThread(w http.ResponseWriter, r *http.Request, messageId int, params GetMessageThreadParams) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	cursor, err := newCursor(params.Before, params.After, params.Limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rooms, err := s.roomRepo.GetByMember(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Could not load thread", http.StatusInternalServerError)
+		return
+	}
+
+	// Threads the user can not read are reported missing to not leak their existence.
+	root, err := s.threadRoot(r.Context(), uint(messageId))
+	if err != nil || !newAudience(userId, rooms).Includes(&root) {
+		http.Error(w, fmt.Sprintf("Message %d does not exist", messageId), http.StatusNotFound)
+		return
+	}
+
+	// One extra reply tells whether another page follows.
+	limit := cursor.Limit
+	cursor.Limit++
+
+	replies, err := s.messageRepo.GetThread(r.Context(), root.ID, cursor)
+	if err != nil {
+		http.Error(w, "Could not load thread", http.StatusInternalServerError)
+		return
+	}
+
+	replies, hasMore := trimPage(replies, cursor, limit)
+
+	messages := append([]models.Message{root}, replies...)
+	if err := s.attachReactions(r.Context(), messages); err != nil {
+		http.Error(w, "Could not load thread", http.StatusInternalServerError)
+		return
+	}
+
+	respBody := ThreadResponse{Root: messageResponse(&messages[0]), HasMore: hasMore, Replies: make([]Message, 0, len(replies))}
+	for i := range messages[1:] {
+		respBody.Replies = append(respBody.Replies, messageResponse(&messages[i+1]))
+	}
+
+	js, _ := json.Marshal(respBody)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+// threadRoot looks up the message that starts the thread of the message with id.
+// Replies always point at the root, so replying to a reply continues the same thread.
+func (s Server) threadRoot(ctx context.Context, id uint) (models.Message, error) {
+	m, err := s.messageRepo.GetById(ctx, id)
+	if err != nil || m.ParentID == nil {
+		return m, err
+	}
+
+	return s.messageRepo.GetById(ctx, *m.ParentID)
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/mocks"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func TestServer_GetMessageThread(t *testing.T) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+	reactionRepoMock := &mocks.ReactionRepository{}
+
+	alice := *mustNewUser("alice", "12345678")
+	bob := *mustNewUser("bob", "12345678")
+
+	general := models.Room{Name: "general"}
+	general.ID = 3
+
+	sentAt := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+	lastReplyAt := sentAt.Add(2 * time.Minute)
+
+	root := models.Message{AuthorUuid: alice.ID, Author: alice, RoomID: &general.ID, Room: &general, Message: "lunch?", ReplyCount: 2, LastReplyAt: &lastReplyAt}
+	root.ID, root.CreatedAt = 7, sentAt
+
+	first := models.Message{AuthorUuid: bob.ID, Author: bob, RoomID: &general.ID, Room: &general, ParentID: &root.ID, Message: "sure"}
+	first.ID, first.CreatedAt = 8, sentAt.Add(time.Minute)
+
+	second := models.Message{AuthorUuid: alice.ID, Author: alice, RoomID: &general.ID, Room: &general, ParentID: &root.ID, Message: "great"}
+	second.ID, second.CreatedAt = 9, lastReplyAt
+
+	tokenRepoMock.On("Get", mock.Anything, "aliceToken").Return(models.Token{Token: "aliceToken", UserId: alice.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+	tokenRepoMock.On("Get", mock.Anything, "bobToken").Return(models.Token{Token: "bobToken", UserId: bob.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+	tokenRepoMock.On("Get", mock.Anything, mock.Anything).Return(models.Token{}, errors.New("record not found"))
+
+	roomRepoMock.On("GetByMember", mock.Anything, alice.ID).Return([]models.Room{general}, nil)
+	roomRepoMock.On("GetByMember", mock.Anything, bob.ID).Return([]models.Room{}, nil)
+
+	for _, m := range []models.Message{root, first, second} {
+		messageRepoMock.On("GetById", mock.Anything, m.ID).Return(m, nil)
+	}
+	messageRepoMock.On("GetById", mock.Anything, mock.Anything).Return(models.Message{}, errors.New("record not found"))
+	messageRepoMock.On("GetThread", mock.Anything, root.ID, message.Cursor{Limit: defaultMessagesPageLimit + 1}).Return([]models.Message{first, second}, nil)
+	messageRepoMock.On("GetThread", mock.Anything, root.ID, message.Cursor{Limit: 2}).Return([]models.Message{first, second}, nil)
+
+	reactionRepoMock.On("CountFor", mock.Anything, mock.Anything).Return(map[uint][]models.ReactionCount{}, nil)
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.reactionRepo = reactionRepoMock
+
+	rootId, replyCount := 7, 2
+
+	rootResponse := Message{Id: 7, Author: "alice", Room: &general.Name, Message: "lunch?", SentAt: sentAt, ReplyCount: &replyCount, LastReplyAt: &lastReplyAt}
+	firstResponse := Message{Id: 8, Author: "bob", Room: &general.Name, ParentId: &rootId, Message: "sure", SentAt: first.CreatedAt}
+	secondResponse := Message{Id: 9, Author: "alice", Room: &general.Name, ParentId: &rootId, Message: "great", SentAt: second.CreatedAt}
+
+	tests := []struct {
+		name     string
+		url      string
+		wantCode int
+		want     ThreadResponse
+	}{
+		{
+			name:     "Invalid token",
+			url:      "/chat/messages/7/thread?token=stolenToken",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Invalid cursor",
+			url:      "/chat/messages/7/thread?token=aliceToken&before=9&after=8",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Unknown message",
+			url:      "/chat/messages/42/thread?token=aliceToken",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Room of another user",
+			url:      "/chat/messages/7/thread?token=bobToken",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Whole thread",
+			url:      "/chat/messages/7/thread?token=aliceToken",
+			wantCode: http.StatusOK,
+			want:     ThreadResponse{Root: rootResponse, Replies: []Message{firstResponse, secondResponse}},
+		},
+		{
+			name:     "Latest reply of the thread of a reply",
+			url:      "/chat/messages/8/thread?token=aliceToken&limit=1",
+			wantCode: http.StatusOK,
+			want:     ThreadResponse{Root: rootResponse, Replies: []Message{secondResponse}, HasMore: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantCode == http.StatusOK {
+				response := ThreadResponse{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+				assert.Equal(t, tt.want, response)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	netUrl "net/url"
@@ -9,14 +11,21 @@ import (
 	"strings"
 	"time"
 
+	"github.com/id-tarzanych/lets-go-chat/db/token"
+	"github.com/id-tarzanych/lets-go-chat/db/user"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
 	"github.com/id-tarzanych/lets-go-chat/models"
 	"github.com/id-tarzanych/lets-go-chat/pkg/generators"
 	"github.com/id-tarzanych/lets-go-chat/pkg/hasher"
+	"github.com/id-tarzanych/lets-go-chat/webhooks"
 )
 
 const rateLimit = 100
 const tokenDuration = time.Hour
 
+// familyIdBytes is the entropy of the id shared by the refresh tokens of a login.
+const familyIdBytes = 16
+
 func (s Server) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var reqBody CreateUserJSONRequestBody
 
@@ -43,14 +52,27 @@ func (s Server) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user := models.NewUser(username, password)
-	if err := s.userRepo.Create(nil, user); err != nil {
+	u, err := models.NewUser(username, password)
+	if err != nil {
+		s.logger.Errorln("Could not hash password. ", err)
+		http.Error(w, fmt.Sprintf("Could not create user %s", username), http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.userRepo.Create(nil, u); err != nil {
+		if errors.Is(err, user.ErrUserNameTaken) {
+			http.Error(w, fmt.Sprintf("User with username %s already exists", username), http.StatusBadRequest)
+			return
+		}
+
 		http.Error(w, fmt.Sprintf("Could not create user %s", username), http.StatusBadRequest)
 		return
 	}
 
-	userId := string(user.ID)
-	respBody := CreateUserResponse{Id: &userId, UserName: &user.UserName}
+	s.publishWebhookEvent(models.WebhookUserRegistered, webhooks.NewUserData(u))
+
+	userId := string(u.ID)
+	respBody := CreateUserResponse{Id: &userId, UserName: &u.UserName}
 
 	w.Header().Set("Content-Type", "application/json")
 	js, _ := json.Marshal(respBody)
@@ -83,39 +105,187 @@ func (s Server) LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !hasher.CheckPasswordHash(password, user.PasswordHash) {
+	if user.Bot {
+		http.Error(w, "Bots log in with an API key", http.StatusBadRequest)
+		return
+	}
+
+	if ok, err := hasher.Verify(password, user.PasswordHash); !ok {
+		if err != nil {
+			s.logger.Errorln("Could not verify password hash. ", err)
+		}
+
 		http.Error(w, "Invalid username/password", http.StatusBadRequest)
 		return
 	}
 
-	token := models.NewToken(
-		generators.RandomString(16),
-		user.ID,
-		time.Now().Add(tokenDuration),
-	)
+	s.upgradePasswordHash(r.Context(), &user, password)
 
-	err = s.tokenRepo.Create(nil, token)
+	familyId, err := generators.TokenWithEntropy(familyIdBytes)
 	if err != nil {
-		http.Error(w, "Could not generate one-time token", http.StatusInternalServerError)
+		s.logger.Errorln("Could not issue tokens. ", err)
+		http.Error(w, "Could not issue tokens", http.StatusInternalServerError)
 		return
 	}
 
-	oneTimeUrl := netUrl.URL{
-		Scheme:   "ws",
-		Host:     r.Host,
-		Path:     "/chat/ws.rtm.start",
-		RawQuery: fmt.Sprintf("token=%s", token.Token),
+	respBody, err := s.issueTokenPair(r.Context(), user.ID, familyId, time.Now().Add(s.refreshTokenTTL))
+	if err != nil {
+		s.logger.Errorln("Could not issue tokens. ", err)
+		http.Error(w, "Could not issue tokens", http.StatusInternalServerError)
+		return
+	}
+
+	s.writeTokenPair(w, respBody)
+}
+
+// RefreshToken exchanges a refresh token for a new access token and the next refresh token of its family.
+// A refresh token is accepted once, presenting it again means it leaked, so the whole family is revoked.
+func (s Server) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var reqBody RefreshTokenJSONRequestBody
+
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		http.Error(w, "Syntax error", http.StatusBadRequest)
+		return
+	}
+
+	if reqBody.RefreshToken == "" {
+		http.Error(w, "Refresh token is required.", http.StatusBadRequest)
+		return
+	}
+
+	t, err := s.tokenRepo.Get(r.Context(), reqBody.RefreshToken)
+	if err != nil || !t.IsRefresh() {
+		http.Error(w, "Refresh token is invalid.", http.StatusBadRequest)
+		return
+	}
+
+	if t.UsedAt != nil {
+		s.revokeTokenFamily(r.Context(), t)
+		http.Error(w, "Refresh token is invalid.", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	if t.Expiration.Before(now) {
+		http.Error(w, "Refresh token expired.", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.tokenRepo.MarkUsed(r.Context(), reqBody.RefreshToken, now); err != nil {
+		if errors.Is(err, token.ErrAlreadyUsed) {
+			s.revokeTokenFamily(r.Context(), t)
+			http.Error(w, "Refresh token is invalid.", http.StatusBadRequest)
+			return
+		}
+
+		s.logger.Errorln("Could not use refresh token. ", err)
+		http.Error(w, "Could not issue tokens", http.StatusInternalServerError)
+		return
+	}
+
+	// Rotated tokens keep the expiration of the login, so refreshing can not extend it forever.
+	respBody, err := s.issueTokenPair(r.Context(), t.UserId, t.FamilyId, t.Expiration)
+	if err != nil {
+		s.logger.Errorln("Could not issue tokens. ", err)
+		http.Error(w, "Could not issue tokens", http.StatusInternalServerError)
+		return
+	}
+
+	s.writeTokenPair(w, respBody)
+}
+
+// issueTokenPair returns a new access token of the session familyId and stores a refresh token of the family
+// expiring at refreshExpiration.
+func (s Server) issueTokenPair(ctx context.Context, userId types.Uuid, familyId string, refreshExpiration time.Time) (LoginUserResponse, error) {
+	accessToken, expiresAt, err := s.accessTokens.Issue(userId, familyId, time.Now())
+	if err != nil {
+		return LoginUserResponse{}, err
+	}
+
+	refreshString, err := generators.Token()
+	if err != nil {
+		return LoginUserResponse{}, err
+	}
+
+	refreshToken := models.NewRefreshToken(refreshString, userId, familyId, refreshExpiration)
+	if err := s.tokenRepo.Create(ctx, refreshToken); err != nil {
+		return LoginUserResponse{}, err
 	}
 
-	respBody := LoginUserResponse{Url: oneTimeUrl.String()}
+	return LoginUserResponse{AccessToken: accessToken, ExpiresAt: expiresAt, RefreshToken: refreshString}, nil
+}
 
+func (s Server) writeTokenPair(w http.ResponseWriter, respBody LoginUserResponse) {
 	js, _ := json.Marshal(respBody)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("X-Rate-Limit", strconv.Itoa(rateLimit))
-	w.Header().Set("X-Expires-After", token.Expiration.Format(time.RFC1123))
-	_, err = w.Write(js)
+	w.Header().Set("X-Expires-After", respBody.ExpiresAt.Format(time.RFC1123))
+	_, err := w.Write(js)
 	if err != nil {
 		s.logger.Errorln("Could not write response")
 	}
 }
+
+// revokeTokenFamily ends the login of the reused token t: the tokens descending from it are deleted,
+// its access tokens rejected and its chat sessions closed.
+func (s Server) revokeTokenFamily(ctx context.Context, t models.Token) {
+	s.logger.Warnln("Refresh token reused, revoking its family. User: ", t.UserId)
+
+	if err := s.tokenRepo.DeleteFamily(ctx, t.FamilyId); err != nil {
+		s.logger.Errorln("Could not revoke refresh tokens. ", err)
+	}
+
+	s.endSession(t.UserId, t.FamilyId, closeReasonTokenReused)
+}
+
+// upgradePasswordHash rehashes the password with the default hasher once it verified against a legacy or outdated hash.
+// Failing to store the new hash does not fail the login, the next one tries again.
+func (s Server) upgradePasswordHash(ctx context.Context, user *models.User, password string) {
+	if !hasher.Default.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	if err := user.SetPassword(password); err != nil {
+		s.logger.Errorln("Could not upgrade password hash. ", err)
+		return
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, user); err != nil {
+		s.logger.Errorln("Could not upgrade password hash. ", err)
+	}
+}
+
+// issueChatToken stores a one-time token the user joins the chat with in the session sessionId.
+func (s Server) issueChatToken(ctx context.Context, userId types.Uuid, sessionId string) (*models.Token, error) {
+	tokenString, err := generators.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	token := models.NewToken(
+		tokenString,
+		userId,
+		time.Now().Add(tokenDuration),
+	)
+	token.FamilyId = sessionId
+
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// chatURL is the link to join the chat with the one-time token.
+func chatURL(r *http.Request, token *models.Token) string {
+	oneTimeUrl := netUrl.URL{
+		Scheme:   "ws",
+		Host:     r.Host,
+		Path:     "/chat/ws.rtm.start",
+		RawQuery: fmt.Sprintf("token=%s", token.Token),
+	}
+
+	return oneTimeUrl.String()
+}
@@ -1,16 +1,25 @@
 package server
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/auth"
+	"github.com/id-tarzanych/lets-go-chat/configurations"
+	"github.com/id-tarzanych/lets-go-chat/db/token"
 	"github.com/id-tarzanych/lets-go-chat/mocks"
 	"github.com/id-tarzanych/lets-go-chat/models"
+	"github.com/id-tarzanych/lets-go-chat/pkg/hasher"
 )
 
 func TestServer_CreateUser(t *testing.T) {
@@ -115,11 +124,14 @@ func TestServer_CreateUser(t *testing.T) {
 
 func TestServer_LoginUser(t *testing.T) {
 	loggerMock, userRepoMock, tokenRepoMock := getUserHandlerMocks(t)
+	loggerMock.On("Errorln", "Could not issue tokens. ", mock.Anything).Return().Once()
 
 	srv := Server{
-		logger:    loggerMock,
-		userRepo:  userRepoMock,
-		tokenRepo: tokenRepoMock,
+		logger:          loggerMock,
+		userRepo:        userRepoMock,
+		tokenRepo:       tokenRepoMock,
+		accessTokens:    testAccessTokens(t),
+		refreshTokenTTL: time.Hour,
 	}
 
 	tests := []struct {
@@ -191,7 +203,7 @@ func TestServer_LoginUser(t *testing.T) {
 			name:        "Token Storage Error",
 			requestJSON: "{\"userName\": \"tokenStorageError\", \"password\": \"12345678\"}",
 			wantCode:    http.StatusInternalServerError,
-			wantMessage: "Could not generate one-time token",
+			wantMessage: "Could not issue tokens",
 		},
 	}
 	for _, tt := range tests {
@@ -217,6 +229,179 @@ func TestServer_LoginUser(t *testing.T) {
 	tokenRepoMock.AssertExpectations(t)
 }
 
+func TestServer_LoginUser_UpgradesPasswordHash(t *testing.T) {
+	loggerMock := &mocks.FieldLogger{}
+	userRepoMock := &mocks.UserRepository{}
+	tokenRepoMock := &mocks.TokenRepository{}
+
+	legacy := models.User{ID: "legacy", UserName: "legacy", PasswordHash: "ef797c8118f02dfb649607dd5d3f8c7623048c9c063d532cc95c5ed7a898a64f"}
+	current := *mustNewUser("current", "12345678")
+	failing := models.User{ID: "failing", UserName: "failing", PasswordHash: legacy.PasswordHash}
+
+	userRepoMock.On("GetByUserName", mock.Anything, "legacy").Return(legacy, nil)
+	userRepoMock.On("GetByUserName", mock.Anything, "current").Return(current, nil)
+	userRepoMock.On("GetByUserName", mock.Anything, "failing").Return(failing, nil)
+	userRepoMock.On("UpdatePassword", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+		ok, _ := hasher.Verify("12345678", u.PasswordHash)
+		return u.ID == "legacy" && ok && !hasher.Default.NeedsRehash(u.PasswordHash)
+	})).Return(nil).Once()
+	userRepoMock.On("UpdatePassword", mock.Anything, mock.MatchedBy(func(u *models.User) bool { return u.ID == "failing" })).Return(errors.New("storage error")).Once()
+	tokenRepoMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+	loggerMock.On("Errorln", "Could not upgrade password hash. ", mock.Anything).Return().Once()
+
+	srv := Server{
+		logger:          loggerMock,
+		userRepo:        userRepoMock,
+		tokenRepo:       tokenRepoMock,
+		accessTokens:    testAccessTokens(t),
+		refreshTokenTTL: time.Hour,
+	}
+
+	for _, userName := range []string{"legacy", "current", "failing"} {
+		w := httptest.NewRecorder()
+		srv.LoginUser(w, httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(`{"userName":"`+userName+`","password":"12345678"}`)))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Incorrect status code for %s, wanted %d, got %d.", userName, http.StatusOK, w.Code)
+		}
+	}
+
+	loggerMock.AssertExpectations(t)
+	userRepoMock.AssertExpectations(t)
+}
+
+func TestServer_LoginUser_TokenPair(t *testing.T) {
+	loggerMock, userRepoMock, _ := getUserHandlerMocks(t)
+	tokenRepoMock := &mocks.TokenRepository{}
+	accessTokens := testAccessTokens(t)
+
+	tokenRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(t *models.Token) bool {
+		return t.IsRefresh() && t.UserId == "uuid" && t.FamilyId != "" && time.Until(t.Expiration) > 59*time.Minute
+	})).Return(nil).Once()
+
+	srv := Server{
+		logger:          loggerMock,
+		userRepo:        userRepoMock,
+		tokenRepo:       tokenRepoMock,
+		accessTokens:    accessTokens,
+		refreshTokenTTL: time.Hour,
+	}
+
+	w := httptest.NewRecorder()
+	srv.LoginUser(w, httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(`{"userName":"existingUser","password":"12345678"}`)))
+	assert.Equal(t, http.StatusOK, w.Code, "unexpected status code")
+
+	response := LoginUserResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+	assert.NotEmpty(t, response.RefreshToken)
+	assert.Equal(t, response.ExpiresAt.Format(time.RFC1123), w.Header().Get("X-Expires-After"))
+
+	claims, err := accessTokens.Verify(response.AccessToken)
+	assert.NoError(t, err, "access token should be valid")
+	assert.Equal(t, "uuid", claims.Subject)
+
+	tokenRepoMock.AssertExpectations(t)
+}
+
+func TestServer_RefreshToken(t *testing.T) {
+	loggerMock := &mocks.FieldLogger{}
+	tokenRepoMock := &mocks.TokenRepository{}
+	accessTokens := testAccessTokens(t)
+
+	expiration := time.Now().Add(time.Hour).Round(time.Second)
+	usedAt := time.Now().Add(-time.Minute)
+
+	reused := *models.NewRefreshToken("reusedToken", "uuid", "stolen", expiration)
+	reused.UsedAt = &usedAt
+
+	tokenRepoMock.On("Get", mock.Anything, "validToken").Return(*models.NewRefreshToken("validToken", "uuid", "family", expiration), nil)
+	tokenRepoMock.On("Get", mock.Anything, "racedToken").Return(*models.NewRefreshToken("racedToken", "uuid", "raced", expiration), nil)
+	tokenRepoMock.On("Get", mock.Anything, "reusedToken").Return(reused, nil)
+	tokenRepoMock.On("Get", mock.Anything, "expiredToken").Return(*models.NewRefreshToken("expiredToken", "uuid", "family", time.Now().Add(-time.Minute)), nil)
+	tokenRepoMock.On("Get", mock.Anything, "chatToken").Return(*models.NewToken("chatToken", "uuid", expiration), nil)
+	tokenRepoMock.On("Get", mock.Anything, mock.Anything).Return(models.Token{}, errors.New("record not found"))
+	tokenRepoMock.On("MarkUsed", mock.Anything, "validToken", mock.Anything).Return(nil).Once()
+	tokenRepoMock.On("MarkUsed", mock.Anything, "racedToken", mock.Anything).Return(token.ErrAlreadyUsed).Once()
+	tokenRepoMock.On("DeleteFamily", mock.Anything, "stolen").Return(nil).Once()
+	tokenRepoMock.On("DeleteFamily", mock.Anything, "raced").Return(nil).Once()
+	tokenRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(t *models.Token) bool {
+		return t.IsRefresh() && t.Token != "validToken" && t.UserId == "uuid" && t.FamilyId == "family" && t.Expiration.Equal(expiration)
+	})).Return(nil).Once()
+	loggerMock.On("Warnln", "Refresh token reused, revoking its family. User: ", mock.Anything).Return().Twice()
+
+	srv := Server{
+		logger:       loggerMock,
+		tokenRepo:    tokenRepoMock,
+		accessTokens: accessTokens,
+		chatData:     wss.NewChatData(),
+	}
+
+	stolenAccessToken, _, err := accessTokens.Issue("uuid", "stolen", time.Now())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// A disconnected chat session of the stolen login, it could be resumed with its entry token.
+	stolenClient := &wss.Client{User: &models.User{ID: "uuid"}, EntryToken: "stolenChatToken", SessionId: "stolen"}
+	srv.chatData.StoreToken(stolenClient.EntryToken, stolenClient)
+
+	tests := []struct {
+		name        string
+		requestJSON string
+		wantCode    int
+		wantMessage string
+	}{
+		{"Invalid syntax", `{123]`, http.StatusBadRequest, "Syntax error"},
+		{"Empty token", `{"refreshToken":""}`, http.StatusBadRequest, "Refresh token is required."},
+		{"Unknown token", `{"refreshToken":"unknownToken"}`, http.StatusBadRequest, "Refresh token is invalid."},
+		{"Chat token", `{"refreshToken":"chatToken"}`, http.StatusBadRequest, "Refresh token is invalid."},
+		{"Expired token", `{"refreshToken":"expiredToken"}`, http.StatusBadRequest, "Refresh token expired."},
+		{"Reused token", `{"refreshToken":"reusedToken"}`, http.StatusBadRequest, "Refresh token is invalid."},
+		{"Concurrently used token", `{"refreshToken":"racedToken"}`, http.StatusBadRequest, "Refresh token is invalid."},
+		{"Valid token", `{"refreshToken":"validToken"}`, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.RefreshToken(w, httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(tt.requestJSON)))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantCode != http.StatusOK {
+				assert.Equal(t, tt.wantMessage, strings.TrimSpace(w.Body.String()))
+				return
+			}
+
+			response := LoginUserResponse{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+			assert.NotEqual(t, "validToken", response.RefreshToken, "refresh tokens should rotate")
+
+			claims, err := accessTokens.Verify(response.AccessToken)
+			assert.NoError(t, err, "access token should be valid")
+			assert.Equal(t, "uuid", claims.Subject)
+		})
+	}
+
+	_, err = accessTokens.Verify(stolenAccessToken)
+	assert.True(t, errors.Is(err, auth.ErrRevoked), "access tokens of a reused login should be rejected, got %v", err)
+	assert.Nil(t, srv.chatData.LoadClient(stolenClient.EntryToken), "chat sessions of a reused login should not be resumable")
+
+	loggerMock.AssertExpectations(t)
+	tokenRepoMock.AssertExpectations(t)
+}
+
+func testAccessTokens(t *testing.T) *auth.AccessTokens {
+	accessTokens, err := auth.NewAccessTokens(configurations.Auth{
+		SigningKeys:    []string{"test:HS256:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))},
+		AccessTokenTTL: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return accessTokens
+}
+
 func getUserHandlerMocks(t *testing.T) (*mocks.FieldLogger, *mocks.UserRepository, *mocks.TokenRepository) {
 	loggerMock := &mocks.FieldLogger{}
 	userRepoMock := &mocks.UserRepository{}
@@ -226,6 +411,7 @@ func getUserHandlerMocks(t *testing.T) (*mocks.FieldLogger, *mocks.UserRepositor
 	userRepoMock.On("GetByUserName", mock.Anything, "tokenStorageError").Maybe().Return(models.User{ID: "uuid-token-storage-error", UserName: "tokenStorageError", PasswordHash: "ef797c8118f02dfb649607dd5d3f8c7623048c9c063d532cc95c5ed7a898a64f"}, nil)
 	userRepoMock.On("GetByUserName", mock.Anything, "storageErrorUser").Maybe().Return(models.User{ID: "uuid"}, errors.New("storage error"))
 	userRepoMock.On("GetByUserName", mock.Anything, "newUser").Maybe().Return(models.User{}, errors.New("could not find user"))
+	userRepoMock.On("UpdatePassword", mock.Anything, mock.MatchedBy(func(u *models.User) bool { return strings.HasPrefix(u.PasswordHash, "$argon2id$") })).Maybe().Return(nil)
 	userRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(u *models.User) bool { return u.UserName == "storageErrorUser" })).Maybe().Return(errors.New("storage error"))
 	userRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(u *models.User) bool { return u.UserName != "storageErrorUser" })).Maybe().Return(nil)
 
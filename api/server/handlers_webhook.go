@@ -0,0 +1,308 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	netUrl "net/url"
+	"strings"
+
+	"github.com/id-tarzanych/lets-go-chat/api/middlewares"
+	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+	"github.com/id-tarzanych/lets-go-chat/pkg/generators"
+)
+
+const (
+	defaultDeliveriesPageLimit = 50
+	maxDeliveriesPageLimit     = 100
+
+	// minWebhookSecretLength keeps chosen secrets from being guessable.
+	minWebhookSecretLength = 16
+)
+
+var (
+	errNotAdmin        = errors.New("user is not an admin")
+	errWebhookNotFound = errors.New("webhook not found")
+)
+
+// messageWebhookEvents maps message events of the chat protocol to webhook events.
+var messageWebhookEvents = map[string]string{
+	wss.EventMessage:        models.WebhookMessageCreated,
+	wss.EventMessageEdited:  models.WebhookMessageEdited,
+	wss.EventMessageDeleted: models.WebhookMessageDeleted,
+}
+
+func (s Server) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.requireAdmin(r.Context(), userId); err != nil {
+		s.webhookError(w, err, 0)
+		return
+	}
+
+	hooks, err := s.webhookRepo.GetAll(r.Context())
+	if err != nil {
+		s.webhookError(w, err, 0)
+		return
+	}
+
+	respBody := WebhooksResponse{Webhooks: make([]Webhook, 0, len(hooks))}
+	for i := range hooks {
+		respBody.Webhooks = append(respBody.Webhooks, webhookResponse(&hooks[i], false))
+	}
+
+	js, _ := json.Marshal(respBody)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+func (s Server) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.requireAdmin(r.Context(), userId); err != nil {
+		s.webhookError(w, err, 0)
+		return
+	}
+
+	var reqBody CreateWebhookJSONRequestBody
+
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		http.Error(w, "Syntax error", http.StatusBadRequest)
+		return
+	}
+
+	url := strings.TrimSpace(reqBody.Url)
+	if u, err := netUrl.Parse(url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		http.Error(w, "Url must be an absolute http or https url", http.StatusBadRequest)
+		return
+	}
+
+	events, err := webhookEvents(reqBody.Events)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var secret string
+	if reqBody.Secret != nil {
+		secret = *reqBody.Secret
+		if len(secret) < minWebhookSecretLength {
+			http.Error(w, fmt.Sprintf("Secret must have at least %d characters", minWebhookSecretLength), http.StatusBadRequest)
+			return
+		}
+	} else if secret, err = generators.Token(); err != nil {
+		s.webhookError(w, err, 0)
+		return
+	}
+
+	hook := models.NewWebhook(url, secret, events, userId)
+	if err := s.webhookRepo.Create(r.Context(), hook); err != nil {
+		s.webhookError(w, err, 0)
+		return
+	}
+
+	js, _ := json.Marshal(webhookResponse(hook, true))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+func (s Server) DeleteWebhook(w http.ResponseWriter, r *http.Request, webhookId int) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.requireAdmin(r.Context(), userId); err != nil {
+		s.webhookError(w, err, webhookId)
+		return
+	}
+
+	if _, err := s.webhookRepo.GetById(r.Context(), uint(webhookId)); err != nil {
+		s.webhookError(w, errWebhookNotFound, webhookId)
+		return
+	}
+
+	if err := s.webhookRepo.Delete(r.Context(), uint(webhookId)); err != nil {
+		s.webhookError(w, err, webhookId)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s Server) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request, webhookId int, params GetWebhookDeliveriesParams) {
+	userId, ok := middlewares.UserIdFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+		return
+	}
+
+	limit := defaultDeliveriesPageLimit
+	if params.Limit != nil {
+		if *params.Limit < 1 {
+			http.Error(w, "Limit must be positive", http.StatusBadRequest)
+			return
+		}
+
+		limit = *params.Limit
+		if limit > maxDeliveriesPageLimit {
+			limit = maxDeliveriesPageLimit
+		}
+	}
+
+	if err := s.requireAdmin(r.Context(), userId); err != nil {
+		s.webhookError(w, err, webhookId)
+		return
+	}
+
+	if _, err := s.webhookRepo.GetById(r.Context(), uint(webhookId)); err != nil {
+		s.webhookError(w, errWebhookNotFound, webhookId)
+		return
+	}
+
+	deliveries, err := s.webhookRepo.GetDeliveries(r.Context(), uint(webhookId), limit)
+	if err != nil {
+		s.webhookError(w, err, webhookId)
+		return
+	}
+
+	respBody := WebhookDeliveriesResponse{Deliveries: make([]WebhookDelivery, 0, len(deliveries))}
+	for i := range deliveries {
+		respBody.Deliveries = append(respBody.Deliveries, webhookDeliveryResponse(&deliveries[i]))
+	}
+
+	js, _ := json.Marshal(respBody)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(js)
+	if err != nil {
+		s.logger.Errorln("Could not write response")
+	}
+}
+
+// publishWebhookEvent queues the event for webhooks. Webhooks never fail the operation that caused the event.
+func (s Server) publishWebhookEvent(event string, data interface{}) {
+	if s.webhooks == nil {
+		return
+	}
+
+	if err := s.webhooks.Publish(context.Background(), event, data); err != nil {
+		s.logger.Errorln("Could not queue webhook event. ", err)
+	}
+}
+
+// requireAdmin fails with errNotAdmin unless the user is an admin.
+func (s Server) requireAdmin(ctx context.Context, userId types.Uuid) error {
+	u, err := s.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if !u.Admin {
+		return errNotAdmin
+	}
+
+	return nil
+}
+
+// webhookEvents validates the subscribed events, dropping duplicates.
+func webhookEvents(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, errors.New("At least one event is required")
+	}
+
+	known := make(map[string]bool, len(models.WebhookEvents))
+	for _, event := range models.WebhookEvents {
+		known[event] = true
+	}
+
+	events := make([]string, 0, len(requested))
+	seen := make(map[string]bool, len(requested))
+
+	for _, event := range requested {
+		if !known[event] {
+			return nil, fmt.Errorf("Unknown event %s, events are %s", event, strings.Join(models.WebhookEvents, ", "))
+		}
+
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func (s Server) webhookError(w http.ResponseWriter, err error, webhookId int) {
+	switch {
+	case errors.Is(err, errNotAdmin):
+		http.Error(w, "Only admins can manage webhooks", http.StatusForbidden)
+	case errors.Is(err, errWebhookNotFound):
+		http.Error(w, fmt.Sprintf("Webhook %d does not exist", webhookId), http.StatusNotFound)
+	default:
+		s.logger.Errorln("Could not manage webhooks. ", err)
+		http.Error(w, "Could not manage webhooks", http.StatusInternalServerError)
+	}
+}
+
+func webhookResponse(hook *models.Webhook, withSecret bool) Webhook {
+	resp := Webhook{Id: int(hook.ID), Url: hook.Url, Events: hook.EventList(), CreatedAt: hook.CreatedAt}
+	if withSecret {
+		secret := hook.Secret
+		resp.Secret = &secret
+	}
+
+	return resp
+}
+
+func webhookDeliveryResponse(d *models.WebhookDelivery) WebhookDelivery {
+	resp := WebhookDelivery{
+		Id:          int(d.ID),
+		Event:       d.Event,
+		Payload:     d.Payload,
+		Status:      WebhookDeliveryStatus(d.Status),
+		Attempts:    d.Attempts,
+		CreatedAt:   d.CreatedAt,
+		DeliveredAt: d.DeliveredAt,
+	}
+
+	if d.LastStatusCode != 0 {
+		code := d.LastStatusCode
+		resp.LastStatusCode = &code
+	}
+
+	if d.LastError != "" {
+		lastError := d.LastError
+		resp.LastError = &lastError
+	}
+
+	if d.Status == models.DeliveryPending {
+		next := d.NextAttemptAt
+		resp.NextAttemptAt = &next
+	}
+
+	return resp
+}
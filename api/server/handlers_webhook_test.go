@@ -0,0 +1,215 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/configurations"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/mocks"
+	"github.com/id-tarzanych/lets-go-chat/models"
+	"github.com/id-tarzanych/lets-go-chat/webhooks"
+)
+
+// newWebhookTestServer authenticates admin alice and regular user bob by their name followed by "Token".
+func newWebhookTestServer() (*Server, *mocks.UserRepository, *mocks.WebhookRepository) {
+	loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock := getChatHandlerMocks()
+	webhookRepoMock := &mocks.WebhookRepository{}
+
+	alice := *mustNewUser("alice", "12345678")
+	alice.Admin = true
+	bob := *mustNewUser("bob", "12345678")
+
+	for _, u := range []models.User{alice, bob} {
+		tokenRepoMock.On("Get", mock.Anything, u.UserName+"Token").Return(models.Token{Token: u.UserName + "Token", UserId: u.ID, Expiration: time.Now().Add(time.Hour)}, nil)
+		userRepoMock.On("GetById", mock.Anything, u.ID).Return(u, nil)
+	}
+	tokenRepoMock.On("Get", mock.Anything, mock.Anything).Return(models.Token{}, errors.New("record not found"))
+
+	srv := newChatTestServer(loggerMock, userRepoMock, tokenRepoMock, messageRepoMock, roomRepoMock)
+	srv.webhookRepo = webhookRepoMock
+
+	return srv, userRepoMock, webhookRepoMock
+}
+
+func TestServer_CreateWebhook(t *testing.T) {
+	srv, _, webhookRepoMock := newWebhookTestServer()
+
+	webhookRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(w *models.Webhook) bool {
+		return w.Url == "https://example.com/hook" && w.Events == "message.created,user.joined" && len(w.Secret) == 43
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Webhook).ID = 7
+	}).Return(nil).Once()
+	webhookRepoMock.On("Create", mock.Anything, mock.MatchedBy(func(w *models.Webhook) bool {
+		return w.Secret == "0123456789abcdef"
+	})).Return(nil).Once()
+
+	tests := []struct {
+		name     string
+		token    string
+		body     string
+		wantCode int
+	}{
+		{"Invalid token", "stolenToken", `{"url":"https://example.com/hook","events":["user.joined"]}`, http.StatusBadRequest},
+		{"Not an admin", "bobToken", `{"url":"https://example.com/hook","events":["user.joined"]}`, http.StatusForbidden},
+		{"Syntax error", "aliceToken", `{"url":`, http.StatusBadRequest},
+		{"Relative url", "aliceToken", `{"url":"/hook","events":["user.joined"]}`, http.StatusBadRequest},
+		{"Unsupported scheme", "aliceToken", `{"url":"ftp://example.com/hook","events":["user.joined"]}`, http.StatusBadRequest},
+		{"No events", "aliceToken", `{"url":"https://example.com/hook","events":[]}`, http.StatusBadRequest},
+		{"Unknown event", "aliceToken", `{"url":"https://example.com/hook","events":["user.typing"]}`, http.StatusBadRequest},
+		{"Short secret", "aliceToken", `{"url":"https://example.com/hook","events":["user.joined"],"secret":"short"}`, http.StatusBadRequest},
+		{"Chosen secret", "aliceToken", `{"url":"http://example.com/hook","events":["user.left"],"secret":"0123456789abcdef"}`, http.StatusCreated},
+		{"Webhook", "aliceToken", `{"url":" https://example.com/hook ","events":["message.created","user.joined","message.created"]}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/webhooks?token="+tt.token, strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantCode == http.StatusCreated {
+				response := Webhook{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+				assert.NotNil(t, response.Secret, "secret should be returned on creation")
+			}
+		})
+	}
+
+	webhookRepoMock.AssertExpectations(t)
+}
+
+func TestServer_ListWebhooks(t *testing.T) {
+	srv, _, webhookRepoMock := newWebhookTestServer()
+
+	hook := *models.NewWebhook("https://example.com/hook", "secret", []string{models.WebhookUserJoined, models.WebhookUserLeft}, "admin")
+	hook.ID = 7
+	webhookRepoMock.On("GetAll", mock.Anything).Return([]models.Webhook{hook}, nil)
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/webhooks?token=bobToken", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code, "only admins list webhooks")
+
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/webhooks?token=aliceToken", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "unexpected status code")
+
+	response := WebhooksResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+	assert.Equal(t, []Webhook{{Id: 7, Url: hook.Url, Events: []string{"user.joined", "user.left"}}}, response.Webhooks, "secrets should not be listed")
+}
+
+func TestServer_DeleteWebhook(t *testing.T) {
+	srv, _, webhookRepoMock := newWebhookTestServer()
+
+	webhookRepoMock.On("GetById", mock.Anything, uint(7)).Return(models.Webhook{ID: 7}, nil)
+	webhookRepoMock.On("GetById", mock.Anything, mock.Anything).Return(models.Webhook{}, errors.New("record not found"))
+	webhookRepoMock.On("Delete", mock.Anything, uint(7)).Return(nil).Once()
+
+	tests := []struct {
+		name     string
+		token    string
+		url      string
+		wantCode int
+	}{
+		{"Not an admin", "bobToken", "/webhooks/7", http.StatusForbidden},
+		{"Invalid id", "aliceToken", "/webhooks/seven", http.StatusBadRequest},
+		{"Unknown webhook", "aliceToken", "/webhooks/8", http.StatusNotFound},
+		{"Webhook", "aliceToken", "/webhooks/7", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodDelete, tt.url+"?token="+tt.token, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+		})
+	}
+
+	webhookRepoMock.AssertExpectations(t)
+}
+
+func TestServer_GetWebhookDeliveries(t *testing.T) {
+	srv, _, webhookRepoMock := newWebhookTestServer()
+
+	createdAt := time.Date(2021, 11, 20, 10, 0, 0, 0, time.UTC)
+	deliveries := []models.WebhookDelivery{
+		{ID: 2, WebhookID: 7, Event: models.WebhookUserLeft, Payload: "{}", Status: models.DeliveryPending, NextAttemptAt: createdAt.Add(time.Minute), Attempts: 1, LastError: "unexpected response status 502 Bad Gateway", LastStatusCode: 502, CreatedAt: createdAt},
+		{ID: 1, WebhookID: 7, Event: models.WebhookUserJoined, Payload: "{}", Status: models.DeliveryDelivered, Attempts: 1, LastStatusCode: 200, CreatedAt: createdAt, DeliveredAt: &createdAt},
+	}
+
+	webhookRepoMock.On("GetById", mock.Anything, uint(7)).Return(models.Webhook{ID: 7}, nil)
+	webhookRepoMock.On("GetById", mock.Anything, mock.Anything).Return(models.Webhook{}, errors.New("record not found"))
+	webhookRepoMock.On("GetDeliveries", mock.Anything, uint(7), maxDeliveriesPageLimit).Return(deliveries, nil).Once()
+
+	tests := []struct {
+		name     string
+		token    string
+		url      string
+		wantCode int
+	}{
+		{"Not an admin", "bobToken", "/webhooks/7/deliveries", http.StatusForbidden},
+		{"Invalid limit", "aliceToken", "/webhooks/7/deliveries?limit=0", http.StatusBadRequest},
+		{"Unknown webhook", "aliceToken", "/webhooks/8/deliveries", http.StatusNotFound},
+		{"Deliveries", "aliceToken", "/webhooks/7/deliveries?limit=500", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := tt.url + "?token=" + tt.token
+			if strings.Contains(tt.url, "?") {
+				url = tt.url + "&token=" + tt.token
+			}
+
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code, "unexpected status code")
+
+			if tt.wantCode == http.StatusOK {
+				response := WebhookDeliveriesResponse{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "json should be valid")
+
+				if assert.Len(t, response.Deliveries, 2) {
+					assert.Equal(t, WebhookDeliveryStatusPending, response.Deliveries[0].Status)
+					assert.Equal(t, 502, *response.Deliveries[0].LastStatusCode)
+					assert.NotNil(t, response.Deliveries[0].NextAttemptAt, "pending deliveries should tell their next attempt")
+					assert.Equal(t, WebhookDeliveryStatusDelivered, response.Deliveries[1].Status)
+					assert.Nil(t, response.Deliveries[1].NextAttemptAt)
+					assert.Nil(t, response.Deliveries[1].LastError)
+				}
+			}
+		})
+	}
+
+	webhookRepoMock.AssertExpectations(t)
+}
+
+func TestServer_deliverMessage_Webhooks(t *testing.T) {
+	srv, _, webhookRepoMock := newWebhookTestServer()
+	srv.webhooks = webhooks.NewDispatcher(webhookRepoMock, configurations.Webhooks{}, srv.logger)
+
+	hook := *models.NewWebhook("https://example.com/hook", "secret", models.WebhookEvents, "admin")
+	webhookRepoMock.On("GetAll", mock.Anything).Return([]models.Webhook{hook}, nil)
+	webhookRepoMock.On("CreateDeliveries", mock.Anything, mock.MatchedBy(func(deliveries []models.WebhookDelivery) bool {
+		return len(deliveries) == 1 && deliveries[0].Event == models.WebhookMessageEdited && strings.Contains(deliveries[0].Payload, `"message":"public"`)
+	})).Return(nil).Once()
+
+	bob := types.Uuid("bob")
+
+	public := &models.Message{AuthorUuid: "alice", Message: "public"}
+	direct := &models.Message{AuthorUuid: "alice", RecipientUuid: &bob, Message: "private"}
+
+	srv.deliverMessage(wss.EventMessageEdited, public, nil)
+	srv.deliverMessage(wss.EventMessageEdited, direct, nil)
+
+	webhookRepoMock.AssertExpectations(t)
+}
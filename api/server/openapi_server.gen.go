@@ -4,6 +4,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -13,9 +14,75 @@ import (
 
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Logs bot into the system
+	// (POST /bot/login)
+	LoginBot(w http.ResponseWriter, r *http.Request)
+	// Create bot account
+	// (POST /bots)
+	CreateBot(w http.ResponseWriter, r *http.Request)
+	// List API keys of a bot
+	// (GET /bots/{botId}/keys)
+	ListBotKeys(w http.ResponseWriter, r *http.Request, botId string)
+	// Issue an API key for a bot
+	// (POST /bots/{botId}/keys)
+	CreateBotKey(w http.ResponseWriter, r *http.Request, botId string)
+	// Revoke an API key of a bot
+	// (DELETE /bots/{botId}/keys/{keyId})
+	DeleteBotKey(w http.ResponseWriter, r *http.Request, botId string, keyId int)
+	// Upload a file to attach to a message
+	// (POST /chat/attachments)
+	UploadAttachment(w http.ResponseWriter, r *http.Request)
+	// Download an attachment
+	// (GET /chat/attachments/{attachmentId})
+	GetAttachment(w http.ResponseWriter, r *http.Request, attachmentId int)
+	// Page through messages mentioning the user
+	// (GET /chat/mentions)
+	GetMentions(w http.ResponseWriter, r *http.Request, params GetMentionsParams)
+	// Page through message history
+	// (GET /chat/messages)
+	GetMessages(w http.ResponseWriter, r *http.Request, params GetMessagesParams)
+	// Search message history
+	// (GET /chat/messages/search)
+	SearchMessages(w http.ResponseWriter, r *http.Request, params SearchMessagesParams)
+	// Delete own message
+	// (DELETE /chat/messages/{messageId})
+	DeleteMessage(w http.ResponseWriter, r *http.Request, messageId int)
+	// Edit own message
+	// (PATCH /chat/messages/{messageId})
+	EditMessage(w http.ResponseWriter, r *http.Request, messageId int)
+	// Page through replies of a thread
+	// (GET /chat/messages/{messageId}/thread)
+	GetMessageThread(w http.ResponseWriter, r *http.Request, messageId int, params GetMessageThreadParams)
+	// Delivery queue metrics
+	// (GET /chat/metrics)
+	GetChatMetrics(w http.ResponseWriter, r *http.Request)
+	// List chat rooms
+	// (GET /chat/rooms)
+	ListRooms(w http.ResponseWriter, r *http.Request)
+	// Create chat room
+	// (POST /chat/rooms)
+	CreateRoom(w http.ResponseWriter, r *http.Request)
+	// Unread message counts per conversation
+	// (GET /chat/unread)
+	GetUnreadCounts(w http.ResponseWriter, r *http.Request)
+	// Issue a one-time url to start real time chat
+	// (POST /chat/ws.rtm.connect)
+	WsRTMConnect(w http.ResponseWriter, r *http.Request)
 	// Endpoint to start real time chat
 	// (GET /chat/ws.rtm.start)
 	WsRTMStart(w http.ResponseWriter, r *http.Request, params WsRTMStartParams)
+	// Post a message through an incoming webhook
+	// (POST /hooks/{token})
+	PostIncomingWebhook(w http.ResponseWriter, r *http.Request, token string)
+	// List incoming webhooks
+	// (GET /incoming-webhooks)
+	ListIncomingWebhooks(w http.ResponseWriter, r *http.Request)
+	// Create an incoming webhook
+	// (POST /incoming-webhooks)
+	CreateIncomingWebhook(w http.ResponseWriter, r *http.Request)
+	// Delete an incoming webhook
+	// (DELETE /incoming-webhooks/{webhookId})
+	DeleteIncomingWebhook(w http.ResponseWriter, r *http.Request, webhookId int)
 	// Register (create) user
 	// (POST /user)
 	CreateUser(w http.ResponseWriter, r *http.Request)
@@ -25,6 +92,33 @@ type ServerInterface interface {
 	// Logs user into the system
 	// (POST /user/login)
 	LoginUser(w http.ResponseWriter, r *http.Request)
+	// Ends the current session
+	// (POST /user/logout)
+	LogoutUser(w http.ResponseWriter, r *http.Request)
+	// Ends all sessions of the user
+	// (POST /user/logout/all)
+	LogoutAllSessions(w http.ResponseWriter, r *http.Request)
+	// Users currently connected to the chat
+	// (GET /user/online)
+	GetOnlineUsers(w http.ResponseWriter, r *http.Request)
+	// Exchanges a refresh token for a new token pair
+	// (POST /user/refresh)
+	RefreshToken(w http.ResponseWriter, r *http.Request)
+	// Ends all sessions of a user
+	// (POST /user/{userName}/revoke)
+	RevokeUserSessions(w http.ResponseWriter, r *http.Request, userName string)
+	// List webhooks
+	// (GET /webhooks)
+	ListWebhooks(w http.ResponseWriter, r *http.Request)
+	// Register a webhook
+	// (POST /webhooks)
+	CreateWebhook(w http.ResponseWriter, r *http.Request)
+	// Delete a webhook
+	// (DELETE /webhooks/{webhookId})
+	DeleteWebhook(w http.ResponseWriter, r *http.Request, webhookId int)
+	// Inspect recent deliveries of a webhook
+	// (GET /webhooks/{webhookId}/deliveries)
+	GetWebhookDeliveries(w http.ResponseWriter, r *http.Request, webhookId int, params GetWebhookDeliveriesParams)
 }
 
 // ServerInterfaceWrapper converts contexts to parameters.
@@ -36,31 +130,717 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(http.HandlerFunc) http.HandlerFunc
 
+// LoginBot operation middleware
+func (siw *ServerInterfaceWrapper) LoginBot(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.LoginBot(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// CreateBot operation middleware
+func (siw *ServerInterfaceWrapper) CreateBot(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateBot(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// ListBotKeys operation middleware
+func (siw *ServerInterfaceWrapper) ListBotKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "botId" -------------
+	var botId string
+
+	err = runtime.BindStyledParameter("simple", false, "botId", chi.URLParam(r, "botId"), &botId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "botId", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListBotKeys(w, r, botId)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// CreateBotKey operation middleware
+func (siw *ServerInterfaceWrapper) CreateBotKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "botId" -------------
+	var botId string
+
+	err = runtime.BindStyledParameter("simple", false, "botId", chi.URLParam(r, "botId"), &botId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "botId", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateBotKey(w, r, botId)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// DeleteBotKey operation middleware
+func (siw *ServerInterfaceWrapper) DeleteBotKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "botId" -------------
+	var botId string
+
+	err = runtime.BindStyledParameter("simple", false, "botId", chi.URLParam(r, "botId"), &botId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "botId", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "keyId" -------------
+	var keyId int
+
+	err = runtime.BindStyledParameter("simple", false, "keyId", chi.URLParam(r, "keyId"), &keyId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "keyId", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteBotKey(w, r, botId, keyId)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// UploadAttachment operation middleware
+func (siw *ServerInterfaceWrapper) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.UploadAttachment(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetAttachment operation middleware
+func (siw *ServerInterfaceWrapper) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "attachmentId" -------------
+	var attachmentId int
+
+	err = runtime.BindStyledParameter("simple", false, "attachmentId", chi.URLParam(r, "attachmentId"), &attachmentId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "attachmentId", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetAttachment(w, r, attachmentId)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetMentions operation middleware
+func (siw *ServerInterfaceWrapper) GetMentions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetMentionsParams
+
+	// ------------- Optional query parameter "before" -------------
+	if paramValue := r.URL.Query().Get("before"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "before", r.URL.Query(), &params.Before)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "before", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "after" -------------
+	if paramValue := r.URL.Query().Get("after"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "after", r.URL.Query(), &params.After)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "after", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := r.URL.Query().Get("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetMentions(w, r, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetMessages operation middleware
+func (siw *ServerInterfaceWrapper) GetMessages(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetMessagesParams
+
+	// ------------- Optional query parameter "before" -------------
+	if paramValue := r.URL.Query().Get("before"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "before", r.URL.Query(), &params.Before)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "before", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "after" -------------
+	if paramValue := r.URL.Query().Get("after"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "after", r.URL.Query(), &params.After)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "after", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := r.URL.Query().Get("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetMessages(w, r, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// SearchMessages operation middleware
+func (siw *ServerInterfaceWrapper) SearchMessages(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params SearchMessagesParams
+
+	// ------------- Required query parameter "q" -------------
+	if paramValue := r.URL.Query().Get("q"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "q"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "q", r.URL.Query(), &params.Q)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "q", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "author" -------------
+	if paramValue := r.URL.Query().Get("author"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "author", r.URL.Query(), &params.Author)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "author", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "room" -------------
+	if paramValue := r.URL.Query().Get("room"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "room", r.URL.Query(), &params.Room)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "room", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "from" -------------
+	if paramValue := r.URL.Query().Get("from"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "from", r.URL.Query(), &params.From)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "from", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "to" -------------
+	if paramValue := r.URL.Query().Get("to"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "to", r.URL.Query(), &params.To)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "to", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "before" -------------
+	if paramValue := r.URL.Query().Get("before"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "before", r.URL.Query(), &params.Before)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "before", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "after" -------------
+	if paramValue := r.URL.Query().Get("after"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "after", r.URL.Query(), &params.After)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "after", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := r.URL.Query().Get("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.SearchMessages(w, r, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// DeleteMessage operation middleware
+func (siw *ServerInterfaceWrapper) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "messageId" -------------
+	var messageId int
+
+	err = runtime.BindStyledParameter("simple", false, "messageId", chi.URLParam(r, "messageId"), &messageId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "messageId", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteMessage(w, r, messageId)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// EditMessage operation middleware
+func (siw *ServerInterfaceWrapper) EditMessage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "messageId" -------------
+	var messageId int
+
+	err = runtime.BindStyledParameter("simple", false, "messageId", chi.URLParam(r, "messageId"), &messageId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "messageId", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.EditMessage(w, r, messageId)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetMessageThread operation middleware
+func (siw *ServerInterfaceWrapper) GetMessageThread(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "messageId" -------------
+	var messageId int
+
+	err = runtime.BindStyledParameter("simple", false, "messageId", chi.URLParam(r, "messageId"), &messageId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "messageId", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetMessageThreadParams
+
+	// ------------- Optional query parameter "before" -------------
+	if paramValue := r.URL.Query().Get("before"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "before", r.URL.Query(), &params.Before)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "before", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "after" -------------
+	if paramValue := r.URL.Query().Get("after"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "after", r.URL.Query(), &params.After)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "after", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := r.URL.Query().Get("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetMessageThread(w, r, messageId, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetChatMetrics operation middleware
+func (siw *ServerInterfaceWrapper) GetChatMetrics(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetChatMetrics(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// ListRooms operation middleware
+func (siw *ServerInterfaceWrapper) ListRooms(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListRooms(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// CreateRoom operation middleware
+func (siw *ServerInterfaceWrapper) CreateRoom(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateRoom(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetUnreadCounts operation middleware
+func (siw *ServerInterfaceWrapper) GetUnreadCounts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetUnreadCounts(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// WsRTMConnect operation middleware
+func (siw *ServerInterfaceWrapper) WsRTMConnect(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.WsRTMConnect(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
 // WsRTMStart operation middleware
 func (siw *ServerInterfaceWrapper) WsRTMStart(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	var err error
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params WsRTMStartParams
+
+	// ------------- Required query parameter "token" -------------
+	if paramValue := r.URL.Query().Get("token"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "token"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "token", r.URL.Query(), &params.Token)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "token", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.WsRTMStart(w, r, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PostIncomingWebhook operation middleware
+func (siw *ServerInterfaceWrapper) PostIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "token" -------------
+	var token string
+
+	err = runtime.BindStyledParameter("simple", false, "token", chi.URLParam(r, "token"), &token)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "token", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostIncomingWebhook(w, r, token)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// ListIncomingWebhooks operation middleware
+func (siw *ServerInterfaceWrapper) ListIncomingWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
 
-	// Parameter object where we will unmarshal all parameters from the context
-	var params WsRTMStartParams
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListIncomingWebhooks(w, r)
+	}
 
-	// ------------- Required query parameter "token" -------------
-	if paramValue := r.URL.Query().Get("token"); paramValue != "" {
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
 
-	} else {
-		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "token"})
-		return
+	handler(w, r.WithContext(ctx))
+}
+
+// CreateIncomingWebhook operation middleware
+func (siw *ServerInterfaceWrapper) CreateIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateIncomingWebhook(w, r)
 	}
 
-	err = runtime.BindQueryParameter("form", true, true, "token", r.URL.Query(), &params.Token)
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// DeleteIncomingWebhook operation middleware
+func (siw *ServerInterfaceWrapper) DeleteIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "webhookId" -------------
+	var webhookId int
+
+	err = runtime.BindStyledParameter("simple", false, "webhookId", chi.URLParam(r, "webhookId"), &webhookId)
 	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "token", Err: err})
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "webhookId", Err: err})
 		return
 	}
 
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
 	var handler = func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.WsRTMStart(w, r, params)
+		siw.Handler.DeleteIncomingWebhook(w, r, webhookId)
 	}
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -89,6 +869,8 @@ func (siw *ServerInterfaceWrapper) CreateUser(w http.ResponseWriter, r *http.Req
 func (siw *ServerInterfaceWrapper) GetActiveUsers(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
 	var handler = func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.GetActiveUsers(w, r)
 	}
@@ -115,6 +897,204 @@ func (siw *ServerInterfaceWrapper) LoginUser(w http.ResponseWriter, r *http.Requ
 	handler(w, r.WithContext(ctx))
 }
 
+// LogoutUser operation middleware
+func (siw *ServerInterfaceWrapper) LogoutUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.LogoutUser(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// LogoutAllSessions operation middleware
+func (siw *ServerInterfaceWrapper) LogoutAllSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.LogoutAllSessions(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetOnlineUsers operation middleware
+func (siw *ServerInterfaceWrapper) GetOnlineUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetOnlineUsers(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// RefreshToken operation middleware
+func (siw *ServerInterfaceWrapper) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.RefreshToken(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// RevokeUserSessions operation middleware
+func (siw *ServerInterfaceWrapper) RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "userName" -------------
+	var userName string
+
+	err = runtime.BindStyledParameter("simple", false, "userName", chi.URLParam(r, "userName"), &userName)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "userName", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.RevokeUserSessions(w, r, userName)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// ListWebhooks operation middleware
+func (siw *ServerInterfaceWrapper) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListWebhooks(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// CreateWebhook operation middleware
+func (siw *ServerInterfaceWrapper) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateWebhook(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// DeleteWebhook operation middleware
+func (siw *ServerInterfaceWrapper) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "webhookId" -------------
+	var webhookId int
+
+	err = runtime.BindStyledParameter("simple", false, "webhookId", chi.URLParam(r, "webhookId"), &webhookId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "webhookId", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteWebhook(w, r, webhookId)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetWebhookDeliveries operation middleware
+func (siw *ServerInterfaceWrapper) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "webhookId" -------------
+	var webhookId int
+
+	err = runtime.BindStyledParameter("simple", false, "webhookId", chi.URLParam(r, "webhookId"), &webhookId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "webhookId", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, TokenScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetWebhookDeliveriesParams
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := r.URL.Query().Get("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetWebhookDeliveries(w, r, webhookId, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
 type UnescapedCookieParamError struct {
 	ParamName string
 	Err       error
@@ -228,9 +1208,75 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 		ErrorHandlerFunc:   options.ErrorHandlerFunc,
 	}
 
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/bot/login", wrapper.LoginBot)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/bots", wrapper.CreateBot)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/bots/{botId}/keys", wrapper.ListBotKeys)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/bots/{botId}/keys", wrapper.CreateBotKey)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/bots/{botId}/keys/{keyId}", wrapper.DeleteBotKey)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/chat/attachments", wrapper.UploadAttachment)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/chat/attachments/{attachmentId}", wrapper.GetAttachment)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/chat/mentions", wrapper.GetMentions)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/chat/messages", wrapper.GetMessages)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/chat/messages/search", wrapper.SearchMessages)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/chat/messages/{messageId}", wrapper.DeleteMessage)
+	})
+	r.Group(func(r chi.Router) {
+		r.Patch(options.BaseURL+"/chat/messages/{messageId}", wrapper.EditMessage)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/chat/messages/{messageId}/thread", wrapper.GetMessageThread)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/chat/metrics", wrapper.GetChatMetrics)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/chat/rooms", wrapper.ListRooms)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/chat/rooms", wrapper.CreateRoom)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/chat/unread", wrapper.GetUnreadCounts)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/chat/ws.rtm.connect", wrapper.WsRTMConnect)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/chat/ws.rtm.start", wrapper.WsRTMStart)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/hooks/{token}", wrapper.PostIncomingWebhook)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/incoming-webhooks", wrapper.ListIncomingWebhooks)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/incoming-webhooks", wrapper.CreateIncomingWebhook)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/incoming-webhooks/{webhookId}", wrapper.DeleteIncomingWebhook)
+	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/user", wrapper.CreateUser)
 	})
@@ -240,6 +1286,33 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/user/login", wrapper.LoginUser)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/user/logout", wrapper.LogoutUser)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/user/logout/all", wrapper.LogoutAllSessions)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/user/online", wrapper.GetOnlineUsers)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/user/refresh", wrapper.RefreshToken)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/user/{userName}/revoke", wrapper.RevokeUserSessions)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/webhooks", wrapper.ListWebhooks)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/webhooks", wrapper.CreateWebhook)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/webhooks/{webhookId}", wrapper.DeleteWebhook)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/webhooks/{webhookId}/deliveries", wrapper.GetWebhookDeliveries)
+	})
 
 	return r
 }
@@ -3,11 +3,112 @@
 // Code generated by unknown module path version unknown version DO NOT EDIT.
 package server
 
+import (
+	"time"
+)
+
+const (
+	TokenScopes = "token.Scopes"
+)
+
+// Defines values for OnlineUserStatus.
+const (
+	OnlineUserStatusAway OnlineUserStatus = "away"
+
+	OnlineUserStatusDnd OnlineUserStatus = "dnd"
+
+	OnlineUserStatusOnline OnlineUserStatus = "online"
+)
+
+// Defines values for WebhookDeliveryStatus.
+const (
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "delivered"
+
+	WebhookDeliveryStatusFailed WebhookDeliveryStatus = "failed"
+
+	WebhookDeliveryStatusPending WebhookDeliveryStatus = "pending"
+)
+
 // ActiveUsersResponse defines model for ActiveUsersResponse.
 type ActiveUsersResponse struct {
 	Count int `json:"count"`
 }
 
+// Attachment defines model for Attachment.
+type Attachment struct {
+	ContentType string `json:"contentType"`
+	FileName    string `json:"fileName"`
+	Id          int    `json:"id"`
+
+	// Size in bytes
+	Size int64 `json:"size"`
+
+	// Path the file is downloaded from
+	Url string `json:"url"`
+}
+
+// BotKey defines model for BotKey.
+type BotKey struct {
+	// The key itself, only returned when it is issued
+	ApiKey    *string   `json:"apiKey,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	Id        int       `json:"id"`
+}
+
+// BotKeysResponse defines model for BotKeysResponse.
+type BotKeysResponse struct {
+	Keys []BotKey `json:"keys"`
+}
+
+// ChatMetricsResponse defines model for ChatMetricsResponse.
+type ChatMetricsResponse struct {
+	// Connected clients
+	Clients int `json:"clients"`
+
+	// Frames discarded for slow consumers since start
+	DroppedFrames int `json:"droppedFrames"`
+
+	// Clients disconnected for being slow consumers since start
+	EvictedClients int `json:"evictedClients"`
+
+	// Deepest send queue of a single client
+	MaxQueueDepth int `json:"maxQueueDepth"`
+
+	// Frames waiting to be written across all clients
+	QueuedFrames int `json:"queuedFrames"`
+}
+
+// CreateBotRequest defines model for CreateBotRequest.
+type CreateBotRequest struct {
+	UserName string `json:"userName"`
+}
+
+// CreateBotResponse defines model for CreateBotResponse.
+type CreateBotResponse struct {
+	Id       string `json:"id"`
+	Key      BotKey `json:"key"`
+	UserName string `json:"userName"`
+}
+
+// CreateIncomingWebhookRequest defines model for CreateIncomingWebhookRequest.
+type CreateIncomingWebhookRequest struct {
+	// What the webhook is used for, e.g. the posting system
+	Name string `json:"name"`
+
+	// Room messages are posted to, the public chat when omitted
+	Room *string `json:"room,omitempty"`
+
+	// Bot account messages are posted as
+	UserName string `json:"userName"`
+}
+
+// CreateRoomRequest defines model for CreateRoomRequest.
+type CreateRoomRequest struct {
+	// Unique room name used to join and address the room
+	Name  string  `json:"name"`
+	Topic *string `json:"topic,omitempty"`
+}
+
 // CreateUserRequest defines model for CreateUserRequest.
 type CreateUserRequest struct {
 	Password string `json:"password"`
@@ -20,6 +121,58 @@ type CreateUserResponse struct {
 	UserName *string `json:"userName,omitempty"`
 }
 
+// CreateWebhookRequest defines model for CreateWebhookRequest.
+type CreateWebhookRequest struct {
+	// Subscribed events: message.created, message.edited, message.deleted, user.joined, user.left
+	// and user.registered
+	Events []string `json:"events"`
+
+	// Key of the delivery signatures, generated when omitted
+	Secret *string `json:"secret,omitempty"`
+
+	// http or https url receiving the events
+	Url string `json:"url"`
+}
+
+// EditMessageRequest defines model for EditMessageRequest.
+type EditMessageRequest struct {
+	Message string `json:"message"`
+}
+
+// IncomingWebhook defines model for IncomingWebhook.
+type IncomingWebhook struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	Room      *string   `json:"room,omitempty"`
+
+	// Url messages are posted to, only returned when the webhook is created
+	Url      *string `json:"url,omitempty"`
+	UserName string  `json:"userName"`
+}
+
+// IncomingWebhooksResponse defines model for IncomingWebhooksResponse.
+type IncomingWebhooksResponse struct {
+	Webhooks []IncomingWebhook `json:"webhooks"`
+}
+
+// LoginBotRequest defines model for LoginBotRequest.
+type LoginBotRequest struct {
+	// API key issued for the bot
+	ApiKey string `json:"apiKey"`
+}
+
+// LoginBotResponse defines model for LoginBotResponse.
+type LoginBotResponse struct {
+	Id string `json:"id"`
+
+	// A url for websoket API with a one-time token for starting chat
+	Url string `json:"url"`
+
+	// Name the bot posts messages as
+	UserName string `json:"userName"`
+}
+
 // LoginUserRequest defines model for LoginUserRequest.
 type LoginUserRequest struct {
 	// The password for login in clear text
@@ -31,24 +184,328 @@ type LoginUserRequest struct {
 
 // LoginUserResponse defines model for LoginUserResponse.
 type LoginUserResponse struct {
+	// Short-lived access token authenticating API requests
+	AccessToken string `json:"accessToken"`
+
+	// Expiration of the access token
+	ExpiresAt time.Time `json:"expiresAt"`
+
+	// Single use token exchanged for a new token pair at /user/refresh
+	RefreshToken string `json:"refreshToken"`
+}
+
+// LogoutUserRequest defines model for LogoutUserRequest.
+type LogoutUserRequest struct {
+	// Refresh token of the session to end
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Message defines model for Message.
+type Message struct {
+	Attachments *[]Attachment `json:"attachments,omitempty"`
+	Author      string        `json:"author"`
+
+	// Message was deleted and only its tombstone remains
+	Deleted     *bool      `json:"deleted,omitempty"`
+	EditedAt    *time.Time `json:"editedAt,omitempty"`
+	Id          int        `json:"id"`
+	LastReplyAt *time.Time `json:"lastReplyAt,omitempty"`
+	Message     string     `json:"message"`
+
+	// Root message of the thread a reply belongs to
+	ParentId *int `json:"parentId,omitempty"`
+
+	// Aggregated reactions, in order of the first reaction with each emoji
+	Reactions *[]ReactionCount `json:"reactions,omitempty"`
+
+	// Number of replies in the thread started by the message
+	ReplyCount *int      `json:"replyCount,omitempty"`
+	Room       *string   `json:"room,omitempty"`
+	SentAt     time.Time `json:"sentAt"`
+
+	// Recipient of a direct message
+	To *string `json:"to,omitempty"`
+}
+
+// MessagesResponse defines model for MessagesResponse.
+type MessagesResponse struct {
+	// More messages exist beyond this page in the requested direction
+	HasMore  bool      `json:"hasMore"`
+	Messages []Message `json:"messages"`
+}
+
+// OnlineUser defines model for OnlineUser.
+type OnlineUser struct {
+	// Seconds since the user last sent a frame on any session
+	IdleSeconds int `json:"idleSeconds"`
+
+	// Start of the oldest live session of the user
+	JoinedAt time.Time        `json:"joinedAt"`
+	Status   OnlineUserStatus `json:"status"`
+	UserName string           `json:"userName"`
+}
+
+// OnlineUserStatus defines model for OnlineUser.Status.
+type OnlineUserStatus string
+
+// OnlineUsersResponse defines model for OnlineUsersResponse.
+type OnlineUsersResponse struct {
+	Users []OnlineUser `json:"users"`
+}
+
+// PostIncomingWebhookRequest defines model for PostIncomingWebhookRequest.
+type PostIncomingWebhookRequest struct {
+	Message string `json:"message"`
+}
+
+// ReactionCount defines model for ReactionCount.
+type ReactionCount struct {
+	Count int    `json:"count"`
+	Emoji string `json:"emoji"`
+}
+
+// RefreshTokenRequest defines model for RefreshTokenRequest.
+type RefreshTokenRequest struct {
+	// Refresh token issued by /user/login or /user/refresh
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Room defines model for Room.
+type Room struct {
+	Id    int     `json:"id"`
+	Name  string  `json:"name"`
+	Topic *string `json:"topic,omitempty"`
+}
+
+// RoomsResponse defines model for RoomsResponse.
+type RoomsResponse struct {
+	Rooms []Room `json:"rooms"`
+}
+
+// ThreadResponse defines model for ThreadResponse.
+type ThreadResponse struct {
+	// More replies exist beyond this page in the requested direction
+	HasMore bool      `json:"hasMore"`
+	Replies []Message `json:"replies"`
+	Root    Message   `json:"root"`
+}
+
+// UnreadCount defines model for UnreadCount.
+type UnreadCount struct {
+	// Unread messages of other users
+	Count int `json:"count"`
+
+	// Room of the conversation
+	Room *string `json:"room,omitempty"`
+
+	// Other participant of a direct conversation
+	To *string `json:"to,omitempty"`
+}
+
+// UnreadCountsResponse defines model for UnreadCountsResponse.
+type UnreadCountsResponse struct {
+	// Conversations with unread messages, the public chat has neither room nor to
+	Conversations []UnreadCount `json:"conversations"`
+
+	// Unread messages across all conversations
+	Total int `json:"total"`
+}
+
+// Webhook defines model for Webhook.
+type Webhook struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Events    []string  `json:"events"`
+	Id        int       `json:"id"`
+
+	// Key of the delivery signatures, only returned when the webhook is registered
+	Secret *string `json:"secret,omitempty"`
+	Url    string  `json:"url"`
+}
+
+// WebhookDeliveriesResponse defines model for WebhookDeliveriesResponse.
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+// WebhookDelivery defines model for WebhookDelivery.
+type WebhookDelivery struct {
+	Attempts    int        `json:"attempts"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty"`
+	Event       string     `json:"event"`
+	Id          int        `json:"id"`
+	LastError   *string    `json:"lastError,omitempty"`
+
+	// Response status of the last attempt, missing when no response was received
+	LastStatusCode *int `json:"lastStatusCode,omitempty"`
+
+	// Time of the next attempt of pending deliveries
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+
+	// Body as posted to the webhook
+	Payload string                `json:"payload"`
+	Status  WebhookDeliveryStatus `json:"status"`
+}
+
+// WebhookDeliveryStatus defines model for WebhookDelivery.Status.
+type WebhookDeliveryStatus string
+
+// WebhooksResponse defines model for WebhooksResponse.
+type WebhooksResponse struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// WsRTMConnectResponse defines model for WsRTMConnectResponse.
+type WsRTMConnectResponse struct {
 	// A url for websoket API with a one-time token for starting chat
 	Url string `json:"url"`
 }
 
+// LoginBotJSONBody defines parameters for LoginBot.
+type LoginBotJSONBody LoginBotRequest
+
+// CreateBotJSONBody defines parameters for CreateBot.
+type CreateBotJSONBody CreateBotRequest
+
+// UploadAttachmentMultipartBody defines parameters for UploadAttachment.
+type UploadAttachmentMultipartBody struct {
+	File string `json:"file"`
+}
+
+// GetMentionsParams defines parameters for GetMentions.
+type GetMentionsParams struct {
+	// Return mentions older than the message with this id
+	Before *int `json:"before,omitempty"`
+
+	// Return mentions newer than the message with this id
+	After *int `json:"after,omitempty"`
+
+	// Maximum number of messages to return
+	Limit *int `json:"limit,omitempty"`
+}
+
+// GetMessagesParams defines parameters for GetMessages.
+type GetMessagesParams struct {
+	// Return messages older than the message with this id
+	Before *int `json:"before,omitempty"`
+
+	// Return messages newer than the message with this id
+	After *int `json:"after,omitempty"`
+
+	// Maximum number of messages to return
+	Limit *int `json:"limit,omitempty"`
+}
+
+// SearchMessagesParams defines parameters for SearchMessages.
+type SearchMessagesParams struct {
+	// Words to look for
+	Q string `json:"q"`
+
+	// Only return messages posted by the user with this name
+	Author *string `json:"author,omitempty"`
+
+	// Only return messages posted to this room, the user must be a member
+	Room *string `json:"room,omitempty"`
+
+	// Only return messages sent at or after this time
+	From *time.Time `json:"from,omitempty"`
+
+	// Only return messages sent before this time
+	To *time.Time `json:"to,omitempty"`
+
+	// Return messages older than the message with this id
+	Before *int `json:"before,omitempty"`
+
+	// Return messages newer than the message with this id
+	After *int `json:"after,omitempty"`
+
+	// Maximum number of messages to return
+	Limit *int `json:"limit,omitempty"`
+}
+
+// EditMessageJSONBody defines parameters for EditMessage.
+type EditMessageJSONBody EditMessageRequest
+
+// GetMessageThreadParams defines parameters for GetMessageThread.
+type GetMessageThreadParams struct {
+	// Return replies older than the reply with this id
+	Before *int `json:"before,omitempty"`
+
+	// Return replies newer than the reply with this id
+	After *int `json:"after,omitempty"`
+
+	// Maximum number of replies to return
+	Limit *int `json:"limit,omitempty"`
+}
+
+// CreateRoomJSONBody defines parameters for CreateRoom.
+type CreateRoomJSONBody CreateRoomRequest
+
 // WsRTMStartParams defines parameters for WsRTMStart.
 type WsRTMStartParams struct {
-	// One time token for a loged user
+	// One time token issued by /chat/ws.rtm.connect
 	Token string `json:"token"`
 }
 
+// PostIncomingWebhookJSONBody defines parameters for PostIncomingWebhook.
+type PostIncomingWebhookJSONBody PostIncomingWebhookRequest
+
+// CreateIncomingWebhookJSONBody defines parameters for CreateIncomingWebhook.
+type CreateIncomingWebhookJSONBody CreateIncomingWebhookRequest
+
 // CreateUserJSONBody defines parameters for CreateUser.
 type CreateUserJSONBody CreateUserRequest
 
 // LoginUserJSONBody defines parameters for LoginUser.
 type LoginUserJSONBody LoginUserRequest
 
+// LogoutUserJSONBody defines parameters for LogoutUser.
+type LogoutUserJSONBody LogoutUserRequest
+
+// RefreshTokenJSONBody defines parameters for RefreshToken.
+type RefreshTokenJSONBody RefreshTokenRequest
+
+// CreateWebhookJSONBody defines parameters for CreateWebhook.
+type CreateWebhookJSONBody CreateWebhookRequest
+
+// GetWebhookDeliveriesParams defines parameters for GetWebhookDeliveries.
+type GetWebhookDeliveriesParams struct {
+	// Maximum number of deliveries to return
+	Limit *int `json:"limit,omitempty"`
+}
+
+// LoginBotJSONRequestBody defines body for LoginBot for application/json ContentType.
+type LoginBotJSONRequestBody LoginBotJSONBody
+
+// CreateBotJSONRequestBody defines body for CreateBot for application/json ContentType.
+type CreateBotJSONRequestBody CreateBotJSONBody
+
+// UploadAttachmentMultipartRequestBody defines body for UploadAttachment for multipart/form-data ContentType.
+type UploadAttachmentMultipartRequestBody UploadAttachmentMultipartBody
+
+// EditMessageJSONRequestBody defines body for EditMessage for application/json ContentType.
+type EditMessageJSONRequestBody EditMessageJSONBody
+
+// CreateRoomJSONRequestBody defines body for CreateRoom for application/json ContentType.
+type CreateRoomJSONRequestBody CreateRoomJSONBody
+
+// PostIncomingWebhookJSONRequestBody defines body for PostIncomingWebhook for application/json ContentType.
+type PostIncomingWebhookJSONRequestBody PostIncomingWebhookJSONBody
+
+// CreateIncomingWebhookJSONRequestBody defines body for CreateIncomingWebhook for application/json ContentType.
+type CreateIncomingWebhookJSONRequestBody CreateIncomingWebhookJSONBody
+
 // CreateUserJSONRequestBody defines body for CreateUser for application/json ContentType.
 type CreateUserJSONRequestBody CreateUserJSONBody
 
 // LoginUserJSONRequestBody defines body for LoginUser for application/json ContentType.
 type LoginUserJSONRequestBody LoginUserJSONBody
+
+// LogoutUserJSONRequestBody defines body for LogoutUser for application/json ContentType.
+type LogoutUserJSONRequestBody LogoutUserJSONBody
+
+// RefreshTokenJSONRequestBody defines body for RefreshToken for application/json ContentType.
+type RefreshTokenJSONRequestBody RefreshTokenJSONBody
+
+// CreateWebhookJSONRequestBody defines body for CreateWebhook for application/json ContentType.
+type CreateWebhookJSONRequestBody CreateWebhookJSONBody
@@ -1,6 +1,10 @@
 package server
 
 import (
+	"context"
+	"net/http"
+	"time"
+
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
@@ -8,13 +12,32 @@ import (
 	"github.com/id-tarzanych/lets-go-chat/api/middlewares"
 	"github.com/id-tarzanych/lets-go-chat/api/wss"
 	"github.com/id-tarzanych/lets-go-chat/configurations"
+	"github.com/id-tarzanych/lets-go-chat/db/apikey"
+	"github.com/id-tarzanych/lets-go-chat/db/attachment"
+	"github.com/id-tarzanych/lets-go-chat/db/mention"
 	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/db/reaction"
+	"github.com/id-tarzanych/lets-go-chat/db/readmarker"
+	"github.com/id-tarzanych/lets-go-chat/db/room"
 	"github.com/id-tarzanych/lets-go-chat/db/token"
 	"github.com/id-tarzanych/lets-go-chat/db/user"
+	"github.com/id-tarzanych/lets-go-chat/db/webhook"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/storage"
+	"github.com/id-tarzanych/lets-go-chat/webhooks"
 )
 
+// AccessTokens issues, verifies and revokes the access tokens of users, see auth.AccessTokens.
+type AccessTokens interface {
+	middlewares.AccessTokenVerifier
+	Issue(userId types.Uuid, sessionId string, now time.Time) (string, time.Time, error)
+	Revoke(userId types.Uuid, at time.Time)
+	RevokeSession(sessionId string, at time.Time)
+}
+
 type Server struct {
-	port int
+	port               int
+	historyReplayLimit int
 
 	logger logrus.FieldLogger
 
@@ -22,14 +45,34 @@ type Server struct {
 	authMiddleware *middlewares.AuthMiddleware
 	router         *mux.Router
 
-	chatData *wss.ChatData
-	taskCh   chan WorkerTask
+	accessTokens    AccessTokens
+	refreshTokenTTL time.Duration
+
+	chatData      *wss.ChatData
+	clientOptions wss.ClientOptions
+	idleTimeout   time.Duration
+	typing        *wss.TypingTracker
+	slashCommands *SlashCommands
 
 	requestUpgrader websocket.Upgrader
 
-	userRepo    user.UserRepository
-	tokenRepo   token.TokenRepository
-	messageRepo message.MessageRepository
+	blobs         storage.Storage
+	maxUploadSize int64
+	allowedTypes  []string
+	unattachedTTL time.Duration
+
+	webhooks *webhooks.Dispatcher
+
+	userRepo       user.UserRepository
+	tokenRepo      token.TokenRepository
+	messageRepo    message.MessageRepository
+	roomRepo       room.RoomRepository
+	readMarkerRepo readmarker.ReadMarkerRepository
+	reactionRepo   reaction.ReactionRepository
+	mentionRepo    mention.MentionRepository
+	attachmentRepo attachment.AttachmentRepository
+	apiKeyRepo     apikey.APIKeyRepository
+	webhookRepo    webhook.WebhookRepository
 }
 
 func New(
@@ -37,30 +80,78 @@ func New(
 	userRepo user.UserRepository,
 	tokenRepo token.TokenRepository,
 	messageRepo message.MessageRepository,
+	roomRepo room.RoomRepository,
+	readMarkerRepo readmarker.ReadMarkerRepository,
+	reactionRepo reaction.ReactionRepository,
+	mentionRepo mention.MentionRepository,
+	attachmentRepo attachment.AttachmentRepository,
+	apiKeyRepo apikey.APIKeyRepository,
+	webhookRepo webhook.WebhookRepository,
+	accessTokens AccessTokens,
+	blobs storage.Storage,
 	logger logrus.FieldLogger,
 ) *Server {
 	s := &Server{
-		port: cfg.Server.Port,
+		port:               cfg.Server.Port,
+		historyReplayLimit: cfg.Server.HistoryReplayLimit,
 
 		logger: logger,
 
 		logMiddleware:  middlewares.NewLogMiddleware(logger),
-		authMiddleware: middlewares.NewAuthMiddleware(tokenRepo),
+		authMiddleware: middlewares.NewAuthMiddleware(accessTokens),
+
+		accessTokens:    accessTokens,
+		refreshTokenTTL: cfg.Auth.RefreshTokenTTL,
 
 		chatData: wss.NewChatData(),
-		taskCh:   make(chan WorkerTask),
+		clientOptions: wss.ClientOptions{
+			QueueSize:    cfg.Server.SendQueueSize,
+			WriteTimeout: cfg.Server.WriteTimeout,
+			Policy:       wss.SlowConsumerPolicy(cfg.Server.SlowConsumerPolicy),
+			PingInterval: cfg.Server.PingInterval,
+			PongTimeout:  cfg.Server.PongTimeout,
+		},
+		idleTimeout:   cfg.Server.IdleTimeout,
+		typing:        wss.NewTypingTracker(cfg.Server.TypingThrottle, cfg.Server.TypingTimeout),
+		slashCommands: defaultSlashCommands(),
 
 		requestUpgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
 		},
 
-		userRepo:    userRepo,
-		tokenRepo:   tokenRepo,
-		messageRepo: messageRepo,
+		blobs:         blobs,
+		maxUploadSize: cfg.Storage.MaxUploadSize,
+		allowedTypes:  cfg.Storage.AllowedTypes,
+		unattachedTTL: cfg.Storage.UnattachedTTL,
+
+		webhooks: webhooks.NewDispatcher(webhookRepo, cfg.Webhooks, logger),
+
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		messageRepo:    messageRepo,
+		roomRepo:       roomRepo,
+		readMarkerRepo: readMarkerRepo,
+		reactionRepo:   reactionRepo,
+		mentionRepo:    mentionRepo,
+		attachmentRepo: attachmentRepo,
+		apiKeyRepo:     apiKeyRepo,
+		webhookRepo:    webhookRepo,
 	}
 
-	go broadcastWorker(s.taskCh, s.logger, s.userRepo)
+	s.chatData.SetPresenceHandler(s.announcePresence)
+
+	if cfg.Server.ReapInterval > 0 {
+		go s.runReaper(cfg.Server.ReapInterval)
+	}
+
+	if cfg.Storage.SweepInterval > 0 && cfg.Storage.UnattachedTTL > 0 {
+		go s.runAttachmentSweeper(cfg.Storage.SweepInterval)
+	}
+
+	if cfg.Webhooks.PollInterval > 0 {
+		go s.webhooks.Run(context.Background())
+	}
 
 	return s
 }
@@ -69,20 +160,48 @@ func (s Server) Port() int {
 	return s.port
 }
 
-func broadcastWorker(taskCh <-chan WorkerTask, logger logrus.FieldLogger, userRepo user.UserRepository) {
-	var err error
+// SlashCommands is the registry of chat commands, additional commands can be registered before serving.
+func (s Server) SlashCommands() *SlashCommands {
+	return s.slashCommands
+}
+
+// Router routes requests to the server, authenticating operations secured by an access token.
+func (s *Server) Router() http.Handler {
+	return HandlerWithOptions(s, ChiServerOptions{Middlewares: []MiddlewareFunc{s.authenticate}})
+}
+
+func (s Server) authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, secured := r.Context().Value(TokenScopes).([]string); !secured {
+			next(w, r)
+			return
+		}
+
+		s.authMiddleware.ValidateToken(next).ServeHTTP(w, r)
+	}
+}
+
+func (s Server) runReaper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-	for {
-		task := <-taskCh
+	for now := range ticker.C {
+		s.reapStaleClients(now)
+	}
+}
 
-		if err = task.Client.SendMessage(task.Message); err != nil {
-			logger.Errorln(err)
-			break
+// reapStaleClients disconnects clients with dead or idle connections and removes them from the chat.
+func (s Server) reapStaleClients(now time.Time) {
+	for _, client := range s.chatData.StaleClients(now, s.clientOptions.PongTimeout, s.idleTimeout) {
+		reason := "idle timeout"
+		if client.Stopped() || (s.clientOptions.PongTimeout > 0 && now.Sub(client.LastSeen()) > s.clientOptions.PongTimeout) {
+			reason = "connection timed out"
 		}
 
-		if err = userRepo.UpdateLastActivity(task.Context, task.Client.User, task.Message.CreatedAt); err != nil {
-			logger.Errorln(err)
-			break
+		if err := client.Close(websocket.CloseGoingAway, reason); err != nil {
+			s.logger.Println("Could not close stale client: ", err)
 		}
+
+		s.chatData.DeleteClient(client)
 	}
 }
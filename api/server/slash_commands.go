@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/id-tarzanych/lets-go-chat/api/wss"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+// SlashCommand is run when a chat message starts with a slash and the command name, e.g. "/who".
+// Commands reply to the invoking client only, unless they post a message themselves.
+type SlashCommand struct {
+	// Name is matched case-insensitively and without the leading slash.
+	Name string
+	// Usage describes the arguments, e.g. "<user> [minutes]".
+	Usage       string
+	Description string
+
+	// MinArgs and MaxArgs bound the number of arguments, negative MaxArgs allows any number.
+	MinArgs int
+	MaxArgs int
+
+	// Authorize rejects invocations the client is not permitted to run, nil permits everyone.
+	Authorize func(s Server, ctx context.Context, call *SlashCall) error
+	// Run executes the command. It must answer the call exactly once, with Reply or with an ack of its own.
+	Run func(s Server, ctx context.Context, call *SlashCall) error
+}
+
+// SlashCall is an invocation of a slash command.
+type SlashCall struct {
+	Client    *wss.Client
+	RequestId string
+	// Payload is the send_message command that carried the invocation.
+	Payload wss.SendMessagePayload
+	// Room is the room the command was sent to, nil outside of rooms.
+	Room *models.Room
+
+	Name string
+	// Args are the arguments split at spaces, double quotes keep spaces within an argument.
+	Args []string
+	// Text is everything after the command name, for commands taking free text.
+	Text string
+}
+
+// Reply acknowledges the invocation with a system message visible to the invoking client only.
+func (c *SlashCall) Reply(format string, args ...interface{}) error {
+	return c.Client.SendAck(c.RequestId, wss.SystemPayload{Message: fmt.Sprintf(format, args...)})
+}
+
+// SlashCommands is the registry of slash commands available in the chat.
+type SlashCommands struct {
+	mu       sync.RWMutex
+	commands map[string]SlashCommand
+}
+
+func NewSlashCommands() *SlashCommands {
+	return &SlashCommands{commands: make(map[string]SlashCommand)}
+}
+
+// Register adds the command to the registry, names can not be registered twice.
+func (r *SlashCommands) Register(command SlashCommand) error {
+	name := strings.ToLower(strings.TrimPrefix(command.Name, "/"))
+	if name == "" || strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("invalid slash command name %q", command.Name)
+	}
+
+	if command.Run == nil {
+		return fmt.Errorf("slash command /%s has nothing to run", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.commands[name]; ok {
+		return fmt.Errorf("slash command /%s is already registered", name)
+	}
+
+	command.Name = name
+	r.commands[name] = command
+
+	return nil
+}
+
+func (r *SlashCommands) Lookup(name string) (SlashCommand, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	command, ok := r.commands[strings.ToLower(name)]
+
+	return command, ok
+}
+
+// List returns registered commands ordered by name.
+func (r *SlashCommands) List() []SlashCommand {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	commands := make([]SlashCommand, 0, len(r.commands))
+	for _, command := range r.commands {
+		commands = append(commands, command)
+	}
+
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+
+	return commands
+}
+
+var errUnterminatedQuote = errors.New("unterminated quote")
+
+// parseSlashCommand splits "/name args" into the command name, its arguments and the raw argument text.
+func parseSlashCommand(text string) (name string, args []string, rest string, err error) {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "/")
+
+	name = text
+	if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+		name, rest = text[:i], strings.TrimSpace(text[i:])
+	}
+
+	args, err = splitArgs(rest)
+
+	return name, args, rest, err
+}
+
+// splitArgs splits text at spaces, except for spaces within double quotes.
+func splitArgs(text string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+
+	quoted, started := false, false
+
+	for _, r := range text {
+		switch {
+		case r == '"':
+			quoted, started = !quoted, true
+		case unicode.IsSpace(r) && !quoted:
+			if started {
+				args = append(args, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+
+	if quoted {
+		return nil, errUnterminatedQuote
+	}
+
+	if started {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// slashCommand runs the slash command the message consists of.
+func (s Server) slashCommand(ctx context.Context, client *wss.Client, requestId string, payload wss.SendMessagePayload) error {
+	name, args, rest, err := parseSlashCommand(payload.Message)
+	if err != nil {
+		return wss.NewError(wss.ErrorInvalidPayload, "invalid arguments: %s", err)
+	}
+
+	command, ok := s.slashCommands.Lookup(name)
+	if !ok {
+		return wss.NewError(wss.ErrorNotFound, "command /%s does not exist, see /help", name)
+	}
+
+	if len(args) < command.MinArgs || (command.MaxArgs >= 0 && len(args) > command.MaxArgs) {
+		return wss.NewError(wss.ErrorInvalidPayload, "usage: /%s %s", command.Name, command.Usage)
+	}
+
+	call := &SlashCall{Client: client, RequestId: requestId, Payload: payload, Name: command.Name, Args: args, Text: rest}
+
+	if payload.Room != "" {
+		room, err := s.memberRoom(ctx, client, payload.Room)
+		if err != nil {
+			return err
+		}
+
+		call.Room = &room
+	}
+
+	if command.Authorize != nil {
+		if err := command.Authorize(s, ctx, call); err != nil {
+			return err
+		}
+	}
+
+	return command.Run(s, ctx, call)
+}
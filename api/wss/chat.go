@@ -1,21 +1,67 @@
 package wss
 
-import "sync"
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
 
 type ChatData struct {
 	Clients      map[*Client]bool
 	ClientTokens map[string]*Client
+	Rooms        map[uint]map[*Client]bool
+
+	// Delivery counters of clients that already left.
+	dropped uint64
+	evicted uint64
+
+	statuses        map[types.Uuid]string
+	presenceHandler PresenceHandler
 
 	mu sync.Mutex
 }
 
+// PresenceHandler is notified with EventUserJoined when the first session of a user is stored
+// and with EventUserLeft when the last one is deleted.
+type PresenceHandler func(event string, user *models.User)
+
+// Presence describes an online user, aggregated over all of its sessions.
+type Presence struct {
+	User       *models.User
+	JoinedAt   time.Time
+	LastActive time.Time
+	Status     string
+}
+
+// QueueMetrics summarizes delivery to connected clients.
+// Dropped frames and evicted clients are counted since the chat started.
+type QueueMetrics struct {
+	Clients        int
+	QueuedFrames   int
+	MaxQueueDepth  int
+	DroppedFrames  uint64
+	EvictedClients uint64
+}
+
 func NewChatData() *ChatData {
 	return &ChatData{
 		Clients:      make(map[*Client]bool),
 		ClientTokens: make(map[string]*Client),
+		Rooms:        make(map[uint]map[*Client]bool),
+		statuses:     make(map[types.Uuid]string),
 	}
 }
 
+func (c *ChatData) SetPresenceHandler(handler PresenceHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.presenceHandler = handler
+}
+
 func (c *ChatData) ClientExists(client *Client) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -31,25 +77,73 @@ func (c *ChatData) ClientExists(client *Client) bool {
 
 func (c *ChatData) StoreClient(client *Client) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
 
+	joined := client.User != nil && !c.Clients[client] && c.countUserClients(client.User.ID) == 0
 	c.Clients[client] = true
+
+	handler := c.presenceHandler
+	c.mu.Unlock()
+
+	if joined && handler != nil {
+		user := client.UserCopy()
+		handler(EventUserJoined, &user)
+	}
 }
 
 func (c *ChatData) DeleteClient(client *Client) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+
+	_, stored := c.Clients[client]
+	c.deleteClient(client)
+
+	left := stored && client.User != nil && c.countUserClients(client.User.ID) == 0
+	if left {
+		delete(c.statuses, client.User.ID)
+	}
+
+	handler := c.presenceHandler
+	c.mu.Unlock()
+
+	if left && handler != nil {
+		user := client.UserCopy()
+		handler(EventUserLeft, &user)
+	}
+}
+
+func (c *ChatData) deleteClient(client *Client) {
+	if _, ok := c.Clients[client]; ok {
+		c.dropped += client.Dropped()
+		if client.Evicted() {
+			c.evicted++
+		}
+	}
 
 	delete(c.Clients, client)
+
+	for roomId, members := range c.Rooms {
+		delete(members, client)
+
+		if len(members) == 0 {
+			delete(c.Rooms, roomId)
+		}
+	}
 }
 
-func (c *ChatData) GetAllClients() []Client {
+// ClientCount returns the number of live sessions.
+func (c *ChatData) ClientCount() int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	clients := make([]Client, 0)
+	return len(c.Clients)
+}
+
+func (c *ChatData) GetAllClients() []*Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clients := make([]*Client, 0)
 	for client := range c.Clients {
-		clients = append(clients, *client)
+		clients = append(clients, client)
 	}
 
 	return clients
@@ -79,4 +173,240 @@ func (c *ChatData) DeleteToken(token string) {
 	defer c.mu.Unlock()
 
 	delete(c.ClientTokens, token)
-}
\ No newline at end of file
+}
+
+// DeleteSessionTokens forgets the entry tokens of every client the user joined with in the session,
+// including disconnected ones, so none of them can be resumed.
+func (c *ChatData) DeleteSessionTokens(userId types.Uuid, sessionId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for token, client := range c.ClientTokens {
+		if client.User != nil && client.User.ID == userId && client.SessionId == sessionId {
+			delete(c.ClientTokens, token)
+		}
+	}
+}
+
+// DeleteUserTokens forgets the entry tokens of every session of the user, including disconnected ones,
+// so none of them can be resumed.
+func (c *ChatData) DeleteUserTokens(userId types.Uuid) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for token, client := range c.ClientTokens {
+		if client.User != nil && client.User.ID == userId {
+			delete(c.ClientTokens, token)
+		}
+	}
+}
+
+func (c *ChatData) JoinRoom(roomId uint, client *Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	members, ok := c.Rooms[roomId]
+	if !ok {
+		members = make(map[*Client]bool)
+		c.Rooms[roomId] = members
+	}
+
+	members[client] = true
+}
+
+func (c *ChatData) LeaveRoom(roomId uint, client *Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	members, ok := c.Rooms[roomId]
+	if !ok {
+		return
+	}
+
+	delete(members, client)
+
+	if len(members) == 0 {
+		delete(c.Rooms, roomId)
+	}
+}
+
+func (c *ChatData) InRoom(roomId uint, client *Client) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.Rooms[roomId][client]
+}
+
+// GetRecipients returns live clients a message posted to the given room should be delivered to.
+// A nil room addresses every connected client.
+func (c *ChatData) GetRecipients(roomId *uint) []*Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	members := c.Clients
+	if roomId != nil {
+		members = c.Rooms[*roomId]
+	}
+
+	clients := make([]*Client, 0, len(members))
+	for client := range members {
+		clients = append(clients, client)
+	}
+
+	return clients
+}
+
+// RenameUser changes the name of the user in every session of it, including disconnected ones.
+func (c *ChatData) RenameUser(userId types.Uuid, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	renamed := make(map[*Client]bool)
+	for client := range c.Clients {
+		renamed[client] = true
+	}
+
+	for _, client := range c.ClientTokens {
+		renamed[client] = true
+	}
+
+	for client := range renamed {
+		if client.User != nil && client.User.ID == userId {
+			client.Rename(name)
+		}
+	}
+}
+
+// GetUserClients returns every live session of the given user.
+func (c *ChatData) GetUserClients(userId types.Uuid) []*Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clients := make([]*Client, 0)
+	for client := range c.Clients {
+		if client.User != nil && client.User.ID == userId {
+			clients = append(clients, client)
+		}
+	}
+
+	return clients
+}
+
+func (c *ChatData) QueueMetrics() QueueMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	metrics := QueueMetrics{Clients: len(c.Clients), DroppedFrames: c.dropped, EvictedClients: c.evicted}
+	for client := range c.Clients {
+		depth := client.QueueDepth()
+
+		metrics.QueuedFrames += depth
+		if depth > metrics.MaxQueueDepth {
+			metrics.MaxQueueDepth = depth
+		}
+
+		metrics.DroppedFrames += client.Dropped()
+		if client.Evicted() {
+			metrics.EvictedClients++
+		}
+	}
+
+	return metrics
+}
+
+// StaleClients returns clients whose connection stopped, missed its pongs for longer than
+// pongTimeout or did not send a frame for longer than idleTimeout. Zero timeouts are not checked.
+func (c *ChatData) StaleClients(now time.Time, pongTimeout, idleTimeout time.Duration) []*Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clients := make([]*Client, 0)
+	for client := range c.Clients {
+		switch {
+		case client.Stopped():
+		case pongTimeout > 0 && now.Sub(client.LastSeen()) > pongTimeout:
+		case idleTimeout > 0 && now.Sub(client.LastActive()) > idleTimeout:
+		default:
+			continue
+		}
+
+		clients = append(clients, client)
+	}
+
+	return clients
+}
+
+// SetStatus changes the presence status shared by every session of the user.
+func (c *ChatData) SetStatus(userId types.Uuid, status string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.statuses[userId] = status
+}
+
+// Status returns presence status of the user, users without a chosen status are online.
+func (c *ChatData) Status(userId types.Uuid) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.status(userId)
+}
+
+// OnlineUsers returns every user with at least one live session.
+// JoinedAt is the start of the oldest session, LastActive the latest frame of any session.
+func (c *ChatData) OnlineUsers() []Presence {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	users := make(map[types.Uuid]*Presence)
+	for client := range c.Clients {
+		if client.User == nil {
+			continue
+		}
+
+		presence, ok := users[client.User.ID]
+		if !ok {
+			user := client.UserCopy()
+			presence = &Presence{User: &user, JoinedAt: client.JoinedAt, Status: c.status(client.User.ID)}
+			users[client.User.ID] = presence
+		}
+
+		if client.JoinedAt.Before(presence.JoinedAt) {
+			presence.JoinedAt = client.JoinedAt
+		}
+
+		if lastActive := client.LastActive(); lastActive.After(presence.LastActive) {
+			presence.LastActive = lastActive
+		}
+	}
+
+	online := make([]Presence, 0, len(users))
+	for _, presence := range users {
+		online = append(online, *presence)
+	}
+
+	sort.Slice(online, func(i, j int) bool {
+		return online[i].User.UserName < online[j].User.UserName
+	})
+
+	return online
+}
+
+func (c *ChatData) status(userId types.Uuid) string {
+	if status, ok := c.statuses[userId]; ok {
+		return status
+	}
+
+	return StatusOnline
+}
+
+func (c *ChatData) countUserClients(userId types.Uuid) int {
+	count := 0
+	for client := range c.Clients {
+		if client.User != nil && client.User.ID == userId {
+			count++
+		}
+	}
+
+	return count
+}
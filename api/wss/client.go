@@ -3,6 +3,8 @@ package wss
 import (
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -10,6 +12,38 @@ import (
 	"github.com/id-tarzanych/lets-go-chat/models"
 )
 
+// SlowConsumerPolicy decides what happens to a client whose send queue is full.
+type SlowConsumerPolicy string
+
+const (
+	// PolicyDropOldest discards the oldest queued frame to make room for the new one.
+	PolicyDropOldest SlowConsumerPolicy = "drop_oldest"
+	// PolicyDisconnect closes the connection of the client.
+	PolicyDisconnect SlowConsumerPolicy = "disconnect"
+)
+
+const (
+	DefaultQueueSize    = 256
+	DefaultWriteTimeout = 10 * time.Second
+	DefaultPingInterval = 30 * time.Second
+	DefaultPongTimeout  = 60 * time.Second
+)
+
+var (
+	ErrClientStopped = errors.New("client is stopped")
+	ErrSlowConsumer  = errors.New("client send queue is full")
+)
+
+// ClientOptions tune delivery of frames to a client.
+// PongTimeout is the read deadline, it has to be longer than PingInterval.
+type ClientOptions struct {
+	QueueSize    int
+	WriteTimeout time.Duration
+	Policy       SlowConsumerPolicy
+	PingInterval time.Duration
+	PongTimeout  time.Duration
+}
+
 type Client struct {
 	ctx       context.Context
 	cancelCtx context.CancelFunc
@@ -17,63 +51,270 @@ type Client struct {
 	JoinedAt time.Time    `json:"joinedAt,omitempty"`
 	User     *models.User `json:"-"`
 
+	// userMu guards the name of User, the user may rename itself while other goroutines read it.
+	userMu sync.RWMutex
+
 	EntryToken string          `json:"-"`
 	IPAddress  string          `json:"-"`
 	WebSocket  *websocket.Conn `json:"-"`
 
-	messageCh chan *models.Message
+	// SessionId is the login the client joined with, empty for bots.
+	SessionId string `json:"-"`
+
+	options ClientOptions
+
+	// queueMu serializes producers so drop oldest can make room without racing other senders.
+	queueMu sync.Mutex
+	queue   chan frame
+
+	dropped uint64
+	evicted uint32
+
+	// Unix nanoseconds of the last frame or pong and of the last frame received from the client.
+	lastSeen   int64
+	lastActive int64
 }
 
-func NewClientObject(joinedAt time.Time, user *models.User, entryToken string, webSocket *websocket.Conn) *Client {
+type frame struct {
+	env       Envelope
+	onWritten func()
+}
+
+func NewClientObject(joinedAt time.Time, user *models.User, entryToken string, webSocket *websocket.Conn, options ClientOptions) *Client {
 	client := &Client{JoinedAt: joinedAt, User: user, EntryToken: entryToken, WebSocket: webSocket}
 
 	client.ctx, client.cancelCtx = context.WithCancel(context.Background())
 
+	if options.QueueSize <= 0 {
+		options.QueueSize = DefaultQueueSize
+	}
+
+	if options.WriteTimeout <= 0 {
+		options.WriteTimeout = DefaultWriteTimeout
+	}
+
+	if options.Policy == "" {
+		options.Policy = PolicyDisconnect
+	}
+
+	if options.PingInterval <= 0 {
+		options.PingInterval = DefaultPingInterval
+	}
+
+	if options.PongTimeout <= 0 {
+		options.PongTimeout = DefaultPongTimeout
+	}
+
+	if options.PongTimeout <= options.PingInterval {
+		options.PongTimeout = 2 * options.PingInterval
+	}
+
+	client.options = options
 	client.IPAddress = webSocket.RemoteAddr().String()
-	client.messageCh = make(chan *models.Message)
+	client.queue = make(chan frame, options.QueueSize)
+
+	client.Touch()
+	webSocket.SetPongHandler(func(string) error {
+		return client.seen(time.Now())
+	})
 
 	client.processIncomingMessages()
 
 	return client
 }
 
-type ClientRequest struct {
-	Message string `json:"message"`
+// Send queues a frame for delivery to the client without waiting for the socket.
+func (c *Client) Send(env Envelope) error {
+	return c.enqueue(frame{env: env})
+}
 
-	EntryToken string          `json:"-"`
-	WebSocket  *websocket.Conn `json:"-"`
+// SendEvent wraps payload into a frame of the given type and queues it for delivery.
+func (c *Client) SendEvent(eventType, id string, payload interface{}) error {
+	env, err := NewEnvelope(eventType, id, payload)
+	if err != nil {
+		return err
+	}
+
+	return c.Send(env)
+}
+
+// SendEventFunc queues an event like SendEvent and calls onWritten from the writer
+// goroutine once the frame reached the socket.
+func (c *Client) SendEventFunc(eventType, id string, payload interface{}, onWritten func()) error {
+	env, err := NewEnvelope(eventType, id, payload)
+	if err != nil {
+		return err
+	}
+
+	return c.enqueue(frame{env: env, onWritten: onWritten})
 }
 
 func (c *Client) SendMessage(message *models.Message) error {
-	var err error
+	return c.SendEvent(EventMessage, "", NewMessagePayload(message))
+}
 
-	defer func() {
-		if r := recover(); r != nil {
-			err = errors.New("channel is closed")
-		}
-	}()
+// SendAck confirms the command with the given id was processed.
+func (c *Client) SendAck(id string, payload interface{}) error {
+	return c.SendEvent(EventAck, id, payload)
+}
 
-	c.messageCh <- message
+// SendError reports a failed command with the given id back to the client.
+func (c *Client) SendError(id string, e *Error) error {
+	return c.SendEvent(EventError, id, e)
+}
 
-	return err
+// UserName returns the current name of the client's user.
+func (c *Client) UserName() string {
+	c.userMu.RLock()
+	defer c.userMu.RUnlock()
+
+	return c.User.UserName
+}
+
+// UserCopy returns a copy of the client's user that is safe to keep, e.g. as the author of a message.
+func (c *Client) UserCopy() models.User {
+	c.userMu.RLock()
+	defer c.userMu.RUnlock()
+
+	return *c.User
+}
+
+// Rename changes the name of the client's user.
+func (c *Client) Rename(name string) {
+	c.userMu.Lock()
+	defer c.userMu.Unlock()
+
+	c.User.UserName = name
+}
+
+// QueueDepth returns the number of frames waiting to be written to the socket.
+func (c *Client) QueueDepth() int {
+	return len(c.queue)
+}
+
+// Dropped returns the number of frames discarded because the client could not keep up.
+func (c *Client) Dropped() uint64 {
+	return atomic.LoadUint64(&c.dropped)
+}
+
+// Evicted tells whether the client was disconnected for being a slow consumer.
+func (c *Client) Evicted() bool {
+	return atomic.LoadUint32(&c.evicted) == 1
+}
+
+// Touch records a frame received from the client and extends the read deadline of its socket.
+// It must be called from the goroutine reading the socket.
+func (c *Client) Touch() error {
+	now := time.Now()
+	atomic.StoreInt64(&c.lastActive, now.UnixNano())
+
+	return c.seen(now)
+}
+
+// LastSeen returns when the client last proved its connection alive with a frame or a pong.
+func (c *Client) LastSeen() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&c.lastSeen))
+}
+
+// LastActive returns when the client last sent a frame.
+func (c *Client) LastActive() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&c.lastActive))
+}
+
+// Stopped tells whether the client no longer delivers frames.
+func (c *Client) Stopped() bool {
+	return c.ctx.Err() != nil
 }
 
 func (c *Client) Stop() {
 	c.cancelCtx()
 }
 
+// Close sends a close frame explaining why the server ends the session and closes the socket.
+func (c *Client) Close(code int, reason string) error {
+	c.Stop()
+
+	deadline := time.Now().Add(c.options.WriteTimeout)
+	err := c.WebSocket.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+
+	if closeErr := c.WebSocket.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func (c *Client) seen(now time.Time) error {
+	atomic.StoreInt64(&c.lastSeen, now.UnixNano())
+
+	return c.WebSocket.SetReadDeadline(now.Add(c.options.PongTimeout))
+}
+
+func (c *Client) enqueue(f frame) error {
+	if c.ctx.Err() != nil {
+		return ErrClientStopped
+	}
+
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	for {
+		select {
+		case c.queue <- f:
+			return nil
+		default:
+		}
+
+		if c.options.Policy != PolicyDropOldest {
+			c.evict()
+
+			return ErrSlowConsumer
+		}
+
+		select {
+		case <-c.queue:
+			atomic.AddUint64(&c.dropped, 1)
+		default:
+		}
+	}
+}
+
+// evict disconnects a slow consumer, the read loop of its session then cleans it up.
+func (c *Client) evict() {
+	atomic.StoreUint32(&c.evicted, 1)
+
+	c.Stop()
+	c.WebSocket.Close()
+}
+
 func (c *Client) processIncomingMessages() {
 	go func() {
+		ticker := time.NewTicker(c.options.PingInterval)
+		defer ticker.Stop()
+
 		for {
 			select {
-			case message := <-c.messageCh:
-				output := struct {
-					Author  string    `json:"author"`
-					Message string    `json:"message"`
-					SentAt  time.Time `json:"sentAt"`
-				}{Author: message.Author.UserName, Message: message.Message, SentAt: message.CreatedAt}
+			case <-ticker.C:
+				deadline := time.Now().Add(c.options.WriteTimeout)
+				if err := c.WebSocket.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+					c.Stop()
+					c.WebSocket.Close()
+
+					return
+				}
 
-				c.WebSocket.WriteJSON(output)
+			case f := <-c.queue:
+				if err := c.write(f.env); err != nil {
+					// A stalled or broken socket ends the session, the read loop cleans it up.
+					c.Stop()
+					c.WebSocket.Close()
+
+					return
+				}
+
+				if f.onWritten != nil {
+					f.onWritten()
+				}
 
 			case <-c.ctx.Done():
 				return
@@ -81,3 +322,11 @@ func (c *Client) processIncomingMessages() {
 		}
 	}()
 }
+
+func (c *Client) write(env Envelope) error {
+	if err := c.WebSocket.SetWriteDeadline(time.Now().Add(c.options.WriteTimeout)); err != nil {
+		return err
+	}
+
+	return c.WebSocket.WriteJSON(env)
+}
@@ -0,0 +1,284 @@
+package wss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+// mustNewUser creates a user for tests, it panics if the password cannot be hashed.
+func mustNewUser(username, password string) *models.User {
+	u, err := models.NewUser(username, password)
+	if err != nil {
+		panic(err)
+	}
+
+	return u
+}
+
+// newStalledClient builds a client whose writer is not running, so queued frames stay queued.
+func newStalledClient(t *testing.T, options ClientOptions) *Client {
+	upgrader := websocket.Upgrader{}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+
+	client := &Client{WebSocket: ws, options: options, queue: make(chan frame, options.QueueSize)}
+	client.ctx, client.cancelCtx = context.WithCancel(context.Background())
+
+	return client
+}
+
+func TestClient_Send_DropOldest(t *testing.T) {
+	client := newStalledClient(t, ClientOptions{QueueSize: 2, WriteTimeout: time.Second, Policy: PolicyDropOldest})
+
+	for _, id := range []string{"1", "2", "3", "4"} {
+		assert.NoError(t, client.Send(Envelope{Version: ProtocolVersion, Type: EventSystem, Id: id}))
+	}
+
+	assert.Equal(t, 2, client.QueueDepth(), "queue should stay bounded")
+	assert.Equal(t, uint64(2), client.Dropped(), "oldest frames should be dropped")
+	assert.False(t, client.Evicted(), "client should stay connected")
+
+	assert.Equal(t, "3", (<-client.queue).env.Id, "newest frames should be kept")
+	assert.Equal(t, "4", (<-client.queue).env.Id, "newest frames should be kept")
+}
+
+func TestClient_Send_Disconnect(t *testing.T) {
+	client := newStalledClient(t, ClientOptions{QueueSize: 1, WriteTimeout: time.Second, Policy: PolicyDisconnect})
+
+	assert.NoError(t, client.Send(Envelope{Version: ProtocolVersion, Type: EventSystem}))
+	assert.ErrorIs(t, client.Send(Envelope{Version: ProtocolVersion, Type: EventSystem}), ErrSlowConsumer)
+	assert.True(t, client.Evicted(), "slow consumer should be evicted")
+
+	assert.ErrorIs(t, client.Send(Envelope{Version: ProtocolVersion, Type: EventSystem}), ErrClientStopped)
+
+	_, _, err := client.WebSocket.ReadMessage()
+	assert.Error(t, err, "connection of evicted client should be closed")
+}
+
+func TestClient_Writer(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	received := make(chan Envelope, 1)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		var env Envelope
+		if err := ws.ReadJSON(&env); err == nil {
+			received <- env
+		}
+	}))
+	defer s.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer ws.Close()
+
+	client := NewClientObject(time.Now(), nil, "token", ws, ClientOptions{})
+	defer client.Stop()
+
+	written := make(chan struct{})
+	assert.NoError(t, client.SendEventFunc(EventSystem, "1", SystemPayload{Message: "hello"}, func() { close(written) }))
+
+	select {
+	case env := <-received:
+		assert.Equal(t, "1", env.Id, "frame should be written to the socket")
+	case <-time.After(time.Second):
+		t.Fatal("frame was not written")
+	}
+
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("write callback was not called")
+	}
+}
+
+func TestClient_Ping(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	pinged := make(chan struct{}, 1)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		ws.SetPingHandler(func(string) error {
+			select {
+			case pinged <- struct{}{}:
+			default:
+			}
+
+			return nil
+		})
+
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer s.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer ws.Close()
+
+	client := NewClientObject(time.Now(), nil, "token", ws, ClientOptions{PingInterval: 10 * time.Millisecond})
+	defer client.Stop()
+
+	select {
+	case <-pinged:
+	case <-time.After(time.Second):
+		t.Fatal("client was not pinged")
+	}
+}
+
+func TestChatData_StaleClients(t *testing.T) {
+	now := time.Now()
+
+	live := newStalledClient(t, ClientOptions{QueueSize: 1})
+	live.lastSeen, live.lastActive = now.UnixNano(), now.UnixNano()
+
+	silent := newStalledClient(t, ClientOptions{QueueSize: 1})
+	silent.lastSeen, silent.lastActive = now.Add(-2*time.Minute).UnixNano(), now.Add(-2*time.Minute).UnixNano()
+
+	idle := newStalledClient(t, ClientOptions{QueueSize: 1})
+	idle.lastSeen, idle.lastActive = now.UnixNano(), now.Add(-time.Hour).UnixNano()
+
+	stopped := newStalledClient(t, ClientOptions{QueueSize: 1})
+	stopped.lastSeen, stopped.lastActive = now.UnixNano(), now.UnixNano()
+	stopped.Stop()
+
+	data := NewChatData()
+	for _, client := range []*Client{live, silent, idle, stopped} {
+		data.StoreClient(client)
+	}
+
+	tests := []struct {
+		name        string
+		pongTimeout time.Duration
+		idleTimeout time.Duration
+		want        []*Client
+	}{
+		{"No timeouts", 0, 0, []*Client{stopped}},
+		{"Pong timeout", time.Minute, 0, []*Client{silent, stopped}},
+		{"Idle timeout", 0, 30 * time.Minute, []*Client{idle, stopped}},
+		{"Both timeouts", time.Minute, 30 * time.Minute, []*Client{silent, idle, stopped}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.want, data.StaleClients(now, tt.pongTimeout, tt.idleTimeout))
+		})
+	}
+}
+
+func TestChatData_Presence(t *testing.T) {
+	now := time.Now()
+
+	alice := mustNewUser("alice", "12345678")
+	bob := mustNewUser("bob", "12345678")
+
+	type presenceEvent struct {
+		event string
+		user  string
+	}
+
+	var events []presenceEvent
+
+	data := NewChatData()
+	data.SetPresenceHandler(func(event string, user *models.User) {
+		events = append(events, presenceEvent{event, user.UserName})
+	})
+
+	aliceDesktop := &Client{User: alice, JoinedAt: now.Add(-time.Hour), lastActive: now.Add(-10 * time.Minute).UnixNano()}
+	alicePhone := &Client{User: alice, JoinedAt: now.Add(-time.Minute), lastActive: now.Add(-time.Minute).UnixNano()}
+	bobDesktop := &Client{User: bob, JoinedAt: now, lastActive: now.UnixNano()}
+
+	data.StoreClient(aliceDesktop)
+	data.StoreClient(alicePhone)
+	data.StoreClient(bobDesktop)
+
+	assert.Equal(t, []presenceEvent{{EventUserJoined, "alice"}, {EventUserJoined, "bob"}}, events, "only first sessions should join")
+
+	data.SetStatus(alice.ID, StatusAway)
+	assert.Equal(t, StatusOnline, data.Status(bob.ID), "users are online by default")
+
+	assert.Equal(t, []Presence{
+		{User: alice, JoinedAt: aliceDesktop.JoinedAt, LastActive: alicePhone.LastActive(), Status: StatusAway},
+		{User: bob, JoinedAt: bobDesktop.JoinedAt, LastActive: bobDesktop.LastActive(), Status: StatusOnline},
+	}, data.OnlineUsers())
+
+	events = nil
+
+	data.DeleteClient(aliceDesktop)
+	data.DeleteClient(aliceDesktop)
+	assert.Empty(t, events, "user with a live session should not leave")
+
+	data.DeleteClient(alicePhone)
+	assert.Equal(t, []presenceEvent{{EventUserLeft, "alice"}}, events, "last session should leave")
+	assert.Equal(t, StatusOnline, data.Status(alice.ID), "status should be reset once the user leaves")
+	assert.Len(t, data.OnlineUsers(), 1)
+}
+
+func TestChatData_RenameUser(t *testing.T) {
+	alice := mustNewUser("alice", "12345678")
+	desktopUser, phoneUser, resumableUser := *alice, *alice, *alice
+	bob := mustNewUser("bob", "12345678")
+
+	desktop := &Client{User: &desktopUser}
+	phone := &Client{User: &phoneUser}
+	resumable := &Client{User: &resumableUser}
+	bobDesktop := &Client{User: bob}
+
+	data := NewChatData()
+	data.StoreClient(desktop)
+	data.StoreClient(phone)
+	data.StoreClient(bobDesktop)
+	data.StoreToken("resumable", resumable)
+
+	data.RenameUser(alice.ID, "alicia")
+
+	for _, client := range []*Client{desktop, phone, resumable} {
+		assert.Equal(t, "alicia", client.UserName(), "every session of the user should be renamed")
+	}
+
+	assert.Equal(t, "bob", bobDesktop.UserName(), "sessions of other users should keep their names")
+}
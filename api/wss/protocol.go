@@ -0,0 +1,302 @@
+package wss
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+// ProtocolVersion is the envelope version spoken by the server.
+// Frames without a version are treated as the current one.
+const ProtocolVersion = 1
+
+// Server to client event types.
+const (
+	EventMessage         = "message"
+	EventMessageEdited   = "message_edited"
+	EventMessageDeleted  = "message_deleted"
+	EventPresence        = "presence"
+	EventUserJoined      = "user_joined"
+	EventUserLeft        = "user_left"
+	EventTyping          = "typing"
+	EventReadReceipt     = "read_receipt"
+	EventReactionAdded   = "reaction_added"
+	EventReactionRemoved = "reaction_removed"
+	EventMention         = "mention"
+	EventError           = "error"
+	EventAck             = "ack"
+	EventSystem          = "system"
+)
+
+// Client to server command types.
+const (
+	CommandSendMessage    = "send_message"
+	CommandEditMessage    = "edit_message"
+	CommandDeleteMessage  = "delete_message"
+	CommandJoinRoom       = "join_room"
+	CommandLeaveRoom      = "leave_room"
+	CommandSetStatus      = "set_status"
+	CommandTyping         = "typing"
+	CommandMarkRead       = "mark_read"
+	CommandAddReaction    = "add_reaction"
+	CommandRemoveReaction = "remove_reaction"
+)
+
+// Presence statuses.
+const (
+	StatusOnline       = "online"
+	StatusAway         = "away"
+	StatusDoNotDisturb = "dnd"
+	StatusOffline      = "offline"
+)
+
+// Error codes reported in error frames.
+const (
+	ErrorInvalidFrame       = "invalid_frame"
+	ErrorUnsupportedVersion = "unsupported_version"
+	ErrorUnknownType        = "unknown_type"
+	ErrorInvalidPayload     = "invalid_payload"
+	ErrorNotFound           = "not_found"
+	ErrorForbidden          = "forbidden"
+	ErrorInternal           = "internal_error"
+)
+
+// Envelope wraps every frame sent over the chat WebSocket in either direction.
+// Id is chosen by the client for commands and echoed back in the matching ack or error frame.
+type Envelope struct {
+	Version int             `json:"version"`
+	Type    string          `json:"type"`
+	Id      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// SendMessagePayload carries a new message. ClientId is an optional identifier generated
+// by the client, retries carrying the same ClientId are acknowledged without posting twice.
+// A reply names its ParentId and is posted to the conversation of the parent.
+// Attachments lists ids of files uploaded by the sender beforehand.
+type SendMessagePayload struct {
+	ClientId    string `json:"clientId,omitempty"`
+	Message     string `json:"message"`
+	Room        string `json:"room,omitempty"`
+	To          string `json:"to,omitempty"`
+	ParentId    uint   `json:"parentId,omitempty"`
+	Attachments []uint `json:"attachments,omitempty"`
+}
+
+type EditMessagePayload struct {
+	Id      uint   `json:"id"`
+	Message string `json:"message"`
+}
+
+type DeleteMessagePayload struct {
+	Id uint `json:"id"`
+}
+
+type RoomPayload struct {
+	Room string `json:"room"`
+}
+
+type MessagePayload struct {
+	Id        uint            `json:"id"`
+	ClientId  string          `json:"clientId,omitempty"`
+	Author    string          `json:"author"`
+	Room      string          `json:"room,omitempty"`
+	To        string          `json:"to,omitempty"`
+	Message   string          `json:"message"`
+	SentAt    time.Time       `json:"sentAt"`
+	EditedAt  *time.Time      `json:"editedAt,omitempty"`
+	Deleted   bool            `json:"deleted,omitempty"`
+	Reactions []ReactionCount `json:"reactions,omitempty"`
+
+	ParentId    uint       `json:"parentId,omitempty"`
+	ReplyCount  int        `json:"replyCount,omitempty"`
+	LastReplyAt *time.Time `json:"lastReplyAt,omitempty"`
+
+	Attachments []AttachmentPayload `json:"attachments,omitempty"`
+}
+
+// AttachmentPayload describes a file sent with a message, Url is the path it is downloaded from.
+type AttachmentPayload struct {
+	Id          uint   `json:"id"`
+	FileName    string `json:"fileName"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	Url         string `json:"url"`
+}
+
+type PresencePayload struct {
+	User   string `json:"user"`
+	Status string `json:"status"`
+}
+
+type StatusPayload struct {
+	Status string `json:"status"`
+}
+
+// TypingPayload starts or stops a typing indicator in a room, a direct conversation or the public chat.
+// User is only set by the server.
+type TypingPayload struct {
+	User   string `json:"user,omitempty"`
+	Room   string `json:"room,omitempty"`
+	To     string `json:"to,omitempty"`
+	Typing bool   `json:"typing"`
+}
+
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+}
+
+// ReactionPayload adds or removes a reaction of User to the message Id.
+// User and Count, the number of reactions with the emoji after the change, are only set by the server.
+type ReactionPayload struct {
+	Id    uint   `json:"id"`
+	Emoji string `json:"emoji"`
+	User  string `json:"user,omitempty"`
+	Count int    `json:"count"`
+}
+
+// MarkReadPayload marks the message and everything before it in its conversation as read.
+type MarkReadPayload struct {
+	Id uint `json:"id"`
+}
+
+// ReadReceiptPayload tells up to which message User has read a conversation.
+// To is the other participant of a direct conversation.
+type ReadReceiptPayload struct {
+	User   string    `json:"user"`
+	Id     uint      `json:"id"`
+	Room   string    `json:"room,omitempty"`
+	To     string    `json:"to,omitempty"`
+	ReadAt time.Time `json:"readAt"`
+}
+
+type SystemPayload struct {
+	Message string `json:"message"`
+}
+
+// Error is a protocol level failure reported back to the client in an error frame.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func NewError(code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewEnvelope builds a current version frame of the given type around payload.
+func NewEnvelope(eventType, id string, payload interface{}) (Envelope, error) {
+	env := Envelope{Version: ProtocolVersion, Type: eventType, Id: id}
+	if payload == nil {
+		return env, nil
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	env.Payload = raw
+
+	return env, nil
+}
+
+// DecodeEnvelope parses an incoming frame and checks it against the supported protocol version.
+func DecodeEnvelope(p []byte) (Envelope, error) {
+	var env Envelope
+
+	if err := json.Unmarshal(p, &env); err != nil {
+		return Envelope{}, NewError(ErrorInvalidFrame, "frame is not a valid envelope: %s", err)
+	}
+
+	if env.Version == 0 {
+		env.Version = ProtocolVersion
+	}
+
+	if env.Version != ProtocolVersion {
+		return env, NewError(ErrorUnsupportedVersion, "protocol version %d is not supported", env.Version)
+	}
+
+	if env.Type == "" {
+		return env, NewError(ErrorInvalidFrame, "frame type is required")
+	}
+
+	return env, nil
+}
+
+// DecodePayload unmarshals the envelope payload into v.
+func (e Envelope) DecodePayload(v interface{}) error {
+	if len(e.Payload) == 0 {
+		return NewError(ErrorInvalidPayload, "%s payload is required", e.Type)
+	}
+
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return NewError(ErrorInvalidPayload, "invalid %s payload: %s", e.Type, err)
+	}
+
+	return nil
+}
+
+func NewMessagePayload(message *models.Message) MessagePayload {
+	payload := MessagePayload{
+		Id:       message.ID,
+		Author:   message.Author.UserName,
+		Message:  message.Message,
+		SentAt:   message.CreatedAt,
+		EditedAt: message.EditedAt,
+		Deleted:  message.DeletedAt.Valid,
+	}
+
+	if message.Room != nil {
+		payload.Room = message.Room.Name
+	}
+
+	if message.Recipient != nil {
+		payload.To = message.Recipient.UserName
+	}
+
+	if message.ClientId != nil {
+		payload.ClientId = *message.ClientId
+	}
+
+	if message.ParentID != nil {
+		payload.ParentId = *message.ParentID
+	}
+
+	if message.ReplyCount > 0 {
+		payload.ReplyCount = message.ReplyCount
+		payload.LastReplyAt = message.LastReplyAt
+	}
+
+	for _, attachment := range message.Attachments {
+		payload.Attachments = append(payload.Attachments, NewAttachmentPayload(&attachment))
+	}
+
+	for _, reaction := range message.Reactions {
+		payload.Reactions = append(payload.Reactions, ReactionCount{Emoji: reaction.Emoji, Count: reaction.Count})
+	}
+
+	return payload
+}
+
+func NewAttachmentPayload(attachment *models.Attachment) AttachmentPayload {
+	return AttachmentPayload{
+		Id:          attachment.ID,
+		FileName:    attachment.FileName,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		Url:         AttachmentURL(attachment.ID),
+	}
+}
+
+// AttachmentURL is the path of the REST API the attachment is downloaded from.
+func AttachmentURL(id uint) string {
+	return fmt.Sprintf("/chat/attachments/%d", id)
+}
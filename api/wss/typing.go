@@ -0,0 +1,127 @@
+package wss
+
+import (
+	"sync"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+)
+
+const (
+	DefaultTypingThrottle = 3 * time.Second
+	DefaultTypingTimeout  = 6 * time.Second
+)
+
+// TypingKey identifies a user typing in a conversation: a room, a direct conversation or the public chat.
+type TypingKey struct {
+	UserId types.Uuid
+	RoomId uint
+	To     types.Uuid
+}
+
+// TypingTracker keeps typing indicators in memory only.
+// Repeated start notifications are relayed at most once per throttle interval and
+// an indicator expires on its own when no notification arrived within the timeout.
+type TypingTracker struct {
+	throttle time.Duration
+	timeout  time.Duration
+
+	entries map[TypingKey]*typingEntry
+
+	mu sync.Mutex
+}
+
+type typingEntry struct {
+	relayedAt time.Time
+	timer     *time.Timer
+	// generation tells a pending expiry apart from the timer that replaced it.
+	generation uint64
+}
+
+func NewTypingTracker(throttle, timeout time.Duration) *TypingTracker {
+	if throttle <= 0 {
+		throttle = DefaultTypingThrottle
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultTypingTimeout
+	}
+
+	return &TypingTracker{
+		throttle: throttle,
+		timeout:  timeout,
+		entries:  make(map[TypingKey]*typingEntry),
+	}
+}
+
+// Start records that the user is typing and tells whether participants should be notified.
+// onExpire is called from its own goroutine if neither Start nor Stop follow within the timeout.
+func (t *TypingTracker) Start(key TypingKey, now time.Time, onExpire func()) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[key]
+	if ok {
+		entry.timer.Stop()
+	} else {
+		entry = &typingEntry{}
+		t.entries[key] = entry
+	}
+
+	entry.generation++
+	generation := entry.generation
+
+	entry.timer = time.AfterFunc(t.timeout, func() {
+		if t.expire(key, entry, generation) {
+			onExpire()
+		}
+	})
+
+	if ok && now.Sub(entry.relayedAt) < t.throttle {
+		return false
+	}
+
+	entry.relayedAt = now
+
+	return true
+}
+
+// Stop clears the indicator and tells whether participants saw it and should be notified.
+func (t *TypingTracker) Stop(key TypingKey) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[key]
+	if !ok {
+		return false
+	}
+
+	entry.timer.Stop()
+	delete(t.entries, key)
+
+	return true
+}
+
+// Typing tells whether the user currently has an indicator in the conversation.
+func (t *TypingTracker) Typing(key TypingKey) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.entries[key]
+
+	return ok
+}
+
+// expire removes the entry unless it was stopped or restarted since its timer fired.
+func (t *TypingTracker) expire(key TypingKey, entry *typingEntry, generation uint64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.entries[key] != entry || entry.generation != generation {
+		return false
+	}
+
+	delete(t.entries, key)
+
+	return true
+}
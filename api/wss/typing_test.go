@@ -0,0 +1,53 @@
+package wss
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+)
+
+func TestTypingTracker_Throttle(t *testing.T) {
+	tracker := NewTypingTracker(3*time.Second, time.Hour)
+	key := TypingKey{UserId: types.Uuid("alice"), RoomId: 1}
+
+	now := time.Now()
+	noop := func() {}
+
+	assert.True(t, tracker.Start(key, now, noop), "first notification should be relayed")
+	assert.False(t, tracker.Start(key, now.Add(time.Second), noop), "notification within throttle interval should be suppressed")
+	assert.True(t, tracker.Start(key, now.Add(3*time.Second), noop), "notification after throttle interval should be relayed")
+	assert.True(t, tracker.Start(TypingKey{UserId: types.Uuid("alice")}, now, noop), "conversations should be throttled separately")
+
+	assert.True(t, tracker.Stop(key), "visible indicator should be stopped")
+	assert.False(t, tracker.Stop(key), "stopped indicator should not be stopped twice")
+	assert.True(t, tracker.Start(key, now.Add(4*time.Second), noop), "indicator should restart after stop")
+}
+
+func TestTypingTracker_Expiry(t *testing.T) {
+	tracker := NewTypingTracker(time.Hour, 50*time.Millisecond)
+	key := TypingKey{UserId: types.Uuid("alice")}
+
+	expired := make(chan struct{}, 2)
+	tracker.Start(key, time.Now(), func() { expired <- struct{}{} })
+
+	select {
+	case <-expired:
+	case <-time.After(time.Second):
+		t.Fatal("indicator should expire")
+	}
+
+	assert.False(t, tracker.Typing(key), "expired indicator should be removed")
+	assert.False(t, tracker.Stop(key), "expired indicator should not be stopped")
+
+	tracker.Start(key, time.Now(), func() { expired <- struct{}{} })
+	tracker.Stop(key)
+
+	select {
+	case <-expired:
+		t.Fatal("stopped indicator should not expire")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -1,28 +1,48 @@
 package app
 
 import (
+	"context"
 	"os"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/id-tarzanych/lets-go-chat/db/apikey"
+	"github.com/id-tarzanych/lets-go-chat/db/attachment"
+	"github.com/id-tarzanych/lets-go-chat/db/mention"
 	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/db/reaction"
+	"github.com/id-tarzanych/lets-go-chat/db/readmarker"
+	"github.com/id-tarzanych/lets-go-chat/db/room"
 	"github.com/id-tarzanych/lets-go-chat/db/token"
 
 	"gorm.io/gorm"
 
+	"github.com/id-tarzanych/lets-go-chat/auth"
 	"github.com/id-tarzanych/lets-go-chat/configurations"
 	"github.com/id-tarzanych/lets-go-chat/db"
 	"github.com/id-tarzanych/lets-go-chat/db/user"
+	"github.com/id-tarzanych/lets-go-chat/db/webhook"
+	"github.com/id-tarzanych/lets-go-chat/storage"
 )
 
 type Application struct {
 	config *configurations.Configuration
 	db     *gorm.DB
+	blobs  storage.Storage
 	logger logrus.FieldLogger
 
-	userRepo    user.UserRepository
-	tokenRepo   token.TokenRepository
-	messageRepo message.MessageRepository
+	accessTokens *auth.AccessTokens
+
+	userRepo       user.UserRepository
+	tokenRepo      token.TokenRepository
+	messageRepo    message.MessageRepository
+	roomRepo       room.RoomRepository
+	readMarkerRepo readmarker.ReadMarkerRepository
+	reactionRepo   reaction.ReactionRepository
+	mentionRepo    mention.MentionRepository
+	attachmentRepo attachment.AttachmentRepository
+	apiKeyRepo     apikey.APIKeyRepository
+	webhookRepo    webhook.WebhookRepository
 }
 
 func New(cfg *configurations.Configuration) (*Application, error) {
@@ -35,12 +55,26 @@ func New(cfg *configurations.Configuration) (*Application, error) {
 		logger.Fatal(err)
 	}
 
+	blobs, err := initStorage(cfg, logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	accessTokens, err := auth.NewAccessTokens(cfg.Auth)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
 	userRepo, err := user.NewDatabaseUserRepository(dbPool)
 	if err != nil {
 		logger.Fatal(err)
 	}
 
-	tokenRepo, err := token.NewDatabaseTokenRepository(dbPool)
+	if err := provisionAdmins(cfg, userRepo, logger); err != nil {
+		logger.Fatal(err)
+	}
+
+	tokenRepo, err := token.NewDatabaseTokenRepository(dbPool, cfg.Auth.TokenSecret)
 	if err != nil {
 		logger.Fatal(err)
 	}
@@ -50,14 +84,61 @@ func New(cfg *configurations.Configuration) (*Application, error) {
 		logger.Fatal(err)
 	}
 
+	logger.Println("Using full-text search module: ", messageRepo.SearchModule())
+
+	roomRepo, err := room.NewDatabaseRoomRepository(dbPool)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	readMarkerRepo, err := readmarker.NewDatabaseReadMarkerRepository(dbPool)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	reactionRepo, err := reaction.NewDatabaseReactionRepository(dbPool)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	mentionRepo, err := mention.NewDatabaseMentionRepository(dbPool)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	attachmentRepo, err := attachment.NewDatabaseAttachmentRepository(dbPool)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	apiKeyRepo, err := apikey.NewDatabaseAPIKeyRepository(dbPool)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	webhookRepo, err := webhook.NewDatabaseWebhookRepository(dbPool)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
 	app := Application{
 		config: cfg,
 		db:     dbPool,
+		blobs:  blobs,
 		logger: logger,
 
-		userRepo:    userRepo,
-		tokenRepo:   tokenRepo,
-		messageRepo: messageRepo,
+		accessTokens: accessTokens,
+
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		messageRepo:    messageRepo,
+		roomRepo:       roomRepo,
+		readMarkerRepo: readMarkerRepo,
+		reactionRepo:   reactionRepo,
+		mentionRepo:    mentionRepo,
+		attachmentRepo: attachmentRepo,
+		apiKeyRepo:     apiKeyRepo,
+		webhookRepo:    webhookRepo,
 	}
 
 	return &app, nil
@@ -76,6 +157,37 @@ func initDB(cfg *configurations.Configuration, logger logrus.FieldLogger) (*gorm
 	}
 }
 
+func initStorage(cfg *configurations.Configuration, logger logrus.FieldLogger) (storage.Storage, error) {
+	switch storage.StorageType(cfg.Storage.Type) {
+	case storage.S3:
+		logger.Println("Using S3 compatible storage...")
+
+		return storage.NewS3Storage(cfg.Storage)
+	default:
+		logger.Println("Using local storage...")
+
+		return storage.NewLocalStorage(cfg.Storage.Path)
+	}
+}
+
+// provisionAdmins grants admin rights to the users listed in the configuration and takes them from everyone else.
+// Listed names without a user are logged, they have to register and the server be restarted.
+func provisionAdmins(cfg *configurations.Configuration, userRepo user.UserRepository, logger logrus.FieldLogger) error {
+	ctx := context.Background()
+
+	if err := userRepo.SetAdmins(ctx, cfg.Auth.Admins); err != nil {
+		return err
+	}
+
+	for _, name := range cfg.Auth.Admins {
+		if _, err := userRepo.GetByUserName(ctx, name); err != nil {
+			logger.Warnln("Admin user does not exist: ", name)
+		}
+	}
+
+	return nil
+}
+
 func (a *Application) Config() *configurations.Configuration {
 	return a.config
 }
@@ -84,10 +196,18 @@ func (a *Application) DB() *gorm.DB {
 	return a.db
 }
 
+func (a *Application) Storage() storage.Storage {
+	return a.blobs
+}
+
 func (a *Application) Logger() logrus.FieldLogger {
 	return a.logger
 }
 
+func (a *Application) AccessTokens() *auth.AccessTokens {
+	return a.accessTokens
+}
+
 func (a *Application) UserRepo() user.UserRepository {
 	return a.userRepo
 }
@@ -99,3 +219,31 @@ func (a *Application) TokenRepo() token.TokenRepository {
 func (a *Application) MessageRepo() message.MessageRepository {
 	return a.messageRepo
 }
+
+func (a *Application) RoomRepo() room.RoomRepository {
+	return a.roomRepo
+}
+
+func (a *Application) ReadMarkerRepo() readmarker.ReadMarkerRepository {
+	return a.readMarkerRepo
+}
+
+func (a *Application) ReactionRepo() reaction.ReactionRepository {
+	return a.reactionRepo
+}
+
+func (a *Application) MentionRepo() mention.MentionRepository {
+	return a.mentionRepo
+}
+
+func (a *Application) AttachmentRepo() attachment.AttachmentRepository {
+	return a.attachmentRepo
+}
+
+func (a *Application) APIKeyRepo() apikey.APIKeyRepository {
+	return a.apiKeyRepo
+}
+
+func (a *Application) WebhookRepo() webhook.WebhookRepository {
+	return a.webhookRepo
+}
@@ -1,3 +1,6 @@
+//go:build wireinject
+// +build wireinject
+
 package app
 
 import (
@@ -7,11 +10,20 @@ import (
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 
+	"github.com/id-tarzanych/lets-go-chat/auth"
 	"github.com/id-tarzanych/lets-go-chat/configurations"
 	"github.com/id-tarzanych/lets-go-chat/db"
+	"github.com/id-tarzanych/lets-go-chat/db/apikey"
+	"github.com/id-tarzanych/lets-go-chat/db/attachment"
+	"github.com/id-tarzanych/lets-go-chat/db/mention"
 	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/db/reaction"
+	"github.com/id-tarzanych/lets-go-chat/db/readmarker"
+	"github.com/id-tarzanych/lets-go-chat/db/room"
 	"github.com/id-tarzanych/lets-go-chat/db/token"
 	"github.com/id-tarzanych/lets-go-chat/db/user"
+	"github.com/id-tarzanych/lets-go-chat/db/webhook"
+	"github.com/id-tarzanych/lets-go-chat/storage"
 )
 
 func InitializeApp(config *configurations.Configuration) (Application, error) {
@@ -19,9 +31,18 @@ func InitializeApp(config *configurations.Configuration) (Application, error) {
 		ProvideApp,
 		ProvideLogger,
 		ProvideDb,
+		ProvideStorage,
 		ProvideUserRepo,
+		ProvideAccessTokens,
 		ProvideTokenRepo,
 		ProvideMessageRepo,
+		ProvideRoomRepo,
+		ProvideReadMarkerRepo,
+		ProvideReactionRepo,
+		ProvideMentionRepo,
+		ProvideAttachmentRepo,
+		ProvideAPIKeyRepo,
+		ProvideWebhookRepo,
 	)
 	return Application{}, nil
 }
@@ -29,21 +50,40 @@ func InitializeApp(config *configurations.Configuration) (Application, error) {
 func ProvideApp(
 	cfg *configurations.Configuration,
 	dbPool *gorm.DB,
+	blobs storage.Storage,
+	accessTokens *auth.AccessTokens,
 
 	logger logrus.FieldLogger,
 
 	userRepo user.UserRepository,
 	tokenRepo token.TokenRepository,
 	messageRepo message.MessageRepository,
+	roomRepo room.RoomRepository,
+	readMarkerRepo readmarker.ReadMarkerRepository,
+	reactionRepo reaction.ReactionRepository,
+	mentionRepo mention.MentionRepository,
+	attachmentRepo attachment.AttachmentRepository,
+	apiKeyRepo apikey.APIKeyRepository,
+	webhookRepo webhook.WebhookRepository,
 ) Application {
 	return Application{
 		config: cfg,
 		db:     dbPool,
+		blobs:  blobs,
 		logger: logger,
 
-		userRepo:    userRepo,
-		tokenRepo:   tokenRepo,
-		messageRepo: messageRepo,
+		accessTokens: accessTokens,
+
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		messageRepo:    messageRepo,
+		roomRepo:       roomRepo,
+		readMarkerRepo: readMarkerRepo,
+		reactionRepo:   reactionRepo,
+		mentionRepo:    mentionRepo,
+		attachmentRepo: attachmentRepo,
+		apiKeyRepo:     apiKeyRepo,
+		webhookRepo:    webhookRepo,
 	}
 }
 
@@ -67,14 +107,75 @@ func ProvideDb(config *configurations.Configuration, logger logrus.FieldLogger)
 	}
 }
 
-func ProvideUserRepo(db *gorm.DB) (user.UserRepository, error) {
-	return user.NewDatabaseUserRepository(db)
+func ProvideStorage(config *configurations.Configuration, logger logrus.FieldLogger) (storage.Storage, error) {
+	switch storage.StorageType(config.Storage.Type) {
+	case storage.S3:
+		logger.Println("Using S3 compatible storage...")
+
+		return storage.NewS3Storage(config.Storage)
+	default:
+		logger.Println("Using local storage...")
+
+		return storage.NewLocalStorage(config.Storage.Path)
+	}
+}
+
+func ProvideAccessTokens(config *configurations.Configuration) (*auth.AccessTokens, error) {
+	return auth.NewAccessTokens(config.Auth)
+}
+
+func ProvideUserRepo(config *configurations.Configuration, db *gorm.DB, logger logrus.FieldLogger) (user.UserRepository, error) {
+	userRepo, err := user.NewDatabaseUserRepository(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := provisionAdmins(config, userRepo, logger); err != nil {
+		return nil, err
+	}
+
+	return userRepo, nil
+}
+
+func ProvideTokenRepo(config *configurations.Configuration, db *gorm.DB) (token.TokenRepository, error) {
+	return token.NewDatabaseTokenRepository(db, config.Auth.TokenSecret)
+}
+
+func ProvideMessageRepo(db *gorm.DB, logger logrus.FieldLogger) (message.MessageRepository, error) {
+	messageRepo, err := message.NewDatabaseMessageRepository(db)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Println("Using full-text search module: ", messageRepo.SearchModule())
+
+	return messageRepo, nil
+}
+
+func ProvideRoomRepo(db *gorm.DB) (room.RoomRepository, error) {
+	return room.NewDatabaseRoomRepository(db)
+}
+
+func ProvideReadMarkerRepo(db *gorm.DB) (readmarker.ReadMarkerRepository, error) {
+	return readmarker.NewDatabaseReadMarkerRepository(db)
+}
+
+func ProvideReactionRepo(db *gorm.DB) (reaction.ReactionRepository, error) {
+	return reaction.NewDatabaseReactionRepository(db)
+}
+
+func ProvideMentionRepo(db *gorm.DB) (mention.MentionRepository, error) {
+	return mention.NewDatabaseMentionRepository(db)
+}
+
+func ProvideAttachmentRepo(db *gorm.DB) (attachment.AttachmentRepository, error) {
+	return attachment.NewDatabaseAttachmentRepository(db)
 }
 
-func ProvideTokenRepo(db *gorm.DB) (token.TokenRepository, error) {
-	return token.NewDatabaseTokenRepository(db)
+func ProvideAPIKeyRepo(db *gorm.DB) (apikey.APIKeyRepository, error) {
+	return apikey.NewDatabaseAPIKeyRepository(db)
 }
 
-func ProvideMessageRepo(db *gorm.DB) (message.MessageRepository, error) {
-	return message.NewDatabaseMessageRepository(db)
+func ProvideWebhookRepo(db *gorm.DB) (webhook.WebhookRepository, error) {
+	return webhook.NewDatabaseWebhookRepository(db)
 }
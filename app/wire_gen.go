@@ -7,11 +7,20 @@
 package app
 
 import (
+	"github.com/id-tarzanych/lets-go-chat/auth"
 	"github.com/id-tarzanych/lets-go-chat/configurations"
 	"github.com/id-tarzanych/lets-go-chat/db"
+	"github.com/id-tarzanych/lets-go-chat/db/apikey"
+	"github.com/id-tarzanych/lets-go-chat/db/attachment"
+	"github.com/id-tarzanych/lets-go-chat/db/mention"
 	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/db/reaction"
+	"github.com/id-tarzanych/lets-go-chat/db/readmarker"
+	"github.com/id-tarzanych/lets-go-chat/db/room"
 	"github.com/id-tarzanych/lets-go-chat/db/token"
 	"github.com/id-tarzanych/lets-go-chat/db/user"
+	"github.com/id-tarzanych/lets-go-chat/db/webhook"
+	"github.com/id-tarzanych/lets-go-chat/storage"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 	"os"
@@ -25,19 +34,55 @@ func InitializeApp(config *configurations.Configuration) (Application, error) {
 	if err != nil {
 		return Application{}, err
 	}
-	userRepository, err := ProvideUserRepo(db)
+	storageStorage, err := ProvideStorage(config, fieldLogger)
 	if err != nil {
 		return Application{}, err
 	}
-	tokenRepository, err := ProvideTokenRepo(db)
+	accessTokens, err := ProvideAccessTokens(config)
 	if err != nil {
 		return Application{}, err
 	}
-	messageRepository, err := ProvideMessageRepo(db)
+	userRepository, err := ProvideUserRepo(config, db, fieldLogger)
 	if err != nil {
 		return Application{}, err
 	}
-	application := ProvideApp(config, db, fieldLogger, userRepository, tokenRepository, messageRepository)
+	tokenRepository, err := ProvideTokenRepo(config, db)
+	if err != nil {
+		return Application{}, err
+	}
+	messageRepository, err := ProvideMessageRepo(db, fieldLogger)
+	if err != nil {
+		return Application{}, err
+	}
+	roomRepository, err := ProvideRoomRepo(db)
+	if err != nil {
+		return Application{}, err
+	}
+	readMarkerRepository, err := ProvideReadMarkerRepo(db)
+	if err != nil {
+		return Application{}, err
+	}
+	reactionRepository, err := ProvideReactionRepo(db)
+	if err != nil {
+		return Application{}, err
+	}
+	mentionRepository, err := ProvideMentionRepo(db)
+	if err != nil {
+		return Application{}, err
+	}
+	attachmentRepository, err := ProvideAttachmentRepo(db)
+	if err != nil {
+		return Application{}, err
+	}
+	apiKeyRepository, err := ProvideAPIKeyRepo(db)
+	if err != nil {
+		return Application{}, err
+	}
+	webhookRepository, err := ProvideWebhookRepo(db)
+	if err != nil {
+		return Application{}, err
+	}
+	application := ProvideApp(config, db, storageStorage, accessTokens, fieldLogger, userRepository, tokenRepository, messageRepository, roomRepository, readMarkerRepository, reactionRepository, mentionRepository, attachmentRepository, apiKeyRepository, webhookRepository)
 	return application, nil
 }
 
@@ -46,21 +91,40 @@ func InitializeApp(config *configurations.Configuration) (Application, error) {
 func ProvideApp(
 	cfg *configurations.Configuration,
 	dbPool *gorm.DB,
+	blobs storage.Storage,
+	accessTokens *auth.AccessTokens,
 
 	logger logrus.FieldLogger,
 
 	userRepo user.UserRepository,
 	tokenRepo token.TokenRepository,
 	messageRepo message.MessageRepository,
+	roomRepo room.RoomRepository,
+	readMarkerRepo readmarker.ReadMarkerRepository,
+	reactionRepo reaction.ReactionRepository,
+	mentionRepo mention.MentionRepository,
+	attachmentRepo attachment.AttachmentRepository,
+	apiKeyRepo apikey.APIKeyRepository,
+	webhookRepo webhook.WebhookRepository,
 ) Application {
 	return Application{
 		config: cfg,
 		db:     dbPool,
+		blobs:  blobs,
 		logger: logger,
 
-		userRepo:    userRepo,
-		tokenRepo:   tokenRepo,
-		messageRepo: messageRepo,
+		accessTokens: accessTokens,
+
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		messageRepo:    messageRepo,
+		roomRepo:       roomRepo,
+		readMarkerRepo: readMarkerRepo,
+		reactionRepo:   reactionRepo,
+		mentionRepo:    mentionRepo,
+		attachmentRepo: attachmentRepo,
+		apiKeyRepo:     apiKeyRepo,
+		webhookRepo:    webhookRepo,
 	}
 }
 
@@ -84,14 +148,75 @@ func ProvideDb(config *configurations.Configuration, logger logrus.FieldLogger)
 	}
 }
 
-func ProvideUserRepo(db2 *gorm.DB) (user.UserRepository, error) {
-	return user.NewDatabaseUserRepository(db2)
+func ProvideStorage(config *configurations.Configuration, logger logrus.FieldLogger) (storage.Storage, error) {
+	switch storage.StorageType(config.Storage.Type) {
+	case storage.S3:
+		logger.Println("Using S3 compatible storage...")
+
+		return storage.NewS3Storage(config.Storage)
+	default:
+		logger.Println("Using local storage...")
+
+		return storage.NewLocalStorage(config.Storage.Path)
+	}
+}
+
+func ProvideAccessTokens(config *configurations.Configuration) (*auth.AccessTokens, error) {
+	return auth.NewAccessTokens(config.Auth)
+}
+
+func ProvideUserRepo(config *configurations.Configuration, db2 *gorm.DB, logger logrus.FieldLogger) (user.UserRepository, error) {
+	userRepo, err := user.NewDatabaseUserRepository(db2)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := provisionAdmins(config, userRepo, logger); err != nil {
+		return nil, err
+	}
+
+	return userRepo, nil
+}
+
+func ProvideTokenRepo(config *configurations.Configuration, db2 *gorm.DB) (token.TokenRepository, error) {
+	return token.NewDatabaseTokenRepository(db2, config.Auth.TokenSecret)
+}
+
+func ProvideMessageRepo(db2 *gorm.DB, logger logrus.FieldLogger) (message.MessageRepository, error) {
+	messageRepo, err := message.NewDatabaseMessageRepository(db2)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Println("Using full-text search module: ", messageRepo.SearchModule())
+
+	return messageRepo, nil
+}
+
+func ProvideRoomRepo(db2 *gorm.DB) (room.RoomRepository, error) {
+	return room.NewDatabaseRoomRepository(db2)
+}
+
+func ProvideReadMarkerRepo(db2 *gorm.DB) (readmarker.ReadMarkerRepository, error) {
+	return readmarker.NewDatabaseReadMarkerRepository(db2)
+}
+
+func ProvideReactionRepo(db2 *gorm.DB) (reaction.ReactionRepository, error) {
+	return reaction.NewDatabaseReactionRepository(db2)
+}
+
+func ProvideMentionRepo(db2 *gorm.DB) (mention.MentionRepository, error) {
+	return mention.NewDatabaseMentionRepository(db2)
+}
+
+func ProvideAttachmentRepo(db2 *gorm.DB) (attachment.AttachmentRepository, error) {
+	return attachment.NewDatabaseAttachmentRepository(db2)
 }
 
-func ProvideTokenRepo(db2 *gorm.DB) (token.TokenRepository, error) {
-	return token.NewDatabaseTokenRepository(db2)
+func ProvideAPIKeyRepo(db2 *gorm.DB) (apikey.APIKeyRepository, error) {
+	return apikey.NewDatabaseAPIKeyRepository(db2)
 }
 
-func ProvideMessageRepo(db2 *gorm.DB) (message.MessageRepository, error) {
-	return message.NewDatabaseMessageRepository(db2)
+func ProvideWebhookRepo(db2 *gorm.DB) (webhook.WebhookRepository, error) {
+	return webhook.NewDatabaseWebhookRepository(db2)
 }
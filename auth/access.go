@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/configurations"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/pkg/generators"
+	"github.com/id-tarzanych/lets-go-chat/pkg/jwt"
+)
+
+// jtiBytes is the entropy of the unique id of every access token.
+const jtiBytes = 16
+
+var (
+	ErrNoSigningKeys = errors.New("no access token signing keys are configured")
+	ErrInvalidTTL    = errors.New("access token lifetime must be positive")
+	ErrNoSubject     = errors.New("access token has no subject")
+	ErrRevoked       = errors.New("access token was revoked")
+)
+
+// AccessTokens issues and verifies short-lived access tokens, JWTs carrying the id of the user
+// and of the session, the login they were issued for.
+// They are verified by signature alone, so they are not stored. Revocations are kept in memory
+// until the tokens they cover expired, so they only apply to the process that recorded them.
+type AccessTokens struct {
+	keys *jwt.KeySet
+	ttl  time.Duration
+
+	mu              sync.Mutex
+	revoked         map[types.Uuid]time.Time
+	revokedSessions map[string]time.Time
+}
+
+// NewAccessTokens parses the signing keys of the configuration. New tokens are signed with the key SigningKeyId,
+// which may be omitted when there is a single key.
+func NewAccessTokens(cfg configurations.Auth) (*AccessTokens, error) {
+	if len(cfg.SigningKeys) == 0 {
+		return nil, ErrNoSigningKeys
+	}
+
+	if cfg.AccessTokenTTL <= 0 {
+		return nil, ErrInvalidTTL
+	}
+
+	keys := make([]jwt.Key, 0, len(cfg.SigningKeys))
+	for _, spec := range cfg.SigningKeys {
+		k, err := jwt.ParseKey(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, k)
+	}
+
+	currentId := cfg.SigningKeyId
+	if currentId == "" {
+		if len(keys) > 1 {
+			return nil, fmt.Errorf("%w: signing key id is required with several keys", jwt.ErrUnknownKey)
+		}
+
+		currentId = keys[0].Id
+	}
+
+	keySet, err := jwt.NewKeySet(currentId, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccessTokens{
+		keys:            keySet,
+		ttl:             cfg.AccessTokenTTL,
+		revoked:         make(map[types.Uuid]time.Time),
+		revokedSessions: make(map[string]time.Time),
+	}, nil
+}
+
+// Issue returns an access token of the user's session valid from now on, and its expiration.
+// Tokens issued within the second of a revocation of the user count as issued the next second,
+// so logging in right after a revocation works.
+func (a *AccessTokens) Issue(userId types.Uuid, sessionId string, now time.Time) (string, time.Time, error) {
+	jti, err := generators.TokenWithEntropy(jtiBytes)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	claims := jwt.Claims{
+		Subject:   string(userId),
+		SessionId: sessionId,
+		IssuedAt:  a.issuedAt(userId, now),
+		ExpiresAt: now.Add(a.ttl).Unix(),
+		Id:        jti,
+	}
+
+	token, err := a.keys.Sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, claims.Expiration(), nil
+}
+
+// Verify returns the claims of the access token, its subject is the id of the user it was issued to.
+// Expired tokens are rejected with jwt.ErrExpired, revoked ones with ErrRevoked.
+func (a *AccessTokens) Verify(token string) (jwt.Claims, error) {
+	claims, err := a.keys.Verify(token, time.Now())
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+
+	if claims.Subject == "" {
+		return jwt.Claims{}, ErrNoSubject
+	}
+
+	if a.isRevoked(claims) {
+		return jwt.Claims{}, ErrRevoked
+	}
+
+	return claims, nil
+}
+
+// Revoke rejects the access tokens issued to the user until at.
+func (a *AccessTokens) Revoke(userId types.Uuid, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.prune(at)
+	a.revoked[userId] = at
+}
+
+// RevokeSession rejects every access token of the session. Sessions are not resumed once they ended,
+// so tokens issued for it later are rejected as well.
+func (a *AccessTokens) RevokeSession(sessionId string, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.prune(at)
+	a.revokedSessions[sessionId] = at
+}
+
+// prune forgets revocations older than the lifetime of tokens, the tokens they cover expired anyway.
+func (a *AccessTokens) prune(at time.Time) {
+	for id, revokedAt := range a.revoked {
+		if at.Sub(revokedAt) > a.ttl {
+			delete(a.revoked, id)
+		}
+	}
+
+	for id, revokedAt := range a.revokedSessions {
+		if at.Sub(revokedAt) > a.ttl {
+			delete(a.revokedSessions, id)
+		}
+	}
+}
+
+// issuedAt returns the Unix time of now, or the second after the last revocation of the user
+// if it was revoked within the same second.
+func (a *AccessTokens) issuedAt(userId types.Uuid, now time.Time) int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if revokedAt, ok := a.revoked[userId]; ok && now.Unix() <= revokedAt.Unix() {
+		return revokedAt.Unix() + 1
+	}
+
+	return now.Unix()
+}
+
+// isRevoked reports whether the session of the token or the tokens its user had until it was issued were revoked.
+// Issue times have a precision of seconds, tokens issued within the second of the revocation are revoked.
+func (a *AccessTokens) isRevoked(claims jwt.Claims) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.revokedSessions[claims.SessionId]; ok && claims.SessionId != "" {
+		return true
+	}
+
+	revokedAt, ok := a.revoked[types.Uuid(claims.Subject)]
+
+	return ok && claims.IssuedAt <= revokedAt.Unix()
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/id-tarzanych/lets-go-chat/configurations"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/pkg/jwt"
+)
+
+var (
+	oldKey = "old:HS256:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("o", 32)))
+	newKey = "new:HS256:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("n", 32)))
+)
+
+func TestNewAccessTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     configurations.Auth
+		wantErr bool
+	}{
+		{"No keys", configurations.Auth{AccessTokenTTL: time.Minute}, true},
+		{"No lifetime", configurations.Auth{SigningKeys: []string{newKey}}, true},
+		{"Invalid key", configurations.Auth{SigningKeys: []string{"new:HS256:c2hvcnQ="}, AccessTokenTTL: time.Minute}, true},
+		{"Unknown key id", configurations.Auth{SigningKeys: []string{newKey}, SigningKeyId: "old", AccessTokenTTL: time.Minute}, true},
+		{"Ambiguous key", configurations.Auth{SigningKeys: []string{oldKey, newKey}, AccessTokenTTL: time.Minute}, true},
+		{"Single key", configurations.Auth{SigningKeys: []string{newKey}, AccessTokenTTL: time.Minute}, false},
+		{"Several keys", configurations.Auth{SigningKeys: []string{oldKey, newKey}, SigningKeyId: "new", AccessTokenTTL: time.Minute}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAccessTokens(tt.cfg)
+
+			assert.Equal(t, tt.wantErr, err != nil, "unexpected error %v", err)
+		})
+	}
+}
+
+func TestAccessTokens_IssueVerify(t *testing.T) {
+	current, err := NewAccessTokens(configurations.Auth{SigningKeys: []string{oldKey, newKey}, SigningKeyId: "new", AccessTokenTTL: time.Minute})
+	assert.NoError(t, err)
+
+	previous, err := NewAccessTokens(configurations.Auth{SigningKeys: []string{oldKey}, AccessTokenTTL: time.Minute})
+	assert.NoError(t, err)
+
+	now := time.Now()
+
+	token, expiresAt, err := current.Issue("alice", "session", now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute).Unix(), expiresAt.Unix())
+
+	claims, err := current.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", claims.Subject)
+	assert.Equal(t, "session", claims.SessionId)
+
+	other, _, err := current.Issue("alice", "session", now)
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, other, "tokens should have unique ids")
+
+	token, _, err = previous.Issue("bob", "", now)
+	assert.NoError(t, err)
+
+	claims, err = current.Verify(token)
+	assert.NoError(t, err, "tokens of retired keys should be accepted until they expire")
+	assert.Equal(t, "bob", claims.Subject)
+
+	token, _, err = current.Issue("alice", "session", now.Add(-time.Hour))
+	assert.NoError(t, err)
+
+	_, err = current.Verify(token)
+	assert.True(t, errors.Is(err, jwt.ErrExpired), "unexpected error %v", err)
+
+	_, err = previous.Verify(other)
+	assert.True(t, errors.Is(err, jwt.ErrUnknownKey), "unexpected error %v", err)
+}
+
+func TestAccessTokens_Revoke(t *testing.T) {
+	accessTokens, err := NewAccessTokens(configurations.Auth{SigningKeys: []string{newKey}, AccessTokenTTL: time.Minute})
+	assert.NoError(t, err)
+
+	now := time.Now()
+
+	before, _, err := accessTokens.Issue("alice", "", now.Add(-time.Second))
+	assert.NoError(t, err)
+	sameSecond, _, err := accessTokens.Issue("alice", "", now)
+	assert.NoError(t, err)
+	other, _, err := accessTokens.Issue("bob", "", now.Add(-time.Second))
+	assert.NoError(t, err)
+
+	accessTokens.Revoke("alice", now)
+
+	after, _, err := accessTokens.Issue("alice", "", now)
+	assert.NoError(t, err)
+
+	_, err = accessTokens.Verify(before)
+	assert.True(t, errors.Is(err, ErrRevoked), "unexpected error %v", err)
+
+	_, err = accessTokens.Verify(sameSecond)
+	assert.True(t, errors.Is(err, ErrRevoked), "tokens issued within the second of the revocation should be rejected, got %v", err)
+
+	_, err = accessTokens.Verify(after)
+	assert.NoError(t, err, "tokens issued after the revocation should be accepted")
+
+	_, err = accessTokens.Verify(other)
+	assert.NoError(t, err, "tokens of other users should be accepted")
+
+	accessTokens.Revoke("bob", now.Add(2*time.Minute))
+	assert.NotContains(t, accessTokens.revoked, types.Uuid("alice"), "expired revocations should be forgotten")
+}
+
+func TestAccessTokens_RevokeSession(t *testing.T) {
+	accessTokens, err := NewAccessTokens(configurations.Auth{SigningKeys: []string{newKey}, AccessTokenTTL: time.Minute})
+	assert.NoError(t, err)
+
+	now := time.Now()
+
+	ended, _, err := accessTokens.Issue("alice", "ended", now.Add(-time.Second))
+	assert.NoError(t, err)
+	other, _, err := accessTokens.Issue("alice", "other", now.Add(-time.Second))
+	assert.NoError(t, err)
+	noSession, _, err := accessTokens.Issue("alice", "", now.Add(-time.Second))
+	assert.NoError(t, err)
+
+	accessTokens.RevokeSession("ended", now)
+	accessTokens.RevokeSession("", now)
+
+	later, _, err := accessTokens.Issue("alice", "ended", now.Add(time.Second))
+	assert.NoError(t, err)
+
+	for _, token := range []string{ended, later} {
+		_, err = accessTokens.Verify(token)
+		assert.True(t, errors.Is(err, ErrRevoked), "tokens of the session should be rejected, got %v", err)
+	}
+
+	for _, token := range []string{other, noSession} {
+		_, err = accessTokens.Verify(token)
+		assert.NoError(t, err, "tokens of other sessions should be accepted")
+	}
+
+	accessTokens.RevokeSession("other", now.Add(2*time.Minute))
+	assert.NotContains(t, accessTokens.revokedSessions, "ended", "expired revocations should be forgotten")
+}
@@ -2,12 +2,35 @@ package configurations
 
 import (
 	"errors"
+	"time"
+
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Configuration struct {
+	Auth     Auth
 	Database Database
 	Server   Server
+	Storage  Storage
+	Webhooks Webhooks
+}
+
+type Auth struct {
+	// TokenSecret keys the hashes chat and refresh tokens are stored by. Changing it invalidates all issued tokens.
+	TokenSecret string `yaml:"tokenSecret" env:"LETS_GO_CHAT_AUTH__TOKEN_SECRET"`
+
+	// SigningKeys sign and verify access tokens, each given as <kid>:<HS256|EdDSA>:<base64 key>.
+	// Retired keys should be kept until the access tokens they signed expired.
+	SigningKeys []string `yaml:"signingKeys" env:"LETS_GO_CHAT_AUTH__SIGNING_KEYS"`
+	// SigningKeyId is the kid of the key signing new access tokens.
+	SigningKeyId string `yaml:"signingKeyId" env:"LETS_GO_CHAT_AUTH__SIGNING_KEY_ID"`
+	// AccessTokenTTL is the lifetime of access tokens. Revoking them only takes effect on the instance that revoked them.
+	AccessTokenTTL time.Duration `yaml:"accessTokenTTL" env:"LETS_GO_CHAT_AUTH__ACCESS_TOKEN_TTL" env-default:"15m"`
+	// RefreshTokenTTL is the lifetime of a login, every refresh rotates the refresh token but keeps its expiration.
+	RefreshTokenTTL time.Duration `yaml:"refreshTokenTTL" env:"LETS_GO_CHAT_AUTH__REFRESH_TOKEN_TTL" env-default:"720h"`
+
+	// Admins lists the names of the admin users. It is applied on startup, users missing from it lose admin rights.
+	Admins []string `yaml:"admins" env:"LETS_GO_CHAT_AUTH__ADMINS"`
 }
 
 type Database struct {
@@ -24,10 +47,69 @@ type Database struct {
 
 type Server struct {
 	Port int `yaml:"port"  env:"LETS_GO_CHAT_SERVER__PORT" env-default:"8080"`
+
+	// HistoryReplayLimit caps the number of missed messages pushed to a client when it connects.
+	HistoryReplayLimit int `yaml:"historyReplayLimit" env:"LETS_GO_CHAT_SERVER__HISTORY_REPLAY_LIMIT" env-default:"100"`
+
+	// SendQueueSize bounds the number of frames waiting to be written to a single client.
+	SendQueueSize int `yaml:"sendQueueSize" env:"LETS_GO_CHAT_SERVER__SEND_QUEUE_SIZE" env-default:"256"`
+	// WriteTimeout limits how long a write to a client socket may block.
+	WriteTimeout time.Duration `yaml:"writeTimeout" env:"LETS_GO_CHAT_SERVER__WRITE_TIMEOUT" env-default:"10s"`
+	// SlowConsumerPolicy is either drop_oldest or disconnect.
+	SlowConsumerPolicy string `yaml:"slowConsumerPolicy" env:"LETS_GO_CHAT_SERVER__SLOW_CONSUMER_POLICY" env-default:"disconnect"`
+
+	// PingInterval is how often clients are pinged to keep connections alive.
+	PingInterval time.Duration `yaml:"pingInterval" env:"LETS_GO_CHAT_SERVER__PING_INTERVAL" env-default:"30s"`
+	// PongTimeout is how long a connection may stay silent, including pongs, before it is considered dead.
+	PongTimeout time.Duration `yaml:"pongTimeout" env:"LETS_GO_CHAT_SERVER__PONG_TIMEOUT" env-default:"60s"`
+	// IdleTimeout disconnects clients that sent no frame for this long, zero disables it.
+	IdleTimeout time.Duration `yaml:"idleTimeout" env:"LETS_GO_CHAT_SERVER__IDLE_TIMEOUT" env-default:"30m"`
+	// ReapInterval is how often stale clients are removed from the chat.
+	ReapInterval time.Duration `yaml:"reapInterval" env:"LETS_GO_CHAT_SERVER__REAP_INTERVAL" env-default:"1m"`
+
+	// TypingThrottle is the minimal interval between relayed typing notifications of a user in a conversation.
+	TypingThrottle time.Duration `yaml:"typingThrottle" env:"LETS_GO_CHAT_SERVER__TYPING_THROTTLE" env-default:"3s"`
+	// TypingTimeout clears a typing indicator that was neither refreshed nor stopped for this long.
+	TypingTimeout time.Duration `yaml:"typingTimeout" env:"LETS_GO_CHAT_SERVER__TYPING_TIMEOUT" env-default:"6s"`
+}
+
+type Storage struct {
+	// Type is either local or s3, any S3 compatible service such as MinIO can be used.
+	Type string `yaml:"type" env:"LETS_GO_CHAT_STORAGE__TYPE" env-default:"local"`
+	// Path is the directory of the local storage.
+	Path string `yaml:"path" env:"LETS_GO_CHAT_STORAGE__PATH" env-default:"uploads"`
+
+	Endpoint  string `yaml:"endpoint" env:"LETS_GO_CHAT_STORAGE__ENDPOINT"`
+	Region    string `yaml:"region" env:"LETS_GO_CHAT_STORAGE__REGION" env-default:"us-east-1"`
+	Bucket    string `yaml:"bucket" env:"LETS_GO_CHAT_STORAGE__BUCKET"`
+	AccessKey string `yaml:"accessKey" env:"LETS_GO_CHAT_STORAGE__ACCESS_KEY"`
+	SecretKey string `yaml:"secretKey" env:"LETS_GO_CHAT_STORAGE__SECRET_KEY"`
+
+	// MaxUploadSize is the largest attachment accepted, in bytes.
+	MaxUploadSize int64 `yaml:"maxUploadSize" env:"LETS_GO_CHAT_STORAGE__MAX_UPLOAD_SIZE" env-default:"10485760"`
+	// AllowedTypes lists media types of attachments, the type is detected from file content.
+	AllowedTypes []string `yaml:"allowedTypes" env:"LETS_GO_CHAT_STORAGE__ALLOWED_TYPES" env-default:"image/png,image/jpeg,image/gif,image/webp,application/pdf,text/plain"`
+
+	// UnattachedTTL is how long an upload may wait to be sent with a message before it is removed, zero keeps it.
+	UnattachedTTL time.Duration `yaml:"unattachedTTL" env:"LETS_GO_CHAT_STORAGE__UNATTACHED_TTL" env-default:"24h"`
+	// SweepInterval is how often expired uploads are removed.
+	SweepInterval time.Duration `yaml:"sweepInterval" env:"LETS_GO_CHAT_STORAGE__SWEEP_INTERVAL" env-default:"1h"`
+}
+
+type Webhooks struct {
+	// Timeout limits a single delivery attempt.
+	Timeout time.Duration `yaml:"timeout" env:"LETS_GO_CHAT_WEBHOOKS__TIMEOUT" env-default:"10s"`
+	// MinBackoff delays the first retry of a failed delivery, the delay doubles with every further attempt up to MaxBackoff.
+	MinBackoff time.Duration `yaml:"minBackoff" env:"LETS_GO_CHAT_WEBHOOKS__MIN_BACKOFF" env-default:"30s"`
+	MaxBackoff time.Duration `yaml:"maxBackoff" env:"LETS_GO_CHAT_WEBHOOKS__MAX_BACKOFF" env-default:"1h"`
+	// MaxAttempts gives up on a delivery after this many failed attempts.
+	MaxAttempts int `yaml:"maxAttempts" env:"LETS_GO_CHAT_WEBHOOKS__MAX_ATTEMPTS" env-default:"10"`
+	// PollInterval is how often the delivery queue is checked for due retries, zero disables deliveries.
+	PollInterval time.Duration `yaml:"pollInterval" env:"LETS_GO_CHAT_WEBHOOKS__POLL_INTERVAL" env-default:"5s"`
 }
 
 func New() (*Configuration, error) {
-	cfg := Configuration{Database{}, Server{}}
+	cfg := Configuration{Auth{}, Database{}, Server{}, Storage{}, Webhooks{}}
 
 	err := cleanenv.ReadConfig("config.yml", &cfg)
 	if err != nil {
@@ -0,0 +1,90 @@
+package apikey
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, k *models.APIKey) error
+	Delete(ctx context.Context, id uint) error
+	DeleteByUserId(ctx context.Context, userId types.Uuid) error
+	GetById(ctx context.Context, id uint) (models.APIKey, error)
+	GetByKey(ctx context.Context, key string) (models.APIKey, error)
+	GetByUserId(ctx context.Context, userId types.Uuid) ([]models.APIKey, error)
+}
+
+type DatabaseAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewDatabaseAPIKeyRepository(db *gorm.DB) (*DatabaseAPIKeyRepository, error) {
+	err := db.AutoMigrate(&models.APIKey{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DatabaseAPIKeyRepository{db}, nil
+}
+
+func (d DatabaseAPIKeyRepository) Create(ctx context.Context, k *models.APIKey) error {
+	if result := d.db.Create(k); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (d DatabaseAPIKeyRepository) Delete(ctx context.Context, id uint) error {
+	if result := d.db.Delete(&models.APIKey{}, id); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (d DatabaseAPIKeyRepository) DeleteByUserId(ctx context.Context, userId types.Uuid) error {
+	if result := d.db.Delete(&models.APIKey{}, "user_id = ?", userId); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (d DatabaseAPIKeyRepository) GetById(ctx context.Context, id uint) (models.APIKey, error) {
+	var k models.APIKey
+
+	result := d.db.First(&k, id)
+	if result.Error != nil {
+		return models.APIKey{}, result.Error
+	}
+
+	return k, nil
+}
+
+// GetByKey finds the key by the hash of its plain text.
+func (d DatabaseAPIKeyRepository) GetByKey(ctx context.Context, key string) (models.APIKey, error) {
+	var k models.APIKey
+
+	result := d.db.First(&k, "key_hash = ?", models.HashAPIKey(key))
+	if result.Error != nil {
+		return models.APIKey{}, result.Error
+	}
+
+	return k, nil
+}
+
+func (d DatabaseAPIKeyRepository) GetByUserId(ctx context.Context, userId types.Uuid) ([]models.APIKey, error) {
+	var keys []models.APIKey
+
+	result := d.db.Where("user_id = ?", userId).Order("id").Find(&keys)
+	if result.Error != nil {
+		return keys, result.Error
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,71 @@
+package attachment
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+type AttachmentRepository interface {
+	Create(ctx context.Context, a *models.Attachment) error
+	GetById(ctx context.Context, id uint) (models.Attachment, error)
+	GetUnattached(ctx context.Context, before time.Time) ([]models.Attachment, error)
+	DeleteUnattached(ctx context.Context, id uint) (bool, error)
+}
+
+type DatabaseAttachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewDatabaseAttachmentRepository(db *gorm.DB) (*DatabaseAttachmentRepository, error) {
+	err := db.AutoMigrate(&models.Attachment{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DatabaseAttachmentRepository{db}, nil
+}
+
+func (d DatabaseAttachmentRepository) Create(ctx context.Context, a *models.Attachment) error {
+	if result := d.db.Create(a); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (d DatabaseAttachmentRepository) GetById(ctx context.Context, id uint) (models.Attachment, error) {
+	var a models.Attachment
+
+	result := d.db.First(&a, id)
+	if result.Error != nil {
+		return a, result.Error
+	}
+
+	return a, nil
+}
+
+// GetUnattached returns attachments uploaded before the time that were not sent with a message.
+func (d DatabaseAttachmentRepository) GetUnattached(ctx context.Context, before time.Time) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+
+	result := d.db.Where("message_id IS NULL AND created_at < ?", before).Order("id").Find(&attachments)
+	if result.Error != nil {
+		return attachments, result.Error
+	}
+
+	return attachments, nil
+}
+
+// DeleteUnattached deletes the attachment unless it was sent with a message meanwhile and reports whether it did.
+func (d DatabaseAttachmentRepository) DeleteUnattached(ctx context.Context, id uint) (bool, error) {
+	result := d.db.Delete(&models.Attachment{}, "id = ? AND message_id IS NULL", id)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
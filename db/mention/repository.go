@@ -0,0 +1,40 @@
+package mention
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+type MentionRepository interface {
+	Create(ctx context.Context, mentions []models.Mention) error
+}
+
+type DatabaseMentionRepository struct {
+	db *gorm.DB
+}
+
+func NewDatabaseMentionRepository(db *gorm.DB) (*DatabaseMentionRepository, error) {
+	err := db.AutoMigrate(&models.Mention{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DatabaseMentionRepository{db}, nil
+}
+
+// Create stores the mentions, a user is recorded once per message however often it is named.
+func (d DatabaseMentionRepository) Create(ctx context.Context, mentions []models.Mention) error {
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	if result := d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&mentions); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
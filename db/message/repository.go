@@ -6,6 +6,7 @@ import (
 
 	"gorm.io/gorm"
 
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
 	"github.com/id-tarzanych/lets-go-chat/models"
 )
 
@@ -15,44 +16,212 @@ type MessageRepository interface {
 	Delete(ctx context.Context, id uint) error
 	GetAll(ctx context.Context) ([]models.Message, error)
 	GetNewerThan(ctx context.Context, time time.Time) ([]models.Message, error)
+	GetAllFor(ctx context.Context, audience Audience) ([]models.Message, error)
+	GetNewerThanFor(ctx context.Context, audience Audience, time time.Time, limit int) ([]models.Message, error)
+	GetPageFor(ctx context.Context, audience Audience, cursor Cursor) ([]models.Message, error)
+	GetThread(ctx context.Context, rootId uint, cursor Cursor) ([]models.Message, error)
+	GetMentionsFor(ctx context.Context, audience Audience, cursor Cursor) ([]models.Message, error)
+	GetByClientId(ctx context.Context, authorId types.Uuid, clientId string) (models.Message, error)
+	GetById(ctx context.Context, id uint) (models.Message, error)
+	GetEdits(ctx context.Context, messageId uint) ([]models.MessageEdit, error)
+	CountUnreadFor(ctx context.Context, audience Audience) ([]UnreadCount, error)
+	Search(ctx context.Context, audience Audience, filter SearchFilter, cursor Cursor) ([]models.Message, error)
+}
+
+// Audience narrows message lookups down to the conversations a reader takes part in.
+// Messages posted outside of any room are visible to everyone, direct messages only to
+// their author and recipient.
+type Audience struct {
+	UserId  types.Uuid
+	RoomIds []uint
+}
+
+func (a Audience) scope(db *gorm.DB) *gorm.DB {
+	rooms := "room_id IS NULL"
+	args := make([]interface{}, 0, 3)
+
+	if len(a.RoomIds) > 0 {
+		rooms = "(room_id IS NULL OR room_id IN ?)"
+		args = append(args, a.RoomIds)
+	}
+
+	args = append(args, a.UserId, a.UserId)
+
+	return db.Where(
+		"(recipient_uuid IS NULL AND "+rooms+") OR recipient_uuid = ? OR (author_uuid = ? AND recipient_uuid IS NOT NULL)",
+		args...,
+	)
+}
+
+// Includes tells whether the message is visible to the audience.
+func (a Audience) Includes(m *models.Message) bool {
+	switch {
+	case m.RecipientUuid != nil:
+		return *m.RecipientUuid == a.UserId || m.AuthorUuid == a.UserId
+	case m.RoomID != nil:
+		for _, id := range a.RoomIds {
+			if id == *m.RoomID {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// Cursor selects a page of at most Limit messages older than Before or newer than After.
+// Without Before and After the latest messages are selected. Pages are ordered oldest first.
+type Cursor struct {
+	Before uint
+	After  uint
+	Limit  int
+}
+
+// UnreadCount is the number of messages of other users past the read marker of a conversation.
+// Conversations are identified like read markers: by RoomID, by PeerUuid or by neither for the public chat.
+type UnreadCount struct {
+	RoomID   uint
+	PeerUuid types.Uuid
+	Count    int
+}
+
+// conversationPeer is the other participant of a direct message received by the reader.
+const conversationPeer = "CASE WHEN messages.recipient_uuid IS NULL THEN '' ELSE messages.author_uuid END"
+
+func (c Cursor) scope(db *gorm.DB) *gorm.DB {
+	if c.After > 0 {
+		return db.Where("id > ?", c.After).Order("id").Limit(c.Limit)
+	}
+
+	if c.Before > 0 {
+		db = db.Where("id < ?", c.Before)
+	}
+
+	return db.Order("id desc").Limit(c.Limit)
 }
 
 type DatabaseMessageRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	search searchIndex
 }
 
 func NewDatabaseMessageRepository(db *gorm.DB) (*DatabaseMessageRepository, error) {
-	err := db.AutoMigrate(&models.Message{})
+	err := db.AutoMigrate(&models.Message{}, &models.MessageEdit{}, &models.Attachment{})
 	if err != nil {
 		return nil, err
 	}
 
-	return &DatabaseMessageRepository{db}, nil
+	search, err := newSearchIndex(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DatabaseMessageRepository{db, search}, nil
+}
+
+// SearchModule names the full-text search implementation the repository uses:
+// postgres, or the fts5 or fts4 module of sqlite.
+func (d DatabaseMessageRepository) SearchModule() string {
+	return d.search.module()
 }
 
+// Create stores the message, a reply also updates reply count and last reply time of its thread root.
 func (d DatabaseMessageRepository) Create(ctx context.Context, u *models.Message) error {
-	if result := d.db.Create(&u); result.Error != nil {
-		return result.Error
+	if u.ParentID == nil {
+		if result := d.db.Create(&u); result.Error != nil {
+			return result.Error
+		}
+
+		return nil
 	}
 
-	return nil
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if result := tx.Create(&u); result.Error != nil {
+			return result.Error
+		}
+
+		result := tx.Model(&models.Message{}).Where("id = ?", *u.ParentID).Updates(map[string]interface{}{
+			"reply_count":   gorm.Expr("reply_count + 1"),
+			"last_reply_at": u.CreatedAt,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		return nil
+	})
 }
 
+// Update stores new text of the message and records its previous text in the edit history.
 func (d DatabaseMessageRepository) Update(ctx context.Context, u *models.Message) error {
-	result := d.db.Model(&u).Updates(models.Message{Author: u.Author, Message: u.Message})
-	if result.Error != nil {
-		return result.Error
-	}
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		var current models.Message
+		if result := tx.First(&current, u.ID); result.Error != nil {
+			return result.Error
+		}
 
-	return nil
+		editedAt := time.Now()
+		if u.EditedAt != nil {
+			editedAt = *u.EditedAt
+		}
+
+		edit := models.MessageEdit{MessageID: current.ID, Message: current.Message, EditedAt: editedAt}
+		if result := tx.Create(&edit); result.Error != nil {
+			return result.Error
+		}
+
+		result := tx.Model(&u).Updates(models.Message{Author: u.Author, Message: u.Message, EditedAt: &editedAt})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		return nil
+	})
 }
 
+// Delete leaves a tombstone of the message: its text, edit history and attachments are erased
+// and the row is soft deleted so replays can tell clients it is gone.
+// Files of the attachments are left for the caller to remove from the blob storage.
+// A deleted reply no longer counts towards replies of its thread root, nor towards its last reply time.
 func (d DatabaseMessageRepository) Delete(ctx context.Context, id uint) error {
-	if result := d.db.Delete(&models.Message{}, id); result.Error != nil {
-		return result.Error
-	}
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		var current models.Message
+		if result := tx.First(&current, id); result.Error != nil {
+			return result.Error
+		}
+
+		if result := tx.Where("message_id = ?", id).Delete(&models.MessageEdit{}); result.Error != nil {
+			return result.Error
+		}
+
+		if result := tx.Where("message_id = ?", id).Delete(&models.Attachment{}); result.Error != nil {
+			return result.Error
+		}
+
+		if result := tx.Model(&models.Message{}).Where("id = ?", id).Update("message", ""); result.Error != nil {
+			return result.Error
+		}
+
+		if result := tx.Delete(&models.Message{}, id); result.Error != nil {
+			return result.Error
+		}
+
+		if current.ParentID != nil {
+			lastReplyAt := tx.Model(&models.Message{}).Select("MAX(created_at)").Where("parent_id = ?", *current.ParentID)
 
-	return nil
+			result := tx.Model(&models.Message{}).Where("id = ?", *current.ParentID).Updates(map[string]interface{}{
+				"reply_count":   gorm.Expr("reply_count - 1"),
+				"last_reply_at": lastReplyAt,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		return nil
+	})
 }
 
 func (d DatabaseMessageRepository) GetAll(ctx context.Context) ([]models.Message, error) {
@@ -76,3 +245,166 @@ func (d DatabaseMessageRepository) GetNewerThan(ctx context.Context, time time.T
 
 	return messages, nil
 }
+
+// GetAllFor returns messages visible to the audience, deleted ones included as tombstones.
+func (d DatabaseMessageRepository) GetAllFor(ctx context.Context, audience Audience) ([]models.Message, error) {
+	var messages []models.Message
+
+	result := d.db.Unscoped().Scopes(audience.scope).Order("created_at").Preload("Author").Preload("Room").Preload("Recipient").Preload("Attachments").Find(&messages)
+	if result.Error != nil {
+		return messages, result.Error
+	}
+
+	return messages, nil
+}
+
+// GetNewerThanFor returns main channel messages visible to the audience that were posted, edited,
+// deleted or replied to after time. Replies themselves are left to their threads.
+// When limit is positive only the latest limit messages are returned.
+func (d DatabaseMessageRepository) GetNewerThanFor(ctx context.Context, audience Audience, time time.Time, limit int) ([]models.Message, error) {
+	var messages []models.Message
+
+	result := d.db.Unscoped().Scopes(audience.scope).Where("parent_id IS NULL").Where("created_at > ? OR edited_at > ? OR deleted_at > ? OR last_reply_at > ?", time, time, time, time).Preload("Author").Preload("Room").Preload("Recipient").Preload("Attachments").Order("created_at desc").Limit(limit).Find(&messages)
+	if result.Error != nil {
+		return messages, result.Error
+	}
+
+	reverse(messages)
+
+	return messages, nil
+}
+
+// GetPageFor returns a page of main channel messages visible to the audience, deleted ones included as tombstones.
+// Replies are only available from their threads.
+func (d DatabaseMessageRepository) GetPageFor(ctx context.Context, audience Audience, cursor Cursor) ([]models.Message, error) {
+	var messages []models.Message
+
+	result := d.db.Unscoped().Scopes(audience.scope, cursor.scope).Where("parent_id IS NULL").Preload("Author").Preload("Room").Preload("Recipient").Preload("Attachments").Find(&messages)
+	if result.Error != nil {
+		return messages, result.Error
+	}
+
+	if cursor.After == 0 {
+		reverse(messages)
+	}
+
+	return messages, nil
+}
+
+// GetThread returns a page of replies to the root message, deleted ones included as tombstones.
+func (d DatabaseMessageRepository) GetThread(ctx context.Context, rootId uint, cursor Cursor) ([]models.Message, error) {
+	var messages []models.Message
+
+	result := d.db.Unscoped().Scopes(cursor.scope).Where("parent_id = ?", rootId).Preload("Author").Preload("Room").Preload("Recipient").Preload("Attachments").Find(&messages)
+	if result.Error != nil {
+		return messages, result.Error
+	}
+
+	if cursor.After == 0 {
+		reverse(messages)
+	}
+
+	return messages, nil
+}
+
+// GetMentionsFor returns a page of messages mentioning the reader that are still visible to the audience.
+func (d DatabaseMessageRepository) GetMentionsFor(ctx context.Context, audience Audience, cursor Cursor) ([]models.Message, error) {
+	var messages []models.Message
+
+	result := d.db.Scopes(audience.scope, cursor.scope).Where("id IN (SELECT message_id FROM mentions WHERE user_uuid = ?)", audience.UserId).Preload("Author").Preload("Room").Preload("Recipient").Preload("Attachments").Find(&messages)
+	if result.Error != nil {
+		return messages, result.Error
+	}
+
+	if cursor.After == 0 {
+		reverse(messages)
+	}
+
+	return messages, nil
+}
+
+// Search returns a page of messages visible to the audience whose text contains every word of the filter query.
+func (d DatabaseMessageRepository) Search(ctx context.Context, audience Audience, filter SearchFilter, cursor Cursor) ([]models.Message, error) {
+	var messages []models.Message
+
+	terms := searchTerms(filter.Query)
+	if len(terms) == 0 {
+		return messages, nil
+	}
+
+	result := d.db.Scopes(audience.scope, filter.scope, d.search.match(terms), cursor.scope).Preload("Author").Preload("Room").Preload("Recipient").Preload("Attachments").Find(&messages)
+	if result.Error != nil {
+		return messages, result.Error
+	}
+
+	if cursor.After == 0 {
+		reverse(messages)
+	}
+
+	return messages, nil
+}
+
+// GetByClientId looks up a message by the identifier its author's client attached to it.
+// Deleted messages are returned too, their identifiers stay taken.
+func (d DatabaseMessageRepository) GetByClientId(ctx context.Context, authorId types.Uuid, clientId string) (models.Message, error) {
+	var m models.Message
+
+	result := d.db.Unscoped().Where("author_uuid = ? AND client_id = ?", authorId, clientId).Preload("Author").Preload("Room").Preload("Recipient").Preload("Attachments").First(&m)
+	if result.Error != nil {
+		return m, result.Error
+	}
+
+	return m, nil
+}
+
+func (d DatabaseMessageRepository) GetById(ctx context.Context, id uint) (models.Message, error) {
+	var m models.Message
+
+	result := d.db.Preload("Author").Preload("Room").Preload("Recipient").Preload("Attachments").First(&m, id)
+	if result.Error != nil {
+		return m, result.Error
+	}
+
+	return m, nil
+}
+
+// GetEdits returns edit history of the message, oldest first.
+func (d DatabaseMessageRepository) GetEdits(ctx context.Context, messageId uint) ([]models.MessageEdit, error) {
+	var edits []models.MessageEdit
+
+	result := d.db.Where("message_id = ?", messageId).Order("edited_at").Find(&edits)
+	if result.Error != nil {
+		return edits, result.Error
+	}
+
+	return edits, nil
+}
+
+// CountUnreadFor counts messages the reader has not read yet, per conversation.
+// Only conversations with unread messages are returned.
+func (d DatabaseMessageRepository) CountUnreadFor(ctx context.Context, audience Audience) ([]UnreadCount, error) {
+	var counts []UnreadCount
+
+	result := d.db.Model(&models.Message{}).
+		Scopes(audience.scope).
+		Where("messages.author_uuid <> ?", audience.UserId).
+		Where(
+			"messages.id > COALESCE((SELECT read_markers.message_id FROM read_markers WHERE read_markers.user_uuid = ? AND read_markers.room_id = COALESCE(messages.room_id, 0) AND read_markers.peer_uuid = "+conversationPeer+"), 0)",
+			audience.UserId,
+		).
+		Select("COALESCE(messages.room_id, 0) AS room_id, " + conversationPeer + " AS peer_uuid, COUNT(*) AS count").
+		Group("COALESCE(messages.room_id, 0), " + conversationPeer).
+		Order("room_id, peer_uuid").
+		Scan(&counts)
+	if result.Error != nil {
+		return counts, result.Error
+	}
+
+	return counts, nil
+}
+
+func reverse(messages []models.Message) {
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+}
@@ -0,0 +1,199 @@
+package message
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+)
+
+// SearchFilter narrows a full-text search down. Query words must all be present in the message text,
+// the remaining fields are only applied when set. Since is inclusive, Until is exclusive.
+type SearchFilter struct {
+	Query      string
+	AuthorUuid types.Uuid
+	RoomID     uint
+	Since      time.Time
+	Until      time.Time
+}
+
+func (f SearchFilter) scope(db *gorm.DB) *gorm.DB {
+	if f.AuthorUuid != "" {
+		db = db.Where("author_uuid = ?", f.AuthorUuid)
+	}
+
+	if f.RoomID > 0 {
+		db = db.Where("room_id = ?", f.RoomID)
+	}
+
+	if !f.Since.IsZero() {
+		db = db.Where("created_at >= ?", f.Since)
+	}
+
+	if !f.Until.IsZero() {
+		db = db.Where("created_at < ?", f.Until)
+	}
+
+	return db
+}
+
+// searchTerms splits the query into lower case words. Anything but letters and digits separates words,
+// so terms never carry operators of the underlying full-text query syntax.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchIndex is the full-text index of message texts in the database the repository is connected to.
+// Both implementations match whole words case-insensitively, without stemming or folding diacritics,
+// so results do not depend on the database.
+type searchIndex interface {
+	migrate(db *gorm.DB) error
+	match(terms []string) func(db *gorm.DB) *gorm.DB
+	// module names the full-text search implementation in use.
+	module() string
+}
+
+func newSearchIndex(db *gorm.DB) (searchIndex, error) {
+	var index searchIndex
+
+	switch db.Dialector.Name() {
+	case "postgres":
+		index = postgresSearchIndex{}
+	case "sqlite":
+		index = &sqliteSearchIndex{modules: []string{"fts5", "fts4"}}
+	default:
+		return nil, fmt.Errorf("full-text search is not supported by %s", db.Dialector.Name())
+	}
+
+	if err := index.migrate(db); err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// postgresSearchIndex matches messages with a GIN index over their tsvector.
+type postgresSearchIndex struct{}
+
+func (postgresSearchIndex) migrate(db *gorm.DB) error {
+	result := db.Exec("CREATE INDEX IF NOT EXISTS idx_messages_search ON messages USING GIN (to_tsvector('simple', message))")
+
+	return result.Error
+}
+
+func (postgresSearchIndex) match(terms []string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("to_tsvector('simple', message) @@ plainto_tsquery('simple', ?)", strings.Join(terms, " "))
+	}
+}
+
+func (postgresSearchIndex) module() string {
+	return "postgres"
+}
+
+// sqliteSearchIndex matches messages with an external content FTS5 table kept in sync by triggers.
+// FTS5 is only compiled in with the sqlite_fts5 build tag, builds without it fall back to FTS4,
+// which is always compiled in and accepts the same queries.
+type sqliteSearchIndex struct {
+	// modules are tried in order until one is compiled in.
+	modules []string
+	used    string
+}
+
+var sqliteSearchTables = map[string]string{
+	"fts5": "CREATE VIRTUAL TABLE messages_fts USING fts5(message, content='messages', content_rowid='id', tokenize='unicode61 remove_diacritics 0')",
+	"fts4": `CREATE VIRTUAL TABLE messages_fts USING fts4(message, content='messages', tokenize=unicode61 "remove_diacritics=0")`,
+}
+
+var sqliteSearchTriggers = map[string][]string{
+	"fts5": {
+		`CREATE TRIGGER IF NOT EXISTS messages_fts_insert AFTER INSERT ON messages BEGIN
+			INSERT INTO messages_fts(rowid, message) VALUES (new.id, new.message);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS messages_fts_delete AFTER DELETE ON messages BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, message) VALUES ('delete', old.id, old.message);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS messages_fts_update AFTER UPDATE OF message ON messages BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, message) VALUES ('delete', old.id, old.message);
+			INSERT INTO messages_fts(rowid, message) VALUES (new.id, new.message);
+		END`,
+	},
+	"fts4": {
+		`CREATE TRIGGER IF NOT EXISTS messages_fts_insert AFTER INSERT ON messages BEGIN
+			INSERT INTO messages_fts(docid, message) VALUES (new.id, new.message);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS messages_fts_delete BEFORE DELETE ON messages BEGIN
+			DELETE FROM messages_fts WHERE docid = old.id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS messages_fts_before_update BEFORE UPDATE OF message ON messages BEGIN
+			DELETE FROM messages_fts WHERE docid = old.id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS messages_fts_update AFTER UPDATE OF message ON messages BEGIN
+			INSERT INTO messages_fts(docid, message) VALUES (new.id, new.message);
+		END`,
+	},
+}
+
+func (s *sqliteSearchIndex) migrate(db *gorm.DB) error {
+	if db.Migrator().HasTable("messages_fts") {
+		var definition string
+		if result := db.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'messages_fts'").Scan(&definition); result.Error != nil {
+			return result.Error
+		}
+
+		s.used = "fts4"
+		if strings.Contains(strings.ToLower(definition), "using fts5") {
+			s.used = "fts5"
+		}
+
+		return nil
+	}
+
+	for _, module := range s.modules {
+		result := db.Exec(sqliteSearchTables[module])
+		if result.Error == nil {
+			s.used = module
+			break
+		}
+
+		if !strings.Contains(result.Error.Error(), "no such module") {
+			return result.Error
+		}
+	}
+
+	if s.used == "" {
+		return fmt.Errorf("none of the full-text search modules %v is compiled into sqlite", s.modules)
+	}
+
+	for _, trigger := range sqliteSearchTriggers[s.used] {
+		if result := db.Exec(trigger); result.Error != nil {
+			return result.Error
+		}
+	}
+
+	// Messages stored before the index existed are indexed once.
+	result := db.Exec("INSERT INTO messages_fts(messages_fts) VALUES ('rebuild')")
+
+	return result.Error
+}
+
+func (*sqliteSearchIndex) match(terms []string) func(db *gorm.DB) *gorm.DB {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, `"`+term+`"`)
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("id IN (SELECT rowid FROM messages_fts WHERE messages_fts MATCH ?)", strings.Join(quoted, " "))
+	}
+}
+
+func (s *sqliteSearchIndex) module() string {
+	return s.used
+}
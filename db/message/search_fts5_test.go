@@ -0,0 +1,14 @@
+//go:build sqlite_fts5
+// +build sqlite_fts5
+
+package message
+
+import "testing"
+
+func TestSqliteSearchIndex_FTS5(t *testing.T) {
+	_, index := newSqliteSearchDB(t, "fts5", "fts4")
+
+	if index.module() != "fts5" {
+		t.Errorf("builds with the sqlite_fts5 tag should use fts5, got %q", index.module())
+	}
+}
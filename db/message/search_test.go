@@ -0,0 +1,96 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+// newSqliteSearchDB opens a private in-memory database with the messages table and a search index
+// built with the first compiled in of modules.
+func newSqliteSearchDB(t *testing.T, modules ...string) (*gorm.DB, *sqliteSearchIndex) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.Message{}, &models.MessageEdit{}, &models.Attachment{}); err != nil {
+		t.Fatalf("could not migrate messages: %v", err)
+	}
+
+	index := &sqliteSearchIndex{modules: modules}
+	if err := index.migrate(db); err != nil {
+		t.Fatalf("could not migrate search index: %v", err)
+	}
+
+	return db, index
+}
+
+func TestSqliteSearchIndex_FTS4(t *testing.T) {
+	db, index := newSqliteSearchDB(t, "fts4")
+
+	if index.module() != "fts4" {
+		t.Fatalf("expected fts4 module, got %q", index.module())
+	}
+
+	repo := DatabaseMessageRepository{db: db, search: index}
+
+	posted := []*models.Message{
+		{AuthorUuid: "author", Message: "Lunch at noon?"},
+		{AuthorUuid: "author", Message: "launch at noon"},
+		{AuthorUuid: "author", Message: "secret lunch plans"},
+	}
+	for _, m := range posted {
+		if err := repo.Create(context.Background(), m); err != nil {
+			t.Fatalf("could not post message: %v", err)
+		}
+	}
+
+	edited := &models.Message{Message: "no more lunch talk"}
+	edited.ID = posted[1].ID
+	if err := repo.Update(context.Background(), edited); err != nil {
+		t.Fatalf("could not edit message: %v", err)
+	}
+
+	if err := repo.Delete(context.Background(), posted[2].ID); err != nil {
+		t.Fatalf("could not delete message: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		terms []string
+		want  []string
+	}{
+		{"Words in any case", []string{"lunch"}, []string{"Lunch at noon?", "no more lunch talk"}},
+		{"Every word must match", []string{"noon", "lunch"}, []string{"Lunch at noon?"}},
+		{"Old text of edited message", []string{"launch"}, []string{}},
+		{"Deleted message", []string{"secret"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			if result := db.Unscoped().Model(&models.Message{}).Scopes(index.match(tt.terms)).Order("id").Pluck("message", &got); result.Error != nil {
+				t.Fatalf("could not search messages: %v", result.Error)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	reopened := &sqliteSearchIndex{modules: []string{"fts5", "fts4"}}
+	if err := reopened.migrate(db); err != nil {
+		t.Fatalf("could not migrate existing search index: %v", err)
+	}
+
+	if reopened.module() != "fts4" {
+		t.Errorf("existing index should keep its module, got %q", reopened.module())
+	}
+}
@@ -0,0 +1,89 @@
+package reaction
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+type ReactionRepository interface {
+	Add(ctx context.Context, r *models.Reaction) (bool, error)
+	Remove(ctx context.Context, r *models.Reaction) (bool, error)
+	DeleteByMessage(ctx context.Context, messageId uint) error
+	CountFor(ctx context.Context, messageIds []uint) (map[uint][]models.ReactionCount, error)
+}
+
+type DatabaseReactionRepository struct {
+	db *gorm.DB
+}
+
+func NewDatabaseReactionRepository(db *gorm.DB) (*DatabaseReactionRepository, error) {
+	err := db.AutoMigrate(&models.Reaction{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DatabaseReactionRepository{db}, nil
+}
+
+// Add stores the reaction and tells whether the user had not reacted with the emoji yet.
+func (d DatabaseReactionRepository) Add(ctx context.Context, r *models.Reaction) (bool, error) {
+	result := d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(r)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// Remove deletes the reaction and tells whether it existed.
+func (d DatabaseReactionRepository) Remove(ctx context.Context, r *models.Reaction) (bool, error) {
+	result := d.db.Delete(&models.Reaction{}, "message_id = ? AND user_uuid = ? AND emoji = ?", r.MessageID, r.UserUuid, r.Emoji)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (d DatabaseReactionRepository) DeleteByMessage(ctx context.Context, messageId uint) error {
+	if result := d.db.Delete(&models.Reaction{}, "message_id = ?", messageId); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// CountFor aggregates reactions of the messages per emoji, in order of the first reaction with each emoji.
+// Messages without reactions are left out.
+func (d DatabaseReactionRepository) CountFor(ctx context.Context, messageIds []uint) (map[uint][]models.ReactionCount, error) {
+	counts := make(map[uint][]models.ReactionCount)
+	if len(messageIds) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		MessageID uint
+		Emoji     string
+		Count     int
+	}
+
+	result := d.db.Model(&models.Reaction{}).
+		Select("message_id, emoji, COUNT(*) AS count").
+		Where("message_id IN ?", messageIds).
+		Group("message_id, emoji").
+		Order("message_id, MIN(created_at), emoji").
+		Scan(&rows)
+	if result.Error != nil {
+		return counts, result.Error
+	}
+
+	for _, row := range rows {
+		counts[row.MessageID] = append(counts[row.MessageID], models.ReactionCount{Emoji: row.Emoji, Count: row.Count})
+	}
+
+	return counts, nil
+}
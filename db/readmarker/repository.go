@@ -0,0 +1,76 @@
+package readmarker
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+type ReadMarkerRepository interface {
+	Mark(ctx context.Context, m *models.ReadMarker) (bool, error)
+	GetByUser(ctx context.Context, userId types.Uuid) ([]models.ReadMarker, error)
+}
+
+type DatabaseReadMarkerRepository struct {
+	db *gorm.DB
+}
+
+func NewDatabaseReadMarkerRepository(db *gorm.DB) (*DatabaseReadMarkerRepository, error) {
+	err := db.AutoMigrate(&models.ReadMarker{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DatabaseReadMarkerRepository{db}, nil
+}
+
+// Mark moves the marker of the conversation forward and tells whether it moved.
+// A marker is never moved back, m is then updated with the stored one.
+func (d DatabaseReadMarkerRepository) Mark(ctx context.Context, m *models.ReadMarker) (bool, error) {
+	moved := false
+
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		stored := models.ReadMarker{}
+
+		conversation := tx.Where("user_uuid = ? AND room_id = ? AND peer_uuid = ?", m.UserUuid, m.RoomID, m.PeerUuid)
+
+		result := conversation.Session(&gorm.Session{}).First(&stored)
+		switch {
+		case errors.Is(result.Error, gorm.ErrRecordNotFound):
+			result = tx.Create(m)
+		case result.Error != nil:
+			return result.Error
+		case stored.MessageID >= m.MessageID:
+			*m = stored
+
+			return nil
+		default:
+			result = conversation.Session(&gorm.Session{}).Model(&models.ReadMarker{}).Updates(map[string]interface{}{"message_id": m.MessageID, "read_at": m.ReadAt})
+		}
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		moved = true
+
+		return nil
+	})
+
+	return moved, err
+}
+
+func (d DatabaseReadMarkerRepository) GetByUser(ctx context.Context, userId types.Uuid) ([]models.ReadMarker, error) {
+	var markers []models.ReadMarker
+
+	result := d.db.Where("user_uuid = ?", userId).Find(&markers)
+	if result.Error != nil {
+		return markers, result.Error
+	}
+
+	return markers, nil
+}
@@ -0,0 +1,172 @@
+package room
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+type RoomRepository interface {
+	Create(ctx context.Context, r *models.Room) error
+	Delete(ctx context.Context, id uint) error
+	GetById(ctx context.Context, id uint) (models.Room, error)
+	GetByName(ctx context.Context, name string) (models.Room, error)
+	GetAll(ctx context.Context) ([]models.Room, error)
+	GetByMember(ctx context.Context, userId types.Uuid) ([]models.Room, error)
+	AddMember(ctx context.Context, roomId uint, userId types.Uuid) error
+	RemoveMember(ctx context.Context, roomId uint, userId types.Uuid) error
+	GetMember(ctx context.Context, roomId uint, userId types.Uuid) (models.RoomMember, error)
+	SetTopic(ctx context.Context, roomId uint, topic string) error
+	Mute(ctx context.Context, roomId uint, userId types.Uuid, until *time.Time) error
+}
+
+type DatabaseRoomRepository struct {
+	db *gorm.DB
+}
+
+func NewDatabaseRoomRepository(db *gorm.DB) (*DatabaseRoomRepository, error) {
+	err := db.AutoMigrate(&models.Room{}, &models.RoomMember{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DatabaseRoomRepository{db}, nil
+}
+
+// Create stores the room, its creator becomes its first member.
+func (d DatabaseRoomRepository) Create(ctx context.Context, r *models.Room) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if result := tx.Create(&r); result.Error != nil {
+			return result.Error
+		}
+
+		if r.CreatedBy == nil {
+			return nil
+		}
+
+		member := models.RoomMember{RoomID: r.ID, UserUuid: *r.CreatedBy, JoinedAt: time.Now()}
+		if result := tx.Create(&member); result.Error != nil {
+			return result.Error
+		}
+
+		return nil
+	})
+}
+
+func (d DatabaseRoomRepository) Delete(ctx context.Context, id uint) error {
+	if result := d.db.Delete(&models.RoomMember{}, "room_id = ?", id); result.Error != nil {
+		return result.Error
+	}
+
+	if result := d.db.Delete(&models.Room{}, id); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (d DatabaseRoomRepository) GetById(ctx context.Context, id uint) (models.Room, error) {
+	r := models.Room{}
+
+	result := d.db.First(&r, id)
+	if result.Error != nil {
+		return models.Room{}, result.Error
+	}
+
+	return r, nil
+}
+
+func (d DatabaseRoomRepository) GetByName(ctx context.Context, name string) (models.Room, error) {
+	r := models.Room{}
+
+	result := d.db.Where("name = ?", name).First(&r)
+	if result.Error != nil {
+		return models.Room{}, result.Error
+	}
+
+	return r, nil
+}
+
+func (d DatabaseRoomRepository) GetAll(ctx context.Context) ([]models.Room, error) {
+	var rooms []models.Room
+
+	result := d.db.Order("name").Find(&rooms)
+	if result.Error != nil {
+		return rooms, result.Error
+	}
+
+	return rooms, nil
+}
+
+func (d DatabaseRoomRepository) GetByMember(ctx context.Context, userId types.Uuid) ([]models.Room, error) {
+	var rooms []models.Room
+
+	result := d.db.
+		Joins("JOIN room_members ON room_members.room_id = rooms.id").
+		Where("room_members.user_uuid = ?", userId).
+		Order("rooms.name").
+		Find(&rooms)
+	if result.Error != nil {
+		return rooms, result.Error
+	}
+
+	return rooms, nil
+}
+
+func (d DatabaseRoomRepository) AddMember(ctx context.Context, roomId uint, userId types.Uuid) error {
+	member := models.RoomMember{RoomID: roomId, UserUuid: userId, JoinedAt: time.Now()}
+
+	if result := d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&member); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (d DatabaseRoomRepository) RemoveMember(ctx context.Context, roomId uint, userId types.Uuid) error {
+	result := d.db.Delete(&models.RoomMember{}, "room_id = ? AND user_uuid = ?", roomId, userId)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (d DatabaseRoomRepository) GetMember(ctx context.Context, roomId uint, userId types.Uuid) (models.RoomMember, error) {
+	m := models.RoomMember{}
+
+	result := d.db.Where("room_id = ? AND user_uuid = ?", roomId, userId).First(&m)
+	if result.Error != nil {
+		return models.RoomMember{}, result.Error
+	}
+
+	return m, nil
+}
+
+func (d DatabaseRoomRepository) SetTopic(ctx context.Context, roomId uint, topic string) error {
+	result := d.db.Model(&models.Room{}).Where("id = ?", roomId).Update("topic", topic)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// Mute keeps the member from posting to the room until the given time, nil lifts the mute.
+func (d DatabaseRoomRepository) Mute(ctx context.Context, roomId uint, userId types.Uuid, until *time.Time) error {
+	result := d.db.Model(&models.RoomMember{}).Where("room_id = ? AND user_uuid = ?", roomId, userId).Update("muted_until", until)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
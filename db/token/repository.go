@@ -2,34 +2,64 @@ package token
 
 import (
 	"context"
+	"errors"
 	"gorm.io/gorm"
+	"time"
 
 	"github.com/id-tarzanych/lets-go-chat/internal/types"
 	"github.com/id-tarzanych/lets-go-chat/models"
 )
 
+// legacyTokenColumn held plaintext tokens before only their hashes were stored.
+const legacyTokenColumn = "token"
+
+var (
+	ErrNoTokenSecret = errors.New("token secret is not configured")
+	ErrAlreadyUsed   = errors.New("token was already used")
+)
+
+// TokenRepository stores tokens by their keyed hash. Tokens are passed in plaintext and hashed by the repository,
+// tokens read from it only carry TokenHash.
 type TokenRepository interface {
 	Create(ctx context.Context, u *models.Token) error
 	Delete(ctx context.Context, token string) error
 	Get(ctx context.Context, token string) (models.Token, error)
 	GetByUserId(ctx context.Context, userId types.Uuid) ([]models.Token, error)
 	GetAll(ctx context.Context) ([]models.Token, error)
+	// MarkUsed records that the refresh token was exchanged at the given time. It fails with ErrAlreadyUsed
+	// when the token was already used, so concurrent exchanges of the same token can not both succeed.
+	MarkUsed(ctx context.Context, token string, at time.Time) error
+	DeleteFamily(ctx context.Context, familyId string) error
+	DeleteByUserId(ctx context.Context, userId types.Uuid) error
 }
 
 type DatabaseTokenRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	secret string
 }
 
-func NewDatabaseTokenRepository(db *gorm.DB) (*DatabaseTokenRepository, error) {
+// NewDatabaseTokenRepository hashes tokens with secret. Plaintext tokens stored by earlier versions are hashed
+// and their column is dropped, so tokens issued before the upgrade stay valid.
+func NewDatabaseTokenRepository(db *gorm.DB, secret string) (*DatabaseTokenRepository, error) {
+	if secret == "" {
+		return nil, ErrNoTokenSecret
+	}
+
 	err := db.AutoMigrate(&models.Token{})
 	if err != nil {
 		return nil, err
 	}
 
-	return &DatabaseTokenRepository{db}, nil
+	if err := migratePlaintextTokens(db, secret); err != nil {
+		return nil, err
+	}
+
+	return &DatabaseTokenRepository{db, secret}, nil
 }
 
 func (d DatabaseTokenRepository) Create(ctx context.Context, t *models.Token) error {
+	t.TokenHash = models.HashToken(d.secret, t.Token)
+
 	if result := d.db.Create(&t); result.Error != nil {
 		return result.Error
 	}
@@ -38,7 +68,7 @@ func (d DatabaseTokenRepository) Create(ctx context.Context, t *models.Token) er
 }
 
 func (d DatabaseTokenRepository) Delete(ctx context.Context, token string) error {
-	if result := d.db.Delete(&models.Token{}, "token = ?", token); result.Error != nil {
+	if result := d.db.Delete(&models.Token{}, "token_hash = ?", models.HashToken(d.secret, token)); result.Error != nil {
 		return result.Error
 	}
 
@@ -48,7 +78,7 @@ func (d DatabaseTokenRepository) Delete(ctx context.Context, token string) error
 func (d DatabaseTokenRepository) Get(ctx context.Context, token string) (models.Token, error) {
 	t := models.Token{}
 
-	result := d.db.First(&t, "token = ?", token)
+	result := d.db.First(&t, "token_hash = ?", models.HashToken(d.secret, token))
 	if result.Error != nil {
 		return models.Token{}, result.Error
 	}
@@ -77,3 +107,65 @@ func (d DatabaseTokenRepository) GetAll(context.Context) ([]models.Token, error)
 
 	return tokens, nil
 }
+
+func (d DatabaseTokenRepository) MarkUsed(ctx context.Context, token string, at time.Time) error {
+	result := d.db.Model(&models.Token{}).
+		Where("token_hash = ? AND used_at IS NULL", models.HashToken(d.secret, token)).
+		Update("used_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrAlreadyUsed
+	}
+
+	return nil
+}
+
+func (d DatabaseTokenRepository) DeleteFamily(ctx context.Context, familyId string) error {
+	if result := d.db.Delete(&models.Token{}, "family_id = ?", familyId); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (d DatabaseTokenRepository) DeleteByUserId(ctx context.Context, userId types.Uuid) error {
+	if result := d.db.Delete(&models.Token{}, "user_id = ?", userId); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// migratePlaintextTokens hashes the tokens of the legacy column and drops it.
+func migratePlaintextTokens(db *gorm.DB, secret string) error {
+	if !db.Migrator().HasColumn(&models.Token{}, legacyTokenColumn) {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var legacy []struct {
+			ID    uint
+			Token string
+		}
+
+		result := tx.Unscoped().Model(&models.Token{}).
+			Select("id, " + legacyTokenColumn).
+			Where("token_hash IS NULL OR token_hash = ''").
+			Scan(&legacy)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		for _, t := range legacy {
+			result := tx.Unscoped().Model(&models.Token{}).Where("id = ?", t.ID).Update("token_hash", models.HashToken(secret, t.Token))
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		return tx.Migrator().DropColumn(&models.Token{}, legacyTokenColumn)
+	})
+}
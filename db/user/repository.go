@@ -2,6 +2,8 @@ package user
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -10,6 +12,9 @@ import (
 	"github.com/id-tarzanych/lets-go-chat/models"
 )
 
+// ErrUserNameTaken is returned when a user is created or renamed with the name of another user.
+var ErrUserNameTaken = errors.New("user name is taken")
+
 type UserRepository interface {
 	Create(ctx context.Context, u *models.User) error
 	Update(ctx context.Context, u *models.User) error
@@ -18,6 +23,9 @@ type UserRepository interface {
 	GetByUserName(ctx context.Context, name string) (models.User, error)
 	GetAll(ctx context.Context) ([]models.User, error)
 	UpdateLastActivity(ctx context.Context, u *models.User, lastActivity time.Time) error
+	UpdatePassword(ctx context.Context, u *models.User) error
+	UpdateUserName(ctx context.Context, u *models.User, name string) error
+	SetAdmins(ctx context.Context, userNames []string) error
 }
 
 type DatabaseUserRepository struct {
@@ -35,6 +43,10 @@ func NewDatabaseUserRepository(db *gorm.DB) (*DatabaseUserRepository, error) {
 
 func (d DatabaseUserRepository) Create(ctx context.Context, u *models.User) error {
 	if result := d.db.Create(&u); result.Error != nil {
+		if isUniqueViolation(result.Error) {
+			return ErrUserNameTaken
+		}
+
 		return result.Error
 	}
 
@@ -91,11 +103,78 @@ func (d DatabaseUserRepository) GetAll(ctx context.Context) ([]models.User, erro
 	return users, nil
 }
 
+// UpdateLastActivity moves the replay cursor of the user forward, an older time leaves it where it is.
+// Only its column is written, chat sessions hold copies of the user that may be outdated by the time
+// they report activity, and concurrent sessions may report it out of order.
 func (d DatabaseUserRepository) UpdateLastActivity(ctx context.Context, u *models.User, lastActivity time.Time) error {
-	u.LastActivity = lastActivity
-	if err := d.Update(ctx, u); err != nil {
-		return err
+	result := d.db.Model(&models.User{}).
+		Where("id = ? AND (last_activity IS NULL OR last_activity < ?)", u.ID, lastActivity).
+		Update("last_activity", lastActivity)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// UpdatePassword stores the password hash of the user, leaving its other columns alone.
+func (d DatabaseUserRepository) UpdatePassword(ctx context.Context, u *models.User) error {
+	result := d.db.Model(&models.User{}).Where("id = ?", u.ID).Update("password", u.PasswordHash)
+	if result.Error != nil {
+		return result.Error
 	}
 
 	return nil
 }
+
+// UpdateUserName renames the user, leaving its other columns alone.
+// Names are unique, ErrUserNameTaken is returned when another user has the name.
+func (d DatabaseUserRepository) UpdateUserName(ctx context.Context, u *models.User, name string) error {
+	result := d.db.Model(&models.User{}).Where("id = ?", u.ID).Update("username", name)
+	if result.Error != nil {
+		if isUniqueViolation(result.Error) {
+			return ErrUserNameTaken
+		}
+
+		return result.Error
+	}
+
+	return nil
+}
+
+// SetAdmins makes the users with the given names admins and every other user a regular one.
+// Bots can not be admins.
+func (d DatabaseUserRepository) SetAdmins(ctx context.Context, userNames []string) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).Where("admin = ?", true).Update("admin", false)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if len(userNames) == 0 {
+			return nil
+		}
+
+		result = tx.Model(&models.User{}).Where("username IN ? AND bot = ?", userNames, false).Update("admin", true)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		return nil
+	})
+}
+
+// sqlStateError is implemented by the errors of the PostgreSQL driver.
+type sqlStateError interface {
+	SQLState() string
+}
+
+// isUniqueViolation reports whether err was caused by a unique index, SQLite only tells so in the message.
+func isUniqueViolation(err error) bool {
+	var stateErr sqlStateError
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState() == "23505"
+	}
+
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
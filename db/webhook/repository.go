@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, w *models.Webhook) error
+	Delete(ctx context.Context, id uint) error
+	GetById(ctx context.Context, id uint) (models.Webhook, error)
+	GetAll(ctx context.Context) ([]models.Webhook, error)
+	CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	UpdateDelivery(ctx context.Context, d *models.WebhookDelivery) error
+	GetDeliveries(ctx context.Context, webhookId uint, limit int) ([]models.WebhookDelivery, error)
+	GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+	CreateIncoming(ctx context.Context, w *models.IncomingWebhook) error
+	DeleteIncoming(ctx context.Context, id uint) error
+	GetIncomingById(ctx context.Context, id uint) (models.IncomingWebhook, error)
+	GetIncomingByToken(ctx context.Context, token string) (models.IncomingWebhook, error)
+	GetAllIncoming(ctx context.Context) ([]models.IncomingWebhook, error)
+}
+
+type DatabaseWebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewDatabaseWebhookRepository(db *gorm.DB) (*DatabaseWebhookRepository, error) {
+	err := db.AutoMigrate(&models.Webhook{}, &models.WebhookDelivery{}, &models.IncomingWebhook{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DatabaseWebhookRepository{db}, nil
+}
+
+func (d DatabaseWebhookRepository) Create(ctx context.Context, w *models.Webhook) error {
+	if result := d.db.Create(w); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// Delete removes the webhook together with its delivery log.
+func (d DatabaseWebhookRepository) Delete(ctx context.Context, id uint) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if result := tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}); result.Error != nil {
+			return result.Error
+		}
+
+		result := tx.Delete(&models.Webhook{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
+
+func (d DatabaseWebhookRepository) GetById(ctx context.Context, id uint) (models.Webhook, error) {
+	var w models.Webhook
+
+	result := d.db.First(&w, id)
+	if result.Error != nil {
+		return models.Webhook{}, result.Error
+	}
+
+	return w, nil
+}
+
+func (d DatabaseWebhookRepository) GetAll(ctx context.Context) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+
+	result := d.db.Order("id").Find(&webhooks)
+	if result.Error != nil {
+		return webhooks, result.Error
+	}
+
+	return webhooks, nil
+}
+
+func (d DatabaseWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if result := d.db.Create(&deliveries); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (d DatabaseWebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if result := d.db.Save(delivery); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// GetDeliveries returns the latest deliveries of the webhook, newest first.
+func (d DatabaseWebhookRepository) GetDeliveries(ctx context.Context, webhookId uint, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+
+	result := d.db.Where("webhook_id = ?", webhookId).Order("id DESC").Limit(limit).Find(&deliveries)
+	if result.Error != nil {
+		return deliveries, result.Error
+	}
+
+	return deliveries, nil
+}
+
+// GetDueDeliveries returns pending deliveries whose next attempt is due, oldest first.
+func (d DatabaseWebhookRepository) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+
+	result := d.db.
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Find(&deliveries)
+	if result.Error != nil {
+		return deliveries, result.Error
+	}
+
+	return deliveries, nil
+}
+
+func (d DatabaseWebhookRepository) CreateIncoming(ctx context.Context, w *models.IncomingWebhook) error {
+	if result := d.db.Create(w); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (d DatabaseWebhookRepository) DeleteIncoming(ctx context.Context, id uint) error {
+	result := d.db.Delete(&models.IncomingWebhook{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (d DatabaseWebhookRepository) GetIncomingById(ctx context.Context, id uint) (models.IncomingWebhook, error) {
+	var w models.IncomingWebhook
+
+	result := d.db.First(&w, id)
+	if result.Error != nil {
+		return models.IncomingWebhook{}, result.Error
+	}
+
+	return w, nil
+}
+
+// GetIncomingByToken looks the webhook up by the hash of its token.
+func (d DatabaseWebhookRepository) GetIncomingByToken(ctx context.Context, token string) (models.IncomingWebhook, error) {
+	var w models.IncomingWebhook
+
+	result := d.db.Where("token_hash = ?", models.HashAPIKey(token)).First(&w)
+	if result.Error != nil {
+		return models.IncomingWebhook{}, result.Error
+	}
+
+	return w, nil
+}
+
+func (d DatabaseWebhookRepository) GetAllIncoming(ctx context.Context) ([]models.IncomingWebhook, error) {
+	var webhooks []models.IncomingWebhook
+
+	result := d.db.Order("id").Find(&webhooks)
+	if result.Error != nil {
+		return webhooks, result.Error
+	}
+
+	return webhooks, nil
+}
@@ -12,19 +12,26 @@ func main() {
 	pwd := generators.RandomString(16)
 	fmt.Println("Password:", pwd)
 
-	hash, _ := hasher.HashPassword(pwd)
+	hash, _ := hasher.Default.Hash(pwd)
 	fmt.Println()
-	fmt.Printf("hasher.HashPassword(\"%s\") = \"%s\"\n", pwd, hash)
+	fmt.Printf("hasher.Default.Hash(\"%s\") = \"%s\"\n", pwd, hash)
 	fmt.Println()
 
 	fmt.Println("Generating random password...")
 	pwd = generators.RandomString(16)
-	hash, _ = hasher.HashPassword(pwd)
+	hash, _ = hasher.Default.Hash(pwd)
 
 	fmt.Println("Checking correct and incorrect hashes")
-	fmt.Printf("hasher.CheckPasswordHash(\"%s\", \"%s\") = %v\n", pwd, hash, hasher.CheckPasswordHash(pwd, hash))
+	ok, err := hasher.Verify(pwd, hash)
+	fmt.Printf("hasher.Verify(\"%s\", \"%s\") = %v, %v\n", pwd, hash, ok, err)
 
 	// Modify hash and try once more.
-	hash = hash[16:32]
-	fmt.Printf("hasher.CheckPasswordHash(\"%s\", \"%s\") = %v\n", pwd, hash, hasher.CheckPasswordHash(pwd, hash))
+	hash = hash[:len(hash)-16]
+	ok, err = hasher.Verify(pwd, hash)
+	fmt.Printf("hasher.Verify(\"%s\", \"%s\") = %v, %v\n", pwd, hash, ok, err)
+
+	// Hashes of other algorithms are verified as well.
+	hash, _ = hasher.NewScrypt(hasher.DefaultScryptParams).Hash(pwd)
+	ok, err = hasher.Verify(pwd, hash)
+	fmt.Printf("hasher.Verify(\"%s\", \"%s\") = %v, %v\n", pwd, hash, ok, err)
 }
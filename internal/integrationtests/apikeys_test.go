@@ -0,0 +1,71 @@
+package integrationtests
+
+import (
+	"testing"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/testdb"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func Test_APIKeys(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	if _, err := testdb.SeedUsers(a.DB()); err != nil {
+		t.Fatal("could not seed users")
+	}
+
+	bot := models.NewBot("robot", "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+	if err := a.UserRepo().Create(nil, bot); err != nil {
+		t.Fatalf("could not create bot: %v", err)
+	}
+
+	stored, err := a.UserRepo().GetByUserName(nil, "robot")
+	if err != nil || !stored.Bot || stored.OwnerId == nil || *stored.OwnerId != "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35" {
+		t.Errorf("bot was not stored with its owner, got %v (%v)", stored, err)
+	}
+
+	first, second := models.NewAPIKey("first-key", bot.ID), models.NewAPIKey("second-key", bot.ID)
+	for _, k := range []*models.APIKey{first, second} {
+		if err := a.APIKeyRepo().Create(nil, k); err != nil {
+			t.Fatalf("could not create API key: %v", err)
+		}
+	}
+
+	if first.KeyHash == "first-key" {
+		t.Error("API key should not be stored in plain text")
+	}
+
+	got, err := a.APIKeyRepo().GetByKey(nil, "second-key")
+	if err != nil || got.ID != second.ID || got.UserId != bot.ID {
+		t.Errorf("expected key %d of the bot, got %v (%v)", second.ID, got, err)
+	}
+
+	if _, err := a.APIKeyRepo().GetByKey(nil, second.KeyHash); err == nil {
+		t.Error("lookup by the stored hash should fail")
+	}
+
+	if err := a.APIKeyRepo().Delete(nil, first.ID); err != nil {
+		t.Fatalf("could not delete API key: %v", err)
+	}
+
+	if _, err := a.APIKeyRepo().GetByKey(nil, "first-key"); err == nil {
+		t.Error("deleted key should not be found")
+	}
+
+	keys, err := a.APIKeyRepo().GetByUserId(nil, bot.ID)
+	if err != nil || len(keys) != 1 || keys[0].ID != second.ID {
+		t.Errorf("expected only key %d to be left, got %v (%v)", second.ID, keys, err)
+	}
+
+	if err := a.APIKeyRepo().DeleteByUserId(nil, bot.ID); err != nil {
+		t.Fatalf("could not delete API keys of the bot: %v", err)
+	}
+
+	if keys, err := a.APIKeyRepo().GetByUserId(nil, bot.ID); err != nil || len(keys) != 0 {
+		t.Errorf("expected no keys to be left, got %v (%v)", keys, err)
+	}
+}
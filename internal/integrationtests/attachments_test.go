@@ -0,0 +1,114 @@
+package integrationtests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/testdb"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func Test_Attachments(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	if _, err := testdb.SeedMessages(a.DB()); err != nil {
+		t.Fatal("could not seed messages")
+	}
+
+	user1 := types.Uuid("6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+
+	attachment := &models.Attachment{UploaderUuid: user1, FileName: "cat.png", ContentType: "image/png", Size: 42, StorageKey: "attachments/cat"}
+	if err := a.AttachmentRepo().Create(nil, attachment); err != nil {
+		t.Fatalf("could not store attachment: %v", err)
+	}
+
+	stored, err := a.AttachmentRepo().GetById(nil, attachment.ID)
+	if err != nil {
+		t.Fatalf("could not load attachment: %v", err)
+	}
+
+	if stored.MessageID != nil {
+		t.Errorf("expected unsent attachment, got message %d", *stored.MessageID)
+	}
+
+	m := &models.Message{AuthorUuid: user1, Message: "look", Attachments: []models.Attachment{stored}}
+	if err := a.MessageRepo().Create(nil, m); err != nil {
+		t.Fatalf("could not post message: %v", err)
+	}
+
+	stored, err = a.AttachmentRepo().GetById(nil, attachment.ID)
+	if err != nil {
+		t.Fatalf("could not load attachment: %v", err)
+	}
+
+	if stored.MessageID == nil || *stored.MessageID != m.ID {
+		t.Errorf("expected attachment linked to message %d, got %v", m.ID, stored.MessageID)
+	}
+
+	loaded, err := a.MessageRepo().GetById(nil, m.ID)
+	if err != nil {
+		t.Fatalf("could not load message: %v", err)
+	}
+
+	if len(loaded.Attachments) != 1 || loaded.Attachments[0].FileName != "cat.png" {
+		t.Errorf("expected message with its attachment, got %v", loaded.Attachments)
+	}
+
+	if err := a.MessageRepo().Delete(nil, m.ID); err != nil {
+		t.Fatalf("could not delete message: %v", err)
+	}
+
+	if _, err := a.AttachmentRepo().GetById(nil, attachment.ID); err == nil {
+		t.Error("expected attachment to be removed with its message")
+	}
+}
+
+func Test_UnattachedAttachments(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	if _, err := testdb.SeedMessages(a.DB()); err != nil {
+		t.Fatal("could not seed messages")
+	}
+
+	user1 := types.Uuid("6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+	uploadedAt := time.Now().Add(-2 * time.Hour)
+
+	expired := &models.Attachment{UploaderUuid: user1, FileName: "old.png", StorageKey: "attachments/old", CreatedAt: uploadedAt}
+	sent := &models.Attachment{UploaderUuid: user1, FileName: "sent.png", StorageKey: "attachments/sent", CreatedAt: uploadedAt}
+	fresh := &models.Attachment{UploaderUuid: user1, FileName: "new.png", StorageKey: "attachments/new"}
+	for _, attachment := range []*models.Attachment{expired, sent, fresh} {
+		if err := a.AttachmentRepo().Create(nil, attachment); err != nil {
+			t.Fatalf("could not store attachment: %v", err)
+		}
+	}
+
+	if err := a.MessageRepo().Create(nil, &models.Message{AuthorUuid: user1, Message: "look", Attachments: []models.Attachment{*sent}}); err != nil {
+		t.Fatalf("could not post message: %v", err)
+	}
+
+	attachments, err := a.AttachmentRepo().GetUnattached(nil, time.Now().Add(-time.Hour))
+	if err != nil || len(attachments) != 1 || attachments[0].ID != expired.ID {
+		t.Fatalf("expected only attachment %d to be expired, got %v (%v)", expired.ID, attachments, err)
+	}
+
+	if deleted, err := a.AttachmentRepo().DeleteUnattached(nil, sent.ID); err != nil || deleted {
+		t.Errorf("sent attachment should be kept, got %v (%v)", deleted, err)
+	}
+
+	if deleted, err := a.AttachmentRepo().DeleteUnattached(nil, expired.ID); err != nil || !deleted {
+		t.Errorf("expired attachment should be deleted, got %v (%v)", deleted, err)
+	}
+
+	if _, err := a.AttachmentRepo().GetById(nil, expired.ID); err == nil {
+		t.Error("deleted attachment should not be found")
+	}
+}
@@ -4,12 +4,19 @@ import (
 	"os"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/id-tarzanych/lets-go-chat/app"
 	"github.com/id-tarzanych/lets-go-chat/configurations"
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	tokenSecret = "integration-tests"
+	// signingKey is an HS256 key of 32 bytes.
+	signingKey = "integration:HS256:aW50ZWdyYXRpb24tdGVzdHMtc2lnbmluZy1rZXktMzI="
+)
+
 var (
 	a *app.Application
 )
@@ -19,10 +26,22 @@ func TestMain(m *testing.M) {
 }
 
 func testMain(m *testing.M) int {
+	uploads, err := os.MkdirTemp("", "uploads")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(uploads)
+
 	port, _ := strconv.Atoi(os.Getenv("LETS_GO_CHAT_DATABASE__PORT"))
 	cfg := &configurations.Configuration{
+		Auth: configurations.Auth{
+			TokenSecret:     tokenSecret,
+			SigningKeys:     []string{signingKey},
+			AccessTokenTTL:  time.Minute,
+			RefreshTokenTTL: time.Hour,
+		},
 		Database: configurations.Database{
-			Type:     "postgres",
+			Type:     os.Getenv("LETS_GO_CHAT_DATABASE__TYPE"),
 			Host:     os.Getenv("LETS_GO_CHAT_DATABASE__HOST"),
 			Port:     port,
 			Protocol: "",
@@ -30,6 +49,9 @@ func testMain(m *testing.M) int {
 			Password: os.Getenv("LETS_GO_CHAT_DATABASE__PASSWORD"),
 			Database: os.Getenv("LETS_GO_CHAT_DATABASE__DATABASE"),
 		},
+		Storage: configurations.Storage{
+			Path: uploads,
+		},
 	}
 
 	application, err := app.New(cfg)
@@ -0,0 +1,76 @@
+package integrationtests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/internal/testdb"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func Test_Mentions(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	messages, err := testdb.SeedMessages(a.DB())
+	if err != nil {
+		t.Fatal("could not seed messages")
+	}
+
+	user3 := types.Uuid("b3341b87-c561-4142-bd28-f9ecde74822b")
+
+	now := time.Now()
+	for _, batch := range [][]string{{"lobby", "general"}, {"general", "dm to user2", "dm to user3"}} {
+		mentions := make([]models.Mention, 0, len(batch))
+		for _, text := range batch {
+			mentions = append(mentions, models.Mention{MessageID: messages[text].ID, UserUuid: user3, CreatedAt: now})
+		}
+
+		if err := a.MentionRepo().Create(nil, mentions); err != nil {
+			t.Fatalf("could not store mentions: %v", err)
+		}
+	}
+
+	member := message.Audience{UserId: user3, RoomIds: []uint{*messages["general"].RoomID}}
+
+	tests := []struct {
+		name     string
+		audience message.Audience
+		cursor   message.Cursor
+		want     []string
+	}{
+		{"Visible mentions", member, message.Cursor{Limit: 10}, []string{"lobby", "general", "dm to user3"}},
+		{"Latest mentions", member, message.Cursor{Limit: 2}, []string{"general", "dm to user3"}},
+		{"Mentions after leaving the room", message.Audience{UserId: user3}, message.Cursor{Limit: 10}, []string{"lobby", "dm to user3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.MessageRepo().GetMentionsFor(nil, tt.audience, tt.cursor)
+			if err != nil {
+				t.Fatalf("could not load mentions: %v", err)
+			}
+
+			if texts := messageTexts(got); !equalTexts(texts, tt.want) {
+				t.Errorf("expected mentions %v, got %v", tt.want, texts)
+			}
+		})
+	}
+
+	if err := a.MessageRepo().Delete(nil, messages["lobby"].ID); err != nil {
+		t.Fatalf("could not delete message: %v", err)
+	}
+
+	got, err := a.MessageRepo().GetMentionsFor(nil, member, message.Cursor{Limit: 10})
+	if err != nil {
+		t.Fatalf("could not load mentions: %v", err)
+	}
+
+	if texts := messageTexts(got); !equalTexts(texts, []string{"general", "dm to user3"}) {
+		t.Errorf("expected deleted message to drop out of mentions, got %v", texts)
+	}
+}
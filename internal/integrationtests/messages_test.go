@@ -0,0 +1,272 @@
+package integrationtests
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/internal/testdb"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func Test_GetAllMessagesFor(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	if _, err := testdb.SeedMessages(a.DB()); err != nil {
+		t.Error("could not seed messages")
+	}
+
+	general, err := a.RoomRepo().GetByName(nil, "general")
+	if err != nil {
+		t.Error("could not load room general")
+	}
+
+	tests := []struct {
+		name     string
+		audience message.Audience
+		want     []string
+	}{
+		{
+			name:     "Author",
+			audience: message.Audience{UserId: "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35", RoomIds: []uint{general.ID}},
+			want:     []string{"dm to user2", "dm to user3", "general", "lobby"},
+		},
+		{
+			name:     "Direct message recipient",
+			audience: message.Audience{UserId: "95a62e6c-e0e7-46ee-8bc3-6cca62b4cb09"},
+			want:     []string{"dm to user2", "lobby"},
+		},
+		{
+			name:     "Room member and direct message recipient",
+			audience: message.Audience{UserId: "b3341b87-c561-4142-bd28-f9ecde74822b", RoomIds: []uint{general.ID}},
+			want:     []string{"dm to user3", "general", "lobby"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := a.MessageRepo().GetAllFor(nil, tt.audience)
+			if err != nil {
+				t.Error("could not load messages from database")
+			}
+
+			got := make([]string, 0, len(messages))
+			for i := range messages {
+				got = append(got, messages[i].Message)
+			}
+			sort.Strings(got)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected messages %v, got %v", tt.want, got)
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected messages %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func Test_GetMessageByClientId(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	if _, err := testdb.SeedUsers(a.DB()); err != nil {
+		t.Error("could not seed users")
+	}
+
+	author := types.Uuid("6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+	clientId := "mobile-1"
+
+	m := &models.Message{AuthorUuid: author, Message: "hello", ClientId: &clientId}
+	if err := a.MessageRepo().Create(nil, m); err != nil {
+		t.Fatal("could not create message")
+	}
+
+	got, err := a.MessageRepo().GetByClientId(nil, author, clientId)
+	if err != nil {
+		t.Fatal("could not load message by client id")
+	}
+
+	if got.ID != m.ID {
+		t.Errorf("expected message %d, got %d", m.ID, got.ID)
+	}
+
+	if _, err := a.MessageRepo().GetByClientId(nil, "95a62e6c-e0e7-46ee-8bc3-6cca62b4cb09", clientId); err == nil {
+		t.Error("client id should be scoped to its author")
+	}
+
+	duplicate := &models.Message{AuthorUuid: author, Message: "hello", ClientId: &clientId}
+	if err := a.MessageRepo().Create(nil, duplicate); err == nil {
+		t.Error("duplicate client id should be rejected")
+	}
+
+	if err := a.MessageRepo().Delete(nil, m.ID); err != nil {
+		t.Fatal("could not delete message")
+	}
+
+	got, err = a.MessageRepo().GetByClientId(nil, author, clientId)
+	if err != nil {
+		t.Fatal("deleted message should still be found by client id")
+	}
+
+	if got.ID != m.ID || !got.DeletedAt.Valid {
+		t.Errorf("expected deleted message %d, got %v", m.ID, got)
+	}
+}
+
+func Test_EditAndDeleteMessage(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	messages, err := testdb.SeedMessages(a.DB())
+	if err != nil {
+		t.Fatal("could not seed messages")
+	}
+
+	author := types.Uuid("6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+	audience := message.Audience{UserId: author}
+
+	// Replay cursor of a reader who has seen every seeded message.
+	before := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	lobby, err := a.MessageRepo().GetById(nil, messages["lobby"].ID)
+	if err != nil {
+		t.Fatal("could not load message")
+	}
+
+	lobby.Message = "lobby, edited"
+	if err := a.MessageRepo().Update(nil, &lobby); err != nil {
+		t.Fatal("could not update message")
+	}
+
+	edits, err := a.MessageRepo().GetEdits(nil, lobby.ID)
+	if err != nil {
+		t.Fatal("could not load edit history")
+	}
+
+	if len(edits) != 1 || edits[0].Message != "lobby" {
+		t.Errorf("expected previous text in edit history, got %v", edits)
+	}
+
+	updated, err := a.MessageRepo().GetNewerThanFor(nil, audience, before, 0)
+	if err != nil {
+		t.Fatal("could not load messages from database")
+	}
+
+	if len(updated) != 1 || updated[0].Message != "lobby, edited" || updated[0].EditedAt == nil {
+		t.Errorf("expected edited message to be replayed, got %v", updated)
+	}
+
+	if err := a.MessageRepo().Delete(nil, lobby.ID); err != nil {
+		t.Fatal("could not delete message")
+	}
+
+	if edits, _ := a.MessageRepo().GetEdits(nil, lobby.ID); len(edits) != 0 {
+		t.Errorf("expected edit history to be erased, got %v", edits)
+	}
+
+	replayed, err := a.MessageRepo().GetAllFor(nil, audience)
+	if err != nil {
+		t.Fatal("could not load messages from database")
+	}
+
+	var tombstone *models.Message
+	for i := range replayed {
+		if replayed[i].ID == lobby.ID {
+			tombstone = &replayed[i]
+		}
+	}
+
+	if tombstone == nil || !tombstone.DeletedAt.Valid || tombstone.Message != "" {
+		t.Errorf("expected tombstone of deleted message to be replayed, got %v", tombstone)
+	}
+
+	if _, err := a.MessageRepo().GetById(nil, lobby.ID); err == nil {
+		t.Error("deleted message should not be found")
+	}
+}
+
+func Test_GetMessagesPageFor(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	if _, err := testdb.SeedUsers(a.DB()); err != nil {
+		t.Fatal("could not seed users")
+	}
+
+	author := types.Uuid("6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+	audience := message.Audience{UserId: author}
+
+	ids := make([]uint, 0, 5)
+	for _, text := range []string{"one", "two", "three", "four", "five"} {
+		m := &models.Message{AuthorUuid: author, Message: text}
+		if err := a.MessageRepo().Create(nil, m); err != nil {
+			t.Fatal("could not create message")
+		}
+
+		ids = append(ids, m.ID)
+	}
+
+	tests := []struct {
+		name   string
+		cursor message.Cursor
+		want   []string
+	}{
+		{"Latest", message.Cursor{Limit: 2}, []string{"four", "five"}},
+		{"Before", message.Cursor{Before: ids[3], Limit: 2}, []string{"two", "three"}},
+		{"Before start", message.Cursor{Before: ids[1], Limit: 2}, []string{"one"}},
+		{"After", message.Cursor{After: ids[0], Limit: 2}, []string{"two", "three"}},
+		{"After end", message.Cursor{After: ids[4], Limit: 2}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := a.MessageRepo().GetPageFor(nil, audience, tt.cursor)
+			if err != nil {
+				t.Fatal("could not load messages from database")
+			}
+
+			got := make([]string, 0, len(messages))
+			for i := range messages {
+				got = append(got, messages[i].Message)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected messages %v, got %v", tt.want, got)
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected messages %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+
+	// Replay of a long absence is capped to the latest messages.
+	replayed, err := a.MessageRepo().GetNewerThanFor(nil, audience, time.Time{}, 3)
+	if err != nil {
+		t.Fatal("could not load messages from database")
+	}
+
+	if len(replayed) != 3 || replayed[0].Message != "three" || replayed[2].Message != "five" {
+		t.Errorf("expected latest three messages, got %v", replayed)
+	}
+}
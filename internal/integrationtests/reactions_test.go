@@ -0,0 +1,90 @@
+package integrationtests
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/testdb"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func Test_Reactions(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	messages, err := testdb.SeedMessages(a.DB())
+	if err != nil {
+		t.Fatal("could not seed messages")
+	}
+
+	lobby := messages["lobby"].ID
+	general := messages["general"].ID
+
+	user1 := types.Uuid("6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+	user3 := types.Uuid("b3341b87-c561-4142-bd28-f9ecde74822b")
+
+	now := time.Now()
+	reactions := []struct {
+		reaction  models.Reaction
+		wantAdded bool
+	}{
+		{models.Reaction{MessageID: lobby, UserUuid: user1, Emoji: "🎉", CreatedAt: now}, true},
+		{models.Reaction{MessageID: lobby, UserUuid: user3, Emoji: "👍", CreatedAt: now.Add(time.Second)}, true},
+		{models.Reaction{MessageID: lobby, UserUuid: user1, Emoji: "👍", CreatedAt: now.Add(2 * time.Second)}, true},
+		{models.Reaction{MessageID: lobby, UserUuid: user1, Emoji: "👍", CreatedAt: now.Add(3 * time.Second)}, false},
+		{models.Reaction{MessageID: general, UserUuid: user3, Emoji: "👀", CreatedAt: now}, true},
+	}
+	for _, tt := range reactions {
+		added, err := a.ReactionRepo().Add(nil, &tt.reaction)
+		if err != nil {
+			t.Fatalf("could not add reaction: %v", err)
+		}
+
+		if added != tt.wantAdded {
+			t.Errorf("expected reaction %v added %t, got %t", tt.reaction, tt.wantAdded, added)
+		}
+	}
+
+	counts, err := a.ReactionRepo().CountFor(nil, []uint{lobby, general, messages["dm to user2"].ID})
+	if err != nil {
+		t.Fatalf("could not count reactions: %v", err)
+	}
+
+	want := map[uint][]models.ReactionCount{
+		lobby:   {{Emoji: "🎉", Count: 1}, {Emoji: "👍", Count: 2}},
+		general: {{Emoji: "👀", Count: 1}},
+	}
+	if !reflect.DeepEqual(want, counts) {
+		t.Errorf("expected reactions %v, got %v", want, counts)
+	}
+
+	for _, wantRemoved := range []bool{true, false} {
+		removed, err := a.ReactionRepo().Remove(nil, &models.Reaction{MessageID: lobby, UserUuid: user1, Emoji: "🎉"})
+		if err != nil {
+			t.Fatalf("could not remove reaction: %v", err)
+		}
+
+		if removed != wantRemoved {
+			t.Errorf("expected reaction removed %t, got %t", wantRemoved, removed)
+		}
+	}
+
+	if err := a.ReactionRepo().DeleteByMessage(nil, lobby); err != nil {
+		t.Fatalf("could not delete reactions: %v", err)
+	}
+
+	counts, err = a.ReactionRepo().CountFor(nil, []uint{lobby, general})
+	if err != nil {
+		t.Fatalf("could not count reactions: %v", err)
+	}
+
+	want = map[uint][]models.ReactionCount{general: {{Emoji: "👀", Count: 1}}}
+	if !reflect.DeepEqual(want, counts) {
+		t.Errorf("expected reactions %v, got %v", want, counts)
+	}
+}
@@ -0,0 +1,122 @@
+package integrationtests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/internal/testdb"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func Test_MarkRead(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	user3 := types.Uuid("b3341b87-c561-4142-bd28-f9ecde74822b")
+
+	tests := []struct {
+		name      string
+		messageId uint
+		wantMoved bool
+		wantId    uint
+	}{
+		{"First marker", 5, true, 5},
+		{"Marker moves forward", 8, true, 8},
+		{"Marker does not move back", 6, false, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marker := models.ReadMarker{UserUuid: user3, RoomID: 1, MessageID: tt.messageId, ReadAt: time.Now()}
+
+			moved, err := a.ReadMarkerRepo().Mark(nil, &marker)
+			if err != nil {
+				t.Fatalf("could not store read marker: %v", err)
+			}
+
+			if moved != tt.wantMoved || marker.MessageID != tt.wantId {
+				t.Errorf("expected marker at %d (moved %t), got %d (moved %t)", tt.wantId, tt.wantMoved, marker.MessageID, moved)
+			}
+		})
+	}
+
+	markers, err := a.ReadMarkerRepo().GetByUser(nil, user3)
+	if err != nil {
+		t.Fatalf("could not load read markers: %v", err)
+	}
+
+	if len(markers) != 1 || markers[0].MessageID != 8 {
+		t.Errorf("expected a single marker at message 8, got %v", markers)
+	}
+}
+
+func Test_CountUnreadFor(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	messages, err := testdb.SeedMessages(a.DB())
+	if err != nil {
+		t.Fatal("could not seed messages")
+	}
+
+	user1 := types.Uuid("6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+	user3 := types.Uuid("b3341b87-c561-4142-bd28-f9ecde74822b")
+	generalId := *messages["general"].RoomID
+
+	reply := models.Message{AuthorUuid: user3, RecipientUuid: &user1, Message: "reply to user1"}
+	if err := a.MessageRepo().Create(nil, &reply); err != nil {
+		t.Fatal("could not store reply")
+	}
+
+	audience := message.Audience{UserId: user3, RoomIds: []uint{generalId}}
+
+	counts, err := a.MessageRepo().CountUnreadFor(nil, audience)
+	if err != nil {
+		t.Fatalf("could not count unread messages: %v", err)
+	}
+
+	want := []message.UnreadCount{
+		{Count: 1},
+		{PeerUuid: user1, Count: 1},
+		{RoomID: generalId, Count: 1},
+	}
+	assertUnreadCounts(t, want, counts)
+
+	marker := models.ReadMarker{UserUuid: user3, RoomID: generalId, MessageID: messages["general"].ID, ReadAt: time.Now()}
+	if _, err := a.ReadMarkerRepo().Mark(nil, &marker); err != nil {
+		t.Fatal("could not store read marker")
+	}
+
+	marker = models.ReadMarker{UserUuid: user3, PeerUuid: user1, MessageID: messages["dm to user3"].ID, ReadAt: time.Now()}
+	if _, err := a.ReadMarkerRepo().Mark(nil, &marker); err != nil {
+		t.Fatal("could not store read marker")
+	}
+
+	counts, err = a.MessageRepo().CountUnreadFor(nil, audience)
+	if err != nil {
+		t.Fatalf("could not count unread messages: %v", err)
+	}
+
+	assertUnreadCounts(t, []message.UnreadCount{{Count: 1}}, counts)
+}
+
+func assertUnreadCounts(t *testing.T, want, got []message.UnreadCount) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("expected unread counts %v, got %v", want, got)
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("expected unread counts %v, got %v", want, got)
+		}
+	}
+}
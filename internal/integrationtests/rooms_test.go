@@ -0,0 +1,198 @@
+package integrationtests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/testdb"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func Test_GetAllRooms(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	expectedRoomsMap, err := testdb.SeedRooms(a.DB())
+	if err != nil {
+		t.Error("could not seed rooms")
+	}
+
+	rooms, err := a.RoomRepo().GetAll(nil)
+	if err != nil {
+		t.Error("could not load rooms from database")
+	}
+
+	if len(expectedRoomsMap) != len(rooms) {
+		t.Error("expected rooms amount does not match received rooms amount")
+	}
+
+	for i := range rooms {
+		e, ok := expectedRoomsMap[rooms[i].Name]
+		if !ok {
+			t.Errorf("unexpected room %s was returned", rooms[i].Name)
+		}
+
+		if match := compareRooms(rooms[i], e); !match {
+			t.Errorf("properties for room %s do not match expected ones. Expected %v, got %v", e.Name, e, rooms[i])
+		}
+	}
+}
+
+func Test_GetRoomByName(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	expectedRoomsMap, err := testdb.SeedRooms(a.DB())
+	if err != nil {
+		t.Error("could not seed rooms")
+	}
+
+	for _, e := range expectedRoomsMap {
+		r, err := a.RoomRepo().GetByName(nil, e.Name)
+		if err != nil {
+			t.Errorf("room %s was not returned", e.Name)
+		}
+
+		if match := compareRooms(r, e); !match {
+			t.Errorf("properties for room %s do not match expected ones. Expected %v, got %v", e.Name, e, r)
+		}
+	}
+}
+
+func Test_RoomMembership(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	expectedRoomsMap, err := testdb.SeedRooms(a.DB())
+	if err != nil {
+		t.Error("could not seed rooms")
+	}
+
+	const userId = "95a62e6c-e0e7-46ee-8bc3-6cca62b4cb09"
+
+	random := expectedRoomsMap["random"]
+	if err = a.RoomRepo().AddMember(nil, random.ID, userId); err != nil {
+		t.Errorf("user %s could not join room %s", userId, random.Name)
+	}
+
+	// Joining twice should be a no-op.
+	if err = a.RoomRepo().AddMember(nil, random.ID, userId); err != nil {
+		t.Errorf("user %s could not re-join room %s", userId, random.Name)
+	}
+
+	rooms, err := a.RoomRepo().GetByMember(nil, userId)
+	if err != nil || len(rooms) != 1 || rooms[0].ID != random.ID {
+		t.Errorf("expected user %s to be a member of room %s only, got %v", userId, random.Name, rooms)
+	}
+
+	if err = a.RoomRepo().RemoveMember(nil, random.ID, userId); err != nil {
+		t.Errorf("user %s could not leave room %s", userId, random.Name)
+	}
+
+	rooms, err = a.RoomRepo().GetByMember(nil, userId)
+	if err != nil || len(rooms) != 0 {
+		t.Errorf("expected user %s to have no rooms, got %v", userId, rooms)
+	}
+}
+
+func Test_RoomModeration(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	expectedRoomsMap, err := testdb.SeedRooms(a.DB())
+	if err != nil {
+		t.Error("could not seed rooms")
+	}
+
+	const userId = "95a62e6c-e0e7-46ee-8bc3-6cca62b4cb09"
+
+	random := expectedRoomsMap["random"]
+	if err = a.RoomRepo().AddMember(nil, random.ID, userId); err != nil {
+		t.Errorf("user %s could not join room %s", userId, random.Name)
+	}
+
+	if err = a.RoomRepo().SetTopic(nil, random.ID, "cats"); err != nil {
+		t.Errorf("could not set topic of room %s", random.Name)
+	}
+
+	room, err := a.RoomRepo().GetById(nil, random.ID)
+	if err != nil || room.Topic != "cats" {
+		t.Errorf("expected room %s to have topic cats, got %v", random.Name, room)
+	}
+
+	now := time.Now()
+	until := now.Add(time.Hour)
+
+	if err = a.RoomRepo().Mute(nil, random.ID, userId, &until); err != nil {
+		t.Errorf("could not mute user %s", userId)
+	}
+
+	member, err := a.RoomRepo().GetMember(nil, random.ID, userId)
+	if err != nil || !member.Muted(now) {
+		t.Errorf("expected user %s to be muted, got %v", userId, member)
+	}
+
+	if err = a.RoomRepo().Mute(nil, random.ID, userId, nil); err != nil {
+		t.Errorf("could not unmute user %s", userId)
+	}
+
+	member, err = a.RoomRepo().GetMember(nil, random.ID, userId)
+	if err != nil || member.Muted(now) {
+		t.Errorf("expected user %s not to be muted, got %v", userId, member)
+	}
+
+	if err = a.RoomRepo().Mute(nil, expectedRoomsMap["general"].ID, userId, &until); err == nil {
+		t.Errorf("expected muting user %s outside of their rooms to fail", userId)
+	}
+}
+
+func Test_CreateRoom(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	if _, err := testdb.SeedUsers(a.DB()); err != nil {
+		t.Error("could not seed users")
+	}
+
+	newRoom := models.NewRoom("testroom", "Testing", "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+	if err := a.RoomRepo().Create(nil, newRoom); err != nil {
+		t.Errorf("could not create room %s", newRoom.Name)
+	}
+
+	roomInDb := models.Room{}
+	result := a.DB().Where("name = ?", newRoom.Name).First(&roomInDb)
+	if err := result.Error; err != nil {
+		t.Errorf("room %s is missing in db", newRoom.Name)
+	}
+
+	if match := compareRooms(*newRoom, roomInDb); !match {
+		t.Errorf("properties for room %s do not match expected ones. Expected %v, got %v", newRoom.Name, newRoom, roomInDb)
+	}
+
+	if roomInDb.CreatedBy == nil || *roomInDb.CreatedBy != *newRoom.CreatedBy {
+		t.Errorf("creator of room %s was not stored. Expected %v, got %v", newRoom.Name, *newRoom.CreatedBy, roomInDb.CreatedBy)
+	}
+
+	if _, err := a.RoomRepo().GetMember(nil, roomInDb.ID, *newRoom.CreatedBy); err != nil {
+		t.Errorf("creator of room %s should be a member: %v", newRoom.Name, err)
+	}
+}
+
+func compareRooms(room1, room2 models.Room) bool {
+	return room1.ID == room2.ID && room1.Name == room2.Name && room1.Topic == room2.Topic
+}
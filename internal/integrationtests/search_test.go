@@ -0,0 +1,87 @@
+package integrationtests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/internal/testdb"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func Test_Search(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	messages, err := testdb.SeedMessages(a.DB())
+	if err != nil {
+		t.Fatal("could not seed messages")
+	}
+
+	user1 := types.Uuid("6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+	user2 := types.Uuid("95a62e6c-e0e7-46ee-8bc3-6cca62b4cb09")
+	user3 := types.Uuid("b3341b87-c561-4142-bd28-f9ecde74822b")
+
+	general := *messages["general"].RoomID
+
+	posted := []*models.Message{
+		{AuthorUuid: user1, Message: "Lunch at noon?"},
+		{AuthorUuid: user2, RoomID: &general, Message: "lunch is LATE today"},
+		{AuthorUuid: user1, RecipientUuid: &user2, Message: "secret lunch plans"},
+		{AuthorUuid: user3, Message: "launch at noon"},
+	}
+	for _, m := range posted {
+		if err := a.MessageRepo().Create(nil, m); err != nil {
+			t.Fatalf("could not post message: %v", err)
+		}
+	}
+
+	edited := &models.Message{Message: "no more lunch talk"}
+	edited.ID = posted[3].ID
+	if err := a.MessageRepo().Update(nil, edited); err != nil {
+		t.Fatalf("could not edit message: %v", err)
+	}
+
+	if err := a.MessageRepo().Delete(nil, posted[2].ID); err != nil {
+		t.Fatalf("could not delete message: %v", err)
+	}
+
+	member := message.Audience{UserId: user3, RoomIds: []uint{general}}
+	recipient := message.Audience{UserId: user2}
+
+	tests := []struct {
+		name     string
+		audience message.Audience
+		filter   message.SearchFilter
+		cursor   message.Cursor
+		want     []string
+	}{
+		{"Words in any case", member, message.SearchFilter{Query: "LUNCH"}, message.Cursor{Limit: 10}, []string{"Lunch at noon?", "lunch is LATE today", "no more lunch talk"}},
+		{"Every word must match", member, message.SearchFilter{Query: "noon lunch"}, message.Cursor{Limit: 10}, []string{"Lunch at noon?"}},
+		{"Old text of edited message", member, message.SearchFilter{Query: "launch"}, message.Cursor{Limit: 10}, []string{}},
+		{"Rooms of other users", recipient, message.SearchFilter{Query: "late"}, message.Cursor{Limit: 10}, []string{}},
+		{"Deleted direct message", recipient, message.SearchFilter{Query: "secret"}, message.Cursor{Limit: 10}, []string{}},
+		{"Author", member, message.SearchFilter{Query: "lunch", AuthorUuid: user1}, message.Cursor{Limit: 10}, []string{"Lunch at noon?"}},
+		{"Room", member, message.SearchFilter{Query: "lunch", RoomID: general}, message.Cursor{Limit: 10}, []string{"lunch is LATE today"}},
+		{"Future messages", member, message.SearchFilter{Query: "lunch", Since: time.Now().Add(time.Hour)}, message.Cursor{Limit: 10}, []string{}},
+		{"Past messages", member, message.SearchFilter{Query: "lunch", Until: time.Now().Add(-time.Hour)}, message.Cursor{Limit: 10}, []string{}},
+		{"Latest match", member, message.SearchFilter{Query: "lunch"}, message.Cursor{Limit: 1}, []string{"no more lunch talk"}},
+		{"Matches before", member, message.SearchFilter{Query: "lunch"}, message.Cursor{Before: posted[1].ID, Limit: 10}, []string{"Lunch at noon?"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := a.MessageRepo().Search(nil, tt.audience, tt.filter, tt.cursor)
+			if err != nil {
+				t.Fatalf("could not search messages: %v", err)
+			}
+
+			if got := messageTexts(found); !equalTexts(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package integrationtests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/db/message"
+	"github.com/id-tarzanych/lets-go-chat/internal/testdb"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func Test_Threads(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	messages, err := testdb.SeedMessages(a.DB())
+	if err != nil {
+		t.Fatal("could not seed messages")
+	}
+
+	lobby := messages["lobby"]
+
+	user2 := types.Uuid("95a62e6c-e0e7-46ee-8bc3-6cca62b4cb09")
+	user3 := types.Uuid("b3341b87-c561-4142-bd28-f9ecde74822b")
+
+	before := time.Now()
+
+	replies := []*models.Message{
+		{AuthorUuid: user2, ParentID: &lobby.ID, Message: "first reply"},
+		{AuthorUuid: user3, ParentID: &lobby.ID, Message: "second reply"},
+	}
+	for _, reply := range replies {
+		if err := a.MessageRepo().Create(nil, reply); err != nil {
+			t.Fatalf("could not post reply: %v", err)
+		}
+	}
+
+	root, err := a.MessageRepo().GetById(nil, lobby.ID)
+	if err != nil {
+		t.Fatalf("could not load thread root: %v", err)
+	}
+
+	if root.ReplyCount != 2 {
+		t.Errorf("expected 2 replies, got %d", root.ReplyCount)
+	}
+
+	if root.LastReplyAt == nil || !root.LastReplyAt.Equal(replies[1].CreatedAt) {
+		t.Errorf("expected last reply at %v, got %v", replies[1].CreatedAt, root.LastReplyAt)
+	}
+
+	thread, err := a.MessageRepo().GetThread(nil, lobby.ID, message.Cursor{Limit: 10})
+	if err != nil {
+		t.Fatalf("could not load thread: %v", err)
+	}
+
+	if got := messageTexts(thread); !equalTexts(got, []string{"first reply", "second reply"}) {
+		t.Errorf("expected replies oldest first, got %v", got)
+	}
+
+	audience := message.Audience{UserId: user2}
+
+	page, err := a.MessageRepo().GetPageFor(nil, audience, message.Cursor{Limit: 10})
+	if err != nil {
+		t.Fatalf("could not load history: %v", err)
+	}
+
+	if got := messageTexts(page); !equalTexts(got, []string{"lobby", "dm to user2"}) {
+		t.Errorf("expected history without replies, got %v", got)
+	}
+
+	// The root is replayed once it gets new replies, the replies stay in their thread.
+	newer, err := a.MessageRepo().GetNewerThanFor(nil, audience, before, 0)
+	if err != nil {
+		t.Fatalf("could not load newer messages: %v", err)
+	}
+
+	if got := messageTexts(newer); !equalTexts(got, []string{"lobby"}) {
+		t.Errorf("expected replay without replies, got %v", got)
+	}
+
+	if err := a.MessageRepo().Delete(nil, replies[1].ID); err != nil {
+		t.Fatalf("could not delete reply: %v", err)
+	}
+
+	root, err = a.MessageRepo().GetById(nil, lobby.ID)
+	if err != nil {
+		t.Fatalf("could not load thread root: %v", err)
+	}
+
+	if root.ReplyCount != 1 {
+		t.Errorf("expected 1 reply after deletion, got %d", root.ReplyCount)
+	}
+
+	if root.LastReplyAt == nil || !root.LastReplyAt.Equal(replies[0].CreatedAt) {
+		t.Errorf("expected last reply at %v after deletion, got %v", replies[0].CreatedAt, root.LastReplyAt)
+	}
+
+	thread, err = a.MessageRepo().GetThread(nil, lobby.ID, message.Cursor{Limit: 10})
+	if err != nil {
+		t.Fatalf("could not load thread: %v", err)
+	}
+
+	if len(thread) != 2 || !thread[1].DeletedAt.Valid {
+		t.Errorf("expected deleted reply to stay as a tombstone, got %v", thread)
+	}
+
+	if err := a.MessageRepo().Delete(nil, replies[0].ID); err != nil {
+		t.Fatalf("could not delete reply: %v", err)
+	}
+
+	root, err = a.MessageRepo().GetById(nil, lobby.ID)
+	if err != nil {
+		t.Fatalf("could not load thread root: %v", err)
+	}
+
+	if root.ReplyCount != 0 || root.LastReplyAt != nil {
+		t.Errorf("expected no replies after deleting all of them, got %d last at %v", root.ReplyCount, root.LastReplyAt)
+	}
+}
+
+func messageTexts(messages []models.Message) []string {
+	texts := make([]string, 0, len(messages))
+	for i := range messages {
+		texts = append(texts, messages[i].Message)
+	}
+
+	return texts
+}
+
+func equalTexts(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,10 +1,15 @@
 package integrationtests
 
 import (
+	"errors"
 	"testing"
 	"time"
 
+	"gorm.io/gorm"
+
+	"github.com/id-tarzanych/lets-go-chat/db/token"
 	"github.com/id-tarzanych/lets-go-chat/internal/testdb"
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
 	"github.com/id-tarzanych/lets-go-chat/models"
 )
 
@@ -15,7 +20,7 @@ func Test_GetAllTokens(t *testing.T) {
 		}
 	}()
 
-	expectedTokensMap, err := testdb.SeedTokens(a.DB())
+	expectedTokensMap, err := testdb.SeedTokens(a.DB(), tokenSecret)
 	if err != nil {
 		t.Error("could not seed tokens")
 	}
@@ -27,15 +32,15 @@ func Test_GetAllTokens(t *testing.T) {
 
 	gotTokensMap := make(map[string]models.Token)
 	for i := range tokens {
-		gotTokensMap[tokens[i].Token] = tokens[i]
+		gotTokensMap[tokens[i].TokenHash] = tokens[i]
 	}
 
 	if len(expectedTokensMap) != len(gotTokensMap) {
 		t.Error("expected token amount does not match received tokens amount")
 	}
 
-	for k, e := range expectedTokensMap {
-		tk, ok := gotTokensMap[k]
+	for _, e := range expectedTokensMap {
+		tk, ok := gotTokensMap[e.TokenHash]
 		if !ok {
 			t.Errorf("token %s was not returned", e.Token)
 		}
@@ -53,7 +58,7 @@ func Test_GetToken(t *testing.T) {
 		}
 	}()
 
-	expectedTokenMap, err := testdb.SeedTokens(a.DB())
+	expectedTokenMap, err := testdb.SeedTokens(a.DB(), tokenSecret)
 	if err != nil {
 		t.Error("could not seed tokens")
 	}
@@ -77,7 +82,7 @@ func Test_GetTokenByUserId(t *testing.T) {
 		}
 	}()
 
-	_, err := testdb.SeedTokens(a.DB())
+	_, err := testdb.SeedTokens(a.DB(), tokenSecret)
 	if err != nil {
 		t.Error("could not seed tokens")
 	}
@@ -89,24 +94,26 @@ func Test_GetTokenByUserId(t *testing.T) {
 
 	gotTokensMap := make(map[string]models.Token)
 	for i := range tokens {
-		gotTokensMap[tokens[i].Token] = tokens[i]
+		gotTokensMap[tokens[i].TokenHash] = tokens[i]
 	}
 
 	expectedTokensMap := map[string]models.Token{
 		"18sqhpLyANr7ypoK": {
 			Token:      "18sqhpLyANr7ypoK",
+			TokenHash:  models.HashToken(tokenSecret, "18sqhpLyANr7ypoK"),
 			UserId:     "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35",
 			Expiration: time.Now().Add(time.Hour * 24),
 		},
 		"8n9hKwlT9l037PZb": {
 			Token:      "8n9hKwlT9l037PZb",
+			TokenHash:  models.HashToken(tokenSecret, "8n9hKwlT9l037PZb"),
 			UserId:     "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35",
 			Expiration: time.Now().Add(time.Hour * 24),
 		},
 	}
 
-	for k, e := range expectedTokensMap {
-		tk, ok := gotTokensMap[k]
+	for _, e := range expectedTokensMap {
+		tk, ok := gotTokensMap[e.TokenHash]
 		if !ok {
 			t.Errorf("token %s was not returned", e.Token)
 		}
@@ -131,7 +138,7 @@ func Test_CreateToken(t *testing.T) {
 	}
 
 	tokenInDb := models.Token{}
-	result := a.DB().Where("token = ?", newToken.Token).First(&tokenInDb)
+	result := a.DB().Where("token_hash = ?", models.HashToken(tokenSecret, newToken.Token)).First(&tokenInDb)
 	if err := result.Error; err != nil {
 		t.Errorf("token %s is missing in db", newToken.Token)
 	}
@@ -148,7 +155,7 @@ func Test_DeleteToken(t *testing.T) {
 		}
 	}()
 
-	expectedTokensMap, err := testdb.SeedTokens(a.DB())
+	expectedTokensMap, err := testdb.SeedTokens(a.DB(), tokenSecret)
 	if err != nil {
 		t.Error("could not seed tokens")
 	}
@@ -166,15 +173,15 @@ func Test_DeleteToken(t *testing.T) {
 
 	gotTokensMap := make(map[string]models.Token)
 	for i := range tokens {
-		gotTokensMap[tokens[i].Token] = tokens[i]
+		gotTokensMap[tokens[i].TokenHash] = tokens[i]
 	}
 
 	if len(expectedTokensMap) != len(gotTokensMap) {
 		t.Error("expected tokens amount does not match received tokens amount")
 	}
 
-	for k, e := range expectedTokensMap {
-		tk, ok := gotTokensMap[k]
+	for _, e := range expectedTokensMap {
+		tk, ok := gotTokensMap[e.TokenHash]
 		if !ok {
 			t.Errorf("token %s was not returned", e.Token)
 		}
@@ -185,6 +192,160 @@ func Test_DeleteToken(t *testing.T) {
 	}
 }
 
+func Test_MarkUsedToken(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	refreshToken := models.NewRefreshToken("refreshToken", "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35", "family", time.Now().Add(time.Hour))
+	if err := a.TokenRepo().Create(nil, refreshToken); err != nil {
+		t.Fatalf("could not create token %s", refreshToken.Token)
+	}
+
+	usedAt := time.Now()
+	if err := a.TokenRepo().MarkUsed(nil, refreshToken.Token, usedAt); err != nil {
+		t.Errorf("token %s could not be marked as used: %v", refreshToken.Token, err)
+	}
+
+	if err := a.TokenRepo().MarkUsed(nil, refreshToken.Token, time.Now()); !errors.Is(err, token.ErrAlreadyUsed) {
+		t.Errorf("token %s should be used once, got %v", refreshToken.Token, err)
+	}
+
+	if err := a.TokenRepo().MarkUsed(nil, "unknownToken", time.Now()); !errors.Is(err, token.ErrAlreadyUsed) {
+		t.Errorf("unknown token should not be marked as used, got %v", err)
+	}
+
+	tk, err := a.TokenRepo().Get(nil, refreshToken.Token)
+	if err != nil {
+		t.Fatalf("token %s was not returned", refreshToken.Token)
+	}
+
+	if !tk.IsRefresh() || tk.FamilyId != "family" || tk.UsedAt == nil || tk.UsedAt.Unix() != usedAt.Unix() {
+		t.Errorf("token %s was not marked as used, got %v", refreshToken.Token, tk)
+	}
+}
+
+func Test_DeleteTokenFamily(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	tokens := []*models.Token{
+		models.NewRefreshToken("firstToken", "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35", "stolen", time.Now().Add(time.Hour)),
+		models.NewRefreshToken("secondToken", "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35", "stolen", time.Now().Add(time.Hour)),
+		models.NewRefreshToken("otherToken", "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35", "other", time.Now().Add(time.Hour)),
+	}
+	for _, tk := range tokens {
+		if err := a.TokenRepo().Create(nil, tk); err != nil {
+			t.Fatalf("could not create token %s", tk.Token)
+		}
+	}
+
+	if err := a.TokenRepo().DeleteFamily(nil, "stolen"); err != nil {
+		t.Errorf("token family could not be deleted: %v", err)
+	}
+
+	for _, tk := range tokens {
+		_, err := a.TokenRepo().Get(nil, tk.Token)
+		if deleted := err != nil; deleted != (tk.FamilyId == "stolen") {
+			t.Errorf("unexpected presence of token %s of family %s", tk.Token, tk.FamilyId)
+		}
+	}
+}
+
+func Test_DeleteTokensByUserId(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	expectedTokensMap, err := testdb.SeedTokens(a.DB(), tokenSecret)
+	if err != nil {
+		t.Error("could not seed tokens")
+	}
+
+	if err := a.TokenRepo().DeleteByUserId(nil, "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35"); err != nil {
+		t.Errorf("tokens of user %s could not be deleted", "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+	}
+
+	tokens, err := a.TokenRepo().GetByUserId(nil, "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+	if err != nil || len(tokens) != 0 {
+		t.Errorf("tokens of user %s should be deleted, got %v", "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35", tokens)
+	}
+
+	for _, e := range expectedTokensMap {
+		if e.UserId == "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35" {
+			continue
+		}
+
+		if _, err := a.TokenRepo().Get(nil, e.Token); err != nil {
+			t.Errorf("token %s of another user should be kept", e.Token)
+		}
+	}
+}
+
+// legacyToken is the token model that stored tokens in plaintext.
+type legacyToken struct {
+	gorm.Model
+
+	Token      string
+	UserId     types.Uuid
+	Expiration time.Time
+}
+
+func (legacyToken) TableName() string {
+	return "tokens"
+}
+
+func Test_MigratePlaintextTokens(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	if _, err := testdb.SeedUsers(a.DB()); err != nil {
+		t.Error("could not seed users")
+	}
+
+	// Tokens used to be stored in plaintext.
+	if err := a.DB().Migrator().DropTable(&models.Token{}); err != nil {
+		t.Fatalf("could not drop tokens table: %v", err)
+	}
+
+	if err := a.DB().AutoMigrate(&legacyToken{}); err != nil {
+		t.Fatalf("could not create legacy tokens table: %v", err)
+	}
+
+	legacy := legacyToken{Token: "legacyToken", UserId: "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35", Expiration: time.Now().Add(time.Hour)}
+	if err := a.DB().Create(&legacy).Error; err != nil {
+		t.Fatalf("could not store legacy token: %v", err)
+	}
+
+	repo, err := token.NewDatabaseTokenRepository(a.DB(), tokenSecret)
+	if err != nil {
+		t.Fatalf("could not migrate tokens: %v", err)
+	}
+
+	if a.DB().Migrator().HasColumn(&models.Token{}, "token") {
+		t.Error("plaintext token column should be dropped")
+	}
+
+	tk, err := repo.Get(nil, "legacyToken")
+	if err != nil {
+		t.Fatal("legacy token should stay valid")
+	}
+
+	if tk.TokenHash != models.HashToken(tokenSecret, "legacyToken") || tk.UserId != "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35" {
+		t.Errorf("legacy token was not migrated, got %v", tk)
+	}
+}
+
 func compareTokens(token1, token2 models.Token) bool {
-	return token1.Token == token2.Token && token1.UserId == token2.UserId && token1.Expiration.Unix() == token2.Expiration.Unix()
+	return token1.TokenHash == token2.TokenHash && token1.UserId == token2.UserId && token1.Expiration.Unix() == token2.Expiration.Unix()
 }
@@ -1,11 +1,15 @@
 package integrationtests
 
 import (
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/id-tarzanych/lets-go-chat/db/user"
 	"github.com/id-tarzanych/lets-go-chat/internal/testdb"
 	"github.com/id-tarzanych/lets-go-chat/internal/types"
 	"github.com/id-tarzanych/lets-go-chat/models"
+	"github.com/id-tarzanych/lets-go-chat/pkg/hasher"
 )
 
 func Test_GetAllUsers(t *testing.T) {
@@ -101,7 +105,11 @@ func Test_CreateUser(t *testing.T) {
 		}
 	}()
 
-	newUser := models.NewUser("testuser", "testpassword")
+	newUser, err := models.NewUser("testuser", "testpassword")
+	if err != nil {
+		t.Fatal("could not hash password")
+	}
+
 	if err := a.UserRepo().Create(nil, newUser); err != nil {
 		t.Errorf("could not create user %s", newUser.UserName)
 	}
@@ -161,6 +169,218 @@ func Test_DeleteUsers(t *testing.T) {
 	}
 }
 
+func Test_UpgradeLegacyPasswordHash(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	if _, err := testdb.SeedUsers(a.DB()); err != nil {
+		t.Error("could not seed users")
+	}
+
+	user, err := a.UserRepo().GetByUserName(nil, "user1")
+	if err != nil {
+		t.Fatal("could not load user from database")
+	}
+
+	if !hasher.Default.NeedsRehash(user.PasswordHash) {
+		t.Fatal("seeded users should have legacy password hashes")
+	}
+
+	// A chat session keeps the user it was started with.
+	session := user
+
+	if err := user.SetPassword("12345678"); err != nil {
+		t.Fatal("could not hash password")
+	}
+
+	if err := a.UserRepo().UpdatePassword(nil, &user); err != nil {
+		t.Fatal("could not update password hash")
+	}
+
+	lastActivity := time.Now().Truncate(time.Second)
+	if err := a.UserRepo().UpdateLastActivity(nil, &session, lastActivity); err != nil {
+		t.Fatal("could not update last activity")
+	}
+
+	upgraded, err := a.UserRepo().GetByUserName(nil, "user1")
+	if err != nil {
+		t.Fatal("could not load user from database")
+	}
+
+	if ok, _ := hasher.Verify("12345678", upgraded.PasswordHash); !ok || hasher.Default.NeedsRehash(upgraded.PasswordHash) {
+		t.Errorf("password hash was not upgraded or was overwritten by a stale copy, got %s", upgraded.PasswordHash)
+	}
+
+	if !upgraded.LastActivity.Equal(lastActivity) {
+		t.Errorf("expected last activity %v, got %v", lastActivity, upgraded.LastActivity)
+	}
+}
+
+func Test_RenameUser(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	if _, err := testdb.SeedUsers(a.DB()); err != nil {
+		t.Error("could not seed users")
+	}
+
+	user, err := a.UserRepo().GetByUserName(nil, "user1")
+	if err != nil {
+		t.Fatal("could not load user from database")
+	}
+
+	// Another chat session keeps the name the user had when it started.
+	session := user
+
+	if err := a.UserRepo().UpdateUserName(nil, &user, "renamed"); err != nil {
+		t.Fatal("could not rename user")
+	}
+
+	if err := a.UserRepo().UpdateLastActivity(nil, &session, time.Now()); err != nil {
+		t.Fatal("could not update last activity")
+	}
+
+	renamed, err := a.UserRepo().GetById(nil, user.ID)
+	if err != nil {
+		t.Fatal("could not load user from database")
+	}
+
+	if renamed.UserName != "renamed" || renamed.PasswordHash != user.PasswordHash {
+		t.Errorf("expected only the name to change, got %v", renamed)
+	}
+}
+
+func Test_UpdateLastActivity(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	if _, err := testdb.SeedUsers(a.DB()); err != nil {
+		t.Error("could not seed users")
+	}
+
+	u, err := a.UserRepo().GetByUserName(nil, "user1")
+	if err != nil {
+		t.Fatal("could not load user from database")
+	}
+
+	now := time.Now().Truncate(time.Millisecond)
+
+	tests := []struct {
+		name         string
+		lastActivity time.Time
+		want         time.Time
+	}{
+		{"First activity", now, now},
+		{"Late report of older activity", now.Add(-time.Minute), now},
+		{"Newer activity", now.Add(time.Second), now.Add(time.Second)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := a.UserRepo().UpdateLastActivity(nil, &u, tt.lastActivity); err != nil {
+				t.Fatal("could not update last activity")
+			}
+
+			got, err := a.UserRepo().GetById(nil, u.ID)
+			if err != nil {
+				t.Fatal("could not load user from database")
+			}
+
+			if !got.LastActivity.Equal(tt.want) {
+				t.Errorf("expected last activity %v, got %v", tt.want, got.LastActivity)
+			}
+		})
+	}
+}
+
+func Test_UniqueUserName(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	if _, err := testdb.SeedUsers(a.DB()); err != nil {
+		t.Error("could not seed users")
+	}
+
+	duplicate, err := models.NewUser("user1", "12345678")
+	if err != nil {
+		t.Fatal("could not hash password")
+	}
+
+	if err := a.UserRepo().Create(nil, duplicate); !errors.Is(err, user.ErrUserNameTaken) {
+		t.Errorf("expected taken user name on create, got %v", err)
+	}
+
+	u, err := a.UserRepo().GetByUserName(nil, "user2")
+	if err != nil {
+		t.Fatal("could not load user from database")
+	}
+
+	if err := a.UserRepo().UpdateUserName(nil, &u, "user1"); !errors.Is(err, user.ErrUserNameTaken) {
+		t.Errorf("expected taken user name on rename, got %v", err)
+	}
+}
+
+func Test_SetAdmins(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	if _, err := testdb.SeedUsers(a.DB()); err != nil {
+		t.Error("could not seed users")
+	}
+
+	former, err := a.UserRepo().GetByUserName(nil, "user2")
+	if err != nil {
+		t.Fatal("could not load user from database")
+	}
+
+	if result := a.DB().Model(&former).Update("admin", true); result.Error != nil {
+		t.Fatal("could not make user an admin")
+	}
+
+	owner := types.Uuid("6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+	if err := a.UserRepo().Create(nil, models.NewBot("bot", owner)); err != nil {
+		t.Fatal("could not create bot")
+	}
+
+	if err := a.UserRepo().SetAdmins(nil, []string{"user1", "bot", "unknown"}); err != nil {
+		t.Fatalf("could not set admins: %v", err)
+	}
+
+	want := map[string]bool{"user1": true, "user2": false, "user3": false, "bot": false}
+	for name, admin := range want {
+		u, err := a.UserRepo().GetByUserName(nil, name)
+		if err != nil {
+			t.Fatalf("could not load user %s from database", name)
+		}
+
+		if u.Admin != admin {
+			t.Errorf("expected admin of %s to be %v", name, admin)
+		}
+	}
+
+	if err := a.UserRepo().SetAdmins(nil, nil); err != nil {
+		t.Fatalf("could not set admins: %v", err)
+	}
+
+	if u, _ := a.UserRepo().GetByUserName(nil, "user1"); u.Admin {
+		t.Error("users missing from the list should lose admin rights")
+	}
+}
+
 func compareUsers(user1, user2 models.User) bool {
 	return user1.ID == user2.ID && user1.UserName == user2.UserName && user1.PasswordHash == user2.PasswordHash
 }
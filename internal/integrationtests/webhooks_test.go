@@ -0,0 +1,104 @@
+package integrationtests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/testdb"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+func Test_Webhooks(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	hook := models.NewWebhook("https://example.com/hook", "secret", []string{models.WebhookUserJoined}, "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+	if err := a.WebhookRepo().Create(nil, hook); err != nil {
+		t.Fatalf("could not create webhook: %v", err)
+	}
+
+	now := time.Now().UTC()
+	deliveries := []models.WebhookDelivery{
+		{WebhookID: hook.ID, Event: models.WebhookUserJoined, Payload: "{}", Status: models.DeliveryPending, NextAttemptAt: now.Add(-time.Minute)},
+		{WebhookID: hook.ID, Event: models.WebhookUserJoined, Payload: "{}", Status: models.DeliveryPending, NextAttemptAt: now.Add(time.Hour)},
+		{WebhookID: hook.ID, Event: models.WebhookUserJoined, Payload: "{}", Status: models.DeliveryDelivered, NextAttemptAt: now.Add(-time.Hour)},
+	}
+	if err := a.WebhookRepo().CreateDeliveries(nil, deliveries); err != nil {
+		t.Fatalf("could not create deliveries: %v", err)
+	}
+
+	due, err := a.WebhookRepo().GetDueDeliveries(nil, now, 10)
+	if err != nil || len(due) != 1 || due[0].ID != deliveries[0].ID {
+		t.Fatalf("expected only delivery %d to be due, got %v (%v)", deliveries[0].ID, due, err)
+	}
+
+	due[0].Status = models.DeliveryFailed
+	due[0].Attempts = 10
+	if err := a.WebhookRepo().UpdateDelivery(nil, &due[0]); err != nil {
+		t.Fatalf("could not update delivery: %v", err)
+	}
+
+	if due, _ := a.WebhookRepo().GetDueDeliveries(nil, now.Add(2*time.Hour), 10); len(due) != 1 || due[0].ID != deliveries[1].ID {
+		t.Errorf("failed and delivered deliveries should not be due, got %v", due)
+	}
+
+	recent, err := a.WebhookRepo().GetDeliveries(nil, hook.ID, 2)
+	if err != nil || len(recent) != 2 || recent[0].ID != deliveries[2].ID {
+		t.Errorf("expected the two newest deliveries, got %v (%v)", recent, err)
+	}
+
+	if err := a.WebhookRepo().Delete(nil, hook.ID); err != nil {
+		t.Fatalf("could not delete webhook: %v", err)
+	}
+
+	if err := a.WebhookRepo().Delete(nil, hook.ID); err == nil {
+		t.Error("deleting a missing webhook should fail")
+	}
+
+	if recent, _ := a.WebhookRepo().GetDeliveries(nil, hook.ID, 10); len(recent) != 0 {
+		t.Errorf("deliveries should be deleted with their webhook, got %v", recent)
+	}
+}
+
+func Test_IncomingWebhooks(t *testing.T) {
+	defer func() {
+		if err := testdb.Truncate(a.DB()); err != nil {
+			t.Error("error truncating test database tables")
+		}
+	}()
+
+	roomId := uint(1)
+	builds := models.NewIncomingWebhook("builds", "builds-token", "95a62e6c-e0e7-46ee-8bc3-6cca62b4cb09", &roomId, "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+	alerts := models.NewIncomingWebhook("alerts", "alerts-token", "95a62e6c-e0e7-46ee-8bc3-6cca62b4cb09", nil, "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35")
+
+	for _, hook := range []*models.IncomingWebhook{builds, alerts} {
+		if err := a.WebhookRepo().CreateIncoming(nil, hook); err != nil {
+			t.Fatalf("could not create incoming webhook: %v", err)
+		}
+	}
+
+	got, err := a.WebhookRepo().GetIncomingByToken(nil, "alerts-token")
+	if err != nil || got.ID != alerts.ID || got.RoomID != nil {
+		t.Errorf("expected incoming webhook %d, got %v (%v)", alerts.ID, got, err)
+	}
+
+	if _, err := a.WebhookRepo().GetIncomingByToken(nil, builds.TokenHash); err == nil {
+		t.Error("lookup by the stored hash should fail")
+	}
+
+	if err := a.WebhookRepo().DeleteIncoming(nil, alerts.ID); err != nil {
+		t.Fatalf("could not delete incoming webhook: %v", err)
+	}
+
+	if _, err := a.WebhookRepo().GetIncomingById(nil, alerts.ID); err == nil {
+		t.Error("deleted incoming webhook should not be found")
+	}
+
+	hooks, err := a.WebhookRepo().GetAllIncoming(nil)
+	if err != nil || len(hooks) != 1 || hooks[0].ID != builds.ID || *hooks[0].RoomID != roomId {
+		t.Errorf("expected only incoming webhook %d to be left, got %v (%v)", builds.ID, hooks, err)
+	}
+}
@@ -8,7 +8,48 @@ import (
 )
 
 func Truncate(db *gorm.DB) error {
-	result := db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.User{})
+	result := db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.MessageEdit{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	result = db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Reaction{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	result = db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.ReadMarker{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	result = db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Mention{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	result = db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Attachment{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	result = db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Message{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	result = db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.APIKey{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	result = db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.User{})
 
 	if result.Error != nil {
 		return result.Error
@@ -20,6 +61,36 @@ func Truncate(db *gorm.DB) error {
 		return result.Error
 	}
 
+	result = db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.RoomMember{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	result = db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Room{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	result = db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.WebhookDelivery{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	result = db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Webhook{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	result = db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.IncomingWebhook{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
 	return nil
 }
 
@@ -56,7 +127,8 @@ func SeedUsers(db *gorm.DB) (map[types.Uuid]models.User, error) {
 	return usersMap, nil
 }
 
-func SeedTokens(db *gorm.DB) (map[string]models.Token, error) {
+// SeedTokens stores tokens hashed with secret, the map is keyed by plaintext tokens.
+func SeedTokens(db *gorm.DB, secret string) (map[string]models.Token, error) {
 	_, err := SeedUsers(db)
 	if err != nil {
 		return nil, err
@@ -80,6 +152,10 @@ func SeedTokens(db *gorm.DB) (map[string]models.Token, error) {
 		},
 	}
 
+	for i := range tokens {
+		tokens[i].TokenHash = models.HashToken(secret, tokens[i].Token)
+	}
+
 	result := db.Create(&tokens)
 
 	if result.Error != nil {
@@ -93,3 +169,72 @@ func SeedTokens(db *gorm.DB) (map[string]models.Token, error) {
 
 	return tokensMap, nil
 }
+
+func SeedRooms(db *gorm.DB) (map[string]models.Room, error) {
+	_, err := SeedUsers(db)
+	if err != nil {
+		return nil, err
+	}
+
+	rooms := []models.Room{
+		{Name: "general", Topic: "Company-wide announcements"},
+		{Name: "project-x"},
+		{Name: "random", Topic: "Off-topic"},
+	}
+
+	result := db.Create(&rooms)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	members := []models.RoomMember{
+		{RoomID: rooms[0].ID, UserUuid: "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35", JoinedAt: time.Now()},
+		{RoomID: rooms[1].ID, UserUuid: "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35", JoinedAt: time.Now()},
+		{RoomID: rooms[0].ID, UserUuid: "b3341b87-c561-4142-bd28-f9ecde74822b", JoinedAt: time.Now()},
+	}
+
+	result = db.Create(&members)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	roomsMap := make(map[string]models.Room)
+	for i := range rooms {
+		roomsMap[rooms[i].Name] = rooms[i]
+	}
+
+	return roomsMap, nil
+}
+
+func SeedMessages(db *gorm.DB) (map[string]models.Message, error) {
+	rooms, err := SeedRooms(db)
+	if err != nil {
+		return nil, err
+	}
+
+	generalId := rooms["general"].ID
+	user2 := types.Uuid("95a62e6c-e0e7-46ee-8bc3-6cca62b4cb09")
+	user3 := types.Uuid("b3341b87-c561-4142-bd28-f9ecde74822b")
+
+	messages := []models.Message{
+		{AuthorUuid: "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35", Message: "lobby"},
+		{AuthorUuid: "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35", RoomID: &generalId, Message: "general"},
+		{AuthorUuid: "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35", RecipientUuid: &user2, Message: "dm to user2"},
+		{AuthorUuid: "6b2db94c-6fce-4673-a1ce-d24ff6bd4d35", RecipientUuid: &user3, Message: "dm to user3"},
+	}
+
+	result := db.Create(&messages)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	messagesMap := make(map[string]models.Message)
+	for i := range messages {
+		messagesMap[messages[i].Message] = messages[i]
+	}
+
+	return messagesMap, nil
+}
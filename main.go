@@ -28,8 +28,8 @@ func main() {
 }
 
 func runServer(app *app.Application) {
-	s := server.New(*app.Config(), app.UserRepo(), app.TokenRepo(), app.MessageRepo(), app.Logger())
-	h := server.Handler(s)
+	s := server.New(*app.Config(), app.UserRepo(), app.TokenRepo(), app.MessageRepo(), app.RoomRepo(), app.ReadMarkerRepo(), app.ReactionRepo(), app.MentionRepo(), app.AttachmentRepo(), app.APIKeyRepo(), app.WebhookRepo(), app.AccessTokens(), app.Storage(), app.Logger())
+	h := s.Router()
 
 	err := http.ListenAndServe(":"+strconv.Itoa(s.Port()), h)
 	if err != nil {
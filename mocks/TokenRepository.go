@@ -8,6 +8,8 @@ import (
 	models "github.com/id-tarzanych/lets-go-chat/models"
 	mock "github.com/stretchr/testify/mock"
 
+	time "time"
+
 	types "github.com/id-tarzanych/lets-go-chat/internal/types"
 )
 
@@ -44,6 +46,34 @@ func (_m *TokenRepository) Delete(ctx context.Context, _a1 string) error {
 	return r0
 }
 
+// DeleteByUserId provides a mock function with given fields: ctx, userId
+func (_m *TokenRepository) DeleteByUserId(ctx context.Context, userId types.Uuid) error {
+	ret := _m.Called(ctx, userId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, types.Uuid) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteFamily provides a mock function with given fields: ctx, familyId
+func (_m *TokenRepository) DeleteFamily(ctx context.Context, familyId string) error {
+	ret := _m.Called(ctx, familyId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, familyId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields: ctx, _a1
 func (_m *TokenRepository) Get(ctx context.Context, _a1 string) (models.Token, error) {
 	ret := _m.Called(ctx, _a1)
@@ -110,3 +140,17 @@ func (_m *TokenRepository) GetByUserId(ctx context.Context, userId types.Uuid) (
 
 	return r0, r1
 }
+
+// MarkUsed provides a mock function with given fields: ctx, _a1, at
+func (_m *TokenRepository) MarkUsed(ctx context.Context, _a1 string, at time.Time) error {
+	ret := _m.Called(ctx, _a1, at)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = rf(ctx, _a1, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -111,6 +111,20 @@ func (_m *UserRepository) GetByUserName(ctx context.Context, name string) (model
 	return r0, r1
 }
 
+// SetAdmins provides a mock function with given fields: ctx, userNames
+func (_m *UserRepository) SetAdmins(ctx context.Context, userNames []string) error {
+	ret := _m.Called(ctx, userNames)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) error); ok {
+		r0 = rf(ctx, userNames)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Update provides a mock function with given fields: ctx, u
 func (_m *UserRepository) Update(ctx context.Context, u *models.User) error {
 	ret := _m.Called(ctx, u)
@@ -138,3 +152,31 @@ func (_m *UserRepository) UpdateLastActivity(ctx context.Context, u *models.User
 
 	return r0
 }
+
+// UpdatePassword provides a mock function with given fields: ctx, u
+func (_m *UserRepository) UpdatePassword(ctx context.Context, u *models.User) error {
+	ret := _m.Called(ctx, u)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) error); ok {
+		r0 = rf(ctx, u)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateUserName provides a mock function with given fields: ctx, u, name
+func (_m *UserRepository) UpdateUserName(ctx context.Context, u *models.User, name string) error {
+	ret := _m.Called(ctx, u, name)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User, string) error); ok {
+		r0 = rf(ctx, u, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+)
+
+// APIKey is a long-lived credential of a bot user. Only a hash of the key is stored,
+// the key itself is shown once when it is issued.
+type APIKey struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserId    types.Uuid `gorm:"index"`
+	KeyHash   string     `gorm:"uniqueIndex"`
+	CreatedAt time.Time
+}
+
+func NewAPIKey(key string, userId types.Uuid) *APIKey {
+	return &APIKey{UserId: userId, KeyHash: HashAPIKey(key)}
+}
+
+// HashAPIKey returns the hash API keys are stored and looked up by.
+// Keys are random and long, so an unsalted hash is enough to keep a database leak from revealing them.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
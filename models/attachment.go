@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+)
+
+// Attachment describes a file kept in the blob storage under StorageKey.
+// It is uploaded on its own and linked to the message it is sent with afterwards.
+type Attachment struct {
+	ID           uint       `gorm:"primaryKey"`
+	UploaderUuid types.Uuid `gorm:"index"`
+	MessageID    *uint      `gorm:"index"`
+	FileName     string
+	ContentType  string
+	Size         int64
+	StorageKey   string
+	CreatedAt    time.Time
+}
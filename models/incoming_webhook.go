@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+)
+
+// IncomingWebhook lets an external system post messages as UserId without a chat connection.
+// Messages go to the room RoomID, or to the public chat when it is nil.
+// The token is part of the webhook url, only its hash is stored like that of an API key.
+type IncomingWebhook struct {
+	ID          uint `gorm:"primaryKey"`
+	Name        string
+	TokenHash   string     `gorm:"uniqueIndex"`
+	UserId      types.Uuid `gorm:"index"`
+	RoomID      *uint
+	CreatorUuid types.Uuid
+	CreatedAt   time.Time
+}
+
+func NewIncomingWebhook(name, token string, userId types.Uuid, roomId *uint, creatorUuid types.Uuid) *IncomingWebhook {
+	return &IncomingWebhook{Name: name, TokenHash: HashAPIKey(token), UserId: userId, RoomID: roomId, CreatorUuid: creatorUuid}
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+)
+
+// Mention records that a message named a user with an @username token.
+type Mention struct {
+	MessageID uint       `gorm:"primaryKey;autoIncrement:false"`
+	UserUuid  types.Uuid `gorm:"primaryKey;index"`
+	CreatedAt time.Time
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 
 	"github.com/id-tarzanych/lets-go-chat/internal/types"
@@ -9,7 +11,45 @@ import (
 type Message struct {
 	gorm.Model
 
-	AuthorUuid types.Uuid
-	Author     User `gorm:"foreignKey:AuthorUuid"`
-	Message    string
+	AuthorUuid    types.Uuid `gorm:"uniqueIndex:idx_messages_author_client"`
+	Author        User       `gorm:"foreignKey:AuthorUuid"`
+	RoomID        *uint
+	Room          *Room
+	RecipientUuid *types.Uuid
+	Recipient     *User `gorm:"foreignKey:RecipientUuid"`
+	Message       string
+	ClientId      *string `gorm:"uniqueIndex:idx_messages_author_client"`
+	EditedAt      *time.Time
+
+	// ParentID points replies at the root message of their thread.
+	ParentID    *uint `gorm:"index"`
+	ReplyCount  int
+	LastReplyAt *time.Time
+
+	// Attachments uploaded beforehand are linked to the message when it is created.
+	Attachments []Attachment
+
+	// Reactions are aggregated by the reaction repository, they are not stored with the message.
+	Reactions []ReactionCount `gorm:"-"`
+}
+
+// ChangedAt returns the latest time the message was posted, edited, deleted or replied to.
+func (m Message) ChangedAt() time.Time {
+	changedAt := m.CreatedAt
+
+	for _, t := range []*time.Time{m.EditedAt, &m.DeletedAt.Time, m.LastReplyAt} {
+		if t != nil && t.After(changedAt) {
+			changedAt = *t
+		}
+	}
+
+	return changedAt
+}
+
+// MessageEdit keeps the text a message had before one of its edits.
+type MessageEdit struct {
+	ID        uint `gorm:"primaryKey"`
+	MessageID uint `gorm:"index"`
+	Message   string
+	EditedAt  time.Time
 }
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestMessage_ChangedAt(t *testing.T) {
+	createdAt := time.Now().Add(-time.Hour)
+	editedAt := createdAt.Add(time.Minute)
+	repliedAt := createdAt.Add(2 * time.Minute)
+	deletedAt := createdAt.Add(3 * time.Minute)
+
+	tests := []struct {
+		name    string
+		message Message
+		want    time.Time
+	}{
+		{
+			name:    "Posted",
+			message: Message{Model: gorm.Model{CreatedAt: createdAt}},
+			want:    createdAt,
+		},
+		{
+			name:    "Edited",
+			message: Message{Model: gorm.Model{CreatedAt: createdAt}, EditedAt: &editedAt},
+			want:    editedAt,
+		},
+		{
+			name:    "Replied to after edit",
+			message: Message{Model: gorm.Model{CreatedAt: createdAt}, EditedAt: &editedAt, LastReplyAt: &repliedAt},
+			want:    repliedAt,
+		},
+		{
+			name: "Deleted",
+			message: Message{
+				Model:       gorm.Model{CreatedAt: createdAt, DeletedAt: gorm.DeletedAt{Time: deletedAt, Valid: true}},
+				EditedAt:    &editedAt,
+				LastReplyAt: &repliedAt,
+			},
+			want: deletedAt,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.message.ChangedAt(); !got.Equal(tt.want) {
+				t.Errorf("ChangedAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+)
+
+// Reaction is an emoji a user attached to a message, a user reacts with the same emoji only once.
+type Reaction struct {
+	MessageID uint       `gorm:"primaryKey;autoIncrement:false"`
+	UserUuid  types.Uuid `gorm:"primaryKey"`
+	Emoji     string     `gorm:"primaryKey"`
+	CreatedAt time.Time
+}
+
+// ReactionCount aggregates reactions with the same emoji on a message.
+type ReactionCount struct {
+	Emoji string
+	Count int
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+)
+
+// ReadMarker points at the last message a user has read in a conversation.
+// Room conversations are identified by RoomID, direct ones by PeerUuid and the public chat by neither.
+type ReadMarker struct {
+	UserUuid  types.Uuid `gorm:"primaryKey"`
+	RoomID    uint       `gorm:"primaryKey;autoIncrement:false"`
+	PeerUuid  types.Uuid `gorm:"primaryKey"`
+	MessageID uint
+	ReadAt    time.Time
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+)
+
+type Room struct {
+	gorm.Model
+
+	Name  string `gorm:"uniqueIndex"`
+	Topic string
+
+	// CreatedBy is the user who created the room, rooms created before it was recorded have none.
+	CreatedBy *types.Uuid `gorm:"index"`
+}
+
+type RoomMember struct {
+	RoomID   uint       `gorm:"primaryKey;autoIncrement:false"`
+	UserUuid types.Uuid `gorm:"primaryKey"`
+	JoinedAt time.Time
+
+	// MutedUntil keeps the member from posting to the room until the given time.
+	MutedUntil *time.Time
+}
+
+// Muted tells whether the member may not post to the room at now.
+func (m RoomMember) Muted(now time.Time) bool {
+	return m.MutedUntil != nil && now.Before(*m.MutedUntil)
+}
+
+func NewRoom(name, topic string, createdBy types.Uuid) *Room {
+	return &Room{Name: name, Topic: topic, CreatedBy: &createdBy}
+}
@@ -1,20 +1,59 @@
 package models
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"gorm.io/gorm"
 	"time"
 
 	"github.com/id-tarzanych/lets-go-chat/internal/types"
 )
 
+// Kinds of tokens.
+const (
+	// TokenChat is a one-time token starting a chat session over WebSocket.
+	TokenChat = "chat"
+	// TokenRefresh is exchanged once for a new access token and the next refresh token of its family.
+	TokenRefresh = "refresh"
+)
+
+// Token is a one-time chat token or a refresh token. Only a keyed hash of the token is stored,
+// so a database dump does not hand out live sessions.
 type Token struct {
 	gorm.Model
 
-	Token      string `gorm:"primaryKey,column:token"`
+	// Token is handed to the client once and never stored.
+	Token      string `gorm:"-"`
+	TokenHash  string `gorm:"uniqueIndex"`
+	Kind       string `gorm:"index;default:chat"`
 	UserId     types.Uuid
 	Expiration time.Time
+
+	// FamilyId is shared by the refresh tokens descending from the same login
+	// and the chat tokens requested with their access tokens.
+	FamilyId string `gorm:"index"`
+	// UsedAt is set once a refresh token was exchanged, presenting it again revokes its family.
+	UsedAt *time.Time
 }
 
 func NewToken(token string, userId types.Uuid, expiration time.Time) *Token {
-	return &Token{Token: token, UserId: userId, Expiration: expiration}
+	return &Token{Token: token, Kind: TokenChat, UserId: userId, Expiration: expiration}
+}
+
+func NewRefreshToken(token string, userId types.Uuid, familyId string, expiration time.Time) *Token {
+	return &Token{Token: token, Kind: TokenRefresh, UserId: userId, Expiration: expiration, FamilyId: familyId}
+}
+
+func (t Token) IsRefresh() bool {
+	return t.Kind == TokenRefresh
+}
+
+// HashToken returns the hash tokens are stored and looked up by, an HMAC-SHA256 keyed with the server secret.
+// Unlike an unsalted hash, it can not be checked against guessed tokens without the secret.
+func HashToken(secret, token string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(token))
+
+	return hex.EncodeToString(mac.Sum(nil))
 }
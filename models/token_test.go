@@ -56,3 +56,25 @@ func TestNewToken(t *testing.T) {
 		})
 	}
 }
+
+func TestHashToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		token  string
+		want   string
+	}{
+		{"Token", "secret", "glf1LdUMtwLssv48", "6ae3a90dc4b79e4ce8e8835537ac997d0c077aede3eda7b27933394125bc989f"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HashToken(tt.secret, tt.token); got != tt.want {
+				t.Errorf("HashToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if HashToken("secret", "glf1LdUMtwLssv48") == HashToken("other", "glf1LdUMtwLssv48") {
+		t.Errorf("HashToken() should depend on the secret")
+	}
+}
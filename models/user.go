@@ -15,23 +15,45 @@ type User struct {
 	gorm.Model
 
 	ID           types.Uuid `gorm:"primaryKey"`
-	UserName     string     `gorm:"column:username"`
+	UserName     string     `gorm:"column:username;uniqueIndex"`
 	PasswordHash string     `gorm:"column:password"`
 	LastActivity time.Time
+
+	// Admin users may moderate the chat.
+	Admin bool
+
+	// Bot users authenticate with API keys instead of a password and belong to the user who created them.
+	Bot     bool
+	OwnerId *types.Uuid `gorm:"index"`
 }
 
-func NewUser(username, password string) *User {
+func NewUser(username, password string) (*User, error) {
 	id, _ := uuid.NewUUID()
 	u := &User{ID: types.Uuid(id.String()), UserName: username}
 
-	u.SetPassword(password)
+	if err := u.SetPassword(password); err != nil {
+		return nil, err
+	}
 
-	return u
+	return u, nil
 }
 
-func (u *User) SetPassword(password string) *User {
-	hash, _ := hasher.HashPassword(password)
+// NewBot creates a bot account owned by ownerId. Bots have no password and can only log in with an API key.
+func NewBot(username string, ownerId types.Uuid) *User {
+	id, _ := uuid.NewUUID()
+
+	return &User{ID: types.Uuid(id.String()), UserName: username, Bot: true, OwnerId: &ownerId}
+}
+
+// SetPassword hashes the password with the default hasher of the hasher package.
+// The stored hash is left unchanged if hashing fails.
+func (u *User) SetPassword(password string) error {
+	hash, err := hasher.Default.Hash(password)
+	if err != nil {
+		return err
+	}
+
 	u.PasswordHash = hash
 
-	return u
+	return nil
 }
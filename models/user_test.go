@@ -1,8 +1,12 @@
 package models
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
+
+	"github.com/id-tarzanych/lets-go-chat/pkg/hasher"
 )
 
 func TestNewUser(t *testing.T) {
@@ -24,16 +28,19 @@ func TestNewUser(t *testing.T) {
 			},
 			want: pair{
 				username: "user1",
-				password: "ef797c8118f02dfb649607dd5d3f8c7623048c9c063d532cc95c5ed7a898a64f",
+				password: "12345678",
 			},
 		},
-		{"User 2", pair{"user2", "87654321"}, pair{"user2", "e24df920078c3dd4e7e8d2442f00e5c9ab2a231bb3918d65cc50906e49ecaef4"}},
+		{"User 2", pair{"user2", "87654321"}, pair{"user2", "87654321"}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := NewUser(tt.args.username, tt.args.password)
+			got, err := NewUser(tt.args.username, tt.args.password)
+			if err != nil {
+				t.Fatalf("NewUser() error = %v", err)
+			}
 
-			if got.UserName != tt.want.username || got.PasswordHash != tt.want.password {
+			if ok, _ := hasher.Verify(tt.want.password, got.PasswordHash); got.UserName != tt.want.username || !ok {
 				t.Errorf("NewUser() = %v, want %v", got, tt.want)
 			}
 		})
@@ -46,54 +53,74 @@ func TestUser_SetPassword(t *testing.T) {
 	tests := []struct {
 		name     string
 		password string
-		wantHash string
 	}{
 		{
 			name:     "Password 1",
 			password: "12345678",
-			wantHash: "ef797c8118f02dfb649607dd5d3f8c7623048c9c063d532cc95c5ed7a898a64f",
 		},
 		{
 			name:     "Password 2",
 			password: "Secr3tP@s$w0rd",
-			wantHash: "39a982ef00b8dff3632db20d56141060648ad3bf0dbc57d3e57386aa6b3b81d1",
 		},
 		{
 			name:     "Password 3",
 			password: "SomePass",
-			wantHash: "c21c4ec6e85ba12ccb2ccf117dd405a9f5915f2722d50dce4739400b164cfb9e",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			user.SetPassword(tt.password)
+			if err := user.SetPassword(tt.password); err != nil {
+				t.Fatalf("SetPassword() error = %v", err)
+			}
 
-			if got := user.PasswordHash; got != tt.wantHash {
-				t.Errorf("Password = %v, want %v", got, tt.wantHash)
+			if got := user.PasswordHash; !strings.HasPrefix(got, "$argon2id$") {
+				t.Errorf("Password = %v, want argon2id hash", got)
+			}
+
+			if ok, err := hasher.Verify(tt.password, user.PasswordHash); !ok {
+				t.Errorf("Password %v does not match its hash: %v", tt.password, err)
 			}
 		})
 	}
 }
 
+func TestUser_SetPassword_Error(t *testing.T) {
+	user := &User{UserName: "user", PasswordHash: "p@ssw0rd"}
+
+	if err := user.SetPassword(""); !errors.Is(err, hasher.ErrEmptyPassword) {
+		t.Errorf("SetPassword() error = %v, want %v", err, hasher.ErrEmptyPassword)
+	}
+
+	if user.PasswordHash != "p@ssw0rd" {
+		t.Errorf("Password = %v, want the previous hash to be kept", user.PasswordHash)
+	}
+
+	if _, err := NewUser("user", ""); !errors.Is(err, hasher.ErrEmptyPassword) {
+		t.Errorf("NewUser() error = %v, want %v", err, hasher.ErrEmptyPassword)
+	}
+}
+
 func ExampleNewUser() {
-	user := NewUser("testuser", "12345678")
+	user, _ := NewUser("testuser", "12345678")
 
 	fmt.Println(user.UserName)
-	fmt.Println(user.PasswordHash)
+	fmt.Println(hasher.Verify("12345678", user.PasswordHash))
 
 	// Output:
 	// testuser
-	// ef797c8118f02dfb649607dd5d3f8c7623048c9c063d532cc95c5ed7a898a64f
+	// true <nil>
 }
 
 func ExampleUser_SetPassword() {
-	user := NewUser("testuser", "12345678")
-	user.SetPassword("87654321")
+	user, _ := NewUser("testuser", "12345678")
+	_ = user.SetPassword("87654321")
 
-	fmt.Println(user.PasswordHash)
+	fmt.Println(hasher.Verify("12345678", user.PasswordHash))
+	fmt.Println(hasher.Verify("87654321", user.PasswordHash))
 
 	// Output:
-	// e24df920078c3dd4e7e8d2442f00e5c9ab2a231bb3918d65cc50906e49ecaef4
+	// false <nil>
+	// true <nil>
 }
 
 func BenchmarkNewUser(b *testing.B) {
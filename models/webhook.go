@@ -0,0 +1,85 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/id-tarzanych/lets-go-chat/internal/types"
+)
+
+// Events delivered to webhooks.
+const (
+	WebhookMessageCreated = "message.created"
+	WebhookMessageEdited  = "message.edited"
+	WebhookMessageDeleted = "message.deleted"
+	WebhookUserJoined     = "user.joined"
+	WebhookUserLeft       = "user.left"
+	WebhookUserRegistered = "user.registered"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to.
+var WebhookEvents = []string{
+	WebhookMessageCreated,
+	WebhookMessageEdited,
+	WebhookMessageDeleted,
+	WebhookUserJoined,
+	WebhookUserLeft,
+	WebhookUserRegistered,
+}
+
+// Webhook is an HTTP endpoint subscribed to chat events. Deliveries are signed with Secret.
+type Webhook struct {
+	ID          uint `gorm:"primaryKey"`
+	Url         string
+	Secret      string
+	CreatorUuid types.Uuid
+	CreatedAt   time.Time
+
+	// Events is the comma separated list of subscribed events.
+	Events string
+}
+
+func NewWebhook(url, secret string, events []string, creatorUuid types.Uuid) *Webhook {
+	return &Webhook{Url: url, Secret: secret, CreatorUuid: creatorUuid, Events: strings.Join(events, ",")}
+}
+
+func (w Webhook) EventList() []string {
+	if w.Events == "" {
+		return []string{}
+	}
+
+	return strings.Split(w.Events, ",")
+}
+
+func (w Webhook) Subscribes(event string) bool {
+	for _, e := range w.EventList() {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is an event queued for a webhook. Payload is stored as sent, so retries are identical.
+type WebhookDelivery struct {
+	ID            uint `gorm:"primaryKey"`
+	WebhookID     uint `gorm:"index"`
+	Event         string
+	Payload       string
+	Status        string    `gorm:"index:idx_webhook_deliveries_due"`
+	NextAttemptAt time.Time `gorm:"index:idx_webhook_deliveries_due"`
+	Attempts      int
+	LastError     string
+	// LastStatusCode is the response status of the last attempt, zero when no response was received.
+	LastStatusCode int
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
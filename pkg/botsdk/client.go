@@ -0,0 +1,551 @@
+/*
+Package botsdk is a client library for bots of the chat.
+
+A bot logs in with an API key issued through POST /bots, joins the chat over WebSocket
+and passes incoming messages and events to the handlers registered with the client.
+Lost connections are re-established with exponential backoff, messages sent while the bot
+was away are replayed by the server once it is back.
+
+	bot := botsdk.New("http://localhost:8080", os.Getenv("BOT_API_KEY"), botsdk.Options{})
+	bot.HandleCommand("ping", func(ctx context.Context, c *botsdk.Client, cmd *botsdk.Command) {
+		_ = c.Reply(ctx, cmd.Message, "pong")
+	})
+
+	log.Fatal(bot.Run(context.Background()))
+*/
+package botsdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultCommandPrefix = "!"
+	defaultMinBackoff    = time.Second
+	defaultMaxBackoff    = time.Minute
+	writeTimeout         = 10 * time.Second
+)
+
+var (
+	// ErrNotConnected is returned when sending while the client has no connection to the chat.
+	ErrNotConnected = errors.New("botsdk: not connected")
+	// ErrUnauthorized is returned by Run when the server rejects the API key. Retrying would not help.
+	ErrUnauthorized = errors.New("botsdk: API key was rejected")
+)
+
+// MessageHandler handles chat messages which are not commands.
+type MessageHandler func(ctx context.Context, c *Client, m *Message)
+
+// CommandHandler handles chat messages invoking a command, e.g. "!roll 2d6".
+type CommandHandler func(ctx context.Context, c *Client, cmd *Command)
+
+// EventHandler handles raw frames of a type, e.g. EventPresence.
+type EventHandler func(ctx context.Context, c *Client, e Envelope)
+
+// Command is a message starting with the command prefix and the command name.
+type Command struct {
+	*Message
+
+	// Name is the lower case command name without the prefix.
+	Name string
+	// Args are the words following the name.
+	Args []string
+	// Text is everything after the name.
+	Text string
+}
+
+type Options struct {
+	// CommandPrefix starts commands in messages, "!" by default.
+	CommandPrefix string
+
+	// MinBackoff is the delay before the first reconnect attempt, it doubles with every failed attempt up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// ReadTimeout drops connections the server has sent nothing on for this long, pings included.
+	// Zero waits forever.
+	ReadTimeout time.Duration
+
+	HTTPClient *http.Client
+	Dialer     *websocket.Dialer
+
+	// Logf reports connection failures, nil discards them.
+	Logf func(format string, args ...interface{})
+}
+
+// Identity is the user the bot is logged in as.
+type Identity struct {
+	Id       string
+	UserName string
+}
+
+// Client is a bot connected to the chat. Handlers are registered before calling Run.
+type Client struct {
+	baseURL string
+	apiKey  string
+	opts    Options
+
+	messageHandlers []MessageHandler
+	commandHandlers map[string]CommandHandler
+	eventHandlers   map[string][]EventHandler
+
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	self    Identity
+	pending map[string]chan error
+	lastId  uint64
+}
+
+// New creates a client of the chat served at baseURL, e.g. "https://chat.example.com".
+func New(baseURL, apiKey string, opts Options) *Client {
+	if opts.CommandPrefix == "" {
+		opts.CommandPrefix = defaultCommandPrefix
+	}
+
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = defaultMinBackoff
+	}
+
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = defaultMaxBackoff
+		if opts.MaxBackoff < opts.MinBackoff {
+			opts.MaxBackoff = opts.MinBackoff
+		}
+	}
+
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+
+	if opts.Dialer == nil {
+		opts.Dialer = websocket.DefaultDialer
+	}
+
+	return &Client{
+		baseURL:         strings.TrimSuffix(baseURL, "/"),
+		apiKey:          apiKey,
+		opts:            opts,
+		commandHandlers: make(map[string]CommandHandler),
+		eventHandlers:   make(map[string][]EventHandler),
+		pending:         make(map[string]chan error),
+	}
+}
+
+// HandleMessage registers h for messages of other users which do not invoke a registered command.
+func (c *Client) HandleMessage(h MessageHandler) {
+	c.messageHandlers = append(c.messageHandlers, h)
+}
+
+// HandleCommand registers h for messages invoking the command name, names are matched case-insensitively.
+func (c *Client) HandleCommand(name string, h CommandHandler) {
+	c.commandHandlers[strings.ToLower(name)] = h
+}
+
+// HandleEvent registers h for every frame of the type, own messages and acks included.
+func (c *Client) HandleEvent(eventType string, h EventHandler) {
+	c.eventHandlers[eventType] = append(c.eventHandlers[eventType], h)
+}
+
+// Self returns the user the bot is logged in as, it is known once the first login succeeded.
+func (c *Client) Self() Identity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.self
+}
+
+// Run connects to the chat and handles incoming frames until ctx is done, reconnecting whenever the connection is lost.
+// It returns ErrUnauthorized when the API key is rejected and the error of ctx otherwise.
+//
+// Handlers run one at a time in the order frames arrive. They may send messages, long running work
+// should move to a goroutine of its own so other frames are not held up.
+func (c *Client) Run(ctx context.Context) error {
+	backoff := c.opts.MinBackoff
+
+	for {
+		connected, err := c.session(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if errors.Is(err, ErrUnauthorized) {
+			return err
+		}
+
+		if connected {
+			backoff = c.opts.MinBackoff
+		}
+
+		c.logf("botsdk: connection lost, reconnecting in %s: %v", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > c.opts.MaxBackoff {
+			backoff = c.opts.MaxBackoff
+		}
+	}
+}
+
+// Send posts the message and waits until the server acknowledges it.
+// Rejected messages return an *Error, messages sent while disconnected return ErrNotConnected.
+func (c *Client) Send(ctx context.Context, m OutgoingMessage) error {
+	c.mu.Lock()
+
+	if c.conn == nil {
+		c.mu.Unlock()
+
+		return ErrNotConnected
+	}
+
+	c.lastId++
+	id := strconv.FormatUint(c.lastId, 10)
+
+	env, err := newEnvelope(commandSendMessage, id, m)
+	if err != nil {
+		c.mu.Unlock()
+
+		return err
+	}
+
+	done := make(chan error, 1)
+	c.pending[id] = done
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := c.conn.WriteJSON(env); err != nil {
+		delete(c.pending, id)
+		c.mu.Unlock()
+
+		return err
+	}
+
+	c.mu.Unlock()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		c.resolve(id, nil)
+
+		return ctx.Err()
+	}
+}
+
+// Reply answers m in the conversation it was posted to, within its thread if it is a reply.
+func (c *Client) Reply(ctx context.Context, m *Message, text string) error {
+	reply := OutgoingMessage{Text: text, Room: m.Room, ParentId: m.ParentId}
+	if m.Room == "" && m.To != "" {
+		reply.To = m.Author
+	}
+
+	return c.Send(ctx, reply)
+}
+
+type loginResponse struct {
+	Url      string `json:"url"`
+	Id       string `json:"id"`
+	UserName string `json:"userName"`
+}
+
+// login exchanges the API key for a one-time link to the chat WebSocket.
+func (c *Client) login(ctx context.Context) (loginResponse, error) {
+	body, err := json.Marshal(map[string]string{"apiKey": c.apiKey})
+	if err != nil {
+		return loginResponse{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/bot/login", bytes.NewReader(body))
+	if err != nil {
+		return loginResponse{}, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return loginResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
+		return loginResponse{}, ErrUnauthorized
+	default:
+		return loginResponse{}, fmt.Errorf("botsdk: login failed with status %s", resp.Status)
+	}
+
+	var login loginResponse
+	if err := json.NewDecoder(resp.Body).Decode(&login); err != nil {
+		return loginResponse{}, fmt.Errorf("botsdk: invalid login response: %w", err)
+	}
+
+	// The server links to the socket without knowing whether it is reached through TLS.
+	if strings.HasPrefix(c.baseURL, "https://") {
+		u, err := url.Parse(login.Url)
+		if err != nil {
+			return loginResponse{}, fmt.Errorf("botsdk: invalid chat url: %w", err)
+		}
+
+		u.Scheme = "wss"
+		login.Url = u.String()
+	}
+
+	return login, nil
+}
+
+// session logs in and handles frames of one connection until it is lost. It reports whether the connection was established.
+func (c *Client) session(ctx context.Context) (bool, error) {
+	login, err := c.login(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	conn, _, err := c.opts.Dialer.DialContext(ctx, login.Url, nil)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	c.self = Identity{Id: login.Id, UserName: login.UserName}
+	c.mu.Unlock()
+
+	frames := newFrameQueue()
+	handled := make(chan struct{})
+
+	go func() {
+		defer close(handled)
+
+		for env, ok := frames.pop(); ok; env, ok = frames.pop() {
+			c.dispatch(ctx, env)
+		}
+	}()
+
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeTimeout))
+			_ = conn.Close()
+		case <-stop:
+		}
+	}()
+
+	err = c.read(conn, frames)
+
+	close(stop)
+	c.disconnect(conn)
+	frames.close()
+	<-handled
+
+	return true, err
+}
+
+// read passes frames of the connection on until reading fails, acks are resolved right away.
+func (c *Client) read(conn *websocket.Conn, frames *frameQueue) error {
+	extend := func() {
+		if c.opts.ReadTimeout > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(c.opts.ReadTimeout))
+		}
+	}
+
+	conn.SetPingHandler(func(data string) error {
+		extend()
+
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(writeTimeout))
+	})
+
+	for {
+		extend()
+
+		_, p, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		var env Envelope
+		if err := json.Unmarshal(p, &env); err != nil {
+			c.logf("botsdk: ignoring invalid frame: %v", err)
+
+			continue
+		}
+
+		switch env.Type {
+		case EventAck:
+			c.resolve(env.Id, nil)
+		case EventError:
+			protocolErr := &Error{}
+			if err := env.Decode(protocolErr); err != nil {
+				protocolErr = &Error{Code: "invalid_frame", Message: err.Error()}
+			}
+
+			c.resolve(env.Id, protocolErr)
+		}
+
+		frames.push(env)
+	}
+}
+
+// disconnect forgets the connection and fails messages still waiting for an ack.
+func (c *Client) disconnect(conn *websocket.Conn) {
+	_ = conn.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.conn = nil
+
+	for id, done := range c.pending {
+		done <- ErrNotConnected
+		delete(c.pending, id)
+	}
+}
+
+func (c *Client) resolve(id string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if done, ok := c.pending[id]; ok {
+		done <- err
+		delete(c.pending, id)
+	}
+}
+
+func (c *Client) dispatch(ctx context.Context, env Envelope) {
+	for _, h := range c.eventHandlers[env.Type] {
+		h(ctx, c, env)
+	}
+
+	if env.Type != EventMessage {
+		return
+	}
+
+	m := &Message{}
+	if err := env.Decode(m); err != nil {
+		c.logf("botsdk: ignoring invalid message: %v", err)
+
+		return
+	}
+
+	// Messages of the bot reach it as well.
+	if m.Author == c.Self().UserName {
+		return
+	}
+
+	if cmd, ok := c.parseCommand(m); ok {
+		if h, ok := c.commandHandlers[cmd.Name]; ok {
+			h(ctx, c, cmd)
+
+			return
+		}
+	}
+
+	for _, h := range c.messageHandlers {
+		h(ctx, c, m)
+	}
+}
+
+func (c *Client) parseCommand(m *Message) (*Command, bool) {
+	text := strings.TrimSpace(m.Text)
+	if !strings.HasPrefix(text, c.opts.CommandPrefix) {
+		return nil, false
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(text, c.opts.CommandPrefix))
+	if len(fields) == 0 {
+		return nil, false
+	}
+
+	rest := strings.TrimSpace(strings.TrimPrefix(text, c.opts.CommandPrefix))
+
+	return &Command{
+		Message: m,
+		Name:    strings.ToLower(fields[0]),
+		Args:    fields[1:],
+		Text:    strings.TrimSpace(rest[len(fields[0]):]),
+	}, true
+}
+
+func (c *Client) logf(format string, args ...interface{}) {
+	if c.opts.Logf != nil {
+		c.opts.Logf(format, args...)
+	}
+}
+
+// frameQueue hands frames from the reader over to the handlers. Pushing never blocks,
+// so acks keep arriving while a handler waits for one.
+type frameQueue struct {
+	mu     sync.Mutex
+	frames []Envelope
+	closed bool
+	ready  chan struct{}
+}
+
+func newFrameQueue() *frameQueue {
+	return &frameQueue{ready: make(chan struct{}, 1)}
+}
+
+func (q *frameQueue) push(env Envelope) {
+	q.mu.Lock()
+	q.frames = append(q.frames, env)
+	q.mu.Unlock()
+
+	q.signal()
+}
+
+// close lets pop return false once the frames already queued are taken.
+func (q *frameQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	q.signal()
+}
+
+func (q *frameQueue) pop() (Envelope, bool) {
+	for {
+		q.mu.Lock()
+
+		if len(q.frames) > 0 {
+			env := q.frames[0]
+			q.frames = q.frames[1:]
+			q.mu.Unlock()
+
+			return env, true
+		}
+
+		if q.closed {
+			q.mu.Unlock()
+
+			return Envelope{}, false
+		}
+
+		q.mu.Unlock()
+
+		<-q.ready
+	}
+}
+
+func (q *frameQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
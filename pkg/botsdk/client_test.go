@@ -0,0 +1,245 @@
+package botsdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeChat serves the bot login and hands the connections of the bot to the test.
+type fakeChat struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	logins int
+	conns  chan *websocket.Conn
+}
+
+func newFakeChat(t *testing.T) *fakeChat {
+	chat := &fakeChat{conns: make(chan *websocket.Conn, 4)}
+	upgrader := websocket.Upgrader{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/bot/login", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ApiKey string `json:"apiKey"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ApiKey != "secret" {
+			http.Error(w, "Invalid API key", http.StatusBadRequest)
+			return
+		}
+
+		chat.mu.Lock()
+		chat.logins++
+		token := fmt.Sprintf("token%d", chat.logins)
+		chat.mu.Unlock()
+
+		_ = json.NewEncoder(w).Encode(loginResponse{
+			Url:      "ws://" + r.Host + "/chat/ws.rtm.start?token=" + token,
+			Id:       "bot-id",
+			UserName: "robot",
+		})
+	})
+	mux.HandleFunc("/chat/ws.rtm.start", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("could not upgrade: %v", err)
+			return
+		}
+
+		chat.conns <- conn
+	})
+
+	chat.Server = httptest.NewServer(mux)
+
+	return chat
+}
+
+func (f *fakeChat) accept(t *testing.T) *websocket.Conn {
+	select {
+	case conn := <-f.conns:
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("bot did not connect")
+		return nil
+	}
+}
+
+func (f *fakeChat) loginCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.logins
+}
+
+func sendFrame(t *testing.T, conn *websocket.Conn, frameType, id string, payload interface{}) {
+	env, err := newEnvelope(frameType, id, payload)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if err := conn.WriteJSON(env); err != nil {
+		t.Fatalf("%v", err)
+	}
+}
+
+func readCommand(t *testing.T, conn *websocket.Conn) (Envelope, OutgoingMessage) {
+	var env Envelope
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&env); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	m := OutgoingMessage{}
+	assert.Equal(t, commandSendMessage, env.Type, "unexpected command")
+	assert.NoError(t, env.Decode(&m), "payload should be valid")
+
+	return env, m
+}
+
+func TestClient_Run(t *testing.T) {
+	chat := newFakeChat(t)
+	defer chat.Close()
+
+	bot := New(chat.URL, "secret", Options{MinBackoff: 10 * time.Millisecond})
+
+	messages := make(chan *Message, 10)
+	bot.HandleMessage(func(ctx context.Context, c *Client, m *Message) {
+		messages <- m
+	})
+	bot.HandleCommand("Echo", func(ctx context.Context, c *Client, cmd *Command) {
+		messages <- cmd.Message
+
+		err := c.Reply(ctx, cmd.Message, strings.Join(cmd.Args, "+")+"|"+cmd.Text)
+		if err != nil {
+			messages <- &Message{Text: "reply failed: " + err.Error()}
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- bot.Run(ctx)
+	}()
+
+	conn := chat.accept(t)
+
+	sendFrame(t, conn, EventMessage, "", Message{Id: 1, Author: "robot", Text: "own message"})
+	sendFrame(t, conn, EventMessage, "", Message{Id: 2, Author: "alice", Room: "general", Text: "hello"})
+	sendFrame(t, conn, EventMessage, "", Message{Id: 3, Author: "alice", To: "robot", Text: " !ECHO a  b "})
+
+	assert.Equal(t, uint(2), (<-messages).Id, "own messages should be skipped")
+	assert.Equal(t, uint(3), (<-messages).Id, "command should be handled")
+
+	env, reply := readCommand(t, conn)
+	assert.Equal(t, OutgoingMessage{Text: "a+b|a  b", To: "alice"}, reply, "direct messages are answered directly")
+	sendFrame(t, conn, EventAck, env.Id, Message{Id: 4, Author: "robot", To: "alice", Text: reply.Text})
+
+	sendFrame(t, conn, EventMessage, "", Message{Id: 5, Author: "alice", Room: "general", ParentId: 2, Text: "!echo"})
+	assert.Equal(t, uint(5), (<-messages).Id, "command should be handled")
+
+	env, reply = readCommand(t, conn)
+	assert.Equal(t, OutgoingMessage{Text: "|", Room: "general", ParentId: 2}, reply, "replies stay in the thread")
+	sendFrame(t, conn, EventError, env.Id, Error{Code: "forbidden", Message: "muted"})
+
+	failure := <-messages
+	assert.Equal(t, "reply failed: botsdk: forbidden: muted", failure.Text, "rejected messages should fail")
+
+	assert.Equal(t, Identity{Id: "bot-id", UserName: "robot"}, bot.Self())
+
+	// A lost connection is replaced by a new login.
+	_ = conn.Close()
+	conn = chat.accept(t)
+	assert.Equal(t, 2, chat.loginCount(), "bot should log in again")
+
+	sendFrame(t, conn, EventMessage, "", Message{Id: 6, Author: "alice", Text: "!unknown command"})
+	assert.Equal(t, uint(6), (<-messages).Id, "unknown commands are plain messages")
+
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+	}
+
+	assert.ErrorIs(t, bot.Send(context.Background(), OutgoingMessage{Text: "hi"}), ErrNotConnected)
+}
+
+func TestClient_Run_Unauthorized(t *testing.T) {
+	chat := newFakeChat(t)
+	defer chat.Close()
+
+	bot := New(chat.URL, "stolen", Options{MinBackoff: 10 * time.Millisecond})
+
+	err := bot.Run(context.Background())
+	assert.True(t, errors.Is(err, ErrUnauthorized), "unexpected error %v", err)
+}
+
+func TestClient_Run_Unavailable(t *testing.T) {
+	chat := newFakeChat(t)
+	chat.Close()
+
+	var mu sync.Mutex
+	attempts := 0
+
+	bot := New(chat.URL, "secret", Options{
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 4 * time.Millisecond,
+		Logf: func(format string, args ...interface{}) {
+			mu.Lock()
+			attempts++
+			mu.Unlock()
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, bot.Run(ctx), context.DeadlineExceeded)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Greater(t, attempts, 2, "bot should keep retrying")
+}
+
+func TestClient_parseCommand(t *testing.T) {
+	bot := New("http://localhost", "secret", Options{CommandPrefix: "/bot "})
+
+	tests := []struct {
+		text     string
+		wantName string
+		wantArgs []string
+		wantOk   bool
+	}{
+		{"/bot roll 2d6", "roll", []string{"2d6"}, true},
+		{"/bot", "", nil, false},
+		{"roll 2d6", "", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			cmd, ok := bot.parseCommand(&Message{Text: tt.text})
+
+			assert.Equal(t, tt.wantOk, ok)
+
+			if ok {
+				assert.Equal(t, tt.wantName, cmd.Name)
+				assert.Equal(t, tt.wantArgs, cmd.Args)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package botsdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// protocolVersion is the envelope version of the chat WebSocket protocol the client speaks.
+const protocolVersion = 1
+
+// Event types sent by the server, see api/asyncapi.yaml for their payloads.
+const (
+	EventMessage        = "message"
+	EventMessageEdited  = "message_edited"
+	EventMessageDeleted = "message_deleted"
+	EventPresence       = "presence"
+	EventUserJoined     = "user_joined"
+	EventUserLeft       = "user_left"
+	EventTyping         = "typing"
+	EventReactionAdded  = "reaction_added"
+	EventMention        = "mention"
+	EventSystem         = "system"
+	EventAck            = "ack"
+	EventError          = "error"
+)
+
+const commandSendMessage = "send_message"
+
+// Envelope wraps every frame exchanged over the chat WebSocket.
+type Envelope struct {
+	Version int             `json:"version"`
+	Type    string          `json:"type"`
+	Id      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Decode unmarshals the payload of the frame into v.
+func (e Envelope) Decode(v interface{}) error {
+	if len(e.Payload) == 0 {
+		return fmt.Errorf("botsdk: %s frame has no payload", e.Type)
+	}
+
+	return json.Unmarshal(e.Payload, v)
+}
+
+func newEnvelope(frameType, id string, payload interface{}) (Envelope, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	return Envelope{Version: protocolVersion, Type: frameType, Id: id, Payload: raw}, nil
+}
+
+// Message is a chat message received by the bot. Room is set for messages posted to a room,
+// To for direct messages, neither for messages of the public chat.
+type Message struct {
+	Id       uint      `json:"id"`
+	Author   string    `json:"author"`
+	Room     string    `json:"room,omitempty"`
+	To       string    `json:"to,omitempty"`
+	Text     string    `json:"message"`
+	SentAt   time.Time `json:"sentAt"`
+	ParentId uint      `json:"parentId,omitempty"`
+}
+
+// OutgoingMessage is a message posted by the bot. Messages naming neither Room nor To go to the public chat,
+// a ParentId posts a reply to the thread of that message.
+type OutgoingMessage struct {
+	Text     string `json:"message"`
+	Room     string `json:"room,omitempty"`
+	To       string `json:"to,omitempty"`
+	ParentId uint   `json:"parentId,omitempty"`
+}
+
+// Error is a command rejected by the server.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("botsdk: %s: %s", e.Code, e.Message)
+}
@@ -18,6 +18,8 @@ func stringWithCharset(length int, charset string) string {
 	return string(b)
 }
 
+// RandomString returns length alphanumeric characters from a clock seeded math/rand.
+// The result is predictable, use Token or SecureString for anything secret.
 func RandomString(length int) string {
 	return stringWithCharset(length, CHARSET)
 }
\ No newline at end of file
@@ -0,0 +1,98 @@
+package generators
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+)
+
+// Alphabets for SecureString.
+const (
+	NumericAlphabet      = "0123456789"
+	AlphanumericAlphabet = CHARSET
+	URLSafeAlphabet      = CHARSET + "-_"
+)
+
+// TokenBytes is the entropy of tokens generated by Token, 256 bits.
+const TokenBytes = 32
+
+var ErrInvalidAlphabet = errors.New("alphabet must consist of 2 to 256 distinct bytes")
+
+// RandomBytes returns n bytes read from crypto/rand.
+func RandomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Token returns TokenBytes random bytes as URL-safe base64 without padding, 43 characters long.
+// Use it for credentials such as session tokens and API keys.
+func Token() (string, error) {
+	return TokenWithEntropy(TokenBytes)
+}
+
+// TokenWithEntropy returns n random bytes as URL-safe base64 without padding.
+func TokenWithEntropy(n int) (string, error) {
+	b, err := RandomBytes(n)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// SecureString returns length characters sampled uniformly from alphabet with crypto/rand.
+// Random bytes are masked to the bit length of the alphabet size and rejected when out of range,
+// so no character is more likely than another.
+func SecureString(length int, alphabet string) (string, error) {
+	if !validAlphabet(alphabet) {
+		return "", ErrInvalidAlphabet
+	}
+
+	// Masked bytes fall into the alphabet more than half of the time.
+	mask := byte(0xff)
+	for int(mask>>1) >= len(alphabet)-1 {
+		mask >>= 1
+	}
+
+	result := make([]byte, 0, length)
+	buf := make([]byte, length+length/2+1)
+
+	for len(result) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+
+		for _, b := range buf {
+			if i := int(b & mask); i < len(alphabet) {
+				result = append(result, alphabet[i])
+
+				if len(result) == length {
+					break
+				}
+			}
+		}
+	}
+
+	return string(result), nil
+}
+
+func validAlphabet(alphabet string) bool {
+	if len(alphabet) < 2 || len(alphabet) > 256 {
+		return false
+	}
+
+	var seen [256]bool
+	for i := 0; i < len(alphabet); i++ {
+		if seen[alphabet[i]] {
+			return false
+		}
+
+		seen[alphabet[i]] = true
+	}
+
+	return true
+}
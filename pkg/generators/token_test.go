@@ -0,0 +1,143 @@
+package generators
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestToken(t *testing.T) {
+	const generateCount = 5
+
+	generatedTokens := make(map[string]bool, generateCount)
+
+	for i := 1; i <= generateCount; i++ {
+		token, err := Token()
+		if err != nil {
+			t.Fatalf("Token() error = %v", err)
+		}
+
+		generatedTokens[token] = true
+	}
+
+	if len(generatedTokens) != generateCount {
+		t.Errorf("Non-unique tokens generated")
+	}
+
+	for token := range generatedTokens {
+		if len(token) != 43 {
+			t.Errorf("Token %s has length %d, want 43", token, len(token))
+		}
+
+		b, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil || len(b) != TokenBytes {
+			t.Errorf("Token %s is not URL-safe base64 of %d bytes", token, TokenBytes)
+		}
+	}
+}
+
+func TestTokenWithEntropy(t *testing.T) {
+	tests := []struct {
+		name       string
+		bytes      int
+		wantLength int
+	}{
+		{"16 bytes", 16, 22},
+		{"24 bytes", 24, 32},
+		{"64 bytes", 64, 86},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := TokenWithEntropy(tt.bytes)
+			if err != nil {
+				t.Fatalf("TokenWithEntropy() error = %v", err)
+			}
+
+			if len(token) != tt.wantLength {
+				t.Errorf("TokenWithEntropy() length = %d, want %d", len(token), tt.wantLength)
+			}
+		})
+	}
+}
+
+func TestSecureString(t *testing.T) {
+	tests := []struct {
+		name     string
+		length   int
+		alphabet string
+		wantErr  error
+	}{
+		{"16 numeric chars", 16, NumericAlphabet, nil},
+		{"24 alphanumeric chars", 24, AlphanumericAlphabet, nil},
+		{"32 URL-safe chars", 32, URLSafeAlphabet, nil},
+		{"Binary", 64, "01", nil},
+		{"Empty string", 0, NumericAlphabet, nil},
+		{"Single character alphabet", 8, "a", ErrInvalidAlphabet},
+		{"Repeated characters", 8, "aab", ErrInvalidAlphabet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SecureString(tt.length, tt.alphabet)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SecureString() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if len(got) != tt.length {
+				t.Errorf("SecureString() length = %d, want %d", len(got), tt.length)
+			}
+
+			for _, char := range got {
+				if !strings.ContainsRune(tt.alphabet, char) {
+					t.Errorf("Unexpected character generated: %c", char)
+				}
+			}
+		})
+	}
+}
+
+func TestSecureString_Distribution(t *testing.T) {
+	const samples = 1000000
+
+	// With 10 characters a modulo of random bytes would favour the first 6 by 4%.
+	s, err := SecureString(samples, NumericAlphabet)
+	if err != nil {
+		t.Fatalf("SecureString() error = %v", err)
+	}
+
+	counts := make(map[rune]int, len(NumericAlphabet))
+	for _, char := range s {
+		counts[char]++
+	}
+
+	expected := samples / len(NumericAlphabet)
+	for _, char := range NumericAlphabet {
+		if deviation := counts[char] - expected; deviation > expected/50 || deviation < -expected/50 {
+			t.Errorf("Character %c generated %d times, want about %d", char, counts[char], expected)
+		}
+	}
+}
+
+func BenchmarkToken(b *testing.B) {
+	var r string
+
+	for n := 0; n < b.N; n++ {
+		r, _ = Token()
+	}
+
+	result = r
+}
+
+func BenchmarkSecureString16(b *testing.B) {
+	var r string
+
+	for n := 0; n < b.N; n++ {
+		r, _ = SecureString(16, AlphanumericAlphabet)
+	}
+
+	result = r
+}
@@ -0,0 +1,109 @@
+package hasher
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"math"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idId = "argon2id"
+
+// Argon2idParams are the cost parameters of argon2id, Memory is given in KiB.
+type Argon2idParams struct {
+	Memory     uint32
+	Time       uint32
+	Threads    uint8
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+// DefaultArgon2idParams follow the OWASP recommendation of 19 MiB of memory and two passes.
+var DefaultArgon2idParams = Argon2idParams{Memory: 19 * 1024, Time: 2, Threads: 1, SaltLength: 16, KeyLength: 32}
+
+// Argon2id hashes passwords with argon2id, encoded as $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>.
+type Argon2id struct {
+	params Argon2idParams
+}
+
+func NewArgon2id(params Argon2idParams) *Argon2id {
+	return &Argon2id{params: params}
+}
+
+func (a Argon2id) Hash(password string) (string, error) {
+	if len(password) == 0 {
+		return "", ErrEmptyPassword
+	}
+
+	salt, err := newSalt(int(a.params.SaltLength))
+	if err != nil {
+		return "", err
+	}
+
+	h := phcHash{
+		id:      argon2idId,
+		version: argon2.Version,
+		params: []phcParam{
+			{"m", int(a.params.Memory)},
+			{"t", int(a.params.Time)},
+			{"p", int(a.params.Threads)},
+		},
+		salt: salt,
+		hash: argon2.IDKey([]byte(password), salt, a.params.Time, a.params.Memory, a.params.Threads, a.params.KeyLength),
+	}
+
+	return h.String(), nil
+}
+
+func (a Argon2id) Verify(password, encoded string) (bool, error) {
+	h, params, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(password), h.salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+
+	return subtle.ConstantTimeCompare(key, h.hash) == 1, nil
+}
+
+func (a Argon2id) NeedsRehash(encoded string) bool {
+	_, params, err := decodeArgon2id(encoded)
+
+	return err != nil || params != a.params
+}
+
+func decodeArgon2id(encoded string) (phcHash, Argon2idParams, error) {
+	h, err := parsePHC(encoded, argon2idId)
+	if err != nil {
+		return phcHash{}, Argon2idParams{}, err
+	}
+
+	if h.version != argon2.Version {
+		return phcHash{}, Argon2idParams{}, fmt.Errorf("%w: unsupported argon2 version %d", ErrMalformedHash, h.version)
+	}
+
+	values := make(map[string]int, 3)
+	for _, name := range []string{"m", "t", "p"} {
+		value, err := h.param(name)
+		if err != nil {
+			return phcHash{}, Argon2idParams{}, err
+		}
+
+		values[name] = value
+	}
+
+	if values["m"] > math.MaxUint32 || values["t"] == 0 || values["t"] > math.MaxUint32 || values["p"] == 0 || values["p"] > math.MaxUint8 {
+		return phcHash{}, Argon2idParams{}, fmt.Errorf("%w: argon2id parameters out of range", ErrMalformedHash)
+	}
+
+	params := Argon2idParams{
+		Memory:     uint32(values["m"]),
+		Time:       uint32(values["t"]),
+		Threads:    uint8(values["p"]),
+		SaltLength: uint32(len(h.salt)),
+		KeyLength:  uint32(len(h.hash)),
+	}
+
+	return h, params, nil
+}
@@ -0,0 +1,81 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testArgon2idParams keep the tests fast.
+var testArgon2idParams = Argon2idParams{Memory: 64, Time: 1, Threads: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2id_Hash(t *testing.T) {
+	a := NewArgon2id(testArgon2idParams)
+
+	hash, err := a.Hash("12345678")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("Hash() = %v, want parameters in PHC format", hash)
+	}
+
+	if other, _ := a.Hash("12345678"); other == hash {
+		t.Errorf("Hash() should use a random salt")
+	}
+
+	if _, err := a.Hash(""); !errors.Is(err, ErrEmptyPassword) {
+		t.Errorf("Hash() error = %v, want %v", err, ErrEmptyPassword)
+	}
+}
+
+func TestArgon2id_Verify(t *testing.T) {
+	a := NewArgon2id(testArgon2idParams)
+	hash, _ := a.Hash("12345678")
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+		wantErr  error
+	}{
+		{"Correct password", "12345678", hash, true, nil},
+		{"Incorrect password", "87654321", hash, false, nil},
+		{"Empty password", "", hash, false, nil},
+		{"Other algorithm", "12345678", "$scrypt$ln=15,r=8,p=1$c2FsdA$aGFzaA", false, ErrUnknownAlgorithm},
+		{"Other version", "12345678", strings.Replace(hash, "v=19", "v=16", 1), false, ErrMalformedHash},
+		{"Missing parameter", "12345678", strings.Replace(hash, ",p=1", "", 1), false, ErrMalformedHash},
+		{"Truncated", "12345678", hash[:strings.LastIndex(hash, "$")], false, ErrMalformedHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.Verify(tt.password, tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArgon2id_NeedsRehash(t *testing.T) {
+	a := NewArgon2id(testArgon2idParams)
+	hash, _ := a.Hash("12345678")
+
+	stronger := testArgon2idParams
+	stronger.Time++
+
+	if a.NeedsRehash(hash) {
+		t.Errorf("NeedsRehash() = true for a hash with current parameters")
+	}
+	if !NewArgon2id(stronger).NeedsRehash(hash) {
+		t.Errorf("NeedsRehash() = false for a hash with other parameters")
+	}
+	if !a.NeedsRehash("ef797c8118f02dfb649607dd5d3f8c7623048c9c063d532cc95c5ed7a898a64f") {
+		t.Errorf("NeedsRehash() = false for a legacy hash")
+	}
+}
@@ -0,0 +1,80 @@
+package hasher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultBcryptCost takes about a quarter of a second on current hardware.
+const DefaultBcryptCost = 12
+
+// maxBcryptPasswordLength is the number of bytes bcrypt takes into account, the rest would be ignored.
+const maxBcryptPasswordLength = 72
+
+var ErrPasswordTooLong = fmt.Errorf("password is longer than %d bytes", maxBcryptPasswordLength)
+
+// Bcrypt hashes passwords with bcrypt in its own modular crypt format $2a$<cost>$<salt and hash>,
+// which records the parameters like a PHC string does.
+type Bcrypt struct {
+	cost int
+}
+
+func NewBcrypt(cost int) *Bcrypt {
+	return &Bcrypt{cost: cost}
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	if len(password) == 0 {
+		return "", ErrEmptyPassword
+	}
+
+	if len(password) > maxBcryptPasswordLength {
+		return "", ErrPasswordTooLong
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (b Bcrypt) Verify(password, encoded string) (bool, error) {
+	if !isBcrypt(encoded) {
+		return false, fmt.Errorf("%w: bcrypt hash expected", ErrUnknownAlgorithm)
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: %v", ErrMalformedHash, err)
+	}
+}
+
+func (b Bcrypt) NeedsRehash(encoded string) bool {
+	if !isBcrypt(encoded) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(encoded))
+
+	return err != nil || cost != b.cost
+}
+
+func isBcrypt(encoded string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(encoded, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
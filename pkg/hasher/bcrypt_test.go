@@ -0,0 +1,71 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcrypt_Hash(t *testing.T) {
+	b := NewBcrypt(bcrypt.MinCost)
+
+	hash, err := b.Hash("12345678")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	if !strings.HasPrefix(hash, "$2a$04$") {
+		t.Errorf("Hash() = %v, want cost in the hash", hash)
+	}
+
+	if _, err := b.Hash(""); !errors.Is(err, ErrEmptyPassword) {
+		t.Errorf("Hash() error = %v, want %v", err, ErrEmptyPassword)
+	}
+
+	if _, err := b.Hash(strings.Repeat("a", 73)); !errors.Is(err, ErrPasswordTooLong) {
+		t.Errorf("Hash() error = %v, want %v", err, ErrPasswordTooLong)
+	}
+}
+
+func TestBcrypt_Verify(t *testing.T) {
+	b := NewBcrypt(bcrypt.MinCost)
+	hash, _ := b.Hash("12345678")
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+		wantErr  error
+	}{
+		{"Correct password", "12345678", hash, true, nil},
+		{"Incorrect password", "87654321", hash, false, nil},
+		{"Other algorithm", "12345678", "$argon2id$v=19$m=64,t=1,p=1$c2FsdA$aGFzaA", false, ErrUnknownAlgorithm},
+		{"Truncated", "12345678", hash[:20], false, ErrMalformedHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := b.Verify(tt.password, tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBcrypt_NeedsRehash(t *testing.T) {
+	b := NewBcrypt(bcrypt.MinCost)
+	hash, _ := b.Hash("12345678")
+
+	if b.NeedsRehash(hash) {
+		t.Errorf("NeedsRehash() = true for a hash with current cost")
+	}
+	if !NewBcrypt(bcrypt.MinCost + 1).NeedsRehash(hash) {
+		t.Errorf("NeedsRehash() = false for a hash with other cost")
+	}
+}
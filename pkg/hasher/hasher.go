@@ -1,8 +1,9 @@
 /*
 Package hasher implements a simple library that allows to hash and verify passwords.
-Currently supports only SHA256
-
-TODO: Rework solution in v2, replace hardcoded hasher with a dynamic value, use struct for functions.
+Supports argon2id, bcrypt and scrypt. Hashes are encoded in the PHC string format
+(bcrypt in its own modular crypt format), so each hash records the algorithm and parameters
+it was computed with and can be verified after the defaults change.
+Unsalted SHA-256 digests of earlier versions are still verified, so they can be upgraded on login.
 
 Licensed under the Apache License, Version 2.0 (the "License");
 you may not use this file except in compliance with the License.
@@ -21,39 +22,90 @@ package hasher
 
 import (
 	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
-	"fmt"
-	"hash"
+	"strings"
+)
+
+var (
+	ErrEmptyPassword    = errors.New("no input supplied")
+	ErrUnknownAlgorithm = errors.New("unknown hash algorithm")
+	ErrMalformedHash    = errors.New("malformed hash")
 )
 
-var hasher hash.Hash
+// Hasher hashes passwords with a single algorithm. Implementations are safe for concurrent use.
+type Hasher interface {
+	// Hash returns the encoded hash of the password with a fresh random salt.
+	Hash(password string) (string, error)
+	// Verify reports whether the password matches the encoded hash, which must use the algorithm of the hasher.
+	// The parameters recorded in the hash are used, not those of the hasher.
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash reports whether the encoded hash was computed with another algorithm or other parameters.
+	NeedsRehash(encoded string) bool
+}
+
+// Default hashes new passwords.
+var Default Hasher = NewArgon2id(DefaultArgon2idParams)
 
-// Initializes SHA256 hash.Hash object.
-func init()  {
-	hasher = sha256.New()
+// Verify reports whether the password matches the encoded hash of any supported algorithm,
+// including legacy SHA-256 digests.
+func Verify(password, encoded string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$"+argon2idId+"$"):
+		return Argon2id{}.Verify(password, encoded)
+	case strings.HasPrefix(encoded, "$"+scryptId+"$"):
+		return Scrypt{}.Verify(password, encoded)
+	case isBcrypt(encoded):
+		return Bcrypt{}.Verify(password, encoded)
+	case isLegacy(encoded):
+		return verifyLegacy(password, encoded), nil
+	default:
+		return false, ErrUnknownAlgorithm
+	}
 }
 
 // HashPassword returns SHA256 hash for password string.
 // Returns error on empty string.
+//
+// Deprecated: unsalted SHA-256 is unfit for passwords, hash them with a Hasher such as Default.
 func HashPassword(password string) (string, error) {
 	if len(password) == 0 {
-		return "", errors.New("no input supplied")
+		return "", ErrEmptyPassword
 	}
 
-	hasher.Write([]byte(password))
-	sum := fmt.Sprintf("%x", hasher.Sum(nil))
-	hasher.Reset()
+	sum := sha256.Sum256([]byte(password))
 
-	return sum, nil
+	return hex.EncodeToString(sum[:]), nil
 }
 
 // CheckPasswordHash verifies if password matches provided hash.
+//
+// Deprecated: use Verify, which also accepts the hashes of every Hasher.
 func CheckPasswordHash(password, hash string) bool {
+	if len(password) == 0 {
+		return false
+	}
+
+	return verifyLegacy(password, hash)
+}
+
+// isLegacy tells SHA-256 hex digests of HashPassword apart from encoded hashes.
+func isLegacy(encoded string) bool {
+	if len(encoded) != hex.EncodedLen(sha256.Size) {
+		return false
+	}
+
+	_, err := hex.DecodeString(encoded)
+
+	return err == nil
+}
+
+func verifyLegacy(password, hash string) bool {
 	calculatedHash, err := HashPassword(password)
-	
 	if err != nil {
 		return false
 	}
-	
-	return calculatedHash == hash
-}
\ No newline at end of file
+
+	return subtle.ConstantTimeCompare([]byte(calculatedHash), []byte(strings.ToLower(hash))) == 1
+}
@@ -17,7 +17,10 @@ limitations under the License.
 
 package hasher
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestHashPassword(t *testing.T) {
 	type args struct {
@@ -71,3 +74,52 @@ func TestCheckPasswordHash(t *testing.T) {
 	}
 }
 
+
+func TestVerify(t *testing.T) {
+	argon2idHash, _ := NewArgon2id(testArgon2idParams).Hash("12345678")
+	scryptHash, _ := NewScrypt(testScryptParams).Hash("12345678")
+	bcryptHash, _ := NewBcrypt(4).Hash("12345678")
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+		wantErr  error
+	}{
+		{"Argon2id", "12345678", argon2idHash, true, nil},
+		{"Scrypt", "12345678", scryptHash, true, nil},
+		{"Bcrypt", "12345678", bcryptHash, true, nil},
+		{"Legacy", "12345678", "ef797c8118f02dfb649607dd5d3f8c7623048c9c063d532cc95c5ed7a898a64f", true, nil},
+		{"Legacy in upper case", "12345678", "EF797C8118F02DFB649607DD5D3F8C7623048C9C063D532CC95C5ED7A898A64F", true, nil},
+		{"Incorrect password", "87654321", argon2idHash, false, nil},
+		{"Incorrect legacy password", "87654321", "ef797c8118f02dfb649607dd5d3f8c7623048c9c063d532cc95c5ed7a898a64f", false, nil},
+		{"Unknown algorithm", "12345678", "$md5$c2FsdA$aGFzaA", false, ErrUnknownAlgorithm},
+		{"No hash", "12345678", "", false, ErrUnknownAlgorithm},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Verify(tt.password, tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	hash, err := Default.Hash("12345678")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	if ok, _ := Verify("12345678", hash); !ok {
+		t.Errorf("Verify() = false for the hash of Default")
+	}
+	if Default.NeedsRehash(hash) {
+		t.Errorf("NeedsRehash() = true for the hash of Default")
+	}
+}
@@ -0,0 +1,120 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// phcParam is a numeric parameter of a PHC string.
+type phcParam struct {
+	name  string
+	value int
+}
+
+// phcHash is a hash in the PHC string format: $id[$v=version]$param=value(,param=value)*$salt$hash,
+// salt and hash being base64 encoded without padding.
+type phcHash struct {
+	id      string
+	version int
+	params  []phcParam
+	salt    []byte
+	hash    []byte
+}
+
+func (h phcHash) String() string {
+	var b strings.Builder
+
+	b.WriteString("$" + h.id)
+
+	if h.version != 0 {
+		fmt.Fprintf(&b, "$v=%d", h.version)
+	}
+
+	params := make([]string, 0, len(h.params))
+	for _, p := range h.params {
+		params = append(params, fmt.Sprintf("%s=%d", p.name, p.value))
+	}
+
+	b.WriteString("$" + strings.Join(params, ","))
+	b.WriteString("$" + base64.RawStdEncoding.EncodeToString(h.salt))
+	b.WriteString("$" + base64.RawStdEncoding.EncodeToString(h.hash))
+
+	return b.String()
+}
+
+// param returns the value of the named parameter.
+func (h phcHash) param(name string) (int, error) {
+	for _, p := range h.params {
+		if p.name == name {
+			return p.value, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %s parameter is missing", ErrMalformedHash, name)
+}
+
+// parsePHC decodes a PHC string of the algorithm id.
+func parsePHC(encoded, id string) (phcHash, error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) < 5 || fields[0] != "" {
+		return phcHash{}, ErrMalformedHash
+	}
+
+	if fields[1] != id {
+		return phcHash{}, fmt.Errorf("%w: %s hash expected, got %s", ErrUnknownAlgorithm, id, fields[1])
+	}
+
+	h := phcHash{id: id}
+	fields = fields[2:]
+
+	if strings.HasPrefix(fields[0], "v=") {
+		version, err := strconv.Atoi(strings.TrimPrefix(fields[0], "v="))
+		if err != nil {
+			return phcHash{}, fmt.Errorf("%w: invalid version", ErrMalformedHash)
+		}
+
+		h.version = version
+		fields = fields[1:]
+	}
+
+	if len(fields) != 3 {
+		return phcHash{}, ErrMalformedHash
+	}
+
+	for _, param := range strings.Split(fields[0], ",") {
+		pair := strings.SplitN(param, "=", 2)
+		if len(pair) != 2 {
+			return phcHash{}, fmt.Errorf("%w: invalid parameter %s", ErrMalformedHash, param)
+		}
+
+		value, err := strconv.Atoi(pair[1])
+		if err != nil || value < 0 {
+			return phcHash{}, fmt.Errorf("%w: invalid parameter %s", ErrMalformedHash, param)
+		}
+
+		h.params = append(h.params, phcParam{name: pair[0], value: value})
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(fields[1]); err != nil || len(h.salt) == 0 {
+		return phcHash{}, fmt.Errorf("%w: invalid salt", ErrMalformedHash)
+	}
+
+	if h.hash, err = base64.RawStdEncoding.DecodeString(fields[2]); err != nil || len(h.hash) == 0 {
+		return phcHash{}, fmt.Errorf("%w: invalid hash", ErrMalformedHash)
+	}
+
+	return h, nil
+}
+
+func newSalt(length int) ([]byte, error) {
+	salt := make([]byte, length)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return salt, nil
+}
@@ -0,0 +1,112 @@
+package hasher
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const scryptId = "scrypt"
+
+// ScryptParams are the cost parameters of scrypt, the CPU/memory cost N is 2^LogN.
+type ScryptParams struct {
+	LogN       uint8
+	R          int
+	P          int
+	SaltLength int
+	KeyLength  int
+}
+
+// DefaultScryptParams use N=2^15, taking 32 MiB of memory per hash.
+var DefaultScryptParams = ScryptParams{LogN: 15, R: 8, P: 1, SaltLength: 16, KeyLength: 32}
+
+// Scrypt hashes passwords with scrypt, encoded as $scrypt$ln=<log2 N>,r=<r>,p=<p>$<salt>$<hash>.
+type Scrypt struct {
+	params ScryptParams
+}
+
+func NewScrypt(params ScryptParams) *Scrypt {
+	return &Scrypt{params: params}
+}
+
+func (s Scrypt) Hash(password string) (string, error) {
+	if len(password) == 0 {
+		return "", ErrEmptyPassword
+	}
+
+	salt, err := newSalt(s.params.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := scrypt.Key([]byte(password), salt, 1<<s.params.LogN, s.params.R, s.params.P, s.params.KeyLength)
+	if err != nil {
+		return "", err
+	}
+
+	h := phcHash{
+		id: scryptId,
+		params: []phcParam{
+			{"ln", int(s.params.LogN)},
+			{"r", s.params.R},
+			{"p", s.params.P},
+		},
+		salt: salt,
+		hash: key,
+	}
+
+	return h.String(), nil
+}
+
+func (s Scrypt) Verify(password, encoded string) (bool, error) {
+	h, params, err := decodeScrypt(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	key, err := scrypt.Key([]byte(password), h.salt, 1<<params.LogN, params.R, params.P, params.KeyLength)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrMalformedHash, err)
+	}
+
+	return subtle.ConstantTimeCompare(key, h.hash) == 1, nil
+}
+
+func (s Scrypt) NeedsRehash(encoded string) bool {
+	_, params, err := decodeScrypt(encoded)
+
+	return err != nil || params != s.params
+}
+
+func decodeScrypt(encoded string) (phcHash, ScryptParams, error) {
+	h, err := parsePHC(encoded, scryptId)
+	if err != nil {
+		return phcHash{}, ScryptParams{}, err
+	}
+
+	values := make(map[string]int, 3)
+	for _, name := range []string{"ln", "r", "p"} {
+		value, err := h.param(name)
+		if err != nil {
+			return phcHash{}, ScryptParams{}, err
+		}
+
+		values[name] = value
+	}
+
+	// scrypt.Key validates r and p, N must fit an int.
+	if values["ln"] < 1 || values["ln"] > 62 {
+		return phcHash{}, ScryptParams{}, fmt.Errorf("%w: scrypt parameters out of range", ErrMalformedHash)
+	}
+
+	params := ScryptParams{
+		LogN:       uint8(values["ln"]),
+		R:          values["r"],
+		P:          values["p"],
+		SaltLength: len(h.salt),
+		KeyLength:  len(h.hash),
+	}
+
+	return h, params, nil
+}
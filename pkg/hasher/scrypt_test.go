@@ -0,0 +1,78 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testScryptParams keep the tests fast.
+var testScryptParams = ScryptParams{LogN: 10, R: 8, P: 1, SaltLength: 16, KeyLength: 32}
+
+func TestScrypt_Hash(t *testing.T) {
+	s := NewScrypt(testScryptParams)
+
+	hash, err := s.Hash("12345678")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	if !strings.HasPrefix(hash, "$scrypt$ln=10,r=8,p=1$") {
+		t.Errorf("Hash() = %v, want parameters in PHC format", hash)
+	}
+
+	if other, _ := s.Hash("12345678"); other == hash {
+		t.Errorf("Hash() should use a random salt")
+	}
+
+	if _, err := s.Hash(""); !errors.Is(err, ErrEmptyPassword) {
+		t.Errorf("Hash() error = %v, want %v", err, ErrEmptyPassword)
+	}
+}
+
+func TestScrypt_Verify(t *testing.T) {
+	s := NewScrypt(testScryptParams)
+	hash, _ := s.Hash("12345678")
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+		wantErr  error
+	}{
+		{"Correct password", "12345678", hash, true, nil},
+		{"Incorrect password", "87654321", hash, false, nil},
+		// Generated with Python: hashlib.scrypt(b"password", salt=b"somesalt", n=1024, r=8, p=1, dklen=32)
+		{"Reference hash", "password", "$scrypt$ln=10,r=8,p=1$c29tZXNhbHQ$wdXoWEig5T693O7BJbufEPRk+qarG40BYOh1xe9tMAc", true, nil},
+		{"Other algorithm", "12345678", "$argon2id$v=19$m=64,t=1,p=1$c2FsdA$aGFzaA", false, ErrUnknownAlgorithm},
+		{"Invalid cost", "12345678", strings.Replace(hash, "ln=10", "ln=0", 1), false, ErrMalformedHash},
+		{"Invalid salt", "12345678", "$scrypt$ln=10,r=8,p=1$!$aGFzaA", false, ErrMalformedHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Verify(tt.password, tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScrypt_NeedsRehash(t *testing.T) {
+	s := NewScrypt(testScryptParams)
+	hash, _ := s.Hash("12345678")
+
+	if s.NeedsRehash(hash) {
+		t.Errorf("NeedsRehash() = true for a hash with current parameters")
+	}
+	if !NewScrypt(DefaultScryptParams).NeedsRehash(hash) {
+		t.Errorf("NeedsRehash() = false for a hash with other parameters")
+	}
+	if !s.NeedsRehash(hash[:len(hash)-1] + "$") {
+		t.Errorf("NeedsRehash() = false for a malformed hash")
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Package jwt implements the subset of JSON Web Tokens (RFC 7519) used for access tokens:
+compact serialization, HS256 and EdDSA (Ed25519) signatures and key rotation through the kid header.
+*/
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformed        = errors.New("jwt: malformed token")
+	ErrUnknownKey       = errors.New("jwt: unknown key")
+	ErrInvalidSignature = errors.New("jwt: invalid signature")
+	ErrExpired          = errors.New("jwt: token expired")
+)
+
+// Claims are the registered claims of a token, times are Unix timestamps.
+type Claims struct {
+	Subject   string `json:"sub"`
+	SessionId string `json:"sid,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	Id        string `json:"jti,omitempty"`
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyId     string `json:"kid"`
+}
+
+// KeySet signs tokens with its current key and verifies tokens signed with any of its keys,
+// so a new key can take over while tokens of the previous one are still valid.
+type KeySet struct {
+	current Key
+	keys    map[string]Key
+}
+
+// NewKeySet returns a key set signing with the key currentId.
+func NewKeySet(currentId string, keys ...Key) (*KeySet, error) {
+	s := &KeySet{keys: make(map[string]Key, len(keys))}
+
+	for _, k := range keys {
+		if _, ok := s.keys[k.Id]; ok {
+			return nil, fmt.Errorf("jwt: duplicate key %s", k.Id)
+		}
+
+		s.keys[k.Id] = k
+	}
+
+	current, ok := s.keys[currentId]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, currentId)
+	}
+
+	s.current = current
+
+	return s, nil
+}
+
+// Sign returns the token of the claims signed with the current key.
+func (s *KeySet) Sign(claims Claims) (string, error) {
+	h, err := json.Marshal(header{Algorithm: s.current.Algorithm, Type: "JWT", KeyId: s.current.Id})
+	if err != nil {
+		return "", err
+	}
+
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+
+	signature, err := s.current.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks the signature of the token and returns its claims unless the token expired at now.
+// The algorithm of the token must be that of its key, so tokens can not pick a weaker one.
+func (s *KeySet) Verify(token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrMalformed
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return Claims{}, err
+	}
+
+	key, ok := s.keys[h.KeyId]
+	if !ok {
+		return Claims{}, fmt.Errorf("%w: %s", ErrUnknownKey, h.KeyId)
+	}
+
+	if h.Algorithm != key.Algorithm {
+		return Claims{}, fmt.Errorf("%w: key %s does not use %s", ErrInvalidSignature, key.Id, h.Algorithm)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrMalformed
+	}
+
+	if !key.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return Claims{}, ErrInvalidSignature
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, err
+	}
+
+	if claims.ExpiresAt == 0 {
+		return Claims{}, fmt.Errorf("%w: no expiration", ErrMalformed)
+	}
+
+	if now.Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrExpired
+	}
+
+	return claims, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformed
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrMalformed
+	}
+
+	return nil
+}
+
+// Expiration returns the expiration time of the claims.
+func (c Claims) Expiration() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
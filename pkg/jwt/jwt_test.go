@@ -0,0 +1,190 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testSecret = []byte("0123456789abcdef0123456789abcdef")
+	testSeed   = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func testKeySet(t *testing.T, currentId string) *KeySet {
+	hs, err := NewHS256Key("hs", testSecret)
+	assert.NoError(t, err)
+
+	ed, err := NewEdDSAKey("ed", ed25519.NewKeyFromSeed(testSeed))
+	assert.NoError(t, err)
+
+	s, err := NewKeySet(currentId, hs, ed)
+	assert.NoError(t, err)
+
+	return s
+}
+
+func TestKeySet_SignVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	claims := Claims{Subject: "alice", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix(), Id: "1"}
+
+	for _, kid := range []string{"hs", "ed"} {
+		t.Run(kid, func(t *testing.T) {
+			s := testKeySet(t, kid)
+
+			token, err := s.Sign(claims)
+			assert.NoError(t, err)
+
+			got, err := s.Verify(token, now)
+			assert.NoError(t, err)
+			assert.Equal(t, claims, got)
+
+			_, err = s.Verify(token, now.Add(time.Minute))
+			assert.ErrorIs(t, err, ErrExpired)
+		})
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	claims := Claims{Subject: "alice", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()}
+
+	previous, err := testKeySet(t, "hs").Sign(claims)
+	assert.NoError(t, err)
+
+	rotated := testKeySet(t, "ed")
+
+	_, err = rotated.Verify(previous, now)
+	assert.NoError(t, err, "tokens of the previous key should stay valid")
+
+	token, err := rotated.Sign(claims)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"alg":"EdDSA","typ":"JWT","kid":"ed"}`, decode(t, strings.Split(token, ".")[0]))
+
+	hs, _ := NewHS256Key("hs", testSecret)
+	retired, err := NewKeySet("hs", hs)
+	assert.NoError(t, err)
+
+	_, err = retired.Verify(token, now)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeySet_Verify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := testKeySet(t, "hs")
+
+	token, _ := s.Sign(Claims{Subject: "alice", ExpiresAt: now.Add(time.Minute).Unix()})
+	parts := strings.Split(token, ".")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"mallory","exp":1800000000}`))
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT","kid":"hs"}`))
+	unknown := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT","kid":"other"}`))
+	noExpiration, _ := s.Sign(Claims{Subject: "alice"})
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"Valid", token, nil},
+		{"Two segments", parts[0] + "." + parts[1], ErrMalformed},
+		{"Invalid header", "e30." + parts[1] + "." + parts[2], ErrUnknownKey},
+		{"Not base64", "!." + parts[1] + "." + parts[2], ErrMalformed},
+		{"Forged claims", parts[0] + "." + forged + "." + parts[2], ErrInvalidSignature},
+		{"Algorithm none", none + "." + parts[1] + ".", ErrInvalidSignature},
+		{"Unknown key", unknown + "." + parts[1] + "." + parts[2], ErrUnknownKey},
+		{"No expiration", noExpiration, ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Verify(tt.token, now)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestKeySet_Verify_RFC7515 verifies the HS256 example of RFC 7515, appendix A.1.
+func TestKeySet_Verify_RFC7515(t *testing.T) {
+	secret, _ := base64.RawURLEncoding.DecodeString("AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow")
+	key, err := NewHS256Key("", secret)
+	assert.NoError(t, err)
+
+	s, err := NewKeySet("", key)
+	assert.NoError(t, err)
+
+	token := "eyJ0eXAiOiJKV1QiLA0KICJhbGciOiJIUzI1NiJ9" +
+		".eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ" +
+		".dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+	claims, err := s.Verify(token, time.Unix(1300819379, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1300819380), claims.ExpiresAt)
+}
+
+func TestNewKeySet(t *testing.T) {
+	hs, _ := NewHS256Key("hs", testSecret)
+
+	_, err := NewKeySet("ed", hs)
+	assert.ErrorIs(t, err, ErrUnknownKey, "the current key must be in the set")
+
+	_, err = NewKeySet("hs", hs, hs)
+	assert.Error(t, err, "key ids must be unique")
+}
+
+func TestParseKey(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString(testSecret)
+	seed := base64.StdEncoding.EncodeToString(testSeed)
+	private := base64.StdEncoding.EncodeToString(ed25519.NewKeyFromSeed(testSeed))
+
+	tests := []struct {
+		name          string
+		spec          string
+		wantAlgorithm string
+		wantErr       bool
+	}{
+		{"HS256", "2021-11:HS256:" + secret, HS256, false},
+		{"EdDSA seed", "2021-12:EdDSA:" + seed, EdDSA, false},
+		{"EdDSA private key", "2021-12:EdDSA:" + private, EdDSA, false},
+		{"Short HS256 secret", "2021-11:HS256:c2hvcnQ=", "", true},
+		{"Short EdDSA key", "2021-12:EdDSA:c2hvcnQ=", "", true},
+		{"Unsupported algorithm", "2021-11:RS256:" + secret, "", true},
+		{"Not base64", "2021-11:HS256:!", "", true},
+		{"No key id", ":HS256:" + secret, "", true},
+		{"Missing key", "2021-11:HS256", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParseKey(tt.spec)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidKey)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantAlgorithm, key.Algorithm)
+			assert.Equal(t, strings.Split(tt.spec, ":")[0], key.Id)
+		})
+	}
+
+	// Seed and private key of the same key sign alike.
+	fromSeed, _ := ParseKey("ed:EdDSA:" + seed)
+	fromPrivate, _ := ParseKey("ed:EdDSA:" + private)
+	a, _ := fromSeed.sign([]byte("input"))
+	b, _ := fromPrivate.sign([]byte("input"))
+	assert.Equal(t, a, b)
+}
+
+func decode(t *testing.T, segment string) string {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	assert.NoError(t, err)
+
+	return string(b)
+}
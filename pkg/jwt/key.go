@@ -0,0 +1,103 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Supported signature algorithms.
+const (
+	HS256 = "HS256"
+	EdDSA = "EdDSA"
+)
+
+// minHS256KeyLength is the size of the hash output, RFC 7518 forbids shorter keys.
+const minHS256KeyLength = 32
+
+var ErrInvalidKey = errors.New("jwt: invalid key")
+
+// Key signs and verifies tokens with a single algorithm. Id is sent as the kid header of the tokens it signs.
+type Key struct {
+	Id        string
+	Algorithm string
+
+	secret     []byte
+	privateKey ed25519.PrivateKey
+}
+
+// NewHS256Key returns a key signing with HMAC-SHA256, the secret must be at least 32 bytes long.
+func NewHS256Key(id string, secret []byte) (Key, error) {
+	if len(secret) < minHS256KeyLength {
+		return Key{}, fmt.Errorf("%w: %s secret must be at least %d bytes long", ErrInvalidKey, HS256, minHS256KeyLength)
+	}
+
+	return Key{Id: id, Algorithm: HS256, secret: secret}, nil
+}
+
+// NewEdDSAKey returns a key signing with Ed25519.
+func NewEdDSAKey(id string, privateKey ed25519.PrivateKey) (Key, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return Key{}, fmt.Errorf("%w: %s private key must be %d bytes long", ErrInvalidKey, EdDSA, ed25519.PrivateKeySize)
+	}
+
+	return Key{Id: id, Algorithm: EdDSA, privateKey: privateKey}, nil
+}
+
+// ParseKey parses a key given as <kid>:<algorithm>:<base64 key>. HS256 keys are the secret,
+// EdDSA keys the 32 bytes seed or the 64 bytes private key.
+func ParseKey(spec string) (Key, error) {
+	fields := strings.SplitN(spec, ":", 3)
+	if len(fields) != 3 || fields[0] == "" {
+		return Key{}, fmt.Errorf("%w: expected <kid>:<algorithm>:<base64 key>", ErrInvalidKey)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(fields[2])
+	if err != nil {
+		return Key{}, fmt.Errorf("%w: key %s is not base64 encoded", ErrInvalidKey, fields[0])
+	}
+
+	switch fields[1] {
+	case HS256:
+		return NewHS256Key(fields[0], raw)
+	case EdDSA:
+		if len(raw) == ed25519.SeedSize {
+			return NewEdDSAKey(fields[0], ed25519.NewKeyFromSeed(raw))
+		}
+
+		return NewEdDSAKey(fields[0], raw)
+	default:
+		return Key{}, fmt.Errorf("%w: unsupported algorithm %s", ErrInvalidKey, fields[1])
+	}
+}
+
+func (k Key) sign(signingInput []byte) ([]byte, error) {
+	switch k.Algorithm {
+	case HS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(signingInput)
+
+		return mac.Sum(nil), nil
+	case EdDSA:
+		return ed25519.Sign(k.privateKey, signingInput), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported algorithm %s", ErrInvalidKey, k.Algorithm)
+	}
+}
+
+func (k Key) verify(signingInput, signature []byte) bool {
+	switch k.Algorithm {
+	case HS256:
+		expected, _ := k.sign(signingInput)
+
+		return hmac.Equal(expected, signature)
+	case EdDSA:
+		return ed25519.Verify(k.privateKey.Public().(ed25519.PublicKey), signingInput, signature)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorage(t *testing.T) {
+	storage, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	ctx := context.Background()
+
+	assert.NoError(t, storage.Put(ctx, "attachments/cat.png", strings.NewReader("meow"), 4, "image/png"))
+
+	r, err := storage.Get(ctx, "attachments/cat.png")
+	if assert.NoError(t, err) {
+		content, _ := io.ReadAll(r)
+		r.Close()
+
+		assert.Equal(t, "meow", string(content))
+	}
+
+	assert.NoError(t, storage.Delete(ctx, "attachments/cat.png"))
+	assert.NoError(t, storage.Delete(ctx, "attachments/cat.png"), "deleting a missing object should succeed")
+
+	_, err = storage.Get(ctx, "attachments/cat.png")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	for _, key := range []string{"", "/", "../escape", "attachments/../../escape"} {
+		assert.Error(t, storage.Put(ctx, key, strings.NewReader("meow"), 4, "image/png"), "key %q should be refused", key)
+	}
+}
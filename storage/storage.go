@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+type StorageType string
+
+const (
+	Local StorageType = "local"
+	S3    StorageType = "s3"
+)
+
+// ErrNotFound is returned for keys that have no stored object.
+var ErrNotFound = errors.New("object not found")
+
+// Storage keeps uploaded files as opaque objects addressed by key.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
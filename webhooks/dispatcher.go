@@ -0,0 +1,300 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/id-tarzanych/lets-go-chat/configurations"
+	"github.com/id-tarzanych/lets-go-chat/db/webhook"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderEvent     = "X-Chat-Event"
+	HeaderDelivery  = "X-Chat-Delivery"
+	HeaderTimestamp = "X-Chat-Timestamp"
+	HeaderSignature = "X-Chat-Signature"
+)
+
+// deliveryBatchSize bounds the deliveries attempted at once.
+const deliveryBatchSize = 50
+
+// maxResponseSize bounds how much of a response is read before the connection is reused.
+const maxResponseSize = 64 << 10
+
+// Event is the JSON body posted to webhooks.
+type Event struct {
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// UserData describes the user of user.* events.
+type UserData struct {
+	Id       string `json:"id"`
+	UserName string `json:"userName"`
+	Bot      bool   `json:"bot,omitempty"`
+}
+
+func NewUserData(u *models.User) UserData {
+	return UserData{Id: string(u.ID), UserName: u.UserName, Bot: u.Bot}
+}
+
+// Dispatcher queues chat events for subscribed webhooks and delivers them in the background.
+// Deliveries are persisted first, so events survive restarts and failed attempts are retried with exponential backoff.
+type Dispatcher struct {
+	repo   webhook.WebhookRepository
+	cfg    configurations.Webhooks
+	client *http.Client
+	logger logrus.FieldLogger
+
+	wake chan struct{}
+}
+
+func NewDispatcher(repo webhook.WebhookRepository, cfg configurations.Webhooks, logger logrus.FieldLogger) *Dispatcher {
+	return &Dispatcher{
+		repo: repo,
+		cfg:  cfg,
+		client: &http.Client{
+			// A redirect is an unexpected answer, following it would resend the payload elsewhere.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		logger: logger,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Publish queues the event for every webhook subscribed to it.
+func (d *Dispatcher) Publish(ctx context.Context, event string, data interface{}) error {
+	hooks, err := d.repo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	body, err := json.Marshal(Event{Event: event, CreatedAt: now, Data: data})
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for _, hook := range hooks {
+		if !hook.Subscribes(event) {
+			continue
+		}
+
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     hook.ID,
+			Event:         event,
+			Payload:       string(body),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := d.repo.CreateDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Run delivers queued events whenever they are published and polls for due retries until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.DeliverDue(ctx, time.Now().UTC())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DeliverDue attempts every delivery due at now.
+func (d *Dispatcher) DeliverDue(ctx context.Context, now time.Time) {
+	hooks := make(map[uint]*models.Webhook)
+
+	for ctx.Err() == nil {
+		due, err := d.repo.GetDueDeliveries(ctx, now, deliveryBatchSize)
+		if err != nil {
+			d.logger.Errorln("Could not load webhook deliveries. ", err)
+			return
+		}
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		stored := true
+
+		for i := range due {
+			delivery := &due[i]
+
+			hook, ok := hooks[delivery.WebhookID]
+			if !ok {
+				h, err := d.repo.GetById(ctx, delivery.WebhookID)
+				switch {
+				case err == nil:
+					hook = &h
+				case !errors.Is(err, gorm.ErrRecordNotFound):
+					// The delivery stays due and is retried on the next poll.
+					d.logger.Errorln("Could not load webhook. ", err)
+
+					mu.Lock()
+					stored = false
+					mu.Unlock()
+
+					continue
+				}
+
+				hooks[delivery.WebhookID] = hook
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				if err := d.attempt(ctx, hook, delivery, now); err != nil {
+					d.logger.Errorln("Could not store webhook delivery. ", err)
+
+					mu.Lock()
+					stored = false
+					mu.Unlock()
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		// Unstored results stay due, they are retried on the next poll rather than in a loop.
+		if len(due) < deliveryBatchSize || !stored {
+			return
+		}
+	}
+}
+
+// attempt posts the delivery once and records the outcome, scheduling a retry on failure.
+// Deliveries of webhooks deleted in the meantime fail right away.
+func (d *Dispatcher) attempt(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery, now time.Time) error {
+	delivery.Attempts++
+
+	if hook == nil {
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = "webhook does not exist"
+
+		return d.repo.UpdateDelivery(ctx, delivery)
+	}
+
+	status, err := d.post(ctx, hook, delivery)
+	delivery.LastStatusCode = status
+
+	switch {
+	case err == nil:
+		delivered := time.Now().UTC()
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = &delivered
+		delivery.LastError = ""
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+
+	return d.repo.UpdateDelivery(ctx, delivery)
+}
+
+// backoff is the delay after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.MinBackoff
+	for i := 1; i < attempts && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > d.cfg.MaxBackoff {
+		delay = d.cfg.MaxBackoff
+	}
+
+	return delay
+}
+
+// post sends the delivery and returns the response status, any non-2xx answer is an error.
+func (d *Dispatcher) post(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.Url, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, []byte(delivery.Payload)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sign computes the X-Chat-Signature of a delivery: the hex encoded HMAC-SHA256 of the timestamp,
+// a dot and the body, keyed by the webhook secret. Receivers should also reject stale timestamps to prevent replays.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature computed by Sign in constant time.
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,166 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+
+	"github.com/id-tarzanych/lets-go-chat/configurations"
+	"github.com/id-tarzanych/lets-go-chat/mocks"
+	"github.com/id-tarzanych/lets-go-chat/models"
+)
+
+var testConfig = configurations.Webhooks{
+	Timeout:      time.Second,
+	MinBackoff:   time.Minute,
+	MaxBackoff:   10 * time.Minute,
+	MaxAttempts:  3,
+	PollInterval: time.Second,
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"user.joined"}`)
+	signature := Sign("secret", "1700000000", body)
+
+	assert.Equal(t, "sha256=", signature[:7])
+	assert.True(t, Verify("secret", "1700000000", body, signature))
+	assert.False(t, Verify("other", "1700000000", body, signature), "secret is part of the signature")
+	assert.False(t, Verify("secret", "1700000001", body, signature), "timestamp is part of the signature")
+	assert.False(t, Verify("secret", "1700000000", []byte(`{}`), signature), "body is part of the signature")
+}
+
+func TestDispatcher_Publish(t *testing.T) {
+	repoMock := &mocks.WebhookRepository{}
+
+	hooks := []models.Webhook{
+		*models.NewWebhook("http://a.example", "secret", []string{models.WebhookUserJoined}, "admin"),
+		*models.NewWebhook("http://b.example", "secret", []string{models.WebhookMessageCreated, models.WebhookUserLeft}, "admin"),
+	}
+	hooks[0].ID, hooks[1].ID = 1, 2
+
+	repoMock.On("GetAll", mock.Anything).Return(hooks, nil)
+	repoMock.On("CreateDeliveries", mock.Anything, mock.MatchedBy(func(deliveries []models.WebhookDelivery) bool {
+		return len(deliveries) == 1 &&
+			deliveries[0].WebhookID == 2 &&
+			deliveries[0].Event == models.WebhookUserLeft &&
+			deliveries[0].Status == models.DeliveryPending
+	})).Return(nil).Once()
+
+	d := NewDispatcher(repoMock, testConfig, &mocks.FieldLogger{})
+
+	assert.NoError(t, d.Publish(context.Background(), models.WebhookUserLeft, UserData{Id: "id", UserName: "alice"}))
+	assert.NoError(t, d.Publish(context.Background(), models.WebhookUserRegistered, UserData{Id: "id", UserName: "alice"}), "events without subscribers are dropped")
+
+	select {
+	case <-d.wake:
+	default:
+		t.Error("publishing should wake the dispatcher")
+	}
+
+	repoMock.AssertExpectations(t)
+}
+
+func TestDispatcher_DeliverDue(t *testing.T) {
+	repoMock := &mocks.WebhookRepository{}
+	loggerMock := &mocks.FieldLogger{}
+
+	var mu sync.Mutex
+	received := make(map[string]bool)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		received[r.Header.Get(HeaderDelivery)] = Verify("secret", r.Header.Get(HeaderTimestamp), body, r.Header.Get(HeaderSignature))
+		mu.Unlock()
+
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer receiver.Close()
+
+	working := *models.NewWebhook(receiver.URL+"/hook", "secret", models.WebhookEvents, "admin")
+	working.ID = 1
+	broken := *models.NewWebhook(receiver.URL+"/broken", "secret", models.WebhookEvents, "admin")
+	broken.ID = 2
+
+	now := time.Now().UTC()
+	payload := `{"event":"user.joined","data":{}}`
+
+	due := []models.WebhookDelivery{
+		{ID: 10, WebhookID: 1, Event: models.WebhookUserJoined, Payload: payload, Status: models.DeliveryPending},
+		{ID: 11, WebhookID: 2, Event: models.WebhookUserJoined, Payload: payload, Status: models.DeliveryPending},
+		{ID: 12, WebhookID: 2, Event: models.WebhookUserJoined, Payload: payload, Status: models.DeliveryPending, Attempts: 2},
+		{ID: 13, WebhookID: 3, Event: models.WebhookUserJoined, Payload: payload, Status: models.DeliveryPending},
+		{ID: 14, WebhookID: 4, Event: models.WebhookUserJoined, Payload: payload, Status: models.DeliveryPending},
+	}
+
+	repoMock.On("GetDueDeliveries", mock.Anything, now, deliveryBatchSize).Return(due, nil).Once()
+	repoMock.On("GetById", mock.Anything, uint(1)).Return(working, nil).Once()
+	repoMock.On("GetById", mock.Anything, uint(2)).Return(broken, nil).Once()
+	repoMock.On("GetById", mock.Anything, uint(3)).Return(models.Webhook{}, gorm.ErrRecordNotFound).Once()
+	repoMock.On("GetById", mock.Anything, uint(4)).Return(models.Webhook{}, errors.New("connection refused")).Once()
+	loggerMock.On("Errorln", "Could not load webhook. ", mock.Anything).Return().Once()
+
+	updated := make(map[uint]models.WebhookDelivery)
+	repoMock.On("UpdateDelivery", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		d := args.Get(1).(*models.WebhookDelivery)
+		updated[d.ID] = *d
+	})
+
+	NewDispatcher(repoMock, testConfig, loggerMock).DeliverDue(context.Background(), now)
+
+	assert.Equal(t, map[string]bool{"10": true, "11": true, "12": true}, received, "deliveries should be signed")
+
+	assert.Equal(t, models.DeliveryDelivered, updated[10].Status)
+	assert.Equal(t, 1, updated[10].Attempts)
+	assert.Equal(t, http.StatusOK, updated[10].LastStatusCode)
+	assert.NotNil(t, updated[10].DeliveredAt)
+
+	assert.Equal(t, models.DeliveryPending, updated[11].Status, "failed deliveries should be retried")
+	assert.Equal(t, http.StatusBadGateway, updated[11].LastStatusCode)
+	assert.Equal(t, now.Add(testConfig.MinBackoff), updated[11].NextAttemptAt)
+	assert.NotEmpty(t, updated[11].LastError)
+
+	assert.Equal(t, models.DeliveryFailed, updated[12].Status, "deliveries should give up after the last attempt")
+	assert.Equal(t, 3, updated[12].Attempts)
+
+	assert.Equal(t, models.DeliveryFailed, updated[13].Status, "deliveries of deleted webhooks should fail")
+
+	_, attempted := updated[14]
+	assert.False(t, attempted, "deliveries of webhooks that could not be loaded should stay pending")
+
+	repoMock.AssertExpectations(t)
+	loggerMock.AssertExpectations(t)
+}
+
+func TestDispatcher_backoff(t *testing.T) {
+	d := NewDispatcher(&mocks.WebhookRepository{}, testConfig, &mocks.FieldLogger{})
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{5, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, d.backoff(tt.attempts), "backoff after %d attempts", tt.attempts)
+	}
+}